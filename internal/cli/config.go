@@ -2,13 +2,20 @@ package cli
 
 import (
 	"fmt"
-	"os"
+	"strings"
 
+	"github.com/GodGMN/ghostmail-cli/internal/config"
+	"github.com/GodGMN/ghostmail-cli/internal/output"
+	emailtypes "github.com/GodGMN/ghostmail-cli/pkg/email"
 	"github.com/spf13/cobra"
 )
 
 const configTemplate = `# Ghostmail Configuration
-# Copy these environment variables to your shell profile or .env file
+# Copy these environment variables to your shell profile or .env file.
+# Several accounts can instead be defined in ~/.config/ghostmail/config.toml
+# and selected with --account (see 'ghostmail config show'). The SMTP, IMAP,
+# OAuth, DKIM, PGP and S/MIME variables below are ignored for an account
+# selected with --account or GHOSTMAIL_ACCOUNT.
 
 # SMTP Configuration (for sending emails)
 export GHOSTMAIL_SMTP_HOST="smtp.gmail.com"
@@ -17,6 +24,17 @@ export GHOSTMAIL_SMTP_USERNAME="your-email@gmail.com"
 export GHOSTMAIL_SMTP_PASSWORD="your-app-password"
 export GHOSTMAIL_SMTP_FROM="your-email@gmail.com"
 export GHOSTMAIL_SMTP_STARTTLS="true"
+# Instead of the password: a file, or the first line a command prints
+# (also for the IMAP password, OAuth secrets and key passphrases)
+# export GHOSTMAIL_SMTP_PASSWORD_FILE="/run/secrets/smtp_password"
+# export GHOSTMAIL_SMTP_PASSWORD_CMD="pass show mail/work"
+
+# DKIM signing of outgoing mail (enabled when a selector and key are set)
+# Generate a key and DNS record with 'ghostmail dkim keygen'
+# export GHOSTMAIL_DKIM_SELECTOR="mail"
+# export GHOSTMAIL_DKIM_KEY_FILE="$HOME/.config/ghostmail/dkim.pem"
+# export GHOSTMAIL_DKIM_DOMAIN="example.com"   # default: the From address's domain
+# export GHOSTMAIL_DKIM_HEADERS="From,To,Subject,Date,Message-ID"
 
 # IMAP Configuration (for reading emails)
 export GHOSTMAIL_IMAP_HOST="imap.gmail.com"
@@ -25,6 +43,47 @@ export GHOSTMAIL_IMAP_USERNAME="your-email@gmail.com"
 export GHOSTMAIL_IMAP_PASSWORD="your-app-password"
 export GHOSTMAIL_IMAP_USE_TLS="true"
 export GHOSTMAIL_IMAP_MAILBOX="INBOX"
+# Mailbox for copies of sent mail (default: the server's \Sent mailbox)
+# export GHOSTMAIL_IMAP_SENT_MAILBOX="Sent"
+
+# Local state such as scheduled and queued messages (default: ~/.local/share/ghostmail)
+# export GHOSTMAIL_DATA_DIR="$HOME/.local/share/ghostmail"
+
+# Named message templates for 'send --template' (default: ~/.config/ghostmail/templates)
+# export GHOSTMAIL_TEMPLATES_DIR="$HOME/.config/ghostmail/templates"
+
+# OpenPGP keys for 'send --sign/--encrypt' and 'read' (default: ~/.config/ghostmail/pgp)
+# export GHOSTMAIL_PGP_DIR="$HOME/.config/ghostmail/pgp"
+# export GHOSTMAIL_PGP_PASSPHRASE="your-key-passphrase"
+
+# Authentication mechanism per protocol: auto, plain, login, cram-md5,
+# external, xoauth2 or oauthbearer (default: auto, negotiated with the server)
+# export GHOSTMAIL_SMTP_AUTH="plain"
+# export GHOSTMAIL_IMAP_AUTH="plain"
+# Authorization identity, to act as another user (PLAIN and EXTERNAL)
+# export GHOSTMAIL_IMAP_AUTHZID="shared-mailbox@example.com"
+# TLS client certificate for EXTERNAL (key read from the certificate file if unset)
+# export GHOSTMAIL_SMTP_CLIENT_CERT="$HOME/certs/client.pem"
+# export GHOSTMAIL_SMTP_CLIENT_KEY="$HOME/certs/client.key"
+
+# OAuth 2.0 instead of passwords (XOAUTH2/OAUTHBEARER, for Gmail and Microsoft 365)
+# Either an access token, a file or a command printing one...
+# export GHOSTMAIL_OAUTH_TOKEN_CMD="oauth2l fetch --credentials client.json --scope https://mail.google.com/"
+# export GHOSTMAIL_OAUTH_TOKEN_FILE="/run/secrets/mail-token"
+# ...or a refresh token exchanged at the token endpoint (cached in the data directory)
+# export GHOSTMAIL_OAUTH_TOKEN_URL="https://oauth2.googleapis.com/token"
+# export GHOSTMAIL_OAUTH_CLIENT_ID="your-client-id"
+# export GHOSTMAIL_OAUTH_CLIENT_SECRET="your-client-secret"
+# export GHOSTMAIL_OAUTH_REFRESH_TOKEN="your-refresh-token"
+# export GHOSTMAIL_OAUTH_SCOPES="https://mail.google.com/"
+
+# S/MIME certificate for 'send --smime-sign/--smime-encrypt' and 'read'
+# export GHOSTMAIL_SMIME_CERT="$HOME/certs/me.p12"
+# export GHOSTMAIL_SMIME_PASSWORD="your-pkcs12-password"
+# Recipients' certificates (default: ~/.config/ghostmail/smime)
+# export GHOSTMAIL_SMIME_CERTS_DIR="$HOME/.config/ghostmail/smime"
+# Trusted CA certificates for verifying signatures (default: system roots)
+# export GHOSTMAIL_SMIME_TRUST_STORE="/etc/ssl/certs/ca-certificates.crt"
 `
 
 func newConfigCmd() *cobra.Command {
@@ -33,11 +92,16 @@ func newConfigCmd() *cobra.Command {
 		Short: "Configuration helper commands",
 		Long: `Helper commands for managing ghostmail configuration.
 
-Environment variables are used for all configuration. No config files needed.
+Settings come from environment variables and, optionally, a configuration
+file with named accounts ($XDG_CONFIG_HOME/ghostmail/config.toml or
+--config). Environment variables take precedence over the file, except
+for an account selected with --account or GHOSTMAIL_ACCOUNT, whose
+settings only come from the file.
 
 COMMANDS:
   example  Print example configuration with all env vars
   check    Verify that required environment variables are set
+  show     Show the accounts, or with --effective the merged settings
 
 EXAMPLES:
   # Print example configuration
@@ -46,6 +110,9 @@ EXAMPLES:
   # Check current configuration
   ghostmail config check
 
+  # Show the settings of an account after merging file and environment
+  ghostmail config show --effective --account work
+
   # Source example config (edit first!)
   eval "$(ghostmail config example)"
 
@@ -54,6 +121,7 @@ For more help, use: ghostmail config --help`,
 
 	cmd.AddCommand(newConfigExampleCmd())
 	cmd.AddCommand(newConfigCheckCmd())
+	cmd.AddCommand(newConfigShowCmd())
 
 	return cmd
 }
@@ -91,33 +159,50 @@ func newConfigCheckCmd() *cobra.Command {
 		Long: `Verifies that all required environment variables are set.
 
 Checks for the presence of SMTP and IMAP configuration variables
-and shows which ones are set or missing.
+and shows which ones are set or missing. For an account selected with
+--account or GHOSTMAIL_ACCOUNT, they are checked in the configuration
+file only: the environment variables do not apply to it.
 
 EXAMPLE:
   ghostmail config check
 
 TIP: If configuration is missing, run 'ghostmail config example' to see
-what variables need to be set.`,
+what variables need to be set. To test that the settings work, run
+'ghostmail doctor', which connects to the servers and logs in.`,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			// This will be implemented to check config
 			fmt.Println("Checking configuration...")
 
+			// Passwords are not needed with OAuth or EXTERNAL. Secrets that
+			// failed to load are reported below.
+			cfg, err := config.Load()
+			if err != nil {
+				return err
+			}
+			cfg.ResolveAllSecrets()
+			if cfg.File != "" {
+				fmt.Printf("Using account %q from %s\n", cfg.Account, cfg.File)
+			}
+			oauth := cfg.SMTP.OAuth.Enabled()
+			smtpNote := passwordNote(cfg.SMTP.Auth, oauth)
+			imapNote := passwordNote(cfg.IMAP.Auth, oauth)
+
 			vars := []struct {
 				name  string
 				value string
 				smtp  bool
 				imap  bool
 			}{
-				{"GHOSTMAIL_SMTP_HOST", os.Getenv("GHOSTMAIL_SMTP_HOST"), true, false},
-				{"GHOSTMAIL_SMTP_PORT", os.Getenv("GHOSTMAIL_SMTP_PORT"), true, false},
-				{"GHOSTMAIL_SMTP_USERNAME", os.Getenv("GHOSTMAIL_SMTP_USERNAME"), true, false},
-				{"GHOSTMAIL_SMTP_PASSWORD", maskPassword(os.Getenv("GHOSTMAIL_SMTP_PASSWORD")), true, false},
-				{"GHOSTMAIL_SMTP_FROM", os.Getenv("GHOSTMAIL_SMTP_FROM"), true, false},
-				{"GHOSTMAIL_IMAP_HOST", os.Getenv("GHOSTMAIL_IMAP_HOST"), false, true},
-				{"GHOSTMAIL_IMAP_PORT", os.Getenv("GHOSTMAIL_IMAP_PORT"), false, true},
-				{"GHOSTMAIL_IMAP_USERNAME", os.Getenv("GHOSTMAIL_IMAP_USERNAME"), false, true},
-				{"GHOSTMAIL_IMAP_PASSWORD", maskPassword(os.Getenv("GHOSTMAIL_IMAP_PASSWORD")), false, true},
-				{"GHOSTMAIL_IMAP_MAILBOX", os.Getenv("GHOSTMAIL_IMAP_MAILBOX"), false, true},
+				{"GHOSTMAIL_SMTP_HOST", settingValue(cfg, "GHOSTMAIL_SMTP_HOST"), true, false},
+				{"GHOSTMAIL_SMTP_PORT", settingValue(cfg, "GHOSTMAIL_SMTP_PORT"), true, false},
+				{"GHOSTMAIL_SMTP_USERNAME", settingValue(cfg, "GHOSTMAIL_SMTP_USERNAME"), true, false},
+				{"GHOSTMAIL_SMTP_PASSWORD", maskPassword(cfg.SMTP.Password), true, false},
+				{"GHOSTMAIL_SMTP_FROM", settingValue(cfg, "GHOSTMAIL_SMTP_FROM"), true, false},
+				{"GHOSTMAIL_IMAP_HOST", settingValue(cfg, "GHOSTMAIL_IMAP_HOST"), false, true},
+				{"GHOSTMAIL_IMAP_PORT", settingValue(cfg, "GHOSTMAIL_IMAP_PORT"), false, true},
+				{"GHOSTMAIL_IMAP_USERNAME", settingValue(cfg, "GHOSTMAIL_IMAP_USERNAME"), false, true},
+				{"GHOSTMAIL_IMAP_PASSWORD", maskPassword(cfg.IMAP.Password), false, true},
+				{"GHOSTMAIL_IMAP_MAILBOX", settingValue(cfg, "GHOSTMAIL_IMAP_MAILBOX"), false, true},
 			}
 
 			fmt.Println("\nSMTP Configuration:")
@@ -128,12 +213,29 @@ what variables need to be set.`,
 					continue
 				}
 				status := "✓"
+				if v.value == "" && smtpNote != "" && strings.HasSuffix(v.name, "_PASSWORD") {
+					fmt.Printf("  - %s (%s)\n", v.name, smtpNote)
+					continue
+				}
+				if src, ok := cfg.Secrets[v.name]; ok {
+					if !printSecret(v.name, v.value, src) {
+						smtpOK = false
+					}
+					continue
+				}
 				if v.value == "" {
 					status = "✗"
 					smtpOK = false
 				}
 				fmt.Printf("  %s %s=%s\n", status, v.name, v.value)
 			}
+			printAuthConfig(cfg, "SMTP")
+			if smtpOK {
+				if err := cfg.ValidateSMTP(); err != nil {
+					fmt.Printf("  ✗ %v\n", err)
+					smtpOK = false
+				}
+			}
 
 			fmt.Println("\nIMAP Configuration:")
 			fmt.Println("-------------------")
@@ -143,12 +245,75 @@ what variables need to be set.`,
 					continue
 				}
 				status := "✓"
+				if v.value == "" && imapNote != "" && strings.HasSuffix(v.name, "_PASSWORD") {
+					fmt.Printf("  - %s (%s)\n", v.name, imapNote)
+					continue
+				}
+				if src, ok := cfg.Secrets[v.name]; ok {
+					if !printSecret(v.name, v.value, src) {
+						imapOK = false
+					}
+					continue
+				}
 				if v.value == "" {
 					status = "✗"
 					imapOK = false
 				}
 				fmt.Printf("  %s %s=%s\n", status, v.name, v.value)
 			}
+			printAuthConfig(cfg, "IMAP")
+			if imapOK {
+				if err := cfg.ValidateIMAP(); err != nil {
+					fmt.Printf("  ✗ %v\n", err)
+					imapOK = false
+				}
+			}
+
+			if oauth {
+				fmt.Println("\nOAuth Configuration:")
+				fmt.Println("--------------------")
+				for _, v := range []struct{ name, value string }{
+					{"GHOSTMAIL_OAUTH_TOKEN", maskPassword(cfg.SMTP.OAuth.Token)},
+					{"GHOSTMAIL_OAUTH_TOKEN_FILE", settingValue(cfg, "GHOSTMAIL_OAUTH_TOKEN_FILE")},
+					{"GHOSTMAIL_OAUTH_TOKEN_CMD", settingValue(cfg, "GHOSTMAIL_OAUTH_TOKEN_CMD")},
+					{"GHOSTMAIL_OAUTH_TOKEN_URL", settingValue(cfg, "GHOSTMAIL_OAUTH_TOKEN_URL")},
+					{"GHOSTMAIL_OAUTH_CLIENT_ID", settingValue(cfg, "GHOSTMAIL_OAUTH_CLIENT_ID")},
+					{"GHOSTMAIL_OAUTH_CLIENT_SECRET", maskPassword(cfg.SMTP.OAuth.ClientSecret)},
+					{"GHOSTMAIL_OAUTH_REFRESH_TOKEN", maskPassword(cfg.SMTP.OAuth.RefreshToken)},
+					{"GHOSTMAIL_OAUTH_SCOPES", settingValue(cfg, "GHOSTMAIL_OAUTH_SCOPES")},
+				} {
+					if src, ok := cfg.Secrets[v.name]; ok {
+						if !printSecret(v.name, v.value, src) {
+							smtpOK, imapOK = false, false
+						}
+					} else if v.value != "" {
+						fmt.Printf("  ✓ %s=%s\n", v.name, v.value)
+					}
+				}
+				if err := cfg.SMTP.OAuth.Validate(); err != nil {
+					fmt.Printf("  ✗ %v\n", err)
+					smtpOK, imapOK = false, false
+				}
+			}
+
+			// Secrets of optional features
+			var other []string
+			for _, name := range []string{"GHOSTMAIL_PGP_PASSPHRASE", "GHOSTMAIL_SMIME_PASSWORD"} {
+				if _, ok := cfg.Secrets[name]; ok {
+					other = append(other, name)
+				}
+			}
+			if len(other) > 0 {
+				fmt.Println("\nOther Secrets:")
+				fmt.Println("--------------")
+				values := map[string]string{
+					"GHOSTMAIL_PGP_PASSPHRASE": cfg.PGP.Passphrase,
+					"GHOSTMAIL_SMIME_PASSWORD": cfg.SMIME.Password,
+				}
+				for _, name := range other {
+					printSecret(name, maskPassword(values[name]), cfg.Secrets[name])
+				}
+			}
 
 			fmt.Println()
 			if smtpOK && imapOK {
@@ -163,6 +328,140 @@ what variables need to be set.`,
 	}
 }
 
+func newConfigShowCmd() *cobra.Command {
+	var effective bool
+
+	cmd := &cobra.Command{
+		Use:   "show",
+		Short: "Show accounts and effective settings",
+		Long: `Shows the configuration file and the accounts it defines. The selected
+account is marked with *.
+
+With --effective, shows every setting of the selected account after
+merging, with the source of its value. Precedence is command flags, then
+environment variables, then the configuration file, then the defaults;
+the settings of an account selected with --account or GHOSTMAIL_ACCOUNT
+are not taken from the environment. Secrets are masked.
+
+EXAMPLES:
+  ghostmail config show
+  ghostmail config show --effective
+  ghostmail config show --effective --account personal --json`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := config.Load()
+			if err != nil {
+				return handleError(err)
+			}
+			err = cfg.ResolveAllSecrets()
+
+			resp := emailtypes.ConfigShowResponse{
+				Success:  true,
+				File:     cfg.File,
+				Account:  cfg.Account,
+				Accounts: cfg.Accounts,
+			}
+			if effective {
+				for _, setting := range cfg.Settings {
+					value := setting.Value
+					if setting.Secret {
+						value = maskPassword(value)
+					}
+					resp.Settings = append(resp.Settings, emailtypes.ConfigSetting{Name: setting.Name, Value: value, Source: setting.Source})
+				}
+			}
+			if err != nil {
+				resp.Error = err.Error()
+			}
+
+			if jsonOutput {
+				return output.NewJSONOutput(true).Print(resp)
+			}
+
+			if cfg.File == "" {
+				fmt.Printf("Configuration file: none (%s does not exist)\n", config.DefaultFile())
+			} else {
+				fmt.Printf("Configuration file: %s\n", cfg.File)
+			}
+			if len(cfg.Accounts) > 0 {
+				fmt.Println("\nAccounts:")
+				for _, name := range cfg.Accounts {
+					marker := " "
+					if name == cfg.Account {
+						marker = "*"
+					}
+					fmt.Printf("  %s %s\n", marker, name)
+				}
+			}
+			if effective {
+				fmt.Println("\nEffective settings:")
+				for _, setting := range resp.Settings {
+					fmt.Printf("  %s=%s (%s)\n", setting.Name, setting.Value, setting.Source)
+				}
+			}
+			if err != nil {
+				fmt.Printf("\n✗ %v\n", err)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&effective, "effective", false, "Show the merged settings of the selected account and their sources")
+
+	return cmd
+}
+
+// passwordNote returns why a protocol authenticating with a mechanism
+// needs no password, or "" if it does.
+func passwordNote(auth string, oauth bool) string {
+	switch auth {
+	case config.AuthExternal:
+		return "not needed with EXTERNAL"
+	case config.AuthXOAuth2, config.AuthOAuthBearer:
+		return "not needed with OAuth"
+	case config.AuthAuto, "":
+		if oauth {
+			return "not needed with OAuth"
+		}
+	}
+	return ""
+}
+
+// printAuthConfig shows the optional authentication settings of a protocol
+// that are set.
+func printAuthConfig(cfg *config.Config, protocol string) {
+	for _, name := range []string{"AUTHZID", "CLIENT_CERT", "CLIENT_KEY"} {
+		name = "GHOSTMAIL_" + protocol + "_" + name
+		if value := settingValue(cfg, name); value != "" {
+			fmt.Printf("  ✓ %s=%s\n", name, value)
+		}
+	}
+	if name := "GHOSTMAIL_" + protocol + "_AUTH"; settingValue(cfg, name) != config.AuthAuto {
+		fmt.Printf("  ✓ %s=%s\n", name, settingValue(cfg, name))
+	}
+}
+
+// settingValue returns the effective value of a setting, or "" if it is
+// not set.
+func settingValue(cfg *config.Config, name string) string {
+	for _, setting := range cfg.Settings {
+		if setting.Name == name {
+			return setting.Value
+		}
+	}
+	return ""
+}
+
+// printSecret shows a masked secret with the source it came from, or why
+// reading it failed. It reports whether the secret was read.
+func printSecret(name, masked string, src config.SecretSource) bool {
+	if src.Err != nil {
+		fmt.Printf("  ✗ %s (from %s): %v\n", name, src, src.Err)
+		return false
+	}
+	fmt.Printf("  ✓ %s=%s (from %s)\n", name, masked, src)
+	return true
+}
+
 func maskPassword(s string) string {
 	if s == "" {
 		return ""
@@ -0,0 +1,179 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/GodGMN/ghostmail-cli/internal/config"
+	emailinternal "github.com/GodGMN/ghostmail-cli/internal/email"
+	"github.com/GodGMN/ghostmail-cli/internal/output"
+	emailtypes "github.com/GodGMN/ghostmail-cli/pkg/email"
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+func newDKIMCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "dkim",
+		Short: "Manage DKIM signing keys",
+		Long: `Commands for DKIM signing of outgoing mail.
+
+Outgoing messages are signed (relaxed/relaxed, SHA-256) when a selector and
+a private key are configured:
+
+  GHOSTMAIL_DKIM_SELECTOR   Selector of the published key
+  GHOSTMAIL_DKIM_KEY_FILE   PEM private key file (RSA or Ed25519)
+  GHOSTMAIL_DKIM_KEY        PEM private key contents, instead of a file
+  GHOSTMAIL_DKIM_DOMAIN     Signing domain (default: the From address's domain)
+  GHOSTMAIL_DKIM_HEADERS    Comma-separated header fields to sign
+
+COMMANDS:
+  keygen  Generate a key and print the DNS record to publish
+
+EXAMPLES:
+  # Generate a key for selector "mail"
+  ghostmail dkim keygen --selector mail --domain example.com --key-file dkim.pem
+
+For more help, use: ghostmail dkim --help`,
+	}
+
+	cmd.AddCommand(newDKIMKeygenCmd())
+
+	return cmd
+}
+
+func newDKIMKeygenCmd() *cobra.Command {
+	var (
+		keyType  string
+		bits     int
+		selector string
+		domain   string
+		keyFile  string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "keygen",
+		Short: "Generate a DKIM key",
+		Long: `Generate a DKIM private key and print the DNS TXT record that publishes
+its public key.
+
+The key is written to --key-file with mode 0600; an existing file is never
+overwritten. The selector and domain default to GHOSTMAIL_DKIM_SELECTOR and
+GHOSTMAIL_DKIM_DOMAIN, then to the domain of the configured sender.
+
+Ed25519 keys are short, but not every receiver verifies them yet. Sign with
+an RSA key unless you know your recipients support Ed25519.
+
+REQUIRED FLAGS:
+  --selector   Selector of the key (or GHOSTMAIL_DKIM_SELECTOR)
+  --key-file   File to write the private key to
+
+EXAMPLES:
+  # Generate a 2048-bit RSA key
+  ghostmail dkim keygen --selector mail --domain example.com --key-file dkim.pem
+
+  # Generate an Ed25519 key
+  ghostmail dkim keygen --type ed25519 --selector ed --key-file dkim-ed.pem
+
+  # Then enable signing with it
+  export GHOSTMAIL_DKIM_SELECTOR=mail
+  export GHOSTMAIL_DKIM_KEY_FILE=dkim.pem
+
+For more help, use: ghostmail dkim keygen --help`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// Load configuration
+			cfg, err := config.Load()
+			if err != nil {
+				return handleError(err)
+			}
+
+			if selector == "" {
+				selector = cfg.SMTP.DKIM.Selector
+			}
+			if selector == "" {
+				return handleError(fmt.Errorf("--selector is required. Use --help for usage info"))
+			}
+			if keyFile == "" {
+				return handleError(fmt.Errorf("--key-file is required. Use --help for usage info"))
+			}
+			if domain == "" {
+				domain = cfg.SMTP.DKIM.Domain
+			}
+			if domain == "" {
+				from := cfg.SMTP.From
+				if from == "" {
+					from = cfg.SMTP.Username
+				}
+				if at := strings.LastIndex(from, "@"); at >= 0 {
+					domain = strings.TrimSuffix(from[at+1:], ">")
+				}
+			}
+			if domain == "" {
+				return handleError(fmt.Errorf("--domain is required when no sender is configured. Use --help for usage info"))
+			}
+
+			keyType = strings.ToLower(keyType)
+			key, record, err := emailinternal.GenerateDKIMKey(keyType, bits)
+			if err != nil {
+				return handleError(err)
+			}
+
+			if dir := filepath.Dir(keyFile); dir != "" {
+				if err := os.MkdirAll(dir, 0700); err != nil {
+					return handleError(fmt.Errorf("failed to create key directory: %w", err))
+				}
+			}
+			f, err := os.OpenFile(keyFile, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+			if err != nil {
+				return handleError(fmt.Errorf("failed to write key: %w", err))
+			}
+			if _, err := f.Write(key); err != nil {
+				f.Close()
+				return handleError(fmt.Errorf("failed to write key: %w", err))
+			}
+			if err := f.Close(); err != nil {
+				return handleError(fmt.Errorf("failed to write key: %w", err))
+			}
+
+			name := selector + "._domainkey." + domain
+
+			// Output
+			if jsonOutput {
+				resp := emailtypes.DKIMKeygenResponse{
+					Success: true,
+					KeyFile: keyFile,
+					KeyType: keyType,
+					Name:    name,
+					Value:   record,
+				}
+				return output.NewJSONOutput(true).Print(resp)
+			}
+
+			if noColor {
+				fmt.Printf("✓ Private key written to %s\n", keyFile)
+			} else {
+				color.Green("✓ Private key written to %s", keyFile)
+			}
+			fmt.Println("\nPublish this DNS record:")
+			fmt.Println()
+
+			parts := emailinternal.SplitTXT(record)
+			for i := range parts {
+				parts[i] = `"` + parts[i] + `"`
+			}
+			fmt.Printf("%s. IN TXT ( %s )\n", name, strings.Join(parts, "\n    "))
+
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&keyType, "type", emailinternal.DKIMKeyRSA, "Key type: rsa or ed25519")
+	cmd.Flags().IntVar(&bits, "bits", 2048, "RSA key size in bits")
+	cmd.Flags().StringVar(&selector, "selector", "", "Selector of the key")
+	cmd.Flags().StringVar(&domain, "domain", "", "Signing domain (default: the sender's domain)")
+	cmd.Flags().StringVar(&keyFile, "key-file", "", "File to write the private key to")
+
+	return cmd
+}
@@ -0,0 +1,218 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/GodGMN/ghostmail-cli/internal/config"
+	emailinternal "github.com/GodGMN/ghostmail-cli/internal/email"
+	"github.com/GodGMN/ghostmail-cli/internal/output"
+	emailtypes "github.com/GodGMN/ghostmail-cli/pkg/email"
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+// checkSymbols mark the result of a diagnostic check.
+var checkSymbols = map[string]string{
+	emailtypes.CheckOK:      "✓",
+	emailtypes.CheckWarning: "!",
+	emailtypes.CheckFailed:  "✗",
+	emailtypes.CheckSkipped: "-",
+}
+
+func newDoctorCmd() *cobra.Command {
+	var (
+		smtpOnly bool
+		imapOnly bool
+		timeout  time.Duration
+	)
+
+	cmd := &cobra.Command{
+		Use:   "doctor",
+		Short: "Diagnose the connection to the mail servers",
+		Long: `Connects to the configured SMTP and IMAP servers and reports each step,
+with hints for common problems.
+
+Unlike 'ghostmail config check', which only looks at the settings, doctor
+actually connects and logs in:
+
+  DNS           Resolving the host name
+  TCP           Connecting to the port, with the time it took
+  TLS/STARTTLS  TLS version and cipher suite
+  Certificate   Trust, host name and expiry of the server certificate chain
+  Capabilities  SMTP extensions (SIZE, SMTPUTF8, ...) or IMAP capabilities
+                (IDLE, MOVE, CONDSTORE, ...)
+  Login         Authentication with the configured credentials
+  Mailbox       Selecting the mailbox and finding the Sent mailbox (IMAP)
+
+No message is sent and nothing is changed on the server. The steps after a
+failure are skipped. The command exits with status 1 if a check failed;
+warnings, such as an unencrypted connection, do not fail it.
+
+EXAMPLES:
+  # Check both servers
+  ghostmail doctor
+
+  # Check only the SMTP server of another account
+  ghostmail doctor --smtp --account personal
+
+  # JSON output for scripting
+  ghostmail doctor --json
+
+For more help, use: ghostmail doctor --help`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if timeout <= 0 {
+				return handleError(fmt.Errorf("--timeout must be positive. Use --help for usage info"))
+			}
+
+			// A configuration file that cannot be read is reported, and the
+			// servers configured in the environment are still checked
+			cfg, err := config.Load()
+			resp := emailtypes.DoctorResponse{Success: true, Account: cfg.Account}
+			if err != nil {
+				resp.Checks = append(resp.Checks, emailtypes.DiagnosticCheck{
+					Name:   "Configuration",
+					Status: emailtypes.CheckFailed,
+					Detail: err.Error(),
+					Hint:   "the servers below are checked with the settings from the environment only",
+				})
+			}
+			if !imapOnly || smtpOnly {
+				var diagnosis *emailtypes.ServerDiagnosis
+				if err := cfg.ValidateSMTP(); err != nil {
+					diagnosis = misconfigured("SMTP", cfg.SMTP.Host, cfg.SMTP.Port, err)
+				} else {
+					diagnosis = emailinternal.NewSender(&cfg.SMTP).Diagnose(timeout)
+				}
+				resp.Servers = append(resp.Servers, *diagnosis)
+			}
+			if !smtpOnly || imapOnly {
+				var diagnosis *emailtypes.ServerDiagnosis
+				if err := cfg.ValidateIMAP(); err != nil {
+					diagnosis = misconfigured("IMAP", cfg.IMAP.Host, cfg.IMAP.Port, err)
+				} else {
+					diagnosis = emailinternal.NewReader(&cfg.IMAP).Diagnose(timeout)
+				}
+				resp.Servers = append(resp.Servers, *diagnosis)
+			}
+
+			failed, warnings := 0, 0
+			checks := resp.Checks
+			for _, server := range resp.Servers {
+				checks = append(checks, server.Checks...)
+			}
+			for _, c := range checks {
+				switch c.Status {
+				case emailtypes.CheckFailed:
+					failed++
+				case emailtypes.CheckWarning:
+					warnings++
+				}
+			}
+			resp.Success = failed == 0
+
+			// Output
+			if jsonOutput {
+				if err := output.NewJSONOutput(true).Print(resp); err != nil {
+					return err
+				}
+				if failed > 0 {
+					os.Exit(1)
+				}
+				return nil
+			}
+
+			if cfg.File != "" {
+				fmt.Printf("Using account %q from %s\n", cfg.Account, cfg.File)
+			}
+			for _, c := range resp.Checks {
+				fmt.Println()
+				printCheck(c)
+			}
+			for _, server := range resp.Servers {
+				printDiagnosis(server)
+			}
+
+			fmt.Println()
+			if failed > 0 {
+				cmd.SilenceUsage = true
+				return fmt.Errorf("%d check(s) failed", failed)
+			}
+			summary := "✓ All checks passed"
+			if warnings > 0 {
+				summary += fmt.Sprintf(" with %d warning(s)", warnings)
+			}
+			if noColor {
+				fmt.Println(summary)
+			} else {
+				color.Green(summary)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&smtpOnly, "smtp", false, "Check only the SMTP server")
+	cmd.Flags().BoolVar(&imapOnly, "imap", false, "Check only the IMAP server")
+	cmd.Flags().DurationVar(&timeout, "timeout", 10*time.Second, "Timeout of each network step")
+
+	return cmd
+}
+
+// misconfigured returns the diagnosis of a server that cannot be checked
+// because its configuration is incomplete.
+func misconfigured(protocol, host string, port int, err error) *emailtypes.ServerDiagnosis {
+	return &emailtypes.ServerDiagnosis{
+		Protocol: protocol,
+		Host:     host,
+		Port:     port,
+		Checks: []emailtypes.DiagnosticCheck{{
+			Name:   "Configuration",
+			Status: emailtypes.CheckFailed,
+			Detail: err.Error(),
+			Hint:   "run 'ghostmail config check' to see which settings are missing",
+		}},
+	}
+}
+
+// printDiagnosis prints the checks of a server, with hints for those that
+// did not pass and the certificate chain.
+func printDiagnosis(d emailtypes.ServerDiagnosis) {
+	title := fmt.Sprintf("%s %s:%d", d.Protocol, d.Host, d.Port)
+	fmt.Printf("\n%s\n%s\n", title, strings.Repeat("-", len(title)))
+
+	for _, c := range d.Checks {
+		printCheck(c)
+		if c.Name == "Certificate" && d.TLS != nil {
+			for i, cert := range d.TLS.Chain {
+				label := "chain:"
+				if i > 0 {
+					label = ""
+				}
+				fmt.Printf("    %-13s %s (expires %s)\n", label, cert.Subject, cert.NotAfter.Format("2006-01-02"))
+			}
+		}
+	}
+}
+
+// printCheck prints a check with its hint.
+func printCheck(c emailtypes.DiagnosticCheck) {
+	line := fmt.Sprintf("  %s %-13s %s", checkSymbols[c.Status], c.Name, c.Detail)
+	if c.DurationMS > 0 {
+		line += fmt.Sprintf(" (%dms)", c.DurationMS)
+	}
+	switch {
+	case noColor || c.Status == emailtypes.CheckSkipped:
+		fmt.Println(line)
+	case c.Status == emailtypes.CheckOK:
+		color.Green("%s", line)
+	case c.Status == emailtypes.CheckWarning:
+		color.Yellow("%s", line)
+	default:
+		color.Red("%s", line)
+	}
+	if c.Hint != "" {
+		fmt.Printf("                  → %s\n", c.Hint)
+	}
+}
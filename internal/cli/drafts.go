@@ -0,0 +1,419 @@
+package cli
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/GodGMN/ghostmail-cli/internal/config"
+	emailinternal "github.com/GodGMN/ghostmail-cli/internal/email"
+	"github.com/GodGMN/ghostmail-cli/internal/mimeutil"
+	"github.com/GodGMN/ghostmail-cli/internal/output"
+	emailtypes "github.com/GodGMN/ghostmail-cli/pkg/email"
+	"github.com/emersion/go-imap"
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+func newDraftsCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "drafts",
+		Short: "List and send drafts from the Drafts mailbox",
+		Long: `Commands for drafts stored in the server's Drafts mailbox.
+
+Drafts are created with 'ghostmail send --draft' or 'ghostmail reply --draft'
+and can be reviewed and edited in any mail client before they are sent.
+The Drafts mailbox is found via its SPECIAL-USE \Drafts attribute, or by
+common names such as "Drafts" on servers without SPECIAL-USE.
+
+COMMANDS:
+  list  List drafts
+  edit  Replace a draft with an edited version
+  send  Send a draft via SMTP and remove it from Drafts
+
+EXAMPLES:
+  # Save a reply for approval, then send it
+  ghostmail reply --uid 12345 --body "Approved" --draft
+  ghostmail drafts list
+  ghostmail drafts edit --uid 42
+  ghostmail drafts send --uid 43
+
+For more help, use: ghostmail drafts --help`,
+	}
+
+	cmd.AddCommand(newDraftsListCmd())
+	cmd.AddCommand(newDraftsEditCmd())
+	cmd.AddCommand(newDraftsSendCmd())
+
+	return cmd
+}
+
+func newDraftsListCmd() *cobra.Command {
+	var limit int
+
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List drafts",
+		Long: `List messages in the Drafts mailbox.
+
+Displays a table of drafts with UID, recipients, subject, and date.
+Use the UID with 'ghostmail drafts send' or 'ghostmail read'.
+
+EXAMPLES:
+  # List drafts
+  ghostmail drafts list
+
+  # JSON output for scripting
+  ghostmail drafts list --json
+
+For more help, use: ghostmail drafts list --help`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// Load configuration
+			cfg, err := config.Load()
+			if err != nil {
+				return handleError(err)
+			}
+
+			if err := cfg.ValidateIMAP(); err != nil {
+				return handleError(fmt.Errorf("%w. Use --help for usage info", err))
+			}
+
+			reader := emailinternal.NewReader(&cfg.IMAP)
+			mailbox, err := reader.FindSpecialMailbox(imap.DraftsAttr)
+			if err != nil {
+				return handleError(err)
+			}
+			cfg.IMAP.Mailbox = mailbox
+
+			messages, err := reader.ListMessages(limit, false)
+			if err != nil {
+				return handleError(fmt.Errorf("%w. Use --help for usage info", err))
+			}
+
+			// Output
+			if jsonOutput {
+				resp := emailtypes.InboxResponse{
+					Success:  true,
+					Messages: messages,
+					Total:    len(messages),
+				}
+				return output.NewJSONOutput(true).Print(resp)
+			}
+
+			if len(messages) == 0 {
+				fmt.Println("No drafts")
+				return nil
+			}
+
+			printMessageTable(messages, "TO")
+
+			return nil
+		},
+	}
+
+	cmd.Flags().IntVarP(&limit, "limit", "l", 20, "Maximum number of drafts to show")
+
+	return cmd
+}
+
+func newDraftsEditCmd() *cobra.Command {
+	var (
+		uid  uint32
+		file string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "edit",
+		Short: "Replace a draft with an edited version",
+		Long: `Edit a draft in the Drafts mailbox.
+
+The draft is opened as a raw message, including its Bcc header, in the editor
+from $VISUAL or $EDITOR (vi if neither is set). With --file the new version
+is read from a file, or from stdin with "-", instead.
+
+The new version is stored in Drafts first, then the old draft is deleted and
+expunged. IMAP messages cannot be changed in place, so the draft gets a new
+UID; use 'ghostmail drafts list' to find it. Nothing is changed if the draft
+was left unchanged.
+
+REQUIRED FLAGS:
+  --uid     The UID of the draft (from 'ghostmail drafts list')
+
+EXAMPLES:
+  # Edit a draft in your editor
+  ghostmail drafts edit --uid 42
+
+  # Replace a draft with a message prepared by a script
+  ghostmail drafts edit --uid 42 --file reply.eml
+
+For more help, use: ghostmail drafts edit --help`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if uid == 0 {
+				return handleError(fmt.Errorf("UID is required (use --uid). Get from 'ghostmail drafts list'. Use --help for usage info"))
+			}
+
+			// Load configuration
+			cfg, err := config.Load()
+			if err != nil {
+				return handleError(err)
+			}
+
+			if err := cfg.ValidateIMAP(); err != nil {
+				return handleError(fmt.Errorf("%w. Use --help for usage info", err))
+			}
+
+			reader := emailinternal.NewReader(&cfg.IMAP)
+			mailbox, err := reader.FindSpecialMailbox(imap.DraftsAttr)
+			if err != nil {
+				return handleError(err)
+			}
+			cfg.IMAP.Mailbox = mailbox
+
+			_, raw, err := reader.ReadMessageRaw(uid)
+			if err != nil {
+				return handleError(fmt.Errorf("failed to fetch draft: %w. Use --help for usage info", err))
+			}
+
+			var edited []byte
+			switch file {
+			case "":
+				edited, err = editDraft(raw)
+			case "-":
+				edited, err = io.ReadAll(os.Stdin)
+			default:
+				edited, err = os.ReadFile(file)
+			}
+			if err != nil {
+				return handleError(fmt.Errorf("failed to read the edited draft: %w", err))
+			}
+			edited = mimeutil.ToCRLF(edited)
+
+			message := fmt.Sprintf("Draft %d unchanged", uid)
+			if !bytes.Equal(edited, mimeutil.ToCRLF(raw)) {
+				// Refuse drafts that could not be sent later
+				if _, err := emailinternal.ParseDraft(edited); err != nil {
+					return handleError(fmt.Errorf("%w; the draft was not changed", err))
+				}
+				if _, err := reader.ReplaceDraft(uid, edited); err != nil {
+					return handleError(err)
+				}
+				message = fmt.Sprintf("Draft %d updated in %s", uid, mailbox)
+			}
+
+			// Output result
+			if jsonOutput {
+				resp := emailtypes.SendResponse{
+					Success: true,
+					Message: message,
+				}
+				return output.NewJSONOutput(true).Print(resp)
+			}
+
+			if !noColor {
+				color.Green("✓ %s", message)
+			} else {
+				fmt.Println(message)
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().Uint32VarP(&uid, "uid", "u", 0, "Draft UID to edit (required). Get from 'ghostmail drafts list'")
+	cmd.Flags().StringVarP(&file, "file", "f", "", "Read the new version from a file (- for stdin) instead of opening an editor")
+
+	cmd.MarkFlagRequired("uid")
+
+	return cmd
+}
+
+// editDraft opens a raw draft in the user's editor and returns the edited
+// content.
+func editDraft(raw []byte) ([]byte, error) {
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if editor == "" {
+		editor = "vi"
+	}
+
+	f, err := os.CreateTemp("", "ghostmail-draft-*.eml")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(f.Name())
+
+	_, err = f.Write(bytes.ReplaceAll(raw, []byte("\r\n"), []byte("\n")))
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// The editor setting may include arguments, e.g. "code --wait"
+	args := append(strings.Fields(editor), f.Name())
+	c := exec.Command(args[0], args[1:]...)
+	c.Stdin, c.Stdout, c.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if err := c.Run(); err != nil {
+		return nil, fmt.Errorf("editor %q failed: %w", editor, err)
+	}
+
+	return os.ReadFile(f.Name())
+}
+
+func newDraftsSendCmd() *cobra.Command {
+	var (
+		uid        uint32
+		noSaveSent bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "send",
+		Short: "Send a draft by UID",
+		Long: `Send a draft from the Drafts mailbox via SMTP.
+
+The draft is submitted as stored, so edits made in another mail client are
+kept, except that the Date header is set to the time it is sent.
+Recipients are taken from its To, Cc and Bcc headers. The draft is removed
+from the Drafts mailbox only after it was sent successfully.
+
+REQUIRED FLAGS:
+  --uid     The UID of the draft (from 'ghostmail drafts list')
+
+EXAMPLES:
+  # Send an approved draft
+  ghostmail drafts send --uid 42
+
+For more help, use: ghostmail drafts send --help`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if uid == 0 {
+				return handleError(fmt.Errorf("UID is required (use --uid). Get from 'ghostmail drafts list'. Use --help for usage info"))
+			}
+
+			// Load configuration
+			cfg, err := config.Load()
+			if err != nil {
+				return handleError(err)
+			}
+
+			if err := cfg.ValidateIMAP(); err != nil {
+				return handleError(fmt.Errorf("IMAP config error: %w. Use --help for usage info", err))
+			}
+			if err := cfg.ValidateSMTP(); err != nil {
+				return handleError(fmt.Errorf("SMTP config error: %w. Use --help for usage info", err))
+			}
+
+			reader := emailinternal.NewReader(&cfg.IMAP)
+			mailbox, err := reader.FindSpecialMailbox(imap.DraftsAttr)
+			if err != nil {
+				return handleError(err)
+			}
+			cfg.IMAP.Mailbox = mailbox
+
+			_, raw, err := reader.ReadMessageRaw(uid)
+			if err != nil {
+				return handleError(fmt.Errorf("failed to fetch draft: %w. Use --help for usage info", err))
+			}
+
+			msg, err := emailinternal.ParseDraft(raw)
+			if err != nil {
+				return handleError(err)
+			}
+
+			// The draft was dated when it was saved
+			msg.SetDate(time.Now())
+			sender := emailinternal.NewSender(&cfg.SMTP)
+			if err := sender.Submit(msg); err != nil {
+				return handleError(err)
+			}
+
+			if err := reader.DeleteMessage(uid); err != nil {
+				return handleError(fmt.Errorf("draft sent, but could not be removed from %s: %w", mailbox, err))
+			}
+
+			// Keep a copy in the Sent mailbox
+			var savedTo, warning string
+			if !noSaveSent {
+				savedTo, warning = saveSentCopy(cfg, msg)
+			}
+
+			// Output result
+			if jsonOutput {
+				resp := emailtypes.SendResponse{
+					Success: true,
+					Message: fmt.Sprintf("Draft sent to %s", strings.Join(msg.Recipients, ", ")),
+					SavedTo: savedTo,
+					Warning: warning,
+				}
+				return output.NewJSONOutput(true).Print(resp)
+			}
+
+			if !noColor {
+				color.Green("✓ Draft sent to %s", strings.Join(msg.Recipients, ", "))
+			} else {
+				fmt.Printf("Draft sent to %s\n", strings.Join(msg.Recipients, ", "))
+			}
+
+			if verbose {
+				fmt.Printf("  Removed from: %s\n", mailbox)
+			}
+			printSentCopy(savedTo, warning)
+
+			return nil
+		},
+	}
+
+	cmd.Flags().Uint32VarP(&uid, "uid", "u", 0, "Draft UID to send (required). Get from 'ghostmail drafts list'")
+	cmd.Flags().BoolVar(&noSaveSent, "no-save-sent", false, "Don't save a copy to the Sent mailbox")
+
+	cmd.MarkFlagRequired("uid")
+
+	return cmd
+}
+
+// saveDraft builds a message and stores it in the Drafts mailbox instead of
+// sending it.
+func saveDraft(cfg *config.Config, sender *emailinternal.Sender, to []string, subject, body string, opts []emailinternal.SendOption) error {
+	// Drafts need no SMTP account, so fall back to the IMAP user as sender
+	if cfg.SMTP.From == "" && cfg.SMTP.Username == "" {
+		cfg.SMTP.From = cfg.IMAP.Username
+	}
+
+	msg, err := sender.Build(to, subject, body, opts...)
+	if err != nil {
+		return handleError(err)
+	}
+
+	mailbox, err := emailinternal.NewReader(&cfg.IMAP).SaveDraft(msg)
+	if err != nil {
+		return handleError(err)
+	}
+
+	// Output result
+	if jsonOutput {
+		resp := emailtypes.SendResponse{
+			Success: true,
+			Message: fmt.Sprintf("Draft saved to %s", mailbox),
+		}
+		return output.NewJSONOutput(true).Print(resp)
+	}
+
+	if !noColor {
+		color.Green("✓ Draft saved to %s", mailbox)
+	} else {
+		fmt.Printf("Draft saved to %s\n", mailbox)
+	}
+
+	if verbose {
+		fmt.Printf("  To: %s\n", strings.Join(to, ", "))
+		fmt.Printf("  Subject: %s\n", subject)
+	}
+
+	return nil
+}
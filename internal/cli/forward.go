@@ -0,0 +1,207 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/GodGMN/ghostmail-cli/internal/config"
+	emailinternal "github.com/GodGMN/ghostmail-cli/internal/email"
+	"github.com/GodGMN/ghostmail-cli/internal/output"
+	emailtypes "github.com/GodGMN/ghostmail-cli/pkg/email"
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+func newForwardCmd() *cobra.Command {
+	var (
+		uid          uint32
+		mailbox      string
+		to           []string
+		cc           []string
+		bcc          []string
+		body         string
+		bodyFile     string
+		asAttachment bool
+		noSaveSent   bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "forward",
+		Short: "Forward an email by UID",
+		Long: `Forward an email by its UID (Unique Identifier) to other recipients.
+
+By default the original is forwarded inline: your note is followed by a
+"Forwarded message" header block (From, Date, Subject, To, Cc), the original
+body, and the original attachments re-attached.
+
+With --as-attachment the original message is attached unchanged as a
+message/rfc822 part instead.
+
+The subject gets a "Fwd:" prefix and the References header points at the
+original message.
+
+REQUIRED FLAGS:
+  --uid     The UID of the email to forward (from 'ghostmail inbox')
+  --to      Recipient email address(es)
+
+EXAMPLES:
+  # Forward inline with a note
+  ghostmail forward --uid 12345 --to oncall@example.com --body "FYI"
+
+  # Forward the original message as an attachment
+  ghostmail forward --uid 12345 --to security@example.com --as-attachment
+
+  # Forward from a specific mailbox to several recipients
+  ghostmail forward --uid 12345 --mailbox Alerts -t a@example.com -t b@example.com
+
+For more help, use: ghostmail forward --help`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if uid == 0 {
+				return handleError(fmt.Errorf("UID is required (use --uid). Get from 'ghostmail inbox'. Use --help for usage info"))
+			}
+			if len(to) == 0 {
+				return handleError(fmt.Errorf("at least one recipient (--to) is required. Use --help for usage info"))
+			}
+			if err := checkRecipients(to, cc, bcc); err != nil {
+				return handleError(err)
+			}
+
+			// Load configuration
+			cfg, err := config.Load()
+			if err != nil {
+				return handleError(err)
+			}
+
+			if err := cfg.ValidateIMAP(); err != nil {
+				return handleError(fmt.Errorf("IMAP config error: %w. Use --help for usage info", err))
+			}
+			if err := cfg.ValidateSMTP(); err != nil {
+				return handleError(fmt.Errorf("SMTP config error: %w. Use --help for usage info", err))
+			}
+
+			// Handle body from file
+			if bodyFile != "" {
+				data, err := os.ReadFile(bodyFile)
+				if err != nil {
+					return handleError(fmt.Errorf("failed to read body file: %w. Use --help for usage info", err))
+				}
+				body = string(data)
+			}
+
+			// Override mailbox if specified
+			if mailbox != "" {
+				cfg.IMAP.Mailbox = mailbox
+			}
+
+			// Fetch original message
+			reader := emailinternal.NewReader(&cfg.IMAP)
+			original, raw, err := reader.ReadMessageRaw(uid)
+			if err != nil {
+				return handleError(fmt.Errorf("failed to fetch original message: %w. Use --help for usage info", err))
+			}
+
+			subject := emailinternal.ForwardSubject(original.Subject)
+
+			var forwardBody string
+			var attachments []emailinternal.AttachmentData
+			if asAttachment {
+				forwardBody = body
+				attachments = []emailinternal.AttachmentData{{
+					Filename:    forwardFilename(original.Subject),
+					ContentType: "message/rfc822",
+					Data:        raw,
+				}}
+			} else {
+				forwardBody = emailinternal.FormatForwardedMessage(body, original)
+				attachments, err = emailinternal.ExtractAttachments(raw)
+				if err != nil {
+					return handleError(fmt.Errorf("failed to extract attachments: %w", err))
+				}
+			}
+
+			// Send the forward
+			sender := emailinternal.NewSender(&cfg.SMTP)
+			opts := []emailinternal.SendOption{
+				emailinternal.WithCC(cc),
+				emailinternal.WithBCC(bcc),
+				emailinternal.WithAttachmentData(attachments),
+			}
+			if original.MessageID != "" {
+				opts = append(opts, emailinternal.WithReferences([]string{original.MessageID}))
+			}
+
+			msg, err := sender.Build(to, subject, forwardBody, opts...)
+			if err != nil {
+				return handleError(err)
+			}
+			if err := sender.Submit(msg); err != nil {
+				return handleError(err)
+			}
+
+			// Keep a copy in the Sent mailbox
+			var savedTo, warning string
+			if !noSaveSent {
+				savedTo, warning = saveSentCopy(cfg, msg)
+			}
+
+			// Output result
+			if jsonOutput {
+				resp := emailtypes.SendResponse{
+					Success:       true,
+					Message:       fmt.Sprintf("Message forwarded to %s", strings.Join(to, ", ")),
+					SavedTo:       savedTo,
+					Warning:       warning,
+					AuthMechanism: sender.Mechanism(),
+				}
+				return output.NewJSONOutput(true).Print(resp)
+			}
+
+			if !noColor {
+				color.Green("✓ Message forwarded to %s", strings.Join(to, ", "))
+			} else {
+				fmt.Printf("Message forwarded to %s\n", strings.Join(to, ", "))
+			}
+
+			if verbose {
+				fmt.Printf("  Subject: %s\n", subject)
+				fmt.Printf("  References: %s\n", original.MessageID)
+				fmt.Printf("  Attachments: %d\n", len(attachments))
+			}
+			printSentCopy(savedTo, warning)
+			printAuthMechanism("SMTP", sender.Mechanism())
+
+			return nil
+		},
+	}
+
+	cmd.Flags().Uint32VarP(&uid, "uid", "u", 0, "Message UID to forward (required). Get from 'ghostmail inbox'")
+	cmd.Flags().StringVarP(&mailbox, "mailbox", "m", "", "Mailbox containing the message (default: INBOX)")
+	cmd.Flags().StringArrayVarP(&to, "to", "t", nil, "Recipient address or comma-separated list (can be specified multiple times)")
+	cmd.Flags().StringArrayVarP(&cc, "cc", "c", nil, "CC recipient or comma-separated list (can be specified multiple times)")
+	cmd.Flags().StringArrayVar(&bcc, "bcc", nil, "BCC recipient or comma-separated list (can be specified multiple times)")
+	cmd.Flags().StringVarP(&body, "body", "b", "", "Note to add above the forwarded message")
+	cmd.Flags().StringVar(&bodyFile, "body-file", "", "Read the note from file")
+	cmd.Flags().BoolVar(&asAttachment, "as-attachment", false, "Attach the original message (message/rfc822) instead of forwarding inline")
+	cmd.Flags().BoolVar(&noSaveSent, "no-save-sent", false, "Don't save a copy to the Sent mailbox")
+
+	cmd.MarkFlagRequired("uid")
+	cmd.MarkFlagRequired("to")
+
+	return cmd
+}
+
+// forwardFilename returns a safe .eml filename derived from a subject.
+func forwardFilename(subject string) string {
+	name := strings.Map(func(r rune) rune {
+		switch r {
+		case '/', '\\', ':', '*', '?', '"', '<', '>', '|':
+			return '_'
+		}
+		return r
+	}, strings.TrimSpace(subject))
+	if name == "" {
+		name = "forwarded message"
+	}
+	return name + ".eml"
+}
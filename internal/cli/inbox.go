@@ -76,9 +76,10 @@ For more help, use: ghostmail inbox --help`,
 			// Output
 			if jsonOutput {
 				resp := emailtypes.InboxResponse{
-					Success:  true,
-					Messages: messages,
-					Total:    len(messages),
+					Success:       true,
+					Messages:      messages,
+					Total:         len(messages),
+					AuthMechanism: reader.Mechanism(),
 				}
 				return output.NewJSONOutput(true).Print(resp)
 			}
@@ -93,32 +94,8 @@ For more help, use: ghostmail inbox --help`,
 				return nil
 			}
 
-			// Print table
-			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
-
-			// Header
-			headerFmt := "%s\t%s\t%s\t%s\n"
-			if !noColor {
-				headerFmt = color.New(color.Bold).Sprintf(headerFmt)
-			}
-			fmt.Fprintf(w, headerFmt, "UID", "FROM", "SUBJECT", "DATE")
-
-			// Rows
-			for _, msg := range messages {
-				from := truncate(msg.From, 25)
-				subject := truncate(msg.Subject, 40)
-				date := formatDate(msg.Date)
-
-				// Highlight unread messages
-				row := fmt.Sprintf("%d\t%s\t%s\t%s\n", msg.UID, from, subject, date)
-				if !noColor && !isRead(msg.Flags) {
-					row = color.New(color.Bold).Sprint(row)
-				}
-				fmt.Fprint(w, row)
-			}
-
-			w.Flush()
-			fmt.Printf("\nTotal: %d messages\n", len(messages))
+			printMessageTable(messages, "FROM")
+			printAuthMechanism("IMAP", reader.Mechanism())
 
 			return nil
 		},
@@ -131,6 +108,40 @@ For more help, use: ghostmail inbox --help`,
 	return cmd
 }
 
+// printMessageTable prints messages as a table. The address column shows
+// the sender, or the recipients when addrColumn is "TO".
+func printMessageTable(messages []emailtypes.Message, addrColumn string) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+
+	// Header
+	headerFmt := "%s\t%s\t%s\t%s\n"
+	if !noColor {
+		headerFmt = color.New(color.Bold).Sprint(headerFmt)
+	}
+	fmt.Fprintf(w, headerFmt, "UID", addrColumn, "SUBJECT", "DATE")
+
+	// Rows
+	for _, msg := range messages {
+		addr := msg.From
+		if addrColumn == "TO" {
+			addr = strings.Join(msg.To, ", ")
+		}
+		addr = truncate(addr, 25)
+		subject := truncate(msg.Subject, 40)
+		date := formatDate(msg.Date)
+
+		// Highlight unread messages
+		row := fmt.Sprintf("%d\t%s\t%s\t%s\n", msg.UID, addr, subject, date)
+		if !noColor && !isRead(msg.Flags) {
+			row = color.New(color.Bold).Sprint(row)
+		}
+		fmt.Fprint(w, row)
+	}
+
+	w.Flush()
+	fmt.Printf("\nTotal: %d messages\n", len(messages))
+}
+
 // truncate truncates a string to max length.
 func truncate(s string, maxLen int) string {
 	if len(s) <= maxLen {
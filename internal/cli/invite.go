@@ -0,0 +1,238 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/GodGMN/ghostmail-cli/internal/config"
+	emailinternal "github.com/GodGMN/ghostmail-cli/internal/email"
+	"github.com/GodGMN/ghostmail-cli/internal/output"
+	emailtypes "github.com/GodGMN/ghostmail-cli/pkg/email"
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+func newInviteCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "invite",
+		Short: "Work with calendar invitations",
+		Long: `Commands for meeting invitations received as text/calendar parts.
+
+Use 'ghostmail read' to see the parsed invitation, then answer it with
+'ghostmail invite respond'.
+
+COMMANDS:
+  respond  Accept, decline or tentatively accept an invitation
+
+EXAMPLES:
+  # Accept an invitation
+  ghostmail invite respond --uid 12345 --accept
+
+For more help, use: ghostmail invite --help`,
+	}
+
+	cmd.AddCommand(newInviteRespondCmd())
+
+	return cmd
+}
+
+func newInviteRespondCmd() *cobra.Command {
+	var (
+		uid        uint32
+		mailbox    string
+		accept     bool
+		decline    bool
+		tentative  bool
+		comment    string
+		noSaveSent bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "respond",
+		Short: "Respond to a calendar invitation by UID",
+		Long: `Respond to a meeting invitation by the UID of the email carrying it.
+
+Builds an iTIP METHOD:REPLY (RFC 5546) with your participation status and
+sends it to the organizer, threaded with the original invitation.
+
+REQUIRED FLAGS:
+  --uid                             The UID of the invitation email
+  --accept | --decline | --tentative  Your answer
+
+EXAMPLES:
+  # Accept an invitation
+  ghostmail invite respond --uid 12345 --accept
+
+  # Decline with a note to the organizer
+  ghostmail invite respond --uid 12345 --decline --comment "On holiday that week"
+
+  # Tentatively accept an invitation stored in another mailbox
+  ghostmail invite respond --uid 12345 --tentative --mailbox Calendar
+
+For more help, use: ghostmail invite respond --help`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if uid == 0 {
+				return handleError(fmt.Errorf("UID is required (use --uid). Get from 'ghostmail inbox'. Use --help for usage info"))
+			}
+
+			var partStat, verb string
+			answers := 0
+			if accept {
+				partStat, verb = emailinternal.PartStatAccepted, "Accepted"
+				answers++
+			}
+			if decline {
+				partStat, verb = emailinternal.PartStatDeclined, "Declined"
+				answers++
+			}
+			if tentative {
+				partStat, verb = emailinternal.PartStatTentative, "Tentative"
+				answers++
+			}
+			if answers != 1 {
+				return handleError(fmt.Errorf("exactly one of --accept, --decline or --tentative is required. Use --help for usage info"))
+			}
+
+			// Load configuration
+			cfg, err := config.Load()
+			if err != nil {
+				return handleError(err)
+			}
+
+			if err := cfg.ValidateIMAP(); err != nil {
+				return handleError(fmt.Errorf("IMAP config error: %w. Use --help for usage info", err))
+			}
+			if err := cfg.ValidateSMTP(); err != nil {
+				return handleError(fmt.Errorf("SMTP config error: %w. Use --help for usage info", err))
+			}
+
+			// Override mailbox if specified
+			if mailbox != "" {
+				cfg.IMAP.Mailbox = mailbox
+			}
+
+			// Fetch the invitation
+			reader := emailinternal.NewReader(&cfg.IMAP)
+			original, raw, err := reader.ReadMessageRaw(uid)
+			if err != nil {
+				return handleError(fmt.Errorf("failed to fetch invitation: %w. Use --help for usage info", err))
+			}
+			if len(original.Events) == 0 {
+				return handleError(fmt.Errorf("message %d does not contain a calendar invitation", uid))
+			}
+			event := original.Events[0]
+			if event.Method != "" && event.Method != emailinternal.CalendarMethodRequest {
+				return handleError(fmt.Errorf("message %d is a %s, not an invitation", uid, event.Method))
+			}
+
+			attendee := selfAttendee(event, cfg.SMTP.From, cfg.SMTP.Username)
+			if attendee == "" {
+				return handleError(fmt.Errorf("you are not listed as an attendee of this invitation"))
+			}
+
+			invitation, err := emailinternal.ExtractCalendar(raw)
+			if err != nil {
+				return handleError(err)
+			}
+			reply, err := emailinternal.BuildCalendarReply(invitation, attendee, partStat)
+			if err != nil {
+				return handleError(err)
+			}
+
+			// Replies go to the organizer, falling back to the sender
+			to := original.From
+			if event.Organizer != "" {
+				to = event.Organizer
+			}
+
+			subject := fmt.Sprintf("%s: %s", verb, event.Summary)
+			body := fmt.Sprintf("%s has %s the invitation.", attendee, describePartStat(partStat))
+			if comment != "" {
+				body = comment + "\n\n" + body
+			}
+
+			sender := emailinternal.NewSender(&cfg.SMTP)
+			opts := []emailinternal.SendOption{
+				emailinternal.WithCalendar(emailinternal.CalendarMethodReply, reply),
+			}
+			if original.MessageID != "" {
+				opts = append(opts, emailinternal.WithInReplyTo(original.MessageID))
+				opts = append(opts, emailinternal.WithReferences([]string{original.MessageID}))
+			}
+
+			msg, err := sender.Build([]string{to}, subject, body, opts...)
+			if err != nil {
+				return handleError(err)
+			}
+			if err := sender.Submit(msg); err != nil {
+				return handleError(err)
+			}
+
+			// Keep a copy in the Sent mailbox
+			var savedTo, warning string
+			if !noSaveSent {
+				savedTo, warning = saveSentCopy(cfg, msg)
+			}
+
+			// Output result
+			if jsonOutput {
+				resp := emailtypes.SendResponse{
+					Success: true,
+					Message: fmt.Sprintf("%s invitation %q, reply sent to %s", verb, event.Summary, to),
+					SavedTo: savedTo,
+					Warning: warning,
+				}
+				return output.NewJSONOutput(true).Print(resp)
+			}
+
+			if !noColor {
+				color.Green("✓ %s invitation, reply sent to %s", verb, to)
+			} else {
+				fmt.Printf("%s invitation, reply sent to %s\n", verb, to)
+			}
+
+			if verbose {
+				fmt.Printf("  Event: %s\n", event.Summary)
+				fmt.Printf("  UID: %s\n", event.UID)
+				fmt.Printf("  Attendee: %s\n", attendee)
+			}
+			printSentCopy(savedTo, warning)
+
+			return nil
+		},
+	}
+
+	cmd.Flags().Uint32VarP(&uid, "uid", "u", 0, "UID of the invitation email (required). Get from 'ghostmail inbox'")
+	cmd.Flags().StringVarP(&mailbox, "mailbox", "m", "", "Mailbox containing the invitation (default: INBOX)")
+	cmd.Flags().BoolVar(&accept, "accept", false, "Accept the invitation")
+	cmd.Flags().BoolVar(&decline, "decline", false, "Decline the invitation")
+	cmd.Flags().BoolVar(&tentative, "tentative", false, "Tentatively accept the invitation")
+	cmd.Flags().StringVar(&comment, "comment", "", "Note to include for the organizer")
+	cmd.Flags().BoolVar(&noSaveSent, "no-save-sent", false, "Don't save a copy to the Sent mailbox")
+
+	cmd.MarkFlagRequired("uid")
+
+	return cmd
+}
+
+// selfAttendee returns the attendee address of the event that belongs to
+// the current user, or an empty string.
+func selfAttendee(event emailtypes.CalendarEvent, from, username string) string {
+	for _, att := range event.Attendees {
+		if isSelf(att.Email, from, username) {
+			return att.Email
+		}
+	}
+	return ""
+}
+
+// describePartStat returns a past-tense description of a participation status.
+func describePartStat(partStat string) string {
+	switch partStat {
+	case emailinternal.PartStatAccepted:
+		return "accepted"
+	case emailinternal.PartStatDeclined:
+		return "declined"
+	default:
+		return "tentatively accepted"
+	}
+}
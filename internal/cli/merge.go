@@ -0,0 +1,313 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/GodGMN/ghostmail-cli/internal/config"
+	emailinternal "github.com/GodGMN/ghostmail-cli/internal/email"
+	"github.com/GodGMN/ghostmail-cli/internal/merge"
+	"github.com/GodGMN/ghostmail-cli/internal/output"
+	emailtypes "github.com/GodGMN/ghostmail-cli/pkg/email"
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+// Merge result statuses.
+const (
+	mergeSent     = "sent"
+	mergeRendered = "rendered"
+	mergeFailed   = "failed"
+)
+
+func newMergeCmd() *cobra.Command {
+	var (
+		dataFile     string
+		templateFile string
+		subject      string
+		dryRun       bool
+		outputDir    string
+		logFile      string
+		delay        time.Duration
+		noSaveSent   bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "merge",
+		Short: "Send personalized emails from CSV or JSON data (mail merge)",
+		Long: `Send one personalized email per row of a CSV or JSON data file.
+
+The template file defines the subject, the text body and optionally an HTML
+body with Go templates. Every column of a row is available by name:
+
+  {{define "subject"}}Your invoice {{.invoice}}{{end}}
+  {{define "text"}}Hello {{.name}},
+
+  your invoice {{.invoice}} is due on {{.due}}.{{end}}
+  {{define "html"}}<p>Hello {{.name}},</p>
+  <p>your invoice <b>{{.invoice}}</b> is due on {{.due}}.</p>{{end}}
+
+The text body uses text/template; the HTML body uses html/template, so
+values are escaped. Referencing a column that a row lacks is an error.
+
+DATA COLUMNS:
+  email        Recipient address(es) (required; "to" is accepted too)
+  cc, bcc      Additional recipients for this row
+  attachments  Files to attach for this row
+
+Lists in a column are separated by commas or semicolons; JSON data may use
+arrays instead. CSV files need a header line; JSON files hold an array of
+objects.
+
+All messages are sent over a single SMTP connection, and their copies saved
+to the Sent mailbox over a single IMAP connection. With --dry-run, the .eml
+files hold the messages exactly as they would be sent, DKIM signature
+included. Every row is recorded in a JSONL result log (--log) with its
+status, Message-ID and error.
+
+REQUIRED FLAGS:
+  --data        CSV or JSON file with one row per recipient
+  --template    Template file
+
+EXAMPLES:
+  # Send per-customer notices
+  ghostmail merge --data customers.csv --template notice.tmpl
+
+  # Render every message to .eml files first, without sending
+  ghostmail merge --data customers.csv --template notice.tmpl --dry-run
+
+  # Subject on the command line, throttled
+  ghostmail merge --data customers.json --template notice.tmpl \
+    --subject "Notice for {{.name}}" --delay 2s
+
+For more help, use: ghostmail merge --help`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// Load configuration
+			cfg, err := config.Load()
+			if err != nil {
+				return handleError(err)
+			}
+
+			// A dry run builds the messages as they would be sent, which
+			// only needs the sender
+			validate := cfg.ValidateSMTP
+			if dryRun {
+				validate = cfg.ValidateSender
+			}
+			if err := validate(); err != nil {
+				return handleError(err)
+			}
+
+			// Load data and template
+			rows, err := merge.LoadData(dataFile)
+			if err != nil {
+				return handleError(fmt.Errorf("%w. Use --help for usage info", err))
+			}
+			data, err := os.ReadFile(templateFile)
+			if err != nil {
+				return handleError(fmt.Errorf("failed to read template file: %w. Use --help for usage info", err))
+			}
+			tmpl, err := merge.ParseTemplate(string(data), subject)
+			if err != nil {
+				return handleError(fmt.Errorf("%w. Use --help for usage info", err))
+			}
+
+			if dryRun {
+				if err := os.MkdirAll(outputDir, 0755); err != nil {
+					return handleError(fmt.Errorf("failed to create output directory: %w", err))
+				}
+			}
+
+			log, err := os.Create(logFile)
+			if err != nil {
+				return handleError(fmt.Errorf("failed to create result log: %w", err))
+			}
+			defer log.Close()
+			enc := json.NewEncoder(log)
+
+			sender := emailinternal.NewSender(&cfg.SMTP)
+			var session *emailinternal.Session
+			defer func() {
+				if session != nil {
+					session.Close()
+				}
+			}()
+			copies := &sentCopies{cfg: cfg}
+			defer copies.close()
+
+			resp := emailtypes.MergeResponse{Success: true, Total: len(rows), Log: logFile}
+			for i, row := range rows {
+				result := emailtypes.MergeResult{Row: i + 1}
+
+				msg, err := buildMergeMessage(sender, tmpl, row, &result)
+				switch {
+				case err != nil:
+					// Reported below
+				case dryRun:
+					if err = sender.Render(msg); err == nil {
+						result.File = filepath.Join(outputDir, mergeFilename(result.Row, result.To[0]))
+						err = os.WriteFile(result.File, msg.Data, 0644)
+					}
+				default:
+					if i > 0 && delay > 0 {
+						time.Sleep(delay)
+					}
+					if session == nil {
+						session, err = sender.Open()
+					}
+					if err == nil {
+						err = session.Submit(msg)
+						if err != nil {
+							// Start over with a fresh connection for the next row
+							session.Close()
+							session = nil
+						}
+					}
+				}
+
+				switch {
+				case err != nil:
+					result.Status = mergeFailed
+					result.Error = err.Error()
+					resp.Failed++
+					resp.Success = false
+				case dryRun:
+					result.Status = mergeRendered
+					resp.Rendered++
+				default:
+					result.Status = mergeSent
+					resp.Sent++
+					if !noSaveSent {
+						_, result.Warning = copies.save(msg)
+					}
+				}
+
+				if err := enc.Encode(result); err != nil {
+					return handleError(fmt.Errorf("failed to write result log: %w", err))
+				}
+				if !jsonOutput {
+					printMergeResult(result)
+				}
+			}
+
+			if session != nil {
+				session.Close()
+				session = nil
+			}
+			copies.close()
+
+			// Output result
+			if jsonOutput {
+				if err := output.NewJSONOutput(true).Print(resp); err != nil {
+					return err
+				}
+				if !resp.Success {
+					os.Exit(1)
+				}
+				return nil
+			}
+
+			done := resp.Sent
+			verb := "sent"
+			if dryRun {
+				done, verb = resp.Rendered, "rendered to "+outputDir
+			}
+			fmt.Printf("\n%d of %d messages %s, %d failed (log: %s)\n", done, resp.Total, verb, resp.Failed, logFile)
+
+			if !resp.Success {
+				return fmt.Errorf("%d of %d messages failed, see %s", resp.Failed, resp.Total, logFile)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&dataFile, "data", "d", "", "CSV or JSON file with one row per recipient (required)")
+	cmd.Flags().StringVar(&templateFile, "template", "", "Template file defining subject, text and html (required)")
+	cmd.Flags().StringVarP(&subject, "subject", "s", "", "Subject template (overrides the template file)")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Render messages to .eml files instead of sending")
+	cmd.Flags().StringVar(&outputDir, "output-dir", "merge-output", "Directory for --dry-run messages")
+	cmd.Flags().StringVar(&logFile, "log", "merge-results.jsonl", "JSONL file recording the result for each row")
+	cmd.Flags().DurationVar(&delay, "delay", 0, "Pause between messages (e.g. 500ms, 2s)")
+	cmd.Flags().BoolVar(&noSaveSent, "no-save-sent", false, "Don't save copies to the Sent mailbox")
+
+	cmd.MarkFlagRequired("data")
+	cmd.MarkFlagRequired("template")
+
+	return cmd
+}
+
+// buildMergeMessage renders and builds the message for one row, filling in
+// the recipients and subject of the result.
+func buildMergeMessage(sender *emailinternal.Sender, tmpl *merge.Template, row merge.Row, result *emailtypes.MergeResult) (*emailinternal.OutgoingMessage, error) {
+	to, cc, bcc, attachments := row.Recipients()
+	result.To = to
+	if len(to) == 0 {
+		return nil, fmt.Errorf("row has no recipient (set the %q column)", merge.ColumnEmail)
+	}
+
+	rendered, err := tmpl.Render(row)
+	if err != nil {
+		return nil, err
+	}
+	result.Subject = rendered.Subject
+
+	for _, att := range attachments {
+		if _, err := os.Stat(att); err != nil {
+			return nil, fmt.Errorf("cannot access attachment %s: %w", att, err)
+		}
+	}
+
+	opts := []emailinternal.SendOption{
+		emailinternal.WithCC(cc),
+		emailinternal.WithBCC(bcc),
+		emailinternal.WithAttachments(attachments),
+	}
+	if rendered.HTML != "" {
+		opts = append(opts, emailinternal.WithHTMLBody(rendered.HTML))
+	}
+
+	msg, err := sender.Build(to, rendered.Subject, rendered.Text, opts...)
+	if err != nil {
+		return nil, err
+	}
+	result.MessageID = msg.MessageID
+	return msg, nil
+}
+
+// printMergeResult prints the result for one row.
+func printMergeResult(r emailtypes.MergeResult) {
+	to := strings.Join(r.To, ", ")
+	switch r.Status {
+	case mergeFailed:
+		if !noColor {
+			color.Red("✗ %d %s: %s", r.Row, to, r.Error)
+		} else {
+			fmt.Printf("Failed %d %s: %s\n", r.Row, to, r.Error)
+		}
+	case mergeRendered:
+		fmt.Printf("  %d %s → %s\n", r.Row, to, r.File)
+	default:
+		if !noColor {
+			color.Green("✓ %d %s", r.Row, to)
+		} else {
+			fmt.Printf("Sent %d %s\n", r.Row, to)
+		}
+		printSentCopy("", r.Warning)
+	}
+}
+
+// mergeFilename returns the .eml file name for a rendered row.
+func mergeFilename(row int, to string) string {
+	name := strings.Map(func(r rune) rune {
+		switch r {
+		case '/', '\\', ':', '*', '?', '"', '<', '>', '|', ' ':
+			return '_'
+		}
+		return r
+	}, to)
+	return fmt.Sprintf("%04d-%s.eml", row, name)
+}
@@ -0,0 +1,271 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/GodGMN/ghostmail-cli/internal/config"
+	emailinternal "github.com/GodGMN/ghostmail-cli/internal/email"
+	"github.com/GodGMN/ghostmail-cli/internal/output"
+	"github.com/GodGMN/ghostmail-cli/internal/spool"
+	emailtypes "github.com/GodGMN/ghostmail-cli/pkg/email"
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+// defaultMaxAttempts is the number of delivery attempts after which an
+// outbox message is moved to the dead-letter directory.
+const defaultMaxAttempts = 10
+
+func newOutboxCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "outbox",
+		Short: "Retry messages that could not be submitted",
+		Long: `Commands for the local outbox of messages queued by
+'ghostmail send --queue-on-failure' when the SMTP server was unreachable or
+answered with a temporary error.
+
+Outbox messages are stored fully built ($GHOSTMAIL_DATA_DIR/outbox) and
+retried by 'ghostmail outbox flush' with exponential backoff. Messages that
+fail permanently, or too often, are moved to $GHOSTMAIL_DATA_DIR/dead-letter
+together with a JSON report.
+
+COMMANDS:
+  list   List queued messages with attempts and last error
+  flush  Retry messages whose backoff has expired
+
+EXAMPLES:
+  # Never lose an alert on a flaky runner
+  ghostmail send --to oncall@example.com --subject "Build failed" \
+    --body-file log.txt --queue-on-failure
+
+  # Retry from cron every five minutes
+  */5 * * * * ghostmail outbox flush
+
+For more help, use: ghostmail outbox --help`,
+	}
+
+	cmd.AddCommand(newOutboxListCmd())
+	cmd.AddCommand(newOutboxFlushCmd())
+
+	return cmd
+}
+
+func newOutboxListCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "list",
+		Short: "List queued messages",
+		Long: `List messages waiting in the outbox, with the time of the next
+attempt, the number of attempts so far and the last error.
+
+EXAMPLES:
+  # List queued messages
+  ghostmail outbox list
+
+  # JSON output for scripting
+  ghostmail outbox list --json
+
+For more help, use: ghostmail outbox list --help`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// Load configuration
+			cfg, err := config.Load()
+			if err != nil {
+				return handleError(err)
+			}
+
+			entries, err := spool.New(cfg.OutboxDir()).List()
+			if err != nil {
+				return handleError(err)
+			}
+
+			// Output
+			if jsonOutput {
+				resp := emailtypes.QueueResponse{
+					Success: true,
+					Entries: entries,
+					Total:   len(entries),
+				}
+				return output.NewJSONOutput(true).Print(resp)
+			}
+
+			if len(entries) == 0 {
+				fmt.Println("Outbox is empty")
+				return nil
+			}
+
+			printQueueTable(entries, "NEXT ATTEMPT", func(e emailtypes.QueueEntry) time.Time { return e.SendAt })
+
+			return nil
+		},
+	}
+}
+
+func newOutboxFlushCmd() *cobra.Command {
+	var (
+		maxAttempts int
+		all         bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "flush",
+		Short: "Retry queued messages",
+		Long: `Retry submitting the messages in the outbox.
+
+Only messages whose backoff has expired are retried, unless --all is given.
+The delay after each failed attempt doubles from one minute up to one hour.
+A message is moved to the dead-letter directory with a JSON report when it
+fails with a permanent error (e.g. SMTP 5xx) or after --max-attempts
+attempts. If the server cannot be reached, the TLS handshake fails or the
+login is refused, the flush stops and every message stays in the outbox.
+
+Messages are claimed before sending. If a flush is killed while sending,
+its claims expire after 30 minutes and a later flush retries those
+messages, counting the interrupted attempt.
+
+Exits with an error if any message failed.
+
+EXAMPLES:
+  # Retry due messages
+  ghostmail outbox flush
+
+  # Retry everything now, e.g. after fixing the network
+  ghostmail outbox flush --all
+
+  # Give up sooner
+  ghostmail outbox flush --max-attempts 3
+
+For more help, use: ghostmail outbox flush --help`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if maxAttempts < 1 {
+				return handleError(fmt.Errorf("--max-attempts must be at least 1. Use --help for usage info"))
+			}
+
+			// Load configuration
+			cfg, err := config.Load()
+			if err != nil {
+				return handleError(err)
+			}
+
+			s := spool.New(cfg.OutboxDir())
+			if _, err := s.Reclaim(time.Now()); err != nil {
+				return handleError(err)
+			}
+			cutoff := time.Now()
+			if all {
+				cutoff = cutoff.AddDate(100, 0, 0)
+			}
+			due, err := s.Due(cutoff)
+			if err != nil {
+				return handleError(err)
+			}
+
+			if len(due) > 0 {
+				if err := cfg.ValidateSMTP(); err != nil {
+					return handleError(err)
+				}
+			}
+
+			sender := emailinternal.NewSender(&cfg.SMTP)
+			resp := emailtypes.QueueRunResponse{Success: true}
+			for _, entry := range due {
+				msg, err := s.Claim(entry.ID)
+				if errors.Is(err, spool.ErrClaimed) || errors.Is(err, spool.ErrNotFound) {
+					// Taken by a concurrent flush
+					continue
+				}
+				if err != nil {
+					return handleError(err)
+				}
+
+				sendErr := sender.Submit(msg)
+				if sendErr == nil {
+					if err := s.Remove(entry.ID); err != nil {
+						return handleError(err)
+					}
+					entry.Error = ""
+					resp.Sent = append(resp.Sent, entry)
+
+					if entry.SaveSent {
+						if _, warning := saveSentCopy(cfg, msg); warning != "" {
+							resp.Warnings = append(resp.Warnings, fmt.Sprintf("%s: %s", entry.ID, warning))
+						}
+					}
+					continue
+				}
+
+				resp.Success = false
+				entry.Error = sendErr.Error()
+
+				// The other messages would fail the same way; they stay
+				// queued without counting an attempt
+				if emailinternal.IsConnectionError(sendErr) {
+					if err := s.Requeue(entry.ID, sendErr); err != nil {
+						return handleError(err)
+					}
+					resp.Failed = append(resp.Failed, entry)
+					resp.Error = "flush stopped: the SMTP server could not be reached or refused the login; the messages stay in the outbox"
+					break
+				}
+
+				entry.Attempts++
+				if !emailinternal.IsRetryable(sendErr) || entry.Attempts >= maxAttempts {
+					if _, err := s.DeadLetter(entry.ID, sendErr, cfg.DeadLetterDir()); err != nil {
+						return handleError(err)
+					}
+					resp.Dead = append(resp.Dead, entry)
+					continue
+				}
+
+				entry.SendAt = time.Now().Add(spool.Backoff(entry.Attempts))
+				if err := s.Release(entry.ID, sendErr, entry.SendAt); err != nil {
+					return handleError(err)
+				}
+				resp.Failed = append(resp.Failed, entry)
+			}
+
+			return printRunResult(resp, "outbox message(s)")
+		},
+	}
+
+	cmd.Flags().IntVar(&maxAttempts, "max-attempts", defaultMaxAttempts, "Attempts before a message is moved to the dead-letter directory")
+	cmd.Flags().BoolVar(&all, "all", false, "Retry all messages, ignoring the backoff")
+
+	return cmd
+}
+
+// queueInOutbox stores a message whose submission failed with a temporary
+// error in the outbox, so 'ghostmail outbox flush' can retry it.
+func queueInOutbox(cfg *config.Config, msg *emailinternal.OutgoingMessage, subject string, sendErr error, saveSent bool) error {
+	entry, err := spool.New(cfg.OutboxDir()).Add(msg, emailtypes.QueueEntry{
+		SendAt:   time.Now().Add(spool.Backoff(1)),
+		Subject:  subject,
+		SaveSent: saveSent,
+		Attempts: 1,
+		Error:    sendErr.Error(),
+	})
+	if err != nil {
+		return handleError(fmt.Errorf("%v; queueing the message also failed: %w", sendErr, err))
+	}
+
+	result := fmt.Sprintf("Email queued in outbox (ID %s), run 'ghostmail outbox flush' to retry", entry.ID)
+	warning := fmt.Sprintf("submission failed: %v", sendErr)
+
+	// Output result
+	if jsonOutput {
+		resp := emailtypes.SendResponse{
+			Success: true,
+			Message: result,
+			Warning: warning,
+		}
+		return output.NewJSONOutput(true).Print(resp)
+	}
+
+	if !noColor {
+		color.Yellow("✓ %s", result)
+	} else {
+		fmt.Println(result)
+	}
+	printSentCopy("", warning)
+	return nil
+}
@@ -0,0 +1,408 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/GodGMN/ghostmail-cli/internal/config"
+	emailinternal "github.com/GodGMN/ghostmail-cli/internal/email"
+	"github.com/GodGMN/ghostmail-cli/internal/output"
+	"github.com/GodGMN/ghostmail-cli/internal/spool"
+	emailtypes "github.com/GodGMN/ghostmail-cli/pkg/email"
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+func newQueueCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "queue",
+		Short: "Manage scheduled messages",
+		Long: `Commands for messages scheduled with 'ghostmail send --at' or '--in'.
+
+Scheduled messages are stored fully built in a local spool directory
+($GHOSTMAIL_DATA_DIR/spool) until 'ghostmail queue run' delivers them.
+Run it from cron or a loop; it only sends messages that are due.
+
+If the SMTP server supports the FUTURERELEASE extension, 'queue run' hands
+messages to the server before they are due. The server holds them until
+the send time and they leave the queue.
+
+COMMANDS:
+  list    List scheduled messages
+  cancel  Remove a scheduled message
+  run     Send all messages that are due
+
+EXAMPLES:
+  # Deliver due messages every minute from cron
+  * * * * * ghostmail queue run
+
+  # Or in a loop
+  while true; do ghostmail queue run; sleep 60; done
+
+For more help, use: ghostmail queue --help`,
+	}
+
+	cmd.AddCommand(newQueueListCmd())
+	cmd.AddCommand(newQueueCancelCmd())
+	cmd.AddCommand(newQueueRunCmd())
+
+	return cmd
+}
+
+func newQueueListCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "list",
+		Short: "List scheduled messages",
+		Long: `List messages waiting in the spool, ordered by send time.
+
+EXAMPLES:
+  # List scheduled messages
+  ghostmail queue list
+
+  # JSON output for scripting
+  ghostmail queue list --json
+
+For more help, use: ghostmail queue list --help`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// Load configuration
+			cfg, err := config.Load()
+			if err != nil {
+				return handleError(err)
+			}
+
+			entries, err := spool.New(cfg.SpoolDir()).List()
+			if err != nil {
+				return handleError(err)
+			}
+
+			// Output
+			if jsonOutput {
+				resp := emailtypes.QueueResponse{
+					Success: true,
+					Entries: entries,
+					Total:   len(entries),
+				}
+				return output.NewJSONOutput(true).Print(resp)
+			}
+
+			if len(entries) == 0 {
+				fmt.Println("No scheduled messages")
+				return nil
+			}
+
+			printQueueTable(entries, "SEND AT", func(e emailtypes.QueueEntry) time.Time { return e.SendAt })
+
+			return nil
+		},
+	}
+}
+
+func newQueueCancelCmd() *cobra.Command {
+	var id string
+
+	cmd := &cobra.Command{
+		Use:   "cancel",
+		Short: "Remove a scheduled message",
+		Long: `Remove a message from the spool so it is never sent.
+
+REQUIRED FLAGS:
+  --id      The queue ID (from 'ghostmail queue list')
+
+EXAMPLES:
+  ghostmail queue cancel --id 3f9a2c1e
+
+For more help, use: ghostmail queue cancel --help`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if id == "" {
+				return handleError(fmt.Errorf("queue ID is required (use --id). Get from 'ghostmail queue list'. Use --help for usage info"))
+			}
+
+			// Load configuration
+			cfg, err := config.Load()
+			if err != nil {
+				return handleError(err)
+			}
+
+			if err := spool.New(cfg.SpoolDir()).Remove(id); err != nil {
+				return handleError(fmt.Errorf("%s: %w", id, err))
+			}
+
+			// Output result
+			if jsonOutput {
+				resp := emailtypes.SendResponse{
+					Success: true,
+					Message: fmt.Sprintf("Scheduled message %s cancelled", id),
+				}
+				return output.NewJSONOutput(true).Print(resp)
+			}
+
+			if !noColor {
+				color.Green("✓ Scheduled message %s cancelled", id)
+			} else {
+				fmt.Printf("Scheduled message %s cancelled\n", id)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&id, "id", "", "Queue ID of the message to cancel (required)")
+
+	cmd.MarkFlagRequired("id")
+
+	return cmd
+}
+
+func newQueueRunCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "run",
+		Short: "Send all messages that are due",
+		Long: `Send every scheduled message whose send time has come.
+
+Messages that are not due yet are handed to the server if it supports
+SMTP FUTURERELEASE; once the server declines a message, it is sent from the
+queue when due. Other hand-off failures, such as a refused login, are
+reported and the message is offered again on the next run. The Date header
+is set when a message is delivered.
+
+Messages that fail stay in the queue with the error recorded and are
+retried on the next run. Messages are claimed before sending, so
+overlapping runs never deliver the same message twice. If a run is killed
+while sending, its claims expire after 30 minutes and a later run sends
+those messages again.
+
+Exits with an error if any message failed.
+
+EXAMPLES:
+  # Deliver due messages
+  ghostmail queue run
+
+  # From cron, every minute
+  * * * * * ghostmail queue run --json >> ~/ghostmail-queue.log
+
+For more help, use: ghostmail queue run --help`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// Load configuration
+			cfg, err := config.Load()
+			if err != nil {
+				return handleError(err)
+			}
+
+			s := spool.New(cfg.SpoolDir())
+			now := time.Now()
+			if _, err := s.Reclaim(now); err != nil {
+				return handleError(err)
+			}
+			entries, err := s.List()
+			if err != nil {
+				return handleError(err)
+			}
+
+			// Messages are sent when due; until then the server is asked once
+			// to hold each of them with FUTURERELEASE
+			var pending []emailtypes.QueueEntry
+			for _, e := range entries {
+				if e.Status == spool.StatusScheduled && (!e.SendAt.After(now) || !e.NoFutureRelease) {
+					pending = append(pending, e)
+				}
+			}
+
+			if len(pending) > 0 {
+				if err := cfg.ValidateSMTP(); err != nil {
+					return handleError(err)
+				}
+			}
+
+			sender := emailinternal.NewSender(&cfg.SMTP)
+			resp := emailtypes.QueueRunResponse{Success: true}
+			for _, entry := range pending {
+				msg, err := s.Claim(entry.ID)
+				if errors.Is(err, spool.ErrClaimed) || errors.Is(err, spool.ErrNotFound) {
+					// Taken by a concurrent run, or cancelled meanwhile
+					continue
+				}
+				if err != nil {
+					return handleError(err)
+				}
+
+				if entry.SendAt.After(now) {
+					msg.SetDate(entry.SendAt)
+					if holdErr := sender.SubmitAt(msg, entry.SendAt); holdErr != nil {
+						if errors.Is(holdErr, emailinternal.ErrFutureReleaseUnsupported) {
+							// Sent from the spool when due instead
+							if err := s.Unclaim(entry.ID); err != nil {
+								return handleError(err)
+							}
+							continue
+						}
+						// Offered again on the next run
+						entry.Error = holdErr.Error()
+						resp.Failed = append(resp.Failed, entry)
+						resp.Success = false
+						if err := s.Requeue(entry.ID, holdErr); err != nil {
+							return handleError(err)
+						}
+						continue
+					}
+					if err := s.Remove(entry.ID); err != nil {
+						return handleError(err)
+					}
+					resp.Held = append(resp.Held, entry)
+				} else {
+					msg.SetDate(time.Now())
+					if sendErr := sender.Submit(msg); sendErr != nil {
+						entry.Attempts++
+						entry.Error = sendErr.Error()
+						resp.Failed = append(resp.Failed, entry)
+						resp.Success = false
+						if err := s.Release(entry.ID, sendErr, time.Time{}); err != nil {
+							return handleError(err)
+						}
+						continue
+					}
+					if err := s.Remove(entry.ID); err != nil {
+						return handleError(err)
+					}
+					entry.Error = ""
+					resp.Sent = append(resp.Sent, entry)
+				}
+
+				if entry.SaveSent {
+					if _, warning := saveSentCopy(cfg, msg); warning != "" {
+						resp.Warnings = append(resp.Warnings, fmt.Sprintf("%s: %s", entry.ID, warning))
+					}
+				}
+			}
+
+			return printRunResult(resp, "scheduled message(s)")
+		},
+	}
+}
+
+// scheduleMessage stores a built message in the local spool to be sent at
+// a later time. 'ghostmail queue run' hands it to the server with SMTP
+// FUTURERELEASE when the server supports it, or sends it when it is due.
+func scheduleMessage(cfg *config.Config, msg *emailinternal.OutgoingMessage, subject string, sendAt time.Time, saveSent bool) error {
+	entry, err := spool.New(cfg.SpoolDir()).Add(msg, emailtypes.QueueEntry{
+		SendAt:   sendAt,
+		Subject:  subject,
+		SaveSent: saveSent,
+	})
+	if err != nil {
+		return handleError(err)
+	}
+	result := fmt.Sprintf("Email scheduled for %s (queue ID %s)", sendAt.Format(time.RFC3339), entry.ID)
+
+	// Output result
+	if jsonOutput {
+		resp := emailtypes.SendResponse{
+			Success: true,
+			Message: result,
+		}
+		return output.NewJSONOutput(true).Print(resp)
+	}
+
+	if !noColor {
+		color.Green("✓ %s", result)
+	} else {
+		fmt.Println(result)
+	}
+	return nil
+}
+
+// printRunResult prints the outcome of delivering queued messages and
+// returns an error if any of them failed.
+func printRunResult(resp emailtypes.QueueRunResponse, what string) error {
+	failed := len(resp.Failed) + len(resp.Dead)
+
+	if jsonOutput {
+		if err := output.NewJSONOutput(true).Print(resp); err != nil {
+			return err
+		}
+		if failed > 0 {
+			os.Exit(1)
+		}
+		return nil
+	}
+
+	for _, e := range resp.Sent {
+		if !noColor {
+			color.Green("✓ Sent %s to %s", e.ID, strings.Join(e.To, ", "))
+		} else {
+			fmt.Printf("Sent %s to %s\n", e.ID, strings.Join(e.To, ", "))
+		}
+	}
+	for _, e := range resp.Held {
+		if !noColor {
+			color.Green("✓ Handed %s to the server for delivery at %s", e.ID, e.SendAt.Format(time.RFC3339))
+		} else {
+			fmt.Printf("Handed %s to the server for delivery at %s\n", e.ID, e.SendAt.Format(time.RFC3339))
+		}
+	}
+	for _, w := range resp.Warnings {
+		printSentCopy("", w)
+	}
+	for _, e := range resp.Failed {
+		if !noColor {
+			color.Red("✗ Failed %s (attempt %d): %s", e.ID, e.Attempts, e.Error)
+		} else {
+			fmt.Printf("Failed %s (attempt %d): %s\n", e.ID, e.Attempts, e.Error)
+		}
+	}
+	for _, e := range resp.Dead {
+		if !noColor {
+			color.Red("✗ Gave up on %s after %d attempt(s): %s", e.ID, e.Attempts, e.Error)
+		} else {
+			fmt.Printf("Gave up on %s after %d attempt(s): %s\n", e.ID, e.Attempts, e.Error)
+		}
+	}
+	if resp.Error != "" {
+		if !noColor {
+			color.Red("✗ %s", resp.Error)
+		} else {
+			fmt.Println(resp.Error)
+		}
+	}
+	if verbose {
+		fmt.Printf("%d sent, %d failed\n", len(resp.Sent), failed)
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d %s could not be sent", failed, what)
+	}
+	return nil
+}
+
+// printQueueTable prints queue entries as a table, with the given time
+// column.
+func printQueueTable(entries []emailtypes.QueueEntry, timeColumn string, timeOf func(emailtypes.QueueEntry) time.Time) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+
+	// Header
+	headerFmt := "%s\t%s\t%s\t%s\t%s\n"
+	if !noColor {
+		headerFmt = color.New(color.Bold).Sprint(headerFmt)
+	}
+	fmt.Fprintf(w, headerFmt, "ID", timeColumn, "TO", "SUBJECT", "STATUS")
+
+	// Rows
+	for _, e := range entries {
+		status := e.Status
+		if e.Error != "" {
+			status += " (last error: " + truncate(e.Error, 40) + ")"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n",
+			e.ID,
+			timeOf(e).Local().Format("2006-01-02 15:04"),
+			truncate(strings.Join(e.To, ", "), 25),
+			truncate(e.Subject, 30),
+			status,
+		)
+	}
+
+	w.Flush()
+	fmt.Printf("\nTotal: %d messages\n", len(entries))
+}
@@ -7,6 +7,8 @@ import (
 	"github.com/GodGMN/ghostmail-cli/internal/config"
 	emailinternal "github.com/GodGMN/ghostmail-cli/internal/email"
 	"github.com/GodGMN/ghostmail-cli/internal/output"
+	"github.com/GodGMN/ghostmail-cli/internal/pgp"
+	"github.com/GodGMN/ghostmail-cli/internal/smime"
 	emailtypes "github.com/GodGMN/ghostmail-cli/pkg/email"
 	"github.com/fatih/color"
 	"github.com/spf13/cobra"
@@ -14,9 +16,11 @@ import (
 
 func newReadCmd() *cobra.Command {
 	var (
-		uid     uint32
-		mailbox string
-		raw     bool
+		uid            uint32
+		mailbox        string
+		raw            bool
+		stripQuotes    bool
+		stripSignature bool
 	)
 
 	cmd := &cobra.Command{
@@ -43,6 +47,19 @@ EXAMPLES:
   # Extract subject using jq
   ghostmail read --uid 12345 --json | jq -r '.message.subject'
 
+  # Only the newly written text of a reply (no quoted history or signature)
+  ghostmail read --uid 12345 --strip-quotes --strip-signature
+
+OpenPGP messages (PGP/MIME or inline) are decrypted and verified with the
+keys in $GHOSTMAIL_PGP_DIR; the result is reported in the security field.
+Keys advertised in Autocrypt headers are added to the keyring. S/MIME messages
+are decrypted with $GHOSTMAIL_SMIME_CERT, and signatures are verified against
+$GHOSTMAIL_SMIME_TRUST_STORE (default: the system roots); the signer's
+certificate subject and validity are reported too.
+
+When quoted history or a signature is detected, the JSON output also contains
+body_new, body_quoted and signature fields.
+
 For more help, use: ghostmail read --help`,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			if uid == 0 {
@@ -64,21 +81,49 @@ For more help, use: ghostmail read --help`,
 				cfg.IMAP.Mailbox = mailbox
 			}
 
+			// Encrypted and signed messages are opened with the keyring
+			// and the S/MIME certificates, loaded (and their secrets read)
+			// only for such messages. A keyring or certificate store that
+			// cannot be loaded only leaves those messages unopened
+			var warnings []string
+			loadPGP := func() (*pgp.Keyring, error) {
+				keyring, err := loadKeyring(cfg)
+				if err != nil {
+					warnings = append(warnings, fmt.Sprintf("PGP disabled: %v", err))
+				}
+				return keyring, err
+			}
+			loadStore := func() (*smime.Store, error) {
+				store, err := loadSMIME(cfg)
+				if err != nil {
+					warnings = append(warnings, fmt.Sprintf("S/MIME disabled: %v", err))
+				}
+				return store, err
+			}
+
 			// Fetch message
-			reader := emailinternal.NewReader(&cfg.IMAP)
+			reader := emailinternal.NewReader(&cfg.IMAP, emailinternal.WithKeyringLoader(loadPGP), emailinternal.WithSMIMELoader(loadStore))
 			msg, err := reader.ReadMessage(uid)
 			if err != nil {
 				return handleError(fmt.Errorf("%w. Use --help for usage info", err))
 			}
 
+			if stripQuotes || stripSignature {
+				stripBody(msg, stripQuotes, stripSignature)
+			}
+
 			// Output
 			if jsonOutput {
 				resp := emailtypes.ReadResponse{
-					Success: true,
-					Message: *msg,
+					Success:  true,
+					Message:  *msg,
+					Warnings: warnings,
 				}
 				return output.NewJSONOutput(true).Print(resp)
 			}
+			for _, warning := range warnings {
+				printWarning(warning)
+			}
 
 			// Human-readable output
 			if !noColor {
@@ -101,6 +146,9 @@ For more help, use: ghostmail read --help`,
 				}
 				fmt.Printf("Date: %s\n", msg.Date.Format("2006-01-02 15:04:05"))
 				fmt.Printf("UID: %d\n", msg.UID)
+				if msg.Security != nil {
+					fmt.Printf("Security: %s\n", formatSecurity(msg.Security))
+				}
 			} else {
 				headerColor.Printf("Subject: ")
 				fmt.Println(msg.Subject)
@@ -120,6 +168,10 @@ For more help, use: ghostmail read --help`,
 				fmt.Println(msg.Date.Format("2006-01-02 15:04:05"))
 				headerColor.Printf("UID: ")
 				fmt.Println(msg.UID)
+				if msg.Security != nil {
+					headerColor.Printf("Security: ")
+					fmt.Println(formatSecurity(msg.Security))
+				}
 			}
 
 			if !noColor {
@@ -152,6 +204,18 @@ For more help, use: ghostmail read --help`,
 				}
 			}
 
+			// Calendar invitations
+			if len(msg.Events) > 0 {
+				if !noColor {
+					color.Cyan("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
+				} else {
+					fmt.Println("----------------------------------------")
+				}
+				for _, ev := range msg.Events {
+					printEvent(ev)
+				}
+			}
+
 			if !noColor {
 				color.Cyan("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
 			} else {
@@ -165,8 +229,99 @@ For more help, use: ghostmail read --help`,
 	cmd.Flags().Uint32VarP(&uid, "uid", "u", 0, "Message UID (required). Get from 'ghostmail inbox'")
 	cmd.Flags().StringVarP(&mailbox, "mailbox", "m", "", "Mailbox to read from (default: INBOX)")
 	cmd.Flags().BoolVar(&raw, "raw", false, "Show raw/preview body only (faster)")
+	cmd.Flags().BoolVar(&stripQuotes, "strip-quotes", false, "Remove quoted history from the body")
+	cmd.Flags().BoolVar(&stripSignature, "strip-signature", false, "Remove the sender's signature from the body")
 
 	cmd.MarkFlagRequired("uid")
 
 	return cmd
 }
+
+// stripBody removes the quoted history and/or signature detected by the
+// reader from the message body.
+func stripBody(msg *emailtypes.Message, quotes, signature bool) {
+	if msg.BodyQuoted == "" && msg.Signature == "" {
+		return
+	}
+
+	body := msg.BodyNew
+	if signature {
+		msg.Signature = ""
+	} else if msg.Signature != "" {
+		body += "\n\n" + msg.Signature
+	}
+	if quotes {
+		msg.BodyQuoted = ""
+	} else if msg.BodyQuoted != "" {
+		body += "\n\n" + msg.BodyQuoted
+	}
+	msg.Body = strings.TrimSpace(body)
+}
+
+// printEvent prints a calendar event in human-readable form.
+func printEvent(ev emailtypes.CalendarEvent) {
+	method := ev.Method
+	if method == "" {
+		method = "EVENT"
+	}
+	fmt.Printf("Invitation (%s): %s\n", method, ev.Summary)
+
+	layout := "2006-01-02 15:04"
+	if ev.AllDay {
+		layout = "2006-01-02"
+	}
+	when := ev.Start.Format(layout)
+	if !ev.End.IsZero() {
+		when += " - " + ev.End.Format(layout)
+	}
+	if ev.TimeZone != "" {
+		when += " (" + ev.TimeZone + ")"
+	}
+	fmt.Printf("  When: %s\n", when)
+
+	if ev.Location != "" {
+		fmt.Printf("  Where: %s\n", ev.Location)
+	}
+	if ev.Organizer != "" {
+		fmt.Printf("  Organizer: %s\n", ev.Organizer)
+	}
+	for _, rule := range ev.Recurrence {
+		fmt.Printf("  Repeats: %s\n", rule)
+	}
+	for _, att := range ev.Attendees {
+		status := att.Status
+		if status == "" {
+			status = "NEEDS-ACTION"
+		}
+		fmt.Printf("  - %s (%s)\n", att.Email, status)
+	}
+}
+
+// formatSecurity describes the encryption and signature of a message.
+func formatSecurity(sec *emailtypes.Security) string {
+	var parts []string
+	switch {
+	case sec.Decrypted:
+		parts = append(parts, "decrypted")
+	case sec.Encrypted:
+		parts = append(parts, "encrypted, not decrypted")
+	}
+	if sec.Signed {
+		signature := "signature " + strings.ReplaceAll(sec.SignatureStatus, "_", " ")
+		if sec.Signer != "" {
+			signature += " from " + sec.Signer
+		}
+		if sec.SignerKeyID != "" {
+			signature += " (key " + sec.SignerKeyID + ")"
+		}
+		if cert := sec.Certificate; cert != nil {
+			signature += fmt.Sprintf(" (certificate issued by %s, valid %s to %s)",
+				cert.Issuer, cert.NotBefore.Format("2006-01-02"), cert.NotAfter.Format("2006-01-02"))
+		}
+		parts = append(parts, signature)
+	}
+	if sec.Error != "" {
+		parts = append(parts, sec.Error)
+	}
+	return sec.Protocol + ": " + strings.Join(parts, "; ")
+}
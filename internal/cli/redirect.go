@@ -0,0 +1,124 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/GodGMN/ghostmail-cli/internal/config"
+	emailinternal "github.com/GodGMN/ghostmail-cli/internal/email"
+	"github.com/GodGMN/ghostmail-cli/internal/output"
+	emailtypes "github.com/GodGMN/ghostmail-cli/pkg/email"
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+func newRedirectCmd() *cobra.Command {
+	var (
+		uid     uint32
+		mailbox string
+		to      []string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "redirect",
+		Short: "Redirect (bounce) an email by UID without altering it",
+		Long: `Redirect an email by its UID to other recipients, unchanged.
+
+Unlike 'ghostmail forward', the original message is delivered as-is: the
+original From, Subject, body and DKIM-signed headers are kept. Only RFC 5322
+Resent-From, Resent-To, Resent-Date and Resent-Message-ID headers are
+prepended, and the message is submitted with the new envelope recipients.
+
+Useful for handing messages over to ticket systems or shared inboxes.
+
+REQUIRED FLAGS:
+  --uid     The UID of the email to redirect (from 'ghostmail inbox')
+  --to      Recipient email address(es)
+
+EXAMPLES:
+  # Hand a message over to the ticket system
+  ghostmail redirect --uid 12345 --to support@tickets.example.com
+
+  # Redirect to several recipients from a specific mailbox
+  ghostmail redirect --uid 12345 --mailbox Shared -t a@example.com -t b@example.com
+
+For more help, use: ghostmail redirect --help`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if uid == 0 {
+				return handleError(fmt.Errorf("UID is required (use --uid). Get from 'ghostmail inbox'. Use --help for usage info"))
+			}
+			if len(to) == 0 {
+				return handleError(fmt.Errorf("at least one recipient (--to) is required. Use --help for usage info"))
+			}
+			if err := checkRecipients(to); err != nil {
+				return handleError(err)
+			}
+
+			// Load configuration
+			cfg, err := config.Load()
+			if err != nil {
+				return handleError(err)
+			}
+
+			if err := cfg.ValidateIMAP(); err != nil {
+				return handleError(fmt.Errorf("IMAP config error: %w. Use --help for usage info", err))
+			}
+			if err := cfg.ValidateSMTP(); err != nil {
+				return handleError(fmt.Errorf("SMTP config error: %w. Use --help for usage info", err))
+			}
+
+			// Override mailbox if specified
+			if mailbox != "" {
+				cfg.IMAP.Mailbox = mailbox
+			}
+
+			// Fetch the raw message
+			reader := emailinternal.NewReader(&cfg.IMAP)
+			original, raw, err := reader.ReadMessageRaw(uid)
+			if err != nil {
+				return handleError(fmt.Errorf("failed to fetch original message: %w. Use --help for usage info", err))
+			}
+			if len(raw) == 0 {
+				return handleError(fmt.Errorf("message %d has no content to redirect", uid))
+			}
+
+			sender := emailinternal.NewSender(&cfg.SMTP)
+			if err := sender.Redirect(raw, to); err != nil {
+				return handleError(err)
+			}
+
+			// Output result
+			if jsonOutput {
+				resp := emailtypes.SendResponse{
+					Success:       true,
+					Message:       fmt.Sprintf("Message redirected to %s", strings.Join(to, ", ")),
+					AuthMechanism: sender.Mechanism(),
+				}
+				return output.NewJSONOutput(true).Print(resp)
+			}
+
+			if !noColor {
+				color.Green("✓ Message redirected to %s", strings.Join(to, ", "))
+			} else {
+				fmt.Printf("Message redirected to %s\n", strings.Join(to, ", "))
+			}
+
+			if verbose {
+				fmt.Printf("  Subject: %s\n", original.Subject)
+				fmt.Printf("  Original From: %s\n", original.From)
+			}
+			printAuthMechanism("SMTP", sender.Mechanism())
+
+			return nil
+		},
+	}
+
+	cmd.Flags().Uint32VarP(&uid, "uid", "u", 0, "Message UID to redirect (required). Get from 'ghostmail inbox'")
+	cmd.Flags().StringVarP(&mailbox, "mailbox", "m", "", "Mailbox containing the message (default: INBOX)")
+	cmd.Flags().StringArrayVarP(&to, "to", "t", nil, "Recipient address or comma-separated list (can be specified multiple times)")
+
+	cmd.MarkFlagRequired("uid")
+	cmd.MarkFlagRequired("to")
+
+	return cmd
+}
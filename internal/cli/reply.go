@@ -15,12 +15,20 @@ import (
 
 func newReplyCmd() *cobra.Command {
 	var (
-		uid      uint32
-		mailbox  string
-		body     string
-		bodyFile string
-		all      bool // Reply to all (include CC)
-		noQuote  bool // Skip quoting original
+		uid        uint32
+		mailbox    string
+		body       string
+		bodyFile   string
+		all        bool // Reply to all (include CC)
+		noQuote    bool // Skip quoting original
+		draft      bool // Save to Drafts instead of sending
+		noSaveSent bool // Don't keep a copy in the Sent mailbox
+		markdown   string
+		mdFile     string
+		headers    []string
+		replyTo    []string
+		priority   string
+		receipt    bool
 	)
 
 	cmd := &cobra.Command{
@@ -40,9 +48,12 @@ The reply body will be formatted as:
   > Original message line 2
   > ...
 
+With --markdown or --markdown-file, the reply is written in Markdown and also
+sent as HTML with inline styles; the quoted original becomes a blockquote.
+
 REQUIRED FLAGS:
   --uid     The UID of the email to reply to (from 'ghostmail inbox')
-  --body    Your reply text (or use --body-file)
+  --body    Your reply text (or use --body-file or --markdown)
 
 EXAMPLES:
   # Simple reply
@@ -60,6 +71,15 @@ EXAMPLES:
   # Body from file
   ghostmail reply --uid 12345 --body-file response.txt
 
+  # Formatted reply in Markdown
+  ghostmail reply --uid 12345 --markdown "**Done**, see the [runbook](https://example.com/runbook)"
+
+  # High priority reply with a tracking header
+  ghostmail reply --uid 12345 --body "Fixed" --priority high --header "X-Ticket-ID: 4711"
+
+  # Save the reply as a draft for a human to approve
+  ghostmail reply --uid 12345 --body-file response.txt --draft
+
 For more help, use: ghostmail reply --help`,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			if uid == 0 {
@@ -75,8 +95,10 @@ For more help, use: ghostmail reply --help`,
 			if err := cfg.ValidateIMAP(); err != nil {
 				return handleError(fmt.Errorf("IMAP config error: %w. Use --help for usage info", err))
 			}
-			if err := cfg.ValidateSMTP(); err != nil {
-				return handleError(fmt.Errorf("SMTP config error: %w. Use --help for usage info", err))
+			if !draft {
+				if err := cfg.ValidateSMTP(); err != nil {
+					return handleError(fmt.Errorf("SMTP config error: %w. Use --help for usage info", err))
+				}
 			}
 
 			// Handle body from file
@@ -88,8 +110,25 @@ For more help, use: ghostmail reply --help`,
 				body = string(data)
 			}
 
+			// Handle Markdown body
+			md, err := readMarkdown(markdown, mdFile)
+			if err != nil {
+				return handleError(err)
+			}
+			if md != "" {
+				if body != "" {
+					return handleError(fmt.Errorf("--markdown cannot be combined with --body or --body-file. Use --help for usage info"))
+				}
+				body = md
+			}
+
 			if body == "" {
-				return handleError(fmt.Errorf("reply body is required (use --body or --body-file). Use --help for usage info"))
+				return handleError(fmt.Errorf("reply body is required (use --body, --body-file or --markdown). Use --help for usage info"))
+			}
+
+			headerOpts, err := headerOptions(headers, replyTo, priority, receipt)
+			if err != nil {
+				return handleError(err)
 			}
 
 			// Override mailbox if specified
@@ -109,24 +148,12 @@ For more help, use: ghostmail reply --help`,
 			var cc []string
 
 			if all {
-				// Reply to all: include original CC recipients (excluding self)
-				for _, addr := range original.CC {
-					if !isSelf(addr, cfg.SMTP.From, cfg.SMTP.Username) {
-						cc = append(cc, addr)
-					}
-				}
-				// Also add original To recipients if not the sender
-				for _, addr := range original.To {
-					if !isSelf(addr, cfg.SMTP.From, cfg.SMTP.Username) && addr != original.From {
-						// Avoid duplicates
-						found := false
-						for _, existing := range cc {
-							if existing == addr {
-								found = true
-								break
-							}
-						}
-						if !found {
+				// Reply to all: include the original CC and To recipients,
+				// except yourself. Build drops addresses repeated across
+				// To and CC, such as the original sender.
+				for _, list := range [][]string{original.CC, original.To} {
+					for _, addr := range list {
+						if !isSelf(addr, cfg.SMTP.From, cfg.SMTP.Username) {
 							cc = append(cc, addr)
 						}
 					}
@@ -162,21 +189,49 @@ For more help, use: ghostmail reply --help`,
 				opts = append(opts, emailinternal.WithCC(cc))
 			}
 
+			// Markdown replies get an HTML part, quoted original included
+			if md != "" {
+				htmlBody, err := emailinternal.MarkdownToHTML(replyBody)
+				if err != nil {
+					return handleError(err)
+				}
+				opts = append(opts, emailinternal.WithHTMLBody(htmlBody))
+			}
+
+			opts = append(opts, headerOpts...)
+
 			// Set threading headers
 			if original.MessageID != "" {
 				opts = append(opts, emailinternal.WithInReplyTo(original.MessageID))
 				opts = append(opts, emailinternal.WithReferences(references))
 			}
 
-			if err := sender.Send(to, subject, replyBody, opts...); err != nil {
+			if draft {
+				return saveDraft(cfg, sender, to, subject, replyBody, opts)
+			}
+
+			msg, err := sender.Build(to, subject, replyBody, opts...)
+			if err != nil {
+				return handleError(err)
+			}
+			if err := sender.Submit(msg); err != nil {
 				return handleError(err)
 			}
 
+			// Keep a copy in the Sent mailbox
+			var savedTo, warning string
+			if !noSaveSent {
+				savedTo, warning = saveSentCopy(cfg, msg)
+			}
+
 			// Output result
 			if jsonOutput {
 				resp := emailtypes.SendResponse{
-					Success: true,
-					Message: fmt.Sprintf("Reply sent to %s", strings.Join(to, ", ")),
+					Success:       true,
+					Message:       fmt.Sprintf("Reply sent to %s", strings.Join(to, ", ")),
+					SavedTo:       savedTo,
+					Warning:       warning,
+					AuthMechanism: sender.Mechanism(),
 				}
 				return output.NewJSONOutput(true).Print(resp)
 			}
@@ -194,6 +249,8 @@ For more help, use: ghostmail reply --help`,
 					fmt.Printf("  CC: %s\n", strings.Join(cc, ", "))
 				}
 			}
+			printSentCopy(savedTo, warning)
+			printAuthMechanism("SMTP", sender.Mechanism())
 
 			return nil
 		},
@@ -203,8 +260,16 @@ For more help, use: ghostmail reply --help`,
 	cmd.Flags().StringVarP(&mailbox, "mailbox", "m", "", "Mailbox containing the message (default: INBOX)")
 	cmd.Flags().StringVarP(&body, "body", "b", "", "Reply body text")
 	cmd.Flags().StringVar(&bodyFile, "body-file", "", "Read reply body from file")
+	cmd.Flags().StringVar(&markdown, "markdown", "", "Reply body in Markdown (sent as HTML with a plain text alternative)")
+	cmd.Flags().StringVar(&mdFile, "markdown-file", "", "Read Markdown reply body from file")
 	cmd.Flags().BoolVarP(&all, "all", "a", false, "Reply to all recipients (include CC)")
 	cmd.Flags().BoolVar(&noQuote, "no-quote", false, "Don't quote the original message")
+	cmd.Flags().StringArrayVarP(&headers, "header", "H", nil, "Custom header as \"Name: value\" (can be specified multiple times)")
+	cmd.Flags().StringArrayVar(&replyTo, "reply-to", nil, "Reply-To address (can be specified multiple times)")
+	cmd.Flags().StringVar(&priority, "priority", "", "Message priority: high, normal or low")
+	cmd.Flags().BoolVar(&receipt, "request-receipt", false, "Request a read receipt (Disposition-Notification-To)")
+	cmd.Flags().BoolVar(&draft, "draft", false, "Save the reply to the Drafts mailbox instead of sending")
+	cmd.Flags().BoolVar(&noSaveSent, "no-save-sent", false, "Don't save a copy to the Sent mailbox")
 
 	cmd.MarkFlagRequired("uid")
 
@@ -213,16 +278,5 @@ For more help, use: ghostmail reply --help`,
 
 // isSelf checks if an address belongs to the current user
 func isSelf(addr, from, username string) bool {
-	addr = strings.ToLower(addr)
-	from = strings.ToLower(from)
-	username = strings.ToLower(username)
-
-	// Extract email from "Name <email>" format
-	if idx := strings.LastIndex(addr, "<"); idx != -1 {
-		if endIdx := strings.LastIndex(addr, ">"); endIdx != -1 && endIdx > idx {
-			addr = addr[idx+1 : endIdx]
-		}
-	}
-
-	return addr == from || addr == username
+	return emailinternal.SameAddress(addr, from) || emailinternal.SameAddress(addr, username)
 }
@@ -0,0 +1,208 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/GodGMN/ghostmail-cli/internal/config"
+	emailinternal "github.com/GodGMN/ghostmail-cli/internal/email"
+	"github.com/GodGMN/ghostmail-cli/internal/output"
+	emailtypes "github.com/GodGMN/ghostmail-cli/pkg/email"
+	"github.com/fatih/color"
+)
+
+// sendRequests sends the SendRequest JSON read from path ("-" for standard
+// input) and prints one SendResponse per request. All requests are read
+// before anything is sent, so malformed input sends nothing. With dryRun,
+// the messages are built and written to outputFile or printed instead.
+func sendRequests(cfg *config.Config, path string, saveSent, dryRun bool, outputFile string) error {
+	var r io.Reader = os.Stdin
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return handleError(fmt.Errorf("failed to read request file: %w. Use --help for usage info", err))
+		}
+		defer f.Close()
+		r = f
+	}
+
+	requests, err := emailinternal.ReadSendRequests(r)
+	if err != nil {
+		return handleError(fmt.Errorf("%w. Use --help for usage info", err))
+	}
+
+	sender := emailinternal.NewSender(&cfg.SMTP)
+	if dryRun {
+		return dryRunRequests(sender, requests, outputFile)
+	}
+	var session *emailinternal.Session
+
+	responses := make([]emailtypes.SendResponse, len(requests))
+	failed := 0
+	for i := range requests {
+		resp := &responses[i]
+
+		msg, err := buildRequest(sender, &requests[i])
+		if err == nil {
+			if session == nil {
+				session, err = sender.Open()
+			}
+			if err == nil {
+				if err = session.Submit(msg); err != nil {
+					// Start over with a fresh connection for the next request
+					session.Close()
+					session = nil
+				}
+			}
+		}
+
+		if err != nil {
+			resp.Error = err.Error()
+			failed++
+			continue
+		}
+		resp.Success = true
+		resp.Message = "Email sent successfully"
+		resp.MessageID = msg.MessageID
+		resp.AuthMechanism = sender.Mechanism()
+		if saveSent {
+			resp.SavedTo, resp.Warning = saveSentCopy(cfg, msg)
+		}
+	}
+
+	if session != nil {
+		session.Close()
+	}
+
+	// Output results: the usual response for a single request, otherwise
+	// one line per request
+	single := len(responses) == 1
+	if jsonOutput {
+		for _, resp := range responses {
+			if err := output.NewJSONOutput(single).Print(resp); err != nil {
+				return err
+			}
+		}
+		if failed > 0 {
+			os.Exit(1)
+		}
+		return nil
+	}
+
+	for i, resp := range responses {
+		prefix := ""
+		if !single {
+			prefix = fmt.Sprintf("%d: ", i+1)
+		}
+		if !resp.Success {
+			if single {
+				return fmt.Errorf("%s", resp.Error)
+			}
+			if !noColor {
+				color.Red("✗ %s%s", prefix, resp.Error)
+			} else {
+				fmt.Printf("Failed %s%s\n", prefix, resp.Error)
+			}
+			continue
+		}
+
+		if !noColor {
+			color.Green("✓ %s%s to %s", prefix, resp.Message, strings.Join(requests[i].To, ", "))
+		} else {
+			fmt.Printf("%s%s to %s\n", prefix, resp.Message, strings.Join(requests[i].To, ", "))
+		}
+		printSentCopy(resp.SavedTo, resp.Warning)
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d messages failed", failed, len(responses))
+	}
+	return nil
+}
+
+// dryRunRequests builds the messages of requests without sending them. A
+// single request is written like send --dry-run; several are written to
+// numbered files next to path (message-1.eml, message-2.eml, ...) or printed
+// as one DryRunResponse per line with --json.
+func dryRunRequests(sender *emailinternal.Sender, requests []emailtypes.SendRequest, path string) error {
+	if len(requests) == 1 {
+		msg, err := buildRequest(sender, &requests[0])
+		if err != nil {
+			return handleError(err)
+		}
+		return writeDryRun(sender, msg, path)
+	}
+	if path == "" && !jsonOutput {
+		return handleError(fmt.Errorf("a dry run of several requests needs --output or --json. Use --help for usage info"))
+	}
+
+	failed := 0
+	for i := range requests {
+		var resp emailtypes.DryRunResponse
+		msg, err := buildRequest(sender, &requests[i])
+		if err == nil {
+			err = sender.Render(msg)
+		}
+		if err == nil && path != "" {
+			resp.Output = numberedPath(path, i+1)
+			err = os.WriteFile(resp.Output, msg.Data, 0600)
+		}
+
+		if err != nil {
+			resp.Output = ""
+			resp.Error = err.Error()
+			failed++
+		} else {
+			resp.Success = true
+			resp.MessageID = msg.MessageID
+			resp.Envelope = emailtypes.Envelope{From: msg.From, Recipients: msg.Recipients}
+			resp.Size = len(msg.Data)
+			if path == "" {
+				resp.Data = string(msg.Data)
+			}
+		}
+
+		if jsonOutput {
+			if err := output.NewJSONOutput(false).Print(resp); err != nil {
+				return err
+			}
+			continue
+		}
+		switch {
+		case !resp.Success && !noColor:
+			color.Red("✗ %d: %s", i+1, resp.Error)
+		case !resp.Success:
+			fmt.Printf("Failed %d: %s\n", i+1, resp.Error)
+		case !noColor:
+			color.Green("✓ %d: Message written to %s (%d bytes, not sent)", i+1, resp.Output, resp.Size)
+		default:
+			fmt.Printf("%d: Message written to %s (%d bytes, not sent)\n", i+1, resp.Output, resp.Size)
+		}
+	}
+
+	if failed > 0 {
+		if jsonOutput {
+			os.Exit(1)
+		}
+		return fmt.Errorf("%d of %d messages failed", failed, len(requests))
+	}
+	return nil
+}
+
+// numberedPath inserts "-n" before the extension of path.
+func numberedPath(path string, n int) string {
+	ext := filepath.Ext(path)
+	return fmt.Sprintf("%s-%d%s", strings.TrimSuffix(path, ext), n, ext)
+}
+
+// buildRequest validates a request, including the attachment limits of
+// send, and builds its message.
+func buildRequest(sender *emailinternal.Sender, req *emailtypes.SendRequest) (*emailinternal.OutgoingMessage, error) {
+	if err := validateAttachments(req.Attachments); err != nil {
+		return nil, err
+	}
+	return sender.BuildRequest(req)
+}
@@ -2,6 +2,10 @@
 package cli
 
 import (
+	"os"
+	"path/filepath"
+
+	"github.com/GodGMN/ghostmail-cli/internal/config"
 	"github.com/spf13/cobra"
 )
 
@@ -18,7 +22,8 @@ func Execute(version, commit, date string) error {
 		Use:   "ghostmail",
 		Short: "A CLI tool for sending and reading emails",
 		Long: `Ghostmail is a command-line email client that supports SMTP for sending
-and IMAP for reading emails. All configuration is done via environment variables.`,
+and IMAP for reading emails. It is configured with environment variables and
+an optional configuration file with named accounts.`,
 		Version: version,
 	}
 
@@ -26,13 +31,31 @@ and IMAP for reading emails. All configuration is done via environment variables
 	rootCmd.PersistentFlags().BoolVarP(&jsonOutput, "json", "j", false, "Output in JSON format")
 	rootCmd.PersistentFlags().BoolVar(&noColor, "no-color", false, "Disable colored output")
 	rootCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "Enable verbose output")
+	rootCmd.PersistentFlags().StringVar(&config.FileFlag, "config", "", "Configuration file (default: $XDG_CONFIG_HOME/ghostmail/config.toml)")
+	rootCmd.PersistentFlags().StringVar(&config.AccountFlag, "account", "", "Account from the configuration file; its settings ignore the environment (default: default_account)")
 
 	// Add commands
 	rootCmd.AddCommand(newSendCmd())
 	rootCmd.AddCommand(newInboxCmd())
 	rootCmd.AddCommand(newReadCmd())
 	rootCmd.AddCommand(newReplyCmd())
+	rootCmd.AddCommand(newForwardCmd())
+	rootCmd.AddCommand(newRedirectCmd())
+	rootCmd.AddCommand(newInviteCmd())
+	rootCmd.AddCommand(newDraftsCmd())
+	rootCmd.AddCommand(newQueueCmd())
+	rootCmd.AddCommand(newOutboxCmd())
+	rootCmd.AddCommand(newMergeCmd())
+	rootCmd.AddCommand(newTemplateCmd())
+	rootCmd.AddCommand(newSendmailCmd())
+	rootCmd.AddCommand(newDKIMCmd())
 	rootCmd.AddCommand(newConfigCmd())
+	rootCmd.AddCommand(newDoctorCmd())
+
+	// Installed as sendmail: behave like it, with the sendmail arguments
+	if filepath.Base(os.Args[0]) == sendmailName {
+		rootCmd.SetArgs(append([]string{sendmailName}, os.Args[1:]...))
+	}
 
 	return rootCmd.Execute()
 }
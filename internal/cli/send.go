@@ -3,27 +3,54 @@ package cli
 import (
 	"fmt"
 	"os"
+	"strings"
+	"time"
 
 	"github.com/GodGMN/ghostmail-cli/internal/config"
 	emailinternal "github.com/GodGMN/ghostmail-cli/internal/email"
 	"github.com/GodGMN/ghostmail-cli/internal/output"
+	"github.com/GodGMN/ghostmail-cli/internal/pgp"
+	"github.com/GodGMN/ghostmail-cli/internal/smime"
+	"github.com/GodGMN/ghostmail-cli/internal/spool"
 	emailtypes "github.com/GodGMN/ghostmail-cli/pkg/email"
 	"github.com/fatih/color"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 )
 
 func newSendCmd() *cobra.Command {
 	var (
-		to          []string
-		cc          []string
-		bcc         []string
-		subject     string
-		body        string
-		bodyFile    string
-		htmlFile    string
-		attachments []string
-		htmlBody    string
-		inReplyTo   string
+		to           []string
+		cc           []string
+		bcc          []string
+		subject      string
+		body         string
+		bodyFile     string
+		htmlFile     string
+		attachments  []string
+		htmlBody     string
+		inReplyTo    string
+		invite       string
+		draft        bool
+		noSaveSent   bool
+		sendAt       string
+		sendIn       string
+		queueOnFail  bool
+		tmplName     string
+		tmplVars     []string
+		markdown     string
+		mdFile       string
+		request      string
+		headers      []string
+		replyTo      []string
+		priority     string
+		receipt      bool
+		pgpSign      bool
+		pgpEncrypt   bool
+		smimeSign    bool
+		smimeEncrypt bool
+		dryRun       bool
+		outputFile   string
 	)
 
 	cmd := &cobra.Command{
@@ -34,10 +61,41 @@ func newSendCmd() *cobra.Command {
 You can provide the email body directly with --body, or read from a file with --body-file.
 HTML content can be provided with --html-file for rich formatting.
 
+With --markdown or --markdown-file, the body is written in Markdown (CommonMark
+with tables, code blocks and links). It is sent as an HTML part with inline
+styles, and the Markdown itself as the plain text alternative.
+
+With --template, recipients, subject, body and attachments come from a stored
+template (see 'ghostmail template --help'). Flags add recipients and
+attachments, and override the subject and body.
+
+With --request, messages are read as SendRequest JSON (fields from, to, cc,
+bcc, subject, body, html_body, attachments, headers) from a file or standard
+input: a single object, or one object per line (NDJSON) to send several.
+One result is printed per request.
+
+With --dry-run, the message is built exactly as it would be submitted
+(Message-ID, Date, threading and custom headers, DKIM signature) and written
+to standard output instead of being sent; --output writes it to a file such
+as message.eml. With --json, the envelope sender and recipients are printed
+along with it. Only a sender address (GHOSTMAIL_SMTP_FROM) is required.
+
+With --sign and --encrypt, the message is sent as OpenPGP/MIME (RFC 3156).
+Keys are read from $GHOSTMAIL_PGP_DIR (default ~/.config/ghostmail/pgp):
+your secret key and the public keys of your recipients, plus keys collected
+from Autocrypt headers by 'ghostmail read'. Every recipient needs a key.
+
+With --smime-sign and --smime-encrypt, the message is sent as S/MIME. Your
+certificate and key are read from $GHOSTMAIL_SMIME_CERT (PEM, or PKCS#12
+.p12/.pfx) and $GHOSTMAIL_SMIME_KEY, the certificates of your recipients
+from $GHOSTMAIL_SMIME_CERTS_DIR (default ~/.config/ghostmail/smime). Every
+recipient needs a certificate.
+
 REQUIRED FLAGS:
   --to      Recipient email address(es)
   --subject Email subject line
   --body    Email body text (or use --body-file)
+  (or --template with its variables, or --request)
 
 EXAMPLES:
   # Simple text email
@@ -61,10 +119,57 @@ EXAMPLES:
   # Body from file
   ghostmail send --to user@example.com --subject "Report" --body-file report.txt
 
+  # Formatted report from Markdown
+  ghostmail send --to team@example.com --subject "Weekly report" \
+    --markdown-file report.md
+
   # Reply to a message (enables threading)
   ghostmail send --to user@example.com --subject "Re: Original" \
     --body "My reply" --in-reply-to "<msg-id@example.com>"
 
+  # Urgent, with replies going to the team and a tracking header
+  ghostmail send --to user@example.com --subject "Outage" --body "Investigating" \
+    --priority high --reply-to team@example.com --header "X-Ticket-ID: 4711"
+
+  # Signed and encrypted with OpenPGP
+  ghostmail send --to partner@example.com --subject "Contract" \
+    --body-file contract.txt --sign --encrypt
+
+  # Signed and encrypted with S/MIME
+  ghostmail send --to partner@example.com --subject "Contract" \
+    --body-file contract.txt --smime-sign --smime-encrypt
+
+  # Check what would be sent, without an SMTP server
+  ghostmail send --to user@example.com --subject "Hello" --body "World" \
+    --dry-run --output message.eml --json
+
+  # Save as a draft for a human to review instead of sending
+  ghostmail send --to user@example.com --subject "Proposal" \
+    --body-file proposal.txt --draft
+
+  # Deliver at 9am in the recipient's time zone
+  ghostmail send --to user@example.com --subject "Good morning" \
+    --body "Hello" --at "2024-06-01T09:00:00+02:00"
+
+  # Deliver in two hours ('ghostmail queue run' sends spooled messages)
+  ghostmail send --to user@example.com --subject "Reminder" \
+    --body "Stand-up soon" --in 2h
+
+  # Keep the message in the outbox if SMTP is down ('ghostmail outbox flush')
+  ghostmail send --to oncall@example.com --subject "Build failed" \
+    --body-file log.txt --queue-on-failure
+
+  # From a stored template
+  ghostmail send --template incident --var service=api --var owner=ann@example.com
+
+  # From JSON, without shell escaping (one object, or NDJSON for many)
+  echo '{"to":["user@example.com"],"subject":"Hi","body":"It's me"}' | \
+    ghostmail send --request - --json
+
+  # Meeting invitation (METHOD:REQUEST) from an iCalendar file
+  ghostmail send --to user@example.com --subject "Sprint planning" \
+    --body "See invitation" --invite event.ics
+
 For more help, use: ghostmail send --help`,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			// Load configuration
@@ -73,8 +178,46 @@ For more help, use: ghostmail send --help`,
 				return handleError(err)
 			}
 
-			if err := cfg.ValidateSMTP(); err != nil {
-				return handleError(err)
+			// Drafts are stored over IMAP, everything else goes out via SMTP.
+			// A dry run only needs the sender.
+			if outputFile != "" {
+				dryRun = true
+			}
+			switch {
+			case dryRun:
+				if draft || sendAt != "" || sendIn != "" {
+					return handleError(fmt.Errorf("--dry-run cannot be combined with --draft, --at or --in. Use --help for usage info"))
+				}
+				if err := cfg.ValidateSender(); err != nil {
+					return handleError(err)
+				}
+			case draft:
+				if err := cfg.ValidateIMAP(); err != nil {
+					return handleError(fmt.Errorf("IMAP config error: %w. Use --help for usage info", err))
+				}
+			default:
+				if err := cfg.ValidateSMTP(); err != nil {
+					return handleError(err)
+				}
+			}
+
+			// Structured input replaces the message flags
+			if request != "" {
+				var conflict string
+				local := cmd.LocalNonPersistentFlags()
+				cmd.Flags().Visit(func(f *pflag.Flag) {
+					switch f.Name {
+					case "request", "no-save-sent", "dry-run", "output":
+						return
+					}
+					if local.Lookup(f.Name) != nil {
+						conflict = f.Name
+					}
+				})
+				if conflict != "" {
+					return handleError(fmt.Errorf("--request cannot be combined with --%s. Use --help for usage info", conflict))
+				}
+				return sendRequests(cfg, request, !noSaveSent, dryRun, outputFile)
 			}
 
 			// Handle body from file
@@ -95,35 +238,86 @@ For more help, use: ghostmail send --help`,
 				htmlBody = string(data)
 			}
 
+			// Handle Markdown body
+			md, err := readMarkdown(markdown, mdFile)
+			if err != nil {
+				return handleError(err)
+			}
+			if md != "" {
+				if body != "" || htmlBody != "" {
+					return handleError(fmt.Errorf("--markdown cannot be combined with --body, --body-file or --html-file. Use --help for usage info"))
+				}
+				body = md
+				htmlBody, err = emailinternal.MarkdownToHTML(md)
+				if err != nil {
+					return handleError(err)
+				}
+			}
+
+			// Handle calendar invitation
+			var invitation []byte
+			if invite != "" {
+				data, err := os.ReadFile(invite)
+				if err != nil {
+					return handleError(fmt.Errorf("failed to read invitation file: %w. Use --help for usage info", err))
+				}
+				invitation, err = emailinternal.PrepareCalendarRequest(data)
+				if err != nil {
+					return handleError(fmt.Errorf("invalid invitation %s: %w. Use --help for usage info", invite, err))
+				}
+			}
+
+			// Fill in from a stored template
+			if tmplName != "" {
+				tm, err := renderTemplate(cfg, tmplName, tmplVars)
+				if err != nil {
+					return handleError(err)
+				}
+				to = append(to, tm.To...)
+				cc = append(cc, tm.CC...)
+				bcc = append(bcc, tm.BCC...)
+				attachments = append(attachments, tm.Attachments...)
+				if subject == "" {
+					subject = tm.Subject
+				}
+				if body == "" && htmlBody == "" {
+					body, htmlBody = tm.Text, tm.HTML
+				}
+			} else if len(tmplVars) > 0 {
+				return handleError(fmt.Errorf("--var requires --template. Use --help for usage info"))
+			}
+
 			// Validate required fields
 			if len(to) == 0 {
 				return handleError(fmt.Errorf("at least one recipient (--to) is required. Use --help for usage info"))
 			}
+			if err := checkRecipients(to, cc, bcc); err != nil {
+				return handleError(err)
+			}
 			if subject == "" {
 				return handleError(fmt.Errorf("subject is required. Use --help for usage info"))
 			}
-			if body == "" && htmlBody == "" {
-				return handleError(fmt.Errorf("either --body or --html-file must be provided. Use --help for usage info"))
+			if body == "" && htmlBody == "" && invitation == nil {
+				return handleError(fmt.Errorf("either --body, --markdown, --html-file or --invite must be provided. Use --help for usage info"))
 			}
 
-			// Validate attachments (max 5 files, 10MB each)
-			const (
-				maxAttachments = 5
-				maxFileSize    = 10 * 1024 * 1024 // 10MB
-			)
-			if len(attachments) > maxAttachments {
-				return handleError(fmt.Errorf("too many attachments: maximum is %d (you have %d). Use --help for usage info", maxAttachments, len(attachments)))
-			}
-			for _, att := range attachments {
-				info, err := os.Stat(att)
-				if err != nil {
-					return handleError(fmt.Errorf("cannot access attachment %s: %w. Use --help for usage info", att, err))
+			// Resolve the send time for scheduled messages
+			var scheduled time.Time
+			if sendAt != "" || sendIn != "" {
+				if draft {
+					return handleError(fmt.Errorf("--draft cannot be combined with --at or --in. Use --help for usage info"))
 				}
-				if info.Size() > maxFileSize {
-					return handleError(fmt.Errorf("attachment %s is too large: %s (max %s). Use --help for usage info", att, formatBytes(info.Size()), formatBytes(maxFileSize)))
+				scheduled, err = spool.ParseSendTime(sendAt, sendIn, time.Now())
+				if err != nil {
+					return handleError(fmt.Errorf("%w. Use --help for usage info", err))
 				}
 			}
 
+			// Validate attachments (max 5 files, 10MB each)
+			if err := validateAttachments(attachments); err != nil {
+				return handleError(fmt.Errorf("%w. Use --help for usage info", err))
+			}
+
 			// Send email
 			sender := emailinternal.NewSender(&cfg.SMTP)
 			opts := []emailinternal.SendOption{
@@ -137,16 +331,71 @@ For more help, use: ghostmail send --help`,
 			if inReplyTo != "" {
 				opts = append(opts, emailinternal.WithInReplyTo(inReplyTo))
 			}
+			headerOpts, err := headerOptions(headers, replyTo, priority, receipt)
+			if err != nil {
+				return handleError(err)
+			}
+			opts = append(opts, headerOpts...)
+			if invitation != nil {
+				opts = append(opts, emailinternal.WithCalendar(emailinternal.CalendarMethodRequest, invitation))
+			}
+			if pgpSign || pgpEncrypt {
+				keyring, err := loadKeyring(cfg)
+				if err != nil {
+					return handleError(err)
+				}
+				opts = append(opts, emailinternal.WithOpenPGP(keyring, pgpSign, pgpEncrypt))
+			}
+			if smimeSign || smimeEncrypt {
+				if pgpSign || pgpEncrypt {
+					return handleError(fmt.Errorf("--smime-sign and --smime-encrypt cannot be combined with --sign or --encrypt. Use --help for usage info"))
+				}
+				store, err := loadSMIME(cfg)
+				if err != nil {
+					return handleError(err)
+				}
+				opts = append(opts, emailinternal.WithSMIME(store, smimeSign, smimeEncrypt))
+			}
+
+			if draft {
+				return saveDraft(cfg, sender, to, subject, body, opts)
+			}
+
+			msg, err := sender.Build(to, subject, body, opts...)
+			if err != nil {
+				return handleError(err)
+			}
+
+			if dryRun {
+				return writeDryRun(sender, msg, outputFile)
+			}
+
+			if !scheduled.IsZero() {
+				return scheduleMessage(cfg, msg, subject, scheduled, !noSaveSent)
+			}
 
-			if err := sender.Send(to, subject, body, opts...); err != nil {
+			if err := sender.Submit(msg); err != nil {
+				if queueOnFail && emailinternal.IsRetryable(err) {
+					return queueInOutbox(cfg, msg, subject, err, !noSaveSent)
+				}
 				return handleError(err)
 			}
 
+			// Keep a copy in the Sent mailbox
+			var savedTo, warning string
+			if !noSaveSent {
+				savedTo, warning = saveSentCopy(cfg, msg)
+			}
+
 			// Output result
 			if jsonOutput {
 				resp := emailtypes.SendResponse{
-					Success: true,
-					Message: "Email sent successfully",
+					Success:       true,
+					Message:       "Email sent successfully",
+					MessageID:     msg.MessageID,
+					SavedTo:       savedTo,
+					Warning:       warning,
+					AuthMechanism: sender.Mechanism(),
 				}
 				return output.NewJSONOutput(true).Print(resp)
 			}
@@ -156,23 +405,43 @@ For more help, use: ghostmail send --help`,
 			} else {
 				fmt.Println("Email sent successfully")
 			}
+			printSentCopy(savedTo, warning)
+			printAuthMechanism("SMTP", sender.Mechanism())
 			return nil
 		},
 	}
 
 	// Flags
-	cmd.Flags().StringArrayVarP(&to, "to", "t", nil, "Recipient email address (can be specified multiple times)")
-	cmd.Flags().StringArrayVarP(&cc, "cc", "c", nil, "CC recipient (can be specified multiple times)")
-	cmd.Flags().StringArrayVarP(&bcc, "bcc", "b", nil, "BCC recipient (can be specified multiple times)")
+	cmd.Flags().StringArrayVarP(&to, "to", "t", nil, "Recipient address or comma-separated list (can be specified multiple times)")
+	cmd.Flags().StringArrayVarP(&cc, "cc", "c", nil, "CC recipient or comma-separated list (can be specified multiple times)")
+	cmd.Flags().StringArrayVarP(&bcc, "bcc", "b", nil, "BCC recipient or comma-separated list (can be specified multiple times)")
 	cmd.Flags().StringVarP(&subject, "subject", "s", "", "Email subject")
 	cmd.Flags().StringVarP(&body, "body", "m", "", "Email body text")
 	cmd.Flags().StringVar(&bodyFile, "body-file", "", "Read email body from file")
 	cmd.Flags().StringVar(&htmlFile, "html-file", "", "Read HTML body from file")
+	cmd.Flags().StringVar(&markdown, "markdown", "", "Email body in Markdown (sent as HTML with a plain text alternative)")
+	cmd.Flags().StringVar(&mdFile, "markdown-file", "", "Read Markdown body from file")
 	cmd.Flags().StringArrayVarP(&attachments, "attach", "a", nil, "File attachment (can be specified multiple times, max 5 files, 10MB each)")
 	cmd.Flags().StringVar(&inReplyTo, "in-reply-to", "", "Message-ID to reply to (enables threading)")
-
-	cmd.MarkFlagRequired("to")
-	cmd.MarkFlagRequired("subject")
+	cmd.Flags().StringArrayVarP(&headers, "header", "H", nil, "Custom header as \"Name: value\" (can be specified multiple times)")
+	cmd.Flags().StringArrayVar(&replyTo, "reply-to", nil, "Reply-To address (can be specified multiple times)")
+	cmd.Flags().StringVar(&priority, "priority", "", "Message priority: high, normal or low")
+	cmd.Flags().BoolVar(&receipt, "request-receipt", false, "Request a read receipt (Disposition-Notification-To)")
+	cmd.Flags().BoolVar(&pgpSign, "sign", false, "Sign the message with your OpenPGP key (PGP/MIME)")
+	cmd.Flags().BoolVar(&pgpEncrypt, "encrypt", false, "Encrypt the message to the recipients' OpenPGP keys (PGP/MIME)")
+	cmd.Flags().BoolVar(&smimeSign, "smime-sign", false, "Sign the message with your S/MIME certificate")
+	cmd.Flags().BoolVar(&smimeEncrypt, "smime-encrypt", false, "Encrypt the message to the recipients' S/MIME certificates")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Print the message exactly as it would be sent, without sending it")
+	cmd.Flags().StringVar(&outputFile, "output", "", "Write the message to a file (e.g. message.eml) instead of sending it; implies --dry-run")
+	cmd.Flags().BoolVar(&draft, "draft", false, "Save to the Drafts mailbox instead of sending")
+	cmd.Flags().StringVar(&sendAt, "at", "", "Send at a later time (RFC 3339, e.g. 2024-06-01T09:00:00+02:00)")
+	cmd.Flags().StringVar(&sendIn, "in", "", "Send after a delay (e.g. 90m, 2h, 1d)")
+	cmd.Flags().BoolVar(&queueOnFail, "queue-on-failure", false, "Queue the message in the local outbox if the SMTP server is unreachable")
+	cmd.Flags().BoolVar(&noSaveSent, "no-save-sent", false, "Don't save a copy to the Sent mailbox")
+	cmd.Flags().StringVar(&request, "request", "", "Read SendRequest JSON (one object, or NDJSON) from a file, or - for stdin")
+	cmd.Flags().StringVar(&tmplName, "template", "", "Fill in the message from a stored template (see 'ghostmail template list')")
+	cmd.Flags().StringArrayVar(&tmplVars, "var", nil, "Template variable as name=value (can be specified multiple times)")
+	cmd.Flags().StringVar(&invite, "invite", "", "Send an iCalendar file as a meeting invitation (METHOD:REQUEST)")
 
 	return cmd
 }
@@ -185,6 +454,150 @@ func handleError(err error) error {
 	return err
 }
 
+// checkRecipients rejects malformed recipient flags before connecting.
+// Each flag value may hold a comma-separated list of addresses.
+func checkRecipients(lists ...[]string) error {
+	for _, list := range lists {
+		if _, err := emailinternal.ParseAddressLists(list); err != nil {
+			return fmt.Errorf("%w. Use --help for usage info", err)
+		}
+	}
+	return nil
+}
+
+// headerOptions returns the send options for --header, --reply-to,
+// --priority and --request-receipt.
+func headerOptions(headers, replyTo []string, priority string, receipt bool) ([]emailinternal.SendOption, error) {
+	var opts []emailinternal.SendOption
+
+	if len(headers) > 0 {
+		parsed, err := emailinternal.ParseHeaders(headers)
+		if err != nil {
+			return nil, fmt.Errorf("%w. Use --help for usage info", err)
+		}
+		opts = append(opts, emailinternal.WithHeaders(parsed))
+	}
+	if len(replyTo) > 0 {
+		opts = append(opts, emailinternal.WithReplyTo(replyTo))
+	}
+	if priority != "" {
+		p, err := emailinternal.ParsePriority(priority)
+		if err != nil {
+			return nil, fmt.Errorf("%w. Use --help for usage info", err)
+		}
+		opts = append(opts, emailinternal.WithPriority(p))
+	}
+	if receipt {
+		opts = append(opts, emailinternal.WithReadReceipt())
+	}
+
+	return opts, nil
+}
+
+// writeDryRun renders a message as it would be submitted and writes it to
+// path, or to standard output if path is empty, with the envelope in JSON
+// output.
+func writeDryRun(sender *emailinternal.Sender, msg *emailinternal.OutgoingMessage, path string) error {
+	if err := sender.Render(msg); err != nil {
+		return handleError(err)
+	}
+
+	resp := emailtypes.DryRunResponse{
+		Success:   true,
+		MessageID: msg.MessageID,
+		Envelope:  emailtypes.Envelope{From: msg.From, Recipients: msg.Recipients},
+		Size:      len(msg.Data),
+		Output:    path,
+	}
+	if path != "" {
+		if err := os.WriteFile(path, msg.Data, 0600); err != nil {
+			return handleError(fmt.Errorf("failed to write message: %w", err))
+		}
+	} else if jsonOutput {
+		resp.Data = string(msg.Data)
+	}
+
+	if jsonOutput {
+		return output.NewJSONOutput(true).Print(resp)
+	}
+	if path == "" {
+		// Only the message, so it can be piped
+		_, err := os.Stdout.Write(msg.Data)
+		return err
+	}
+
+	if !noColor {
+		color.Green("✓ Message written to %s (%d bytes, not sent)", path, len(msg.Data))
+	} else {
+		fmt.Printf("Message written to %s (%d bytes, not sent)\n", path, len(msg.Data))
+	}
+	fmt.Printf("Envelope from: %s\n", msg.From)
+	fmt.Printf("Envelope to: %s\n", strings.Join(msg.Recipients, ", "))
+	return nil
+}
+
+// loadKeyring loads the OpenPGP keyring.
+func loadKeyring(cfg *config.Config) (*pgp.Keyring, error) {
+	if err := cfg.ResolveSecrets("GHOSTMAIL_PGP_PASSPHRASE"); err != nil {
+		return nil, fmt.Errorf("OpenPGP keyring error: %w", err)
+	}
+	keyring, err := pgp.Load(cfg.PGP.Dir, cfg.PGP.Passphrase)
+	if err != nil {
+		return nil, fmt.Errorf("OpenPGP keyring error: %w", err)
+	}
+	return keyring, nil
+}
+
+// loadSMIME loads the S/MIME certificates.
+func loadSMIME(cfg *config.Config) (*smime.Store, error) {
+	if err := cfg.ResolveSecrets("GHOSTMAIL_SMIME_PASSWORD"); err != nil {
+		return nil, fmt.Errorf("S/MIME error: %w", err)
+	}
+	store, err := smime.Load(&cfg.SMIME)
+	if err != nil {
+		return nil, fmt.Errorf("S/MIME error: %w", err)
+	}
+	return store, nil
+}
+
+// validateAttachments checks the attachment limits: at most 5 files of at
+// most 10MB each.
+func validateAttachments(attachments []string) error {
+	const (
+		maxAttachments = 5
+		maxFileSize    = 10 * 1024 * 1024 // 10MB
+	)
+	if len(attachments) > maxAttachments {
+		return fmt.Errorf("too many attachments: maximum is %d (you have %d)", maxAttachments, len(attachments))
+	}
+	for _, att := range attachments {
+		info, err := os.Stat(att)
+		if err != nil {
+			return fmt.Errorf("cannot access attachment %s: %w", att, err)
+		}
+		if info.Size() > maxFileSize {
+			return fmt.Errorf("attachment %s is too large: %s (max %s)", att, formatBytes(info.Size()), formatBytes(maxFileSize))
+		}
+	}
+	return nil
+}
+
+// readMarkdown returns the Markdown given with --markdown or read from
+// --markdown-file.
+func readMarkdown(markdown, markdownFile string) (string, error) {
+	if markdownFile == "" {
+		return markdown, nil
+	}
+	if markdown != "" {
+		return "", fmt.Errorf("use either --markdown or --markdown-file, not both. Use --help for usage info")
+	}
+	data, err := os.ReadFile(markdownFile)
+	if err != nil {
+		return "", fmt.Errorf("failed to read Markdown file: %w. Use --help for usage info", err)
+	}
+	return string(data), nil
+}
+
 // formatBytes formats bytes into human-readable format
 func formatBytes(bytes int64) string {
 	const (
@@ -0,0 +1,106 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/GodGMN/ghostmail-cli/internal/config"
+	emailinternal "github.com/GodGMN/ghostmail-cli/internal/email"
+	"github.com/spf13/cobra"
+)
+
+// sendmailName is the program name that selects sendmail mode when
+// ghostmail is installed as (a symlink named) sendmail.
+const sendmailName = "sendmail"
+
+func newSendmailCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "sendmail [options] [recipient...]",
+		Short: "Sendmail-compatible interface for scripts and legacy tools",
+		Long: `Read a complete RFC 822 message from standard input and submit it through
+the configured SMTP account, like /usr/sbin/sendmail.
+
+The same mode is used when ghostmail is invoked through a symlink named
+sendmail, so it can replace a local MTA for cron, mailx and scripts:
+
+  ln -s "$(command -v ghostmail)" /usr/sbin/sendmail
+
+Recipients are the address arguments and, with -t, the To, Cc and Bcc
+headers (both are used when given together). Bcc headers are removed, and
+missing From, Date and Message-ID headers are added. Input without a header
+section is sent as the body. Nothing is printed on success.
+
+OPTIONS:
+  -t         Read recipients from the message headers
+  -f ADDR    Envelope sender (default: GHOSTMAIL_SMTP_FROM); -r is the same
+  -F NAME    Sender name for a generated From header
+  -i, -oi    Don't treat a line with a single dot as the end of input
+  -v         Report the recipients on standard error
+
+Options without an argument can be combined, as in -ti. Other sendmail
+options such as -oem, -odi or -N are accepted and ignored.
+Queue and daemon modes (-bp, -bd, -q) are not supported.
+
+EXAMPLES:
+  # Recipients from the headers
+  printf 'To: ops@example.com\nSubject: Disk full\n\n/var is at 98%%\n' | ghostmail sendmail -t
+
+  # Explicit recipient, as cron and mailx call it
+  echo "backup done" | ghostmail sendmail -i -- ops@example.com
+
+For more help, use: ghostmail sendmail --help`,
+		// Sendmail options such as -oi or -faddr are not POSIX flags
+		DisableFlagParsing: true,
+		SilenceUsage:       true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			for _, arg := range args {
+				if arg == "--help" {
+					return cmd.Help()
+				}
+				if arg == "--" {
+					break
+				}
+			}
+
+			opts, err := emailinternal.ParseSendmailArgs(args)
+			if err != nil {
+				return handleError(fmt.Errorf("%w. Use --help for usage info", err))
+			}
+
+			// Load configuration
+			cfg, err := config.Load()
+			if err != nil {
+				return handleError(err)
+			}
+			if err := cfg.ValidateSMTP(); err != nil {
+				return handleError(err)
+			}
+
+			raw, err := emailinternal.ReadSendmailMessage(os.Stdin, opts.IgnoreDots)
+			if err != nil {
+				return handleError(fmt.Errorf("failed to read message: %w", err))
+			}
+
+			sender := emailinternal.NewSender(&cfg.SMTP)
+			defaultFrom := cfg.SMTP.From
+			if defaultFrom == "" {
+				defaultFrom = cfg.SMTP.Username
+			}
+			msg, err := emailinternal.PrepareSendmailMessage(raw, opts, defaultFrom, time.Now())
+			if err != nil {
+				return handleError(err)
+			}
+
+			if err := sender.Submit(msg); err != nil {
+				return handleError(err)
+			}
+
+			if opts.Verbose {
+				fmt.Fprintf(os.Stderr, "Sent %s from %s to %s\n", msg.MessageID, msg.From, strings.Join(msg.Recipients, ", "))
+			}
+			return nil
+		},
+	}
+}
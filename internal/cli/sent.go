@@ -0,0 +1,90 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/GodGMN/ghostmail-cli/internal/config"
+	emailinternal "github.com/GodGMN/ghostmail-cli/internal/email"
+	"github.com/fatih/color"
+)
+
+// saveSentCopy stores a copy of a submitted message in the Sent mailbox.
+// It returns the mailbox used, or a warning when no copy could be saved;
+// the message itself has already been delivered at this point, so failing
+// to save it is not an error. Nothing is saved without IMAP configuration.
+func saveSentCopy(cfg *config.Config, msg *emailinternal.OutgoingMessage) (savedTo, warning string) {
+	if err := cfg.ValidateIMAP(); err != nil {
+		return "", ""
+	}
+
+	mailbox, err := emailinternal.NewReader(&cfg.IMAP).SaveSent(msg)
+	if err != nil {
+		return "", fmt.Sprintf("message sent, but no copy was saved: %v", err)
+	}
+	return mailbox, ""
+}
+
+// sentCopies saves copies of several submitted messages over one IMAP
+// connection, like saveSentCopy. The connection is opened for the first
+// copy, and again for the next one after a failure.
+type sentCopies struct {
+	cfg     *config.Config
+	mailbox *emailinternal.SentMailbox
+}
+
+// save stores a copy of a submitted message (see saveSentCopy).
+func (s *sentCopies) save(msg *emailinternal.OutgoingMessage) (savedTo, warning string) {
+	if s.mailbox == nil {
+		if err := s.cfg.ValidateIMAP(); err != nil {
+			return "", ""
+		}
+		mailbox, err := emailinternal.NewReader(&s.cfg.IMAP).OpenSent()
+		if err != nil {
+			return "", fmt.Sprintf("message sent, but no copy was saved: %v", err)
+		}
+		s.mailbox = mailbox
+	}
+
+	savedTo, err := s.mailbox.Save(msg)
+	if err != nil {
+		s.close()
+		return "", fmt.Sprintf("message sent, but no copy was saved: %v", err)
+	}
+	return savedTo, ""
+}
+
+// close closes the IMAP connection, if open.
+func (s *sentCopies) close() {
+	if s.mailbox != nil {
+		s.mailbox.Close()
+		s.mailbox = nil
+	}
+}
+
+// printSentCopy reports where the sent copy went in human-readable output.
+func printSentCopy(savedTo, warning string) {
+	if warning != "" {
+		printWarning(warning)
+	}
+	if verbose && savedTo != "" {
+		fmt.Printf("  Saved to: %s\n", savedTo)
+	}
+}
+
+// printWarning reports a problem that did not stop the command on stderr.
+func printWarning(warning string) {
+	if !noColor {
+		color.New(color.FgYellow).Fprintf(os.Stderr, "Warning: %s\n", warning)
+	} else {
+		fmt.Fprintf(os.Stderr, "Warning: %s\n", warning)
+	}
+}
+
+// printAuthMechanism reports the mechanism a connection authenticated with
+// in verbose output.
+func printAuthMechanism(protocol, mech string) {
+	if verbose && mech != "" {
+		fmt.Printf("  %s authentication: %s\n", protocol, mech)
+	}
+}
@@ -0,0 +1,324 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/GodGMN/ghostmail-cli/internal/config"
+	"github.com/GodGMN/ghostmail-cli/internal/output"
+	"github.com/GodGMN/ghostmail-cli/internal/templates"
+	emailtypes "github.com/GodGMN/ghostmail-cli/pkg/email"
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+func newTemplateCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "template",
+		Short: "Manage reusable message templates",
+		Long: `Commands for the named message templates used by 'ghostmail send --template'.
+
+Templates are <name>.tmpl files in $GHOSTMAIL_TEMPLATES_DIR
+(default ~/.config/ghostmail/templates). A template starts with front matter
+between "---" lines, followed by the text body and an optional HTML body:
+
+  ---
+  description: Incident notification
+  subject: [{{upper .severity}}] {{.service}} is down
+  to: oncall@example.com
+  cc: {{.owner}}
+  vars: service, owner, severity=sev3
+  ---
+  {{.service}} has been down since {{date "15:04" .since}}.
+  {{define "html"}}<p><b>{{.service}}</b> is down.</p>{{end}}
+
+FRONT MATTER:
+  description  Shown by 'ghostmail template list'
+  subject      Subject template
+  to, cc, bcc  Recipients, separated by commas or semicolons
+  attachments  Files to attach, separated by commas or semicolons
+  vars         Variables, with defaults as name=value
+
+Every variable a template uses is required unless it has a default.
+
+HELPER FUNCTIONS:
+  now, date LAYOUT VALUE, upper, lower, title, trim, default DEF VALUE,
+  join SEP LIST, split SEP STRING, and the standard ones such as printf
+
+COMMANDS:
+  list    List templates with their variables
+  show    Print a template
+  render  Render a template without sending it
+
+EXAMPLES:
+  # Send an incident notification
+  ghostmail send --template incident --var service=api --var owner=ann@example.com \
+    --var since="2024-06-01 14:05"
+
+For more help, use: ghostmail template --help`,
+	}
+
+	cmd.AddCommand(newTemplateListCmd())
+	cmd.AddCommand(newTemplateShowCmd())
+	cmd.AddCommand(newTemplateRenderCmd())
+
+	return cmd
+}
+
+func newTemplateListCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "list",
+		Short: "List templates",
+		Long: `List the templates in the templates directory with their description
+and variables. Required variables are marked with *.
+
+EXAMPLES:
+  # List templates
+  ghostmail template list
+
+  # JSON output for scripting
+  ghostmail template list --json
+
+For more help, use: ghostmail template list --help`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// Load configuration
+			cfg, err := config.Load()
+			if err != nil {
+				return handleError(err)
+			}
+
+			list, err := templates.List(cfg.TemplatesDir)
+			if err != nil {
+				return handleError(err)
+			}
+
+			// Output
+			if jsonOutput {
+				resp := emailtypes.TemplateListResponse{
+					Success: true,
+					Total:   len(list),
+				}
+				for _, t := range list {
+					resp.Templates = append(resp.Templates, templateInfo(t))
+				}
+				return output.NewJSONOutput(true).Print(resp)
+			}
+
+			if len(list) == 0 {
+				fmt.Printf("No templates in %s\n", cfg.TemplatesDir)
+				return nil
+			}
+
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+
+			// Header
+			headerFmt := "%s\t%s\t%s\n"
+			if !noColor {
+				headerFmt = color.New(color.Bold).Sprint(headerFmt)
+			}
+			fmt.Fprintf(w, headerFmt, "NAME", "DESCRIPTION", "VARIABLES")
+
+			// Rows
+			for _, t := range list {
+				fmt.Fprintf(w, "%s\t%s\t%s\n", t.Name, truncate(t.Description, 40), formatVariables(t))
+			}
+
+			w.Flush()
+			fmt.Printf("\nTotal: %d templates\n", len(list))
+
+			return nil
+		},
+	}
+}
+
+func newTemplateShowCmd() *cobra.Command {
+	var name string
+
+	cmd := &cobra.Command{
+		Use:   "show",
+		Short: "Print a template",
+		Long: `Print the source of a template and its variables.
+
+REQUIRED FLAGS:
+  --name    The template name (from 'ghostmail template list')
+
+EXAMPLES:
+  ghostmail template show --name incident
+
+For more help, use: ghostmail template show --help`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// Load configuration
+			cfg, err := config.Load()
+			if err != nil {
+				return handleError(err)
+			}
+
+			t, err := templates.Load(cfg.TemplatesDir, name)
+			if err != nil {
+				return handleError(err)
+			}
+			source, err := os.ReadFile(t.Path)
+			if err != nil {
+				return handleError(fmt.Errorf("failed to read template: %w", err))
+			}
+
+			// Output
+			if jsonOutput {
+				resp := emailtypes.TemplateShowResponse{
+					Success:  true,
+					Template: templateInfo(t),
+					Source:   string(source),
+				}
+				return output.NewJSONOutput(true).Print(resp)
+			}
+
+			if verbose {
+				fmt.Printf("Path:      %s\n", t.Path)
+			}
+			fmt.Printf("Variables: %s\n\n", formatVariables(t))
+			fmt.Print(string(source))
+
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&name, "name", "n", "", "Template name (required)")
+
+	cmd.MarkFlagRequired("name")
+
+	return cmd
+}
+
+func newTemplateRenderCmd() *cobra.Command {
+	var (
+		name string
+		vars []string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "render",
+		Short: "Render a template without sending it",
+		Long: `Render a template with variables and print the resulting message,
+to check it before sending.
+
+REQUIRED FLAGS:
+  --name    The template name (from 'ghostmail template list')
+
+EXAMPLES:
+  ghostmail template render --name incident --var service=api \
+    --var owner=ann@example.com --var since="2024-06-01 14:05"
+
+For more help, use: ghostmail template render --help`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// Load configuration
+			cfg, err := config.Load()
+			if err != nil {
+				return handleError(err)
+			}
+
+			msg, err := renderTemplate(cfg, name, vars)
+			if err != nil {
+				return handleError(err)
+			}
+
+			// Output
+			if jsonOutput {
+				resp := emailtypes.TemplateRenderResponse{
+					Success:     true,
+					Subject:     msg.Subject,
+					To:          msg.To,
+					CC:          msg.CC,
+					BCC:         msg.BCC,
+					Attachments: msg.Attachments,
+					Text:        msg.Text,
+					HTML:        msg.HTML,
+				}
+				return output.NewJSONOutput(true).Print(resp)
+			}
+
+			headerColor := color.New(color.Bold, color.FgWhite)
+			printField := func(label, value string) {
+				if value == "" {
+					return
+				}
+				if noColor {
+					fmt.Printf("%s: %s\n", label, value)
+				} else {
+					headerColor.Printf("%s: ", label)
+					fmt.Println(value)
+				}
+			}
+			printField("Subject", msg.Subject)
+			printField("To", strings.Join(msg.To, ", "))
+			printField("CC", strings.Join(msg.CC, ", "))
+			printField("BCC", strings.Join(msg.BCC, ", "))
+			printField("Attachments", strings.Join(msg.Attachments, ", "))
+			fmt.Println()
+			fmt.Print(msg.Text)
+			if msg.HTML != "" {
+				fmt.Printf("\n--- HTML ---\n%s\n", msg.HTML)
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&name, "name", "n", "", "Template name (required)")
+	cmd.Flags().StringArrayVar(&vars, "var", nil, "Template variable as name=value (can be specified multiple times)")
+
+	cmd.MarkFlagRequired("name")
+
+	return cmd
+}
+
+// renderTemplate loads a template and renders it with name=value variables.
+func renderTemplate(cfg *config.Config, name string, pairs []string) (*templates.Message, error) {
+	vars, err := templates.ParseVars(pairs)
+	if err != nil {
+		return nil, fmt.Errorf("%w. Use --help for usage info", err)
+	}
+
+	t, err := templates.Load(cfg.TemplatesDir, name)
+	if err != nil {
+		return nil, err
+	}
+
+	msg, err := t.Render(vars)
+	if errors.Is(err, templates.ErrMissingVariables) {
+		return nil, fmt.Errorf("template %s: %w (use --var name=value)", name, err)
+	}
+	return msg, err
+}
+
+// templateInfo describes a template for JSON output.
+func templateInfo(t *templates.Template) emailtypes.TemplateInfo {
+	info := emailtypes.TemplateInfo{
+		Name:        t.Name,
+		Path:        t.Path,
+		Description: t.Description,
+		Subject:     t.Subject,
+		Variables:   t.Variables(),
+		Required:    t.Required(),
+	}
+	if len(t.Defaults) > 0 {
+		info.Defaults = t.Defaults
+	}
+	return info
+}
+
+// formatVariables lists the variables of a template, marking required ones
+// with * and showing defaults.
+func formatVariables(t *templates.Template) string {
+	var vars []string
+	for _, v := range t.Variables() {
+		if def, ok := t.Defaults[v]; ok {
+			vars = append(vars, v+"="+def)
+		} else {
+			vars = append(vars, v+"*")
+		}
+	}
+	return strings.Join(vars, ", ")
+}
@@ -4,15 +4,72 @@ package config
 import (
 	"fmt"
 	"os"
-	"strconv"
+	"path/filepath"
+	"strings"
 )
 
 // Config holds all configuration for the application.
 type Config struct {
 	SMTP SMTPConfig `json:"smtp"`
 	IMAP IMAPConfig `json:"imap"`
+
+	// DataDir holds local state such as the spool of scheduled messages
+	// and the outbox.
+	DataDir string `json:"data_dir"`
+
+	// TemplatesDir holds named message templates.
+	TemplatesDir string `json:"templates_dir"`
+
+	PGP   PGPConfig   `json:"pgp"`
+	SMIME SMIMEConfig `json:"smime"`
+
+	// File is the configuration file read, if any, and Account the
+	// account selected from it; Accounts lists the accounts it defines.
+	File     string   `json:"-"`
+	Account  string   `json:"-"`
+	Accounts []string `json:"-"`
+
+	// Settings lists the effective settings that are set, and Secrets
+	// where each secret came from, by environment variable.
+	Settings []Setting               `json:"-"`
+	Secrets  map[string]SecretSource `json:"-"`
+
+	// pending holds the secrets in files and commands, which are read
+	// when a command needs them (see ResolveSecrets)
+	pending []*pendingSecret
+}
+
+// PGPConfig holds OpenPGP configuration.
+type PGPConfig struct {
+	Dir        string `json:"dir"` // Keyring directory
+	Passphrase string `json:"-"`   // Unlocks the secret keys
+}
+
+// SMIMEConfig holds S/MIME configuration.
+type SMIMEConfig struct {
+	Cert       string `json:"cert"`        // PEM certificate chain or PKCS#12 file
+	Key        string `json:"key"`         // PEM private key, unless in Cert
+	Password   string `json:"-"`           // Decrypts a PKCS#12 file
+	CertsDir   string `json:"certs_dir"`   // Certificates of recipients
+	TrustStore string `json:"trust_store"` // PEM CA certificates; the system roots if empty
 }
 
+// Authentication mechanisms. With AuthAuto, OAuth is used if configured,
+// PLAIN if an authorization identity is set, and otherwise the mechanism
+// preferred for the protocol.
+const (
+	AuthAuto        = "auto"
+	AuthPlain       = "plain"
+	AuthLogin       = "login"
+	AuthCRAMMD5     = "cram-md5"
+	AuthExternal    = "external" // TLS client certificate
+	AuthXOAuth2     = "xoauth2"
+	AuthOAuthBearer = "oauthbearer"
+)
+
+// AuthMechanisms lists the valid authentication mechanisms.
+var AuthMechanisms = []string{AuthAuto, AuthPlain, AuthLogin, AuthCRAMMD5, AuthExternal, AuthXOAuth2, AuthOAuthBearer}
+
 // SMTPConfig holds SMTP server configuration.
 type SMTPConfig struct {
 	Host     string `json:"host"`
@@ -22,44 +79,256 @@ type SMTPConfig struct {
 	UseTLS   bool   `json:"use_tls"`
 	StartTLS bool   `json:"start_tls"`
 	From     string `json:"from"`
+
+	Auth       string `json:"auth"`        // Authentication mechanism
+	AuthzID    string `json:"authzid"`     // Authorization identity (PLAIN, EXTERNAL)
+	ClientCert string `json:"client_cert"` // PEM TLS client certificate
+	ClientKey  string `json:"client_key"`  // PEM key of the client certificate, unless in ClientCert
+
+	DKIM  DKIMConfig  `json:"dkim"`
+	OAuth OAuthConfig `json:"oauth"`
+}
+
+// DKIMConfig holds DKIM signing configuration. Signing is enabled when a
+// selector and a private key are set.
+type DKIMConfig struct {
+	Selector string   `json:"selector"`
+	Domain   string   `json:"domain"`   // Defaults to the domain of the From header
+	KeyFile  string   `json:"key_file"` // PEM private key file
+	Key      string   `json:"-"`        // PEM private key, e.g. from a secret store
+	Headers  []string `json:"headers"`  // Header fields to sign
+}
+
+// Enabled reports whether DKIM signing is configured.
+func (c *DKIMConfig) Enabled() bool {
+	return c.Selector != "" && (c.KeyFile != "" || c.Key != "")
 }
 
 // IMAPConfig holds IMAP server configuration.
 type IMAPConfig struct {
-	Host     string `json:"host"`
-	Port     int    `json:"port"`
-	Username string `json:"username"`
-	Password string `json:"password"`
-	UseTLS   bool   `json:"use_tls"`
-	Mailbox  string `json:"mailbox"`
+	Host        string `json:"host"`
+	Port        int    `json:"port"`
+	Username    string `json:"username"`
+	Password    string `json:"password"`
+	UseTLS      bool   `json:"use_tls"`
+	Mailbox     string `json:"mailbox"`
+	SentMailbox string `json:"sent_mailbox"`
+
+	Auth       string `json:"auth"`        // Authentication mechanism
+	AuthzID    string `json:"authzid"`     // Authorization identity (PLAIN, EXTERNAL)
+	ClientCert string `json:"client_cert"` // PEM TLS client certificate
+	ClientKey  string `json:"client_key"`  // PEM key of the client certificate, unless in ClientCert
+
+	OAuth OAuthConfig `json:"oauth"`
+}
+
+// OAuthConfig holds OAuth 2.0 configuration, shared by IMAP and SMTP. When
+// it is enabled, ghostmail authenticates with an access token (XOAUTH2 or
+// OAUTHBEARER) instead of the password. The token is given directly, read
+// from a file or a command, or obtained from a token endpoint with a
+// refresh token.
+type OAuthConfig struct {
+	Token        string   `json:"-"`          // Access token
+	TokenFile    string   `json:"token_file"` // File holding an access token
+	TokenCmd     string   `json:"token_cmd"`  // Command printing an access token
+	TokenURL     string   `json:"token_url"`  // Token endpoint for the refresh flow
+	ClientID     string   `json:"client_id"`
+	ClientSecret string   `json:"-"`
+	RefreshToken string   `json:"-"`
+	Scopes       []string `json:"scopes"`
+	CacheDir     string   `json:"cache_dir"` // Cached access tokens
+
+	refreshPending bool // RefreshToken is set but not read yet
 }
 
-// Load loads configuration from environment variables.
+// Enabled reports whether OAuth authentication is configured.
+func (c *OAuthConfig) Enabled() bool {
+	return c.Token != "" || c.TokenFile != "" || c.TokenCmd != "" || c.RefreshToken != "" || c.refreshPending
+}
+
+// Validate checks that the refresh flow has a token endpoint and a client.
+func (c *OAuthConfig) Validate() error {
+	if c.RefreshToken == "" || c.Token != "" || c.TokenFile != "" || c.TokenCmd != "" {
+		return nil
+	}
+	if c.TokenURL == "" {
+		return fmt.Errorf("OAuth token endpoint is required with a refresh token (set GHOSTMAIL_OAUTH_TOKEN_URL)")
+	}
+	if c.ClientID == "" {
+		return fmt.Errorf("OAuth client ID is required with a refresh token (set GHOSTMAIL_OAUTH_CLIENT_ID)")
+	}
+	return nil
+}
+
+// Load loads the configuration of the selected account: each setting from
+// its environment variable, or else from the account in the configuration
+// file, or else its default. When the account is selected with --account
+// or GHOSTMAIL_ACCOUNT, its settings (server, credential, OAuth, DKIM,
+// OpenPGP and S/MIME, see accountPrefixes) are not taken from the
+// environment, so variables set for the usual account do not leak into
+// it. Secrets can also be read from a file or a command (see
+// lookupSecret); those are only read by ResolveSecrets, which the
+// Validate methods call for the credentials they check, so a secret that
+// cannot be read only fails the commands that need it. If the
+// configuration file or the account cannot be read, Load returns the
+// configuration from the environment and the defaults along with the
+// error, for commands such as doctor that report it and go on.
 func Load() (*Config, error) {
+	// Without a readable file or account, the settings come from the
+	// environment and the defaults
+	f, path, err := readFile()
+	if err != nil {
+		f, path = &file{}, ""
+	}
+	name, account, explicit, accountErr := f.account(path)
+	if accountErr != nil {
+		name, account, explicit = "", &fileAccount{}, false
+		if err == nil {
+			err = accountErr
+		}
+	}
+	smtp, imap, fo := &account.SMTP, &account.IMAP, &account.OAuth
+	dkim, pgp, smime := &account.DKIM, &account.PGP, &account.SMIME
+
+	l := &loader{secrets: make(map[string]SecretSource), account: explicit}
+	dataDir := l.str("GHOSTMAIL_DATA_DIR", "", defaultDataDir())
+	oauth := OAuthConfig{
+		Token:        l.private("GHOSTMAIL_OAUTH_TOKEN"),
+		TokenFile:    l.str("GHOSTMAIL_OAUTH_TOKEN_FILE", fo.TokenFile, ""),
+		TokenCmd:     l.str("GHOSTMAIL_OAUTH_TOKEN_CMD", fo.TokenCmd, ""),
+		TokenURL:     l.str("GHOSTMAIL_OAUTH_TOKEN_URL", fo.TokenURL, ""),
+		ClientID:     l.str("GHOSTMAIL_OAUTH_CLIENT_ID", fo.ClientID, ""),
+		ClientSecret: l.secret("GHOSTMAIL_OAUTH_CLIENT_SECRET", fileSecret{fo.ClientSecret, fo.ClientSecretFile, fo.ClientSecretCmd}),
+		RefreshToken: l.secret("GHOSTMAIL_OAUTH_REFRESH_TOKEN", fileSecret{fo.RefreshToken, fo.RefreshTokenFile, fo.RefreshTokenCmd}),
+		Scopes:       l.list("GHOSTMAIL_OAUTH_SCOPES", fo.Scopes),
+		CacheDir:     filepath.Join(dataDir, "oauth"),
+
+		refreshPending: l.pends("GHOSTMAIL_OAUTH_REFRESH_TOKEN"),
+	}
+
 	cfg := &Config{
 		SMTP: SMTPConfig{
-			Host:     getEnv("GHOSTMAIL_SMTP_HOST", ""),
-			Port:     getEnvAsInt("GHOSTMAIL_SMTP_PORT", 587),
-			Username: getEnv("GHOSTMAIL_SMTP_USERNAME", ""),
-			Password: getEnv("GHOSTMAIL_SMTP_PASSWORD", ""),
-			UseTLS:   getEnvAsBool("GHOSTMAIL_SMTP_USE_TLS", false),
-			StartTLS: getEnvAsBool("GHOSTMAIL_SMTP_STARTTLS", true),
-			From:     getEnv("GHOSTMAIL_SMTP_FROM", ""),
+			Host:     l.str("GHOSTMAIL_SMTP_HOST", smtp.Host, ""),
+			Port:     l.int("GHOSTMAIL_SMTP_PORT", smtp.Port, 587),
+			Username: l.str("GHOSTMAIL_SMTP_USERNAME", smtp.Username, ""),
+			Password: l.secret("GHOSTMAIL_SMTP_PASSWORD", fileSecret{smtp.Password, smtp.PasswordFile, smtp.PasswordCmd}),
+			UseTLS:   l.bool("GHOSTMAIL_SMTP_USE_TLS", smtp.UseTLS, false),
+			StartTLS: l.bool("GHOSTMAIL_SMTP_STARTTLS", smtp.StartTLS, true),
+			From:     l.str("GHOSTMAIL_SMTP_FROM", smtp.From, ""),
+
+			Auth:       strings.ToLower(l.str("GHOSTMAIL_SMTP_AUTH", smtp.Auth, AuthAuto)),
+			AuthzID:    l.str("GHOSTMAIL_SMTP_AUTHZID", smtp.AuthzID, ""),
+			ClientCert: l.str("GHOSTMAIL_SMTP_CLIENT_CERT", smtp.ClientCert, ""),
+			ClientKey:  l.str("GHOSTMAIL_SMTP_CLIENT_KEY", smtp.ClientKey, ""),
+
+			DKIM: DKIMConfig{
+				Selector: l.str("GHOSTMAIL_DKIM_SELECTOR", dkim.Selector, ""),
+				Domain:   l.str("GHOSTMAIL_DKIM_DOMAIN", dkim.Domain, ""),
+				KeyFile:  l.str("GHOSTMAIL_DKIM_KEY_FILE", dkim.KeyFile, ""),
+				Key:      l.private("GHOSTMAIL_DKIM_KEY"),
+				Headers:  l.list("GHOSTMAIL_DKIM_HEADERS", dkim.Headers),
+			},
+			OAuth: oauth,
 		},
 		IMAP: IMAPConfig{
-			Host:     getEnv("GHOSTMAIL_IMAP_HOST", ""),
-			Port:     getEnvAsInt("GHOSTMAIL_IMAP_PORT", 993),
-			Username: getEnv("GHOSTMAIL_IMAP_USERNAME", ""),
-			Password: getEnv("GHOSTMAIL_IMAP_PASSWORD", ""),
-			UseTLS:   getEnvAsBool("GHOSTMAIL_IMAP_USE_TLS", true),
-			Mailbox:  getEnv("GHOSTMAIL_IMAP_MAILBOX", "INBOX"),
+			Host:        l.str("GHOSTMAIL_IMAP_HOST", imap.Host, ""),
+			Port:        l.int("GHOSTMAIL_IMAP_PORT", imap.Port, 993),
+			Username:    l.str("GHOSTMAIL_IMAP_USERNAME", imap.Username, ""),
+			Password:    l.secret("GHOSTMAIL_IMAP_PASSWORD", fileSecret{imap.Password, imap.PasswordFile, imap.PasswordCmd}),
+			UseTLS:      l.bool("GHOSTMAIL_IMAP_USE_TLS", imap.UseTLS, true),
+			Mailbox:     l.str("GHOSTMAIL_IMAP_MAILBOX", imap.Mailbox, "INBOX"),
+			SentMailbox: l.str("GHOSTMAIL_IMAP_SENT_MAILBOX", imap.SentMailbox, ""),
+			Auth:        strings.ToLower(l.str("GHOSTMAIL_IMAP_AUTH", imap.Auth, AuthAuto)),
+			AuthzID:     l.str("GHOSTMAIL_IMAP_AUTHZID", imap.AuthzID, ""),
+			ClientCert:  l.str("GHOSTMAIL_IMAP_CLIENT_CERT", imap.ClientCert, ""),
+			ClientKey:   l.str("GHOSTMAIL_IMAP_CLIENT_KEY", imap.ClientKey, ""),
+			OAuth:       oauth,
+		},
+		DataDir:      dataDir,
+		TemplatesDir: l.str("GHOSTMAIL_TEMPLATES_DIR", "", defaultTemplatesDir()),
+		PGP: PGPConfig{
+			Dir:        l.str("GHOSTMAIL_PGP_DIR", pgp.Dir, defaultConfigDir("pgp")),
+			Passphrase: l.secret("GHOSTMAIL_PGP_PASSPHRASE", fileSecret{pgp.Passphrase, pgp.PassphraseFile, pgp.PassphraseCmd}),
+		},
+		SMIME: SMIMEConfig{
+			Cert:       l.str("GHOSTMAIL_SMIME_CERT", smime.Cert, ""),
+			Key:        l.str("GHOSTMAIL_SMIME_KEY", smime.Key, ""),
+			Password:   l.secret("GHOSTMAIL_SMIME_PASSWORD", fileSecret{smime.Password, smime.PasswordFile, smime.PasswordCmd}),
+			CertsDir:   l.str("GHOSTMAIL_SMIME_CERTS_DIR", smime.CertsDir, defaultConfigDir("smime")),
+			TrustStore: l.str("GHOSTMAIL_SMIME_TRUST_STORE", smime.TrustStore, ""),
 		},
+		File:     path,
+		Account:  name,
+		Accounts: f.names(),
+		Settings: l.settings,
+		Secrets:  l.secrets,
+		pending:  l.pending,
+	}
+
+	// Fill in the settings of each secret once it is read
+	targets := map[string][]*string{
+		"GHOSTMAIL_SMTP_PASSWORD":       {&cfg.SMTP.Password},
+		"GHOSTMAIL_IMAP_PASSWORD":       {&cfg.IMAP.Password},
+		"GHOSTMAIL_OAUTH_CLIENT_SECRET": {&cfg.SMTP.OAuth.ClientSecret, &cfg.IMAP.OAuth.ClientSecret},
+		"GHOSTMAIL_OAUTH_REFRESH_TOKEN": {&cfg.SMTP.OAuth.RefreshToken, &cfg.IMAP.OAuth.RefreshToken},
+		"GHOSTMAIL_PGP_PASSPHRASE":      {&cfg.PGP.Passphrase},
+		"GHOSTMAIL_SMIME_PASSWORD":      {&cfg.SMIME.Password},
+	}
+	for _, p := range cfg.pending {
+		p.targets = targets[p.key]
+	}
+
+	return cfg, err
+}
+
+// SpoolDir returns the directory holding scheduled messages.
+func (c *Config) SpoolDir() string {
+	return filepath.Join(c.DataDir, "spool")
+}
+
+// OutboxDir returns the directory holding messages whose submission
+// failed and will be retried.
+func (c *Config) OutboxDir() string {
+	return filepath.Join(c.DataDir, "outbox")
+}
+
+// DeadLetterDir returns the directory holding messages that could not be
+// delivered from the outbox.
+func (c *Config) DeadLetterDir() string {
+	return filepath.Join(c.DataDir, "dead-letter")
+}
+
+// defaultDataDir returns $XDG_DATA_HOME/ghostmail, falling back to
+// ~/.local/share/ghostmail.
+func defaultDataDir() string {
+	if dir := os.Getenv("XDG_DATA_HOME"); dir != "" {
+		return filepath.Join(dir, "ghostmail")
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return filepath.Join(os.TempDir(), "ghostmail")
 	}
+	return filepath.Join(home, ".local", "share", "ghostmail")
+}
 
-	return cfg, nil
+// defaultTemplatesDir returns $XDG_CONFIG_HOME/ghostmail/templates, falling
+// back to ~/.config/ghostmail/templates.
+func defaultTemplatesDir() string {
+	return defaultConfigDir("templates")
 }
 
-// ValidateSMTP validates SMTP configuration.
+// defaultConfigDir returns the named directory in $XDG_CONFIG_HOME/ghostmail,
+// falling back to ~/.config/ghostmail.
+func defaultConfigDir(name string) string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return name
+	}
+	return filepath.Join(dir, "ghostmail", name)
+}
+
+// ValidateSMTP validates SMTP configuration, reading the secrets its
+// authentication mechanism needs.
 func (c *Config) ValidateSMTP() error {
 	if c.SMTP.Host == "" {
 		return fmt.Errorf("SMTP host is required (set GHOSTMAIL_SMTP_HOST)")
@@ -67,13 +336,23 @@ func (c *Config) ValidateSMTP() error {
 	if c.SMTP.Username == "" {
 		return fmt.Errorf("SMTP username is required (set GHOSTMAIL_SMTP_USERNAME)")
 	}
-	if c.SMTP.Password == "" {
-		return fmt.Errorf("SMTP password is required (set GHOSTMAIL_SMTP_PASSWORD)")
+	if err := c.ResolveSecrets(authSecrets("SMTP", c.SMTP.Auth, &c.SMTP.OAuth)...); err != nil {
+		return err
+	}
+	return validateAuth("SMTP", c.SMTP.Auth, c.SMTP.AuthzID, c.SMTP.Password, c.SMTP.ClientCert, &c.SMTP.OAuth)
+}
+
+// ValidateSender validates that a sender address is configured, which is
+// all that building a message without sending it needs.
+func (c *Config) ValidateSender() error {
+	if c.SMTP.From == "" && c.SMTP.Username == "" {
+		return fmt.Errorf("sender address is required (set GHOSTMAIL_SMTP_FROM)")
 	}
 	return nil
 }
 
-// ValidateIMAP validates IMAP configuration.
+// ValidateIMAP validates IMAP configuration, reading the secrets its
+// authentication mechanism needs.
 func (c *Config) ValidateIMAP() error {
 	if c.IMAP.Host == "" {
 		return fmt.Errorf("IMAP host is required (set GHOSTMAIL_IMAP_HOST)")
@@ -81,42 +360,67 @@ func (c *Config) ValidateIMAP() error {
 	if c.IMAP.Username == "" {
 		return fmt.Errorf("IMAP username is required (set GHOSTMAIL_IMAP_USERNAME)")
 	}
-	if c.IMAP.Password == "" {
-		return fmt.Errorf("IMAP password is required (set GHOSTMAIL_IMAP_PASSWORD)")
+	if err := c.ResolveSecrets(authSecrets("IMAP", c.IMAP.Auth, &c.IMAP.OAuth)...); err != nil {
+		return err
 	}
-	return nil
+	return validateAuth("IMAP", c.IMAP.Auth, c.IMAP.AuthzID, c.IMAP.Password, c.IMAP.ClientCert, &c.IMAP.OAuth)
 }
 
-// getEnv retrieves an environment variable or returns a default value.
-func getEnv(key, defaultValue string) string {
-	if value := os.Getenv(key); value != "" {
-		return value
+// authSecrets returns the secrets that authenticating with a mechanism
+// needs: none for EXTERNAL, the OAuth client secret and refresh token for
+// OAuth, and otherwise the password of the protocol.
+func authSecrets(protocol, auth string, oauth *OAuthConfig) []string {
+	switch {
+	case auth == AuthExternal:
+		return nil
+	case auth == AuthXOAuth2, auth == AuthOAuthBearer, (auth == "" || auth == AuthAuto) && oauth.Enabled():
+		return []string{"GHOSTMAIL_OAUTH_CLIENT_SECRET", "GHOSTMAIL_OAUTH_REFRESH_TOKEN"}
 	}
-	return defaultValue
+	return []string{"GHOSTMAIL_" + protocol + "_PASSWORD"}
 }
 
-// getEnvAsInt retrieves an environment variable as an integer.
-func getEnvAsInt(key string, defaultValue int) int {
-	valueStr := getEnv(key, "")
-	if valueStr == "" {
-		return defaultValue
+// validateAuth checks that the credentials the authentication mechanism of
+// a protocol needs are set.
+func validateAuth(protocol, auth, authzID, password, clientCert string, oauth *OAuthConfig) error {
+	switch auth {
+	case "", AuthAuto:
+		if oauth.Enabled() {
+			return oauth.Validate()
+		}
+	case AuthPlain:
+	case AuthLogin, AuthCRAMMD5:
+		if authzID != "" {
+			return fmt.Errorf("%s %s authentication cannot use an authorization identity; use plain (set GHOSTMAIL_%s_AUTH)", protocol, strings.ToUpper(auth), protocol)
+		}
+	case AuthExternal:
+		if clientCert == "" {
+			return fmt.Errorf("%s EXTERNAL authentication needs a client certificate (set GHOSTMAIL_%s_CLIENT_CERT)", protocol, protocol)
+		}
+		return nil
+	case AuthXOAuth2, AuthOAuthBearer:
+		if authzID != "" {
+			return fmt.Errorf("%s %s authentication cannot use an authorization identity (unset GHOSTMAIL_%s_AUTHZID)", protocol, strings.ToUpper(auth), protocol)
+		}
+		if !oauth.Enabled() {
+			return fmt.Errorf("%s %s authentication needs an OAuth token (set GHOSTMAIL_OAUTH_TOKEN or GHOSTMAIL_OAUTH_REFRESH_TOKEN)", protocol, strings.ToUpper(auth))
+		}
+		return oauth.Validate()
+	default:
+		return fmt.Errorf("invalid %s authentication mechanism %q (set GHOSTMAIL_%s_AUTH to one of %s)", protocol, auth, protocol, strings.Join(AuthMechanisms, ", "))
 	}
-	value, err := strconv.Atoi(valueStr)
-	if err != nil {
-		return defaultValue
+	if password == "" {
+		return fmt.Errorf("%s password is required (set GHOSTMAIL_%s_PASSWORD)", protocol, protocol)
 	}
-	return value
+	return nil
 }
 
-// getEnvAsBool retrieves an environment variable as a boolean.
-func getEnvAsBool(key string, defaultValue bool) bool {
-	valueStr := getEnv(key, "")
-	if valueStr == "" {
-		return defaultValue
-	}
-	value, err := strconv.ParseBool(valueStr)
-	if err != nil {
-		return defaultValue
+// splitList splits a comma-separated setting into a list.
+func splitList(value string) []string {
+	var list []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
 	}
-	return value
+	return list
 }
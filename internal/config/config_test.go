@@ -2,78 +2,66 @@ package config
 
 import (
 	"os"
+	"strings"
 	"testing"
 )
 
-func TestGetEnv(t *testing.T) {
-	// Test with set environment variable
-	os.Setenv("TEST_ENV_VAR", "test_value")
-	defer os.Unsetenv("TEST_ENV_VAR")
+func TestLoaderSettings(t *testing.T) {
+	t.Setenv("GHOSTMAIL_TEST_HOST", "env.example.com")
+	t.Setenv("GHOSTMAIL_TEST_PORT", "2525")
+	t.Setenv("GHOSTMAIL_TEST_BAD_PORT", "not_a_number")
+	t.Setenv("GHOSTMAIL_TEST_TLS", "0")
+	t.Setenv("GHOSTMAIL_TEST_BAD_TLS", "invalid")
+	t.Setenv("GHOSTMAIL_TEST_LIST", " a, ,b ")
+	yes := true
 
-	value := getEnv("TEST_ENV_VAR", "default")
-	if value != "test_value" {
-		t.Errorf("getEnv() = %v, want %v", value, "test_value")
+	l := &loader{secrets: make(map[string]SecretSource)}
+	if got := l.str("GHOSTMAIL_TEST_HOST", "file.example.com", "default"); got != "env.example.com" {
+		t.Errorf("str() = %q, want the environment value", got)
 	}
-
-	// Test with unset environment variable
-	value = getEnv("UNSET_TEST_VAR", "default")
-	if value != "default" {
-		t.Errorf("getEnv() = %v, want %v", value, "default")
+	if got := l.str("GHOSTMAIL_TEST_UNSET", "file.example.com", "default"); got != "file.example.com" {
+		t.Errorf("str() = %q, want the file value", got)
 	}
-}
-
-func TestGetEnvAsInt(t *testing.T) {
-	// Test with valid integer
-	os.Setenv("TEST_INT_VAR", "42")
-	defer os.Unsetenv("TEST_INT_VAR")
-
-	value := getEnvAsInt("TEST_INT_VAR", 0)
-	if value != 42 {
-		t.Errorf("getEnvAsInt() = %v, want %v", value, 42)
+	if got := l.str("GHOSTMAIL_TEST_UNSET", "", "default"); got != "default" {
+		t.Errorf("str() = %q, want the default", got)
 	}
-
-	// Test with unset variable
-	value = getEnvAsInt("UNSET_INT_VAR", 100)
-	if value != 100 {
-		t.Errorf("getEnvAsInt() = %v, want %v", value, 100)
+	if got := l.int("GHOSTMAIL_TEST_PORT", 465, 587); got != 2525 {
+		t.Errorf("int() = %d, want the environment value", got)
 	}
-
-	// Test with invalid integer
-	os.Setenv("TEST_INVALID_INT", "not_a_number")
-	defer os.Unsetenv("TEST_INVALID_INT")
-
-	value = getEnvAsInt("TEST_INVALID_INT", 50)
-	if value != 50 {
-		t.Errorf("getEnvAsInt() = %v, want %v", value, 50)
+	if got := l.int("GHOSTMAIL_TEST_BAD_PORT", 465, 587); got != 465 {
+		t.Errorf("int() with an invalid value = %d, want the file value", got)
+	}
+	if got := l.bool("GHOSTMAIL_TEST_TLS", &yes, true); got {
+		t.Error("bool() = true, want the environment value")
+	}
+	if got := l.bool("GHOSTMAIL_TEST_BAD_TLS", nil, true); !got {
+		t.Error("bool() with an invalid value = false, want the default")
+	}
+	if got := l.list("GHOSTMAIL_TEST_LIST", []string{"c"}); strings.Join(got, ",") != "a,b" {
+		t.Errorf("list() = %v, want [a b]", got)
 	}
-}
 
-func TestGetEnvAsBool(t *testing.T) {
-	tests := []struct {
-		name         string
-		value        string
-		defaultValue bool
-		want         bool
-	}{
-		{"true value", "true", false, true},
-		{"false value", "false", true, false},
-		{"1 as true", "1", false, true},
-		{"0 as false", "0", true, false},
-		{"unset uses default", "", true, true},
-		{"invalid uses default", "invalid", false, false},
+	sources := map[string]string{}
+	for _, s := range l.settings {
+		sources[s.Name] = s.Source
+	}
+	if sources["GHOSTMAIL_TEST_HOST"] != (SecretSource{Kind: SourceEnv}).String() || sources["GHOSTMAIL_TEST_BAD_PORT"] != (SecretSource{Kind: SourceConfig}).String() {
+		t.Errorf("recorded sources = %v", sources)
 	}
 
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			if tt.value != "" {
-				os.Setenv("TEST_BOOL_VAR", tt.value)
-				defer os.Unsetenv("TEST_BOOL_VAR")
-			}
-			got := getEnvAsBool("TEST_BOOL_VAR", tt.defaultValue)
-			if got != tt.want {
-				t.Errorf("getEnvAsBool() = %v, want %v", got, tt.want)
-			}
-		})
+	// The settings of an explicitly selected account ignore the environment
+	l = &loader{secrets: make(map[string]SecretSource), account: true}
+	t.Setenv("GHOSTMAIL_SMTP_HOST", "env.example.com")
+	if got := l.str("GHOSTMAIL_SMTP_HOST", "file.example.com", ""); got != "file.example.com" {
+		t.Errorf("str() for an explicit account = %q, want the file value", got)
+	}
+	t.Setenv("GHOSTMAIL_DATA_DIR", "/env/data")
+	if got := l.str("GHOSTMAIL_DATA_DIR", "", "/default"); got != "/env/data" {
+		t.Errorf("str() of a global setting for an explicit account = %q, want the environment value", got)
+	}
+	l = &loader{secrets: make(map[string]SecretSource)}
+	if got := l.str("GHOSTMAIL_SMTP_HOST", "file.example.com", ""); got != "env.example.com" {
+		t.Errorf("str() for the default account = %q, want the environment value", got)
 	}
 }
 
@@ -81,9 +69,9 @@ func TestLoad(t *testing.T) {
 	// Clean environment before test
 	cleanEnv := []string{
 		"GHOSTMAIL_SMTP_HOST", "GHOSTMAIL_SMTP_PORT", "GHOSTMAIL_SMTP_USERNAME",
-		"GHOSTMAIL_SMTP_PASSWORD", "GHOSTMAIL_SMTP_FROM",
+		"GHOSTMAIL_SMTP_PASSWORD", "GHOSTMAIL_SMTP_FROM", "GHOSTMAIL_SMTP_AUTH",
 		"GHOSTMAIL_IMAP_HOST", "GHOSTMAIL_IMAP_PORT", "GHOSTMAIL_IMAP_USERNAME",
-		"GHOSTMAIL_IMAP_PASSWORD", "GHOSTMAIL_IMAP_MAILBOX",
+		"GHOSTMAIL_IMAP_PASSWORD", "GHOSTMAIL_IMAP_MAILBOX", "GHOSTMAIL_IMAP_SENT_MAILBOX",
 	}
 
 	// Save and clear environment
@@ -123,6 +111,9 @@ func TestLoad(t *testing.T) {
 	if cfg.SMTP.Username != "test@example.com" {
 		t.Errorf("SMTP.Username = %v, want %v", cfg.SMTP.Username, "test@example.com")
 	}
+	if cfg.SMTP.Auth != AuthAuto {
+		t.Errorf("SMTP.Auth = %v, want %v", cfg.SMTP.Auth, AuthAuto)
+	}
 
 	// Check IMAP config
 	if cfg.IMAP.Host != "imap.example.com" {
@@ -131,6 +122,9 @@ func TestLoad(t *testing.T) {
 	if cfg.IMAP.Port != 993 {
 		t.Errorf("IMAP.Port = %v, want %v", cfg.IMAP.Port, 993)
 	}
+	if cfg.IMAP.SentMailbox != "" {
+		t.Errorf("IMAP.SentMailbox = %v, want empty (use \\Sent)", cfg.IMAP.SentMailbox)
+	}
 }
 
 func TestValidateSMTP(t *testing.T) {
@@ -183,6 +177,114 @@ func TestValidateSMTP(t *testing.T) {
 			},
 			wantErr: true,
 		},
+		{
+			name: "OAuth token instead of password",
+			config: Config{
+				SMTP: SMTPConfig{
+					Host:     "smtp.example.com",
+					Username: "test@example.com",
+					OAuth:    OAuthConfig{TokenCmd: "oauth2l fetch"},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "OAuth refresh token without endpoint",
+			config: Config{
+				SMTP: SMTPConfig{
+					Host:     "smtp.example.com",
+					Username: "test@example.com",
+					OAuth:    OAuthConfig{RefreshToken: "1//refresh", ClientID: "ghostmail"},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "unknown auth mechanism",
+			config: Config{
+				SMTP: SMTPConfig{
+					Host:     "smtp.example.com",
+					Username: "test@example.com",
+					Password: "password",
+					Auth:     "digest-md5",
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "PLAIN with OAuth configured needs password",
+			config: Config{
+				SMTP: SMTPConfig{
+					Host:     "smtp.example.com",
+					Username: "test@example.com",
+					Auth:     AuthPlain,
+					OAuth:    OAuthConfig{TokenCmd: "oauth2l fetch"},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "XOAUTH2 without OAuth token",
+			config: Config{
+				SMTP: SMTPConfig{
+					Host:     "smtp.example.com",
+					Username: "test@example.com",
+					Password: "password",
+					Auth:     AuthXOAuth2,
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "EXTERNAL with client certificate instead of password",
+			config: Config{
+				SMTP: SMTPConfig{
+					Host:       "smtp.example.com",
+					Username:   "test@example.com",
+					Auth:       AuthExternal,
+					ClientCert: "/etc/ghostmail/client.pem",
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "EXTERNAL without client certificate",
+			config: Config{
+				SMTP: SMTPConfig{
+					Host:     "smtp.example.com",
+					Username: "test@example.com",
+					Password: "password",
+					Auth:     AuthExternal,
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "authorization identity with PLAIN",
+			config: Config{
+				SMTP: SMTPConfig{
+					Host:     "smtp.example.com",
+					Username: "test@example.com",
+					Password: "password",
+					Auth:     AuthPlain,
+					AuthzID:  "shared@example.com",
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "authorization identity with LOGIN",
+			config: Config{
+				SMTP: SMTPConfig{
+					Host:     "smtp.example.com",
+					Username: "test@example.com",
+					Password: "password",
+					Auth:     AuthLogin,
+					AuthzID:  "shared@example.com",
+				},
+			},
+			wantErr: true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -195,6 +297,27 @@ func TestValidateSMTP(t *testing.T) {
 	}
 }
 
+func TestValidateSender(t *testing.T) {
+	tests := []struct {
+		name    string
+		smtp    SMTPConfig
+		wantErr bool
+	}{
+		{name: "from", smtp: SMTPConfig{From: "me@example.com"}},
+		{name: "username", smtp: SMTPConfig{Username: "me@example.com"}},
+		{name: "neither", smtp: SMTPConfig{Host: "smtp.example.com"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := Config{SMTP: tt.smtp}
+			if err := cfg.ValidateSender(); (err != nil) != tt.wantErr {
+				t.Errorf("ValidateSender() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
 func TestValidateIMAP(t *testing.T) {
 	tests := []struct {
 		name    string
@@ -245,6 +368,28 @@ func TestValidateIMAP(t *testing.T) {
 			},
 			wantErr: true,
 		},
+		{
+			name: "OAuth token instead of password",
+			config: Config{
+				IMAP: IMAPConfig{
+					Host:     "imap.example.com",
+					Username: "test@example.com",
+					OAuth:    OAuthConfig{TokenCmd: "oauth2l fetch"},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "OAuth refresh token without endpoint",
+			config: Config{
+				IMAP: IMAPConfig{
+					Host:     "imap.example.com",
+					Username: "test@example.com",
+					OAuth:    OAuthConfig{RefreshToken: "1//refresh", ClientID: "ghostmail"},
+				},
+			},
+			wantErr: true,
+		},
 	}
 
 	for _, tt := range tests {
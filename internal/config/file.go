@@ -0,0 +1,337 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+)
+
+// Selection of the configuration file and account, set from the global
+// --config and --account flags. They take precedence over GHOSTMAIL_CONFIG
+// and GHOSTMAIL_ACCOUNT.
+var (
+	FileFlag    string
+	AccountFlag string
+)
+
+// file is the configuration file: named accounts and the default one.
+type file struct {
+	DefaultAccount string                 `toml:"default_account"`
+	Accounts       map[string]fileAccount `toml:"accounts"`
+}
+
+// fileAccount holds the settings of an account. Settings it leaves unset
+// fall back to the defaults.
+type fileAccount struct {
+	SMTP  fileSMTP  `toml:"smtp"`
+	IMAP  fileIMAP  `toml:"imap"`
+	OAuth fileOAuth `toml:"oauth"`
+	DKIM  fileDKIM  `toml:"dkim"`
+	PGP   filePGP   `toml:"pgp"`
+	SMIME fileSMIME `toml:"smime"`
+}
+
+// fileServer holds the settings shared by SMTP and IMAP.
+type fileServer struct {
+	Host         string `toml:"host"`
+	Port         int    `toml:"port"`
+	Username     string `toml:"username"`
+	Password     string `toml:"password"`
+	PasswordFile string `toml:"password_file"`
+	PasswordCmd  string `toml:"password_cmd"`
+	UseTLS       *bool  `toml:"use_tls"`
+	Auth         string `toml:"auth"`
+	AuthzID      string `toml:"authzid"`
+	ClientCert   string `toml:"client_cert"`
+	ClientKey    string `toml:"client_key"`
+}
+
+// fileSMTP holds the SMTP settings of an account.
+type fileSMTP struct {
+	fileServer
+	StartTLS *bool  `toml:"starttls"`
+	From     string `toml:"from"`
+}
+
+// fileIMAP holds the IMAP settings of an account.
+type fileIMAP struct {
+	fileServer
+	Mailbox     string `toml:"mailbox"`
+	SentMailbox string `toml:"sent_mailbox"`
+}
+
+// fileOAuth holds the OAuth settings of an account.
+type fileOAuth struct {
+	TokenFile        string   `toml:"token_file"`
+	TokenCmd         string   `toml:"token_cmd"`
+	TokenURL         string   `toml:"token_url"`
+	ClientID         string   `toml:"client_id"`
+	ClientSecret     string   `toml:"client_secret"`
+	ClientSecretFile string   `toml:"client_secret_file"`
+	ClientSecretCmd  string   `toml:"client_secret_cmd"`
+	RefreshToken     string   `toml:"refresh_token"`
+	RefreshTokenFile string   `toml:"refresh_token_file"`
+	RefreshTokenCmd  string   `toml:"refresh_token_cmd"`
+	Scopes           []string `toml:"scopes"`
+}
+
+// fileDKIM holds the DKIM signing settings of an account.
+type fileDKIM struct {
+	Selector string   `toml:"selector"`
+	Domain   string   `toml:"domain"`
+	KeyFile  string   `toml:"key_file"`
+	Headers  []string `toml:"headers"`
+}
+
+// filePGP holds the OpenPGP settings of an account.
+type filePGP struct {
+	Dir            string `toml:"dir"`
+	Passphrase     string `toml:"passphrase"`
+	PassphraseFile string `toml:"passphrase_file"`
+	PassphraseCmd  string `toml:"passphrase_cmd"`
+}
+
+// fileSMIME holds the S/MIME settings of an account.
+type fileSMIME struct {
+	Cert         string `toml:"cert"`
+	Key          string `toml:"key"`
+	Password     string `toml:"password"`
+	PasswordFile string `toml:"password_file"`
+	PasswordCmd  string `toml:"password_cmd"`
+	CertsDir     string `toml:"certs_dir"`
+	TrustStore   string `toml:"trust_store"`
+}
+
+// fileSecret is a secret set in the configuration file, directly or as a
+// file or command to read it from.
+type fileSecret struct {
+	value, file, cmd string
+}
+
+// DefaultFile returns the default configuration file,
+// $XDG_CONFIG_HOME/ghostmail/config.toml, falling back to
+// ~/.config/ghostmail/config.toml.
+func DefaultFile() string {
+	return defaultConfigDir("config.toml")
+}
+
+// readFile reads the configuration file given with --config or
+// GHOSTMAIL_CONFIG, or else the default one if it exists. It returns the
+// path read, or "" if there is none. A file that other users can read is
+// refused, since it may hold passwords.
+func readFile() (*file, string, error) {
+	path := FileFlag
+	if path == "" {
+		path = os.Getenv("GHOSTMAIL_CONFIG")
+	}
+	explicit := path != ""
+	if !explicit {
+		path = DefaultFile()
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) && !explicit {
+		return &file{}, "", nil
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read configuration file: %w", err)
+	}
+	if info, err := os.Stat(path); err == nil && runtime.GOOS != "windows" && info.Mode().Perm()&0044 != 0 {
+		return nil, "", fmt.Errorf("configuration file %s is readable by other users (mode %04o); run chmod 600 %s", path, info.Mode().Perm(), path)
+	}
+
+	var f file
+	md, err := toml.Decode(string(data), &f)
+	if err != nil {
+		return nil, "", fmt.Errorf("invalid configuration file %s: %w", path, err)
+	}
+	if undecoded := md.Undecoded(); len(undecoded) > 0 {
+		return nil, "", fmt.Errorf("invalid configuration file %s: unknown setting %s", path, undecoded[0])
+	}
+	return &f, path, nil
+}
+
+// account returns the account selected with --account or GHOSTMAIL_ACCOUNT,
+// or else the default account, or the only one. It returns "" and no
+// account if the file defines none, and reports whether the account was
+// selected explicitly.
+func (f *file) account(path string) (string, *fileAccount, bool, error) {
+	name := AccountFlag
+	if name == "" {
+		name = os.Getenv("GHOSTMAIL_ACCOUNT")
+	}
+	explicit := name != ""
+	if name == "" {
+		name = f.DefaultAccount
+	}
+	if name == "" {
+		switch len(f.Accounts) {
+		case 0:
+			return "", &fileAccount{}, false, nil
+		case 1:
+			for only := range f.Accounts {
+				name = only
+			}
+		default:
+			return "", nil, false, fmt.Errorf("%s defines several accounts (%s); choose one with --account or set default_account", path, strings.Join(f.names(), ", "))
+		}
+	}
+
+	account, ok := f.Accounts[name]
+	if !ok {
+		if path == "" {
+			return "", nil, false, fmt.Errorf("account %q not found: no configuration file at %s", name, DefaultFile())
+		}
+		return "", nil, false, fmt.Errorf("account %q not found in %s (accounts: %s)", name, path, strings.Join(f.names(), ", "))
+	}
+	return name, &account, explicit, nil
+}
+
+// names returns the names of the accounts, sorted.
+func (f *file) names() []string {
+	names := make([]string, 0, len(f.Accounts))
+	for name := range f.Accounts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Setting is an effective setting, named by its environment variable, and
+// where its value came from.
+type Setting struct {
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	Source string `json:"source"`
+	Secret bool   `json:"secret,omitempty"`
+}
+
+// loader reads settings from the environment, falling back to the
+// configuration file and then to the defaults, and records the effective
+// settings and the secrets still to be read. With account set, the
+// settings of the account are only read from the file, so that the
+// environment meant for the default account cannot leak into another one.
+type loader struct {
+	settings []Setting
+	secrets  map[string]SecretSource
+	pending  []*pendingSecret
+	account  bool
+}
+
+// accountPrefixes are the prefixes of the settings that belong to an
+// account.
+var accountPrefixes = []string{"GHOSTMAIL_SMTP_", "GHOSTMAIL_IMAP_", "GHOSTMAIL_OAUTH_", "GHOSTMAIL_DKIM_", "GHOSTMAIL_PGP_", "GHOSTMAIL_SMIME_"}
+
+// env returns the environment variable key, unless it is the setting of an
+// explicitly selected account.
+func (l *loader) env(key string) string {
+	if l.account {
+		for _, prefix := range accountPrefixes {
+			if strings.HasPrefix(key, prefix) {
+				return ""
+			}
+		}
+	}
+	return os.Getenv(key)
+}
+
+// record adds an effective setting, unless it is empty.
+func (l *loader) record(key, value, kind string) {
+	if value != "" {
+		l.settings = append(l.settings, Setting{Name: key, Value: value, Source: SecretSource{Kind: kind}.String()})
+	}
+}
+
+// str returns a string setting.
+func (l *loader) str(key, fileValue, defaultValue string) string {
+	value, kind := defaultValue, SourceDefault
+	if fileValue != "" {
+		value, kind = fileValue, SourceConfig
+	}
+	if env := l.env(key); env != "" {
+		value, kind = env, SourceEnv
+	}
+	l.record(key, value, kind)
+	return value
+}
+
+// int returns an integer setting; an invalid value in the environment is
+// ignored.
+func (l *loader) int(key string, fileValue, defaultValue int) int {
+	value, kind := defaultValue, SourceDefault
+	if fileValue != 0 {
+		value, kind = fileValue, SourceConfig
+	}
+	if env, err := strconv.Atoi(l.env(key)); err == nil {
+		value, kind = env, SourceEnv
+	}
+	l.record(key, strconv.Itoa(value), kind)
+	return value
+}
+
+// bool returns a boolean setting; an invalid value in the environment is
+// ignored.
+func (l *loader) bool(key string, fileValue *bool, defaultValue bool) bool {
+	value, kind := defaultValue, SourceDefault
+	if fileValue != nil {
+		value, kind = *fileValue, SourceConfig
+	}
+	if env, err := strconv.ParseBool(l.env(key)); err == nil {
+		value, kind = env, SourceEnv
+	}
+	l.record(key, strconv.FormatBool(value), kind)
+	return value
+}
+
+// list returns a list setting, comma-separated in the environment.
+func (l *loader) list(key string, fileValue []string) []string {
+	value, kind := fileValue, SourceConfig
+	if env := splitList(l.env(key)); len(env) > 0 {
+		value, kind = env, SourceEnv
+	}
+	l.record(key, strings.Join(value, ","), kind)
+	return value
+}
+
+// private returns a sensitive setting that is only read from the
+// environment, such as a private key, recording it as secret.
+func (l *loader) private(key string) string {
+	value := l.env(key)
+	if value != "" {
+		l.settings = append(l.settings, Setting{Name: key, Value: value, Source: SecretSource{Kind: SourceEnv}.String(), Secret: true})
+	}
+	return value
+}
+
+// secret returns a secret from the environment or the configuration file
+// (see lookupSecret). A secret in a file or command is not read yet: it is
+// added to the pending secrets and "" is returned.
+func (l *loader) secret(key string, fallback fileSecret) string {
+	src, value, ok := lookupSecret(key, fallback, l.env)
+	if !ok {
+		return ""
+	}
+	if src.Kind == SourceFile || src.Kind == SourceCommand {
+		l.pending = append(l.pending, &pendingSecret{key: key, src: src})
+		return ""
+	}
+	l.secrets[key] = src
+	l.settings = append(l.settings, Setting{Name: key, Value: value, Source: src.String(), Secret: true})
+	return value
+}
+
+// pends reports whether the secret key is pending.
+func (l *loader) pends(key string) bool {
+	for _, p := range l.pending {
+		if p.key == key {
+			return true
+		}
+	}
+	return false
+}
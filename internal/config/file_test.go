@@ -0,0 +1,196 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testConfigFile = `default_account = "work"
+
+[accounts.work.smtp]
+host = "smtp.work.example"
+port = 465
+username = "ann@work.example"
+password = "work-secret"
+use_tls = true
+
+[accounts.work.imap]
+host = "imap.work.example"
+username = "ann@work.example"
+password_cmd = "echo imap-secret"
+sent_mailbox = "Sent Items"
+
+[accounts.work.oauth]
+scopes = ["https://mail.google.com/"]
+
+[accounts.personal.smtp]
+host = "smtp.home.example"
+username = "ann@home.example"
+starttls = false
+
+[accounts.personal.dkim]
+selector = "home"
+key_file = "/keys/home.pem"
+
+[accounts.personal.pgp]
+dir = "/keys/pgp"
+passphrase_cmd = "echo pgp-secret"
+
+[accounts.personal.smime]
+cert = "/keys/home.p12"
+`
+
+// useConfigFile writes a configuration file, selects it and clears the
+// environment variables the tests look at.
+func useConfigFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.toml")
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	FileFlag, AccountFlag = path, ""
+	t.Cleanup(func() { FileFlag, AccountFlag = "", "" })
+	for _, key := range []string{
+		"GHOSTMAIL_ACCOUNT", "GHOSTMAIL_SMTP_HOST", "GHOSTMAIL_SMTP_PORT", "GHOSTMAIL_SMTP_PASSWORD",
+		"GHOSTMAIL_SMTP_USE_TLS", "GHOSTMAIL_SMTP_STARTTLS", "GHOSTMAIL_IMAP_PASSWORD", "GHOSTMAIL_IMAP_MAILBOX",
+		"GHOSTMAIL_IMAP_SENT_MAILBOX", "GHOSTMAIL_OAUTH_SCOPES", "GHOSTMAIL_DKIM_SELECTOR", "GHOSTMAIL_PGP_DIR",
+		"GHOSTMAIL_PGP_PASSPHRASE", "GHOSTMAIL_SMIME_CERT",
+	} {
+		t.Setenv(key, "")
+	}
+	return path
+}
+
+func TestLoadFile(t *testing.T) {
+	path := useConfigFile(t, testConfigFile)
+	t.Setenv("GHOSTMAIL_SMTP_PORT", "587")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.File != path || cfg.Account != "work" || strings.Join(cfg.Accounts, ",") != "personal,work" {
+		t.Errorf("Load() file %q, account %q, accounts %v", cfg.File, cfg.Account, cfg.Accounts)
+	}
+	if err := cfg.ResolveAllSecrets(); err != nil {
+		t.Fatalf("ResolveAllSecrets() error = %v", err)
+	}
+	if cfg.SMTP.Host != "smtp.work.example" || !cfg.SMTP.UseTLS || !cfg.SMTP.StartTLS || cfg.SMTP.Password != "work-secret" {
+		t.Errorf("Load() SMTP = %+v", cfg.SMTP)
+	}
+	if cfg.IMAP.Password != "imap-secret" || cfg.IMAP.SentMailbox != "Sent Items" || cfg.IMAP.Mailbox != "INBOX" || cfg.IMAP.Port != 993 {
+		t.Errorf("Load() IMAP = %+v", cfg.IMAP)
+	}
+	if len(cfg.IMAP.OAuth.Scopes) != 1 {
+		t.Errorf("Load() OAuth scopes = %v", cfg.IMAP.OAuth.Scopes)
+	}
+
+	// The environment takes precedence over the file
+	if cfg.SMTP.Port != 587 {
+		t.Errorf("SMTP.Port = %d, want 587 from the environment", cfg.SMTP.Port)
+	}
+
+	sources := make(map[string]Setting)
+	for _, setting := range cfg.Settings {
+		sources[setting.Name] = setting
+	}
+	for name, want := range map[string]string{
+		"GHOSTMAIL_SMTP_HOST":     "config file",
+		"GHOSTMAIL_SMTP_PORT":     "environment",
+		"GHOSTMAIL_IMAP_MAILBOX":  "default",
+		"GHOSTMAIL_SMTP_PASSWORD": "config file",
+		"GHOSTMAIL_IMAP_PASSWORD": "command `echo imap-secret`",
+	} {
+		if got := sources[name].Source; got != want {
+			t.Errorf("%s source = %q, want %q", name, got, want)
+		}
+	}
+	if !sources["GHOSTMAIL_SMTP_PASSWORD"].Secret || sources["GHOSTMAIL_SMTP_HOST"].Secret {
+		t.Error("Load() settings do not mark secrets")
+	}
+}
+
+func TestLoadFileAccount(t *testing.T) {
+	useConfigFile(t, testConfigFile)
+
+	t.Setenv("GHOSTMAIL_ACCOUNT", "personal")
+	cfg, err := Load()
+	if err != nil || cfg.Account != "personal" || cfg.SMTP.Host != "smtp.home.example" || cfg.SMTP.StartTLS {
+		t.Fatalf("Load() with GHOSTMAIL_ACCOUNT = %+v, %v", cfg, err)
+	}
+	if cfg.SMTP.DKIM.Selector != "home" || cfg.PGP.Dir != "/keys/pgp" || cfg.SMIME.Cert != "/keys/home.p12" {
+		t.Errorf("Load() DKIM %+v, PGP %+v, S/MIME %+v, want the account's", cfg.SMTP.DKIM, cfg.PGP, cfg.SMIME)
+	}
+	if err := cfg.ResolveSecrets("GHOSTMAIL_PGP_PASSPHRASE"); err != nil || cfg.PGP.Passphrase != "pgp-secret" {
+		t.Errorf("PGP passphrase = %q, %v", cfg.PGP.Passphrase, err)
+	}
+
+	// The environment does not override an explicitly selected account
+	t.Setenv("GHOSTMAIL_SMTP_HOST", "smtp.env.example")
+	t.Setenv("GHOSTMAIL_SMTP_PASSWORD", "env-secret")
+	t.Setenv("GHOSTMAIL_PGP_DIR", "/env/pgp")
+	if cfg, err := Load(); err != nil || cfg.SMTP.Host != "smtp.home.example" || cfg.SMTP.Password != "" || cfg.PGP.Dir != "/keys/pgp" {
+		t.Errorf("Load() with the environment = %+v, %v, want only the account's settings", cfg, err)
+	}
+	t.Setenv("GHOSTMAIL_ACCOUNT", "")
+	if cfg, err := Load(); err != nil || cfg.Account != "work" || cfg.SMTP.Host != "smtp.env.example" {
+		t.Errorf("Load() of the default account = %+v, %v, want the environment to apply", cfg, err)
+	}
+	t.Setenv("GHOSTMAIL_ACCOUNT", "personal")
+
+	// The flag takes precedence over GHOSTMAIL_ACCOUNT
+	AccountFlag = "work"
+	if cfg, err := Load(); err != nil || cfg.Account != "work" {
+		t.Errorf("Load() with --account = %+v, %v", cfg, err)
+	}
+
+	AccountFlag = "nope"
+	if _, err := Load(); err == nil || !strings.Contains(err.Error(), `account "nope" not found`) || !strings.Contains(err.Error(), "personal, work") {
+		t.Errorf("Load() with an unknown account error = %v", err)
+	}
+}
+
+func TestLoadFileErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{"unknown setting", "[accounts.work.smtp]\nhots = \"smtp.example.com\"\n", "unknown setting accounts.work.smtp.hots"},
+		{"IMAP setting in SMTP", "[accounts.work.smtp]\nmailbox = \"INBOX\"\n", "unknown setting"},
+		{"syntax error", "[accounts.work\n", "invalid configuration file"},
+		{"several accounts without default", "[accounts.a.smtp]\n[accounts.b.smtp]\n", "choose one with --account"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useConfigFile(t, tt.content)
+			if _, err := Load(); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Load() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+
+	// Files readable by other users are refused
+	path := useConfigFile(t, testConfigFile)
+	if err := os.Chmod(path, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(); err == nil || !strings.Contains(err.Error(), "chmod 600") {
+		t.Errorf("Load() of a world-readable file error = %v", err)
+	}
+
+	FileFlag = filepath.Join(t.TempDir(), "missing.toml")
+	defer func() { FileFlag = "" }()
+	if _, err := Load(); err == nil {
+		t.Error("Load() with a missing --config file expected an error")
+	}
+
+	// The settings from the environment are still returned with the error
+	t.Setenv("GHOSTMAIL_SMTP_HOST", "smtp.env.example.com")
+	cfg, err := Load()
+	if err == nil || cfg == nil || cfg.SMTP.Host != "smtp.env.example.com" {
+		t.Errorf("Load() with a missing --config file = %+v, %v, want the environment settings and an error", cfg, err)
+	}
+}
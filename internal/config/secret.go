@@ -0,0 +1,190 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// Sources of secrets and settings.
+const (
+	SourceEnv     = "env"
+	SourceConfig  = "config" // The configuration file
+	SourceDefault = "default"
+	SourceFile    = "file"
+	SourceCommand = "command"
+)
+
+// SecretSource records where a secret came from.
+type SecretSource struct {
+	Kind string // One of the Source constants
+	From string // File or command the secret was read from
+	Err  error  // Why reading the secret failed
+}
+
+// String describes the source for display.
+func (s SecretSource) String() string {
+	switch s.Kind {
+	case SourceFile:
+		return "file " + s.From
+	case SourceCommand:
+		return "command `" + s.From + "`"
+	case SourceConfig:
+		return "config file"
+	case SourceDefault:
+		return "default"
+	}
+	return "environment"
+}
+
+// secretResult is a secret read from a file or command.
+type secretResult struct {
+	value string
+	err   error
+}
+
+// secretCache holds the secrets read from files and commands, so a command
+// such as `pass show mail` runs once per process even when it provides
+// several secrets or the configuration is loaded again.
+var (
+	secretMu    sync.Mutex
+	secretCache = make(map[string]secretResult)
+)
+
+// pendingSecret is a secret in a file or command that is only read when a
+// command needs it, and the settings it fills in.
+type pendingSecret struct {
+	key     string
+	src     SecretSource
+	targets []*string
+	read    bool
+	err     error
+}
+
+// lookupSecret returns where the secret key comes from: the environment
+// variable key, or else the file named by key_FILE, or else the shell
+// command key_CMD, or else the configuration file, directly or as a file
+// or command. Environment variables are looked up with getenv. The value
+// is returned unless it has to be read from a file or command. It reports
+// false if the secret is not set.
+func lookupSecret(key string, fallback fileSecret, getenv func(string) string) (SecretSource, string, bool) {
+	switch {
+	case getenv(key) != "":
+		return SecretSource{Kind: SourceEnv}, getenv(key), true
+	case getenv(key+"_FILE") != "":
+		return SecretSource{Kind: SourceFile, From: getenv(key + "_FILE")}, "", true
+	case getenv(key+"_CMD") != "":
+		return SecretSource{Kind: SourceCommand, From: getenv(key + "_CMD")}, "", true
+	case fallback.value != "":
+		return SecretSource{Kind: SourceConfig}, fallback.value, true
+	case fallback.file != "":
+		return SecretSource{Kind: SourceFile, From: fallback.file}, "", true
+	case fallback.cmd != "":
+		return SecretSource{Kind: SourceCommand, From: fallback.cmd}, "", true
+	}
+	return SecretSource{}, "", false
+}
+
+// ResolveSecrets reads the secrets in files and commands that are named by
+// their environment variables, such as GHOSTMAIL_SMTP_PASSWORD, and fills
+// in their settings. Each is read once; a secret that cannot be read is
+// recorded in Secrets and its error returned, also on later calls.
+func (c *Config) ResolveSecrets(keys ...string) error {
+	var errs []error
+	for _, p := range c.pending {
+		if slices.Contains(keys, p.key) {
+			errs = append(errs, c.resolve(p))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// ResolveAllSecrets reads all secrets in files and commands (see
+// ResolveSecrets).
+func (c *Config) ResolveAllSecrets() error {
+	var errs []error
+	for _, p := range c.pending {
+		errs = append(errs, c.resolve(p))
+	}
+	return errors.Join(errs...)
+}
+
+// resolve reads a pending secret, unless it has been read already.
+func (c *Config) resolve(p *pendingSecret) error {
+	if p.read {
+		return p.err
+	}
+	p.read = true
+
+	var value string
+	value, p.err = readCached(p.key, p.src, c.Secrets)
+	for _, target := range p.targets {
+		*target = value
+	}
+	if value != "" {
+		c.Settings = append(c.Settings, Setting{Name: p.key, Value: value, Source: p.src.String(), Secret: true})
+	}
+	return p.err
+}
+
+// readCached reads a secret from a file or command source, once per
+// process, and records the source.
+func readCached(key string, src SecretSource, sources map[string]SecretSource) (string, error) {
+	cacheKey := src.Kind + "\x00" + src.From
+	secretMu.Lock()
+	result, ok := secretCache[cacheKey]
+	if !ok {
+		if src.Kind == SourceFile {
+			result.value, result.err = readSecretFile(key, src.From)
+		} else {
+			result.value, result.err = runSecretCommand(key, src.From)
+		}
+		secretCache[cacheKey] = result
+	}
+	secretMu.Unlock()
+
+	src.Err = result.err
+	sources[key] = src
+	return result.value, result.err
+}
+
+// readSecretFile reads a secret from a file, such as a Docker or
+// Kubernetes secret, without its trailing newline.
+func readSecretFile(key, path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", key, err)
+	}
+	value := strings.TrimRight(string(data), "\r\n")
+	if value == "" {
+		return "", fmt.Errorf("%s file %s is empty", key, path)
+	}
+	return value, nil
+}
+
+// runSecretCommand runs a command with the shell and returns the first
+// line of its output, which is where password managers such as pass print
+// the password. The command gets no standard input, which may hold the
+// message being sent.
+func runSecretCommand(key, command string) (string, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command("sh", "-c", command)
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("%s command failed: %w: %s", key, err, msg)
+		}
+		return "", fmt.Errorf("%s command failed: %w", key, err)
+	}
+	value, _, _ := strings.Cut(stdout.String(), "\n")
+	value = strings.TrimRight(value, "\r")
+	if value == "" {
+		return "", fmt.Errorf("%s command printed no secret", key)
+	}
+	return value, nil
+}
@@ -0,0 +1,149 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoaderSecret(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "password")
+	if err := os.WriteFile(file, []byte("from-file\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		env      map[string]string
+		want     string
+		wantKind string
+		wantErr  string
+	}{
+		{"unset", nil, "", "", ""},
+		{"environment", map[string]string{"GHOSTMAIL_TEST_PASSWORD": "from-env", "GHOSTMAIL_TEST_PASSWORD_FILE": file}, "from-env", SourceEnv, ""},
+		{"file", map[string]string{"GHOSTMAIL_TEST_PASSWORD_FILE": file, "GHOSTMAIL_TEST_PASSWORD_CMD": "echo other"}, "from-file", SourceFile, ""},
+		{"missing file", map[string]string{"GHOSTMAIL_TEST_PASSWORD_FILE": filepath.Join(dir, "missing")}, "", SourceFile, "failed to read GHOSTMAIL_TEST_PASSWORD"},
+		{"command first line", map[string]string{"GHOSTMAIL_TEST_PASSWORD_CMD": "printf 'from cmd\\nlogin: ann\\n'"}, "from cmd", SourceCommand, ""},
+		{"failing command", map[string]string{"GHOSTMAIL_TEST_PASSWORD_CMD": "echo 'not in the password store' >&2; exit 1"}, "", SourceCommand, "GHOSTMAIL_TEST_PASSWORD command failed: exit status 1: not in the password store"},
+		{"empty command output", map[string]string{"GHOSTMAIL_TEST_PASSWORD_CMD": "true"}, "", SourceCommand, "printed no secret"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, suffix := range []string{"", "_FILE", "_CMD"} {
+				t.Setenv("GHOSTMAIL_TEST_PASSWORD"+suffix, tt.env["GHOSTMAIL_TEST_PASSWORD"+suffix])
+			}
+			got, src, err := loadSecret("GHOSTMAIL_TEST_PASSWORD", fileSecret{})
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("secret() error = %v, want %q", err, tt.wantErr)
+				}
+			} else if err != nil || got != tt.want {
+				t.Errorf("secret() = %q, %v, want %q", got, err, tt.want)
+			}
+			if src.Kind != tt.wantKind || (src.Err != nil) != (tt.wantErr != "") {
+				t.Errorf("secret() source = %+v, want kind %q", src, tt.wantKind)
+			}
+		})
+	}
+}
+
+func TestLoaderSecretFallback(t *testing.T) {
+	for _, suffix := range []string{"", "_FILE", "_CMD"} {
+		t.Setenv("GHOSTMAIL_TEST_PASSWORD"+suffix, "")
+	}
+	tests := []struct {
+		name     string
+		fallback fileSecret
+		want     string
+		wantKind string
+	}{
+		{"value", fileSecret{value: "from-config", cmd: "echo other"}, "from-config", SourceConfig},
+		{"command", fileSecret{cmd: "echo from-config-cmd"}, "from-config-cmd", SourceCommand},
+	}
+	for _, tt := range tests {
+		got, src, err := loadSecret("GHOSTMAIL_TEST_PASSWORD", tt.fallback)
+		if err != nil || got != tt.want || src.Kind != tt.wantKind {
+			t.Errorf("%s: secret() = %q, %+v, %v, want %q from %s", tt.name, got, src, err, tt.want, tt.wantKind)
+		}
+	}
+
+	// The environment comes first
+	t.Setenv("GHOSTMAIL_TEST_PASSWORD_CMD", "echo from-env-cmd")
+	if got, _, err := loadSecret("GHOSTMAIL_TEST_PASSWORD", fileSecret{value: "from-config"}); err != nil || got != "from-env-cmd" {
+		t.Errorf("secret() = %q, %v, want the environment command", got, err)
+	}
+}
+
+func TestLoaderSecretCachesCommand(t *testing.T) {
+	counter := filepath.Join(t.TempDir(), "runs")
+	t.Setenv("GHOSTMAIL_TEST_PASSWORD_CMD", "echo run >> "+counter+"; echo s3cret")
+	t.Setenv("GHOSTMAIL_OTHER_PASSWORD_CMD", "echo run >> "+counter+"; echo s3cret")
+
+	for _, key := range []string{"GHOSTMAIL_TEST_PASSWORD", "GHOSTMAIL_TEST_PASSWORD", "GHOSTMAIL_OTHER_PASSWORD"} {
+		if got, _, err := loadSecret(key, fileSecret{}); err != nil || got != "s3cret" {
+			t.Fatalf("secret(%s) = %q, %v", key, got, err)
+		}
+	}
+	data, err := os.ReadFile(counter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if runs := strings.Count(string(data), "run"); runs != 1 {
+		t.Errorf("command ran %d times, want 1", runs)
+	}
+}
+
+func TestLoadSecretError(t *testing.T) {
+	counter := filepath.Join(t.TempDir(), "runs")
+	t.Setenv("GHOSTMAIL_SMTP_HOST", "smtp.example.com")
+	t.Setenv("GHOSTMAIL_SMTP_USERNAME", "ann@example.com")
+	t.Setenv("GHOSTMAIL_SMTP_AUTH", "")
+	t.Setenv("GHOSTMAIL_SMTP_PASSWORD", "")
+	t.Setenv("GHOSTMAIL_SMTP_PASSWORD_FILE", filepath.Join(t.TempDir(), "missing"))
+	t.Setenv("GHOSTMAIL_IMAP_HOST", "imap.example.com")
+	t.Setenv("GHOSTMAIL_IMAP_USERNAME", "ann@example.com")
+	t.Setenv("GHOSTMAIL_IMAP_AUTH", "")
+	t.Setenv("GHOSTMAIL_IMAP_PASSWORD", "")
+	t.Setenv("GHOSTMAIL_IMAP_PASSWORD_CMD", "echo run >> "+counter+"; echo imap-secret")
+	for _, key := range []string{"GHOSTMAIL_OAUTH_TOKEN", "GHOSTMAIL_OAUTH_REFRESH_TOKEN", "GHOSTMAIL_OAUTH_REFRESH_TOKEN_FILE", "GHOSTMAIL_OAUTH_REFRESH_TOKEN_CMD"} {
+		t.Setenv(key, "")
+	}
+
+	// Secrets are only read when a command needs them
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if _, err := os.Stat(counter); err == nil {
+		t.Error("Load() ran the IMAP password command")
+	}
+
+	// A broken SMTP password does not affect IMAP
+	if err := cfg.ValidateIMAP(); err != nil || cfg.IMAP.Password != "imap-secret" {
+		t.Errorf("ValidateIMAP() = %v, password %q", err, cfg.IMAP.Password)
+	}
+	if err := cfg.ValidateSMTP(); err == nil || !strings.Contains(err.Error(), "failed to read GHOSTMAIL_SMTP_PASSWORD") {
+		t.Errorf("ValidateSMTP() error = %v", err)
+	}
+	if cfg.Secrets["GHOSTMAIL_SMTP_PASSWORD"].Err == nil {
+		t.Error("the failed secret is not recorded")
+	}
+	if err := cfg.ResolveAllSecrets(); err == nil {
+		t.Error("ResolveAllSecrets() expected the SMTP password error")
+	}
+}
+
+// loadSecret reads a secret the way Load does: it is looked up by the
+// loader and, if it is in a file or command, read by ResolveSecrets.
+func loadSecret(key string, fallback fileSecret) (string, SecretSource, error) {
+	l := &loader{secrets: make(map[string]SecretSource)}
+	value := l.secret(key, fallback)
+	for _, p := range l.pending {
+		p.targets = []*string{&value}
+	}
+	cfg := &Config{Secrets: l.secrets, pending: l.pending}
+	err := cfg.ResolveSecrets(key)
+	return value, cfg.Secrets[key], err
+}
@@ -0,0 +1,212 @@
+package email
+
+import (
+	"fmt"
+	"net/mail"
+	"strings"
+
+	"golang.org/x/net/idna"
+)
+
+// Address is a mailbox parsed from RFC 5322 syntax. Internationalized
+// domain names are converted to their ASCII (punycode) form, unless the
+// local part is UTF-8 (RFC 6532): such an address can only be sent with
+// SMTPUTF8 and keeps its domain in UTF-8 as well.
+type Address struct {
+	Name    string // Display name, may be empty
+	Address string // addr-spec, without quoting
+}
+
+// ParseAddress parses a single address: a bare addr-spec or a name-addr
+// such as "Ann <ann@example.com>".
+func ParseAddress(s string) (Address, error) {
+	parsed, err := mail.ParseAddress(s)
+	if err != nil {
+		return Address{}, fmt.Errorf("invalid address %q: %w", s, err)
+	}
+	return newAddress(parsed)
+}
+
+// ParseAddressList parses a comma-separated list of addresses. Groups
+// ("Team: a@example.com, b@example.com;") are expanded into their members.
+func ParseAddressList(s string) ([]Address, error) {
+	list, err := mail.ParseAddressList(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid address %q: %w", s, err)
+	}
+	addrs := make([]Address, 0, len(list))
+	for _, parsed := range list {
+		addr, err := newAddress(parsed)
+		if err != nil {
+			return nil, err
+		}
+		addrs = append(addrs, addr)
+	}
+	return addrs, nil
+}
+
+// ParseAddressLists parses values that may each hold a list of addresses,
+// such as repeated --to flags.
+func ParseAddressLists(values []string) ([]Address, error) {
+	var addrs []Address
+	for _, value := range values {
+		list, err := ParseAddressList(value)
+		if err != nil {
+			return nil, err
+		}
+		addrs = append(addrs, list...)
+	}
+	return addrs, nil
+}
+
+// newAddress converts a parsed address, checking its domain.
+func newAddress(parsed *mail.Address) (Address, error) {
+	at := strings.LastIndex(parsed.Address, "@")
+	local, domain := parsed.Address[:at], parsed.Address[at+1:]
+	ascii, err := idna.Lookup.ToASCII(domain)
+	if err != nil {
+		return Address{}, fmt.Errorf("invalid domain in address %q: %w", parsed.Address, err)
+	}
+	if isASCII(local) {
+		return Address{Name: parsed.Name, Address: local + "@" + ascii}, nil
+	}
+	unicode, err := idna.Lookup.ToUnicode(ascii)
+	if err != nil {
+		return Address{}, fmt.Errorf("invalid domain in address %q: %w", parsed.Address, err)
+	}
+	return Address{Name: parsed.Name, Address: local + "@" + unicode}, nil
+}
+
+// International reports whether the address has a UTF-8 local part, so
+// it needs a server supporting SMTPUTF8 (RFC 6531).
+func (a Address) International() bool {
+	return !isASCII(a.Address)
+}
+
+// isASCII reports whether s holds only ASCII characters.
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= 0x80 {
+			return false
+		}
+	}
+	return true
+}
+
+// asciiDomain returns a domain in its ASCII (punycode) form, or as it is if
+// it is not a valid domain name.
+func asciiDomain(domain string) string {
+	if ascii, err := idna.Lookup.ToASCII(domain); err == nil {
+		return ascii
+	}
+	return domain
+}
+
+// displayDomain returns a domain with its punycode labels decoded for
+// display, or as it is if it is not a valid domain name.
+func displayDomain(domain string) string {
+	if unicode, err := idna.Display.ToUnicode(domain); err == nil {
+		return unicode
+	}
+	return domain
+}
+
+// String formats the address for a header field, quoting and encoding the
+// display name and local part as needed. An international address is
+// written in UTF-8.
+func (a Address) String() string {
+	if a.Name == "" {
+		return a.Envelope()
+	}
+	if isPhrase(a.Name) {
+		return a.Name + " <" + a.Envelope() + ">"
+	}
+	return (&mail.Address{Name: a.Name, Address: a.Address}).String()
+}
+
+// Display formats the address for display. Unlike String, it leaves a
+// non-ASCII display name unencoded; it is quoted only if it holds special
+// characters, so the result still parses as an address.
+func (a Address) Display() string {
+	if a.Name == "" {
+		return a.Envelope()
+	}
+	name := a.Name
+	if strings.ContainsAny(name, `()<>[]:;@\,."`) || strings.TrimSpace(name) != name {
+		name = `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(name) + `"`
+	}
+	return name + " <" + a.Envelope() + ">"
+}
+
+// isPhrase reports whether a display name can be written as is: words of
+// ASCII atom characters separated by single spaces.
+func isPhrase(name string) bool {
+	if name == "" || strings.TrimSpace(name) != name || strings.Contains(name, "  ") {
+		return false
+	}
+	for _, r := range name {
+		switch {
+		case r == ' ', 'a' <= r && r <= 'z', 'A' <= r && r <= 'Z', '0' <= r && r <= '9':
+		case strings.ContainsRune("!#$%&'*+-/=?^_`{|}~", r):
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// Envelope returns the address for the SMTP envelope, with the local part
+// quoted if needed.
+func (a Address) Envelope() string {
+	s := (&mail.Address{Address: a.Address}).String()
+	return strings.TrimSuffix(strings.TrimPrefix(s, "<"), ">")
+}
+
+// key identifies an address for deduplication, ignoring case.
+func (a Address) key() string {
+	return strings.ToLower(a.Address)
+}
+
+// DedupeAddresses removes repeated addresses, ignoring case, within and
+// across the lists. An address is kept in the first list it appears in,
+// so one in To is dropped from Cc and Bcc.
+func DedupeAddresses(lists ...[]Address) [][]Address {
+	seen := make(map[string]bool)
+	result := make([][]Address, len(lists))
+	for i, list := range lists {
+		for _, addr := range list {
+			if !seen[addr.key()] {
+				seen[addr.key()] = true
+				result[i] = append(result[i], addr)
+			}
+		}
+	}
+	return result
+}
+
+// FormatAddresses formats addresses for a header field.
+func FormatAddresses(addrs []Address) []string {
+	if len(addrs) == 0 {
+		return nil
+	}
+	formatted := make([]string, len(addrs))
+	for i, addr := range addrs {
+		formatted[i] = addr.String()
+	}
+	return formatted
+}
+
+// SameAddress reports whether two address strings, each bare or with a
+// display name, name the same mailbox, ignoring case. Unparsable strings
+// are compared as they are.
+func SameAddress(a, b string) bool {
+	return addressKey(a) == addressKey(b)
+}
+
+// addressKey returns the deduplication key of an address string.
+func addressKey(s string) string {
+	if addr, err := ParseAddress(s); err == nil {
+		return addr.key()
+	}
+	return strings.ToLower(strings.TrimSpace(s))
+}
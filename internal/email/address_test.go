@@ -0,0 +1,177 @@
+package email
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/GodGMN/ghostmail-cli/internal/config"
+	"github.com/emersion/go-imap"
+)
+
+func TestParseAddressList(t *testing.T) {
+	tests := []struct {
+		input string
+		want  []Address
+	}{
+		{"ann@example.com", []Address{{Address: "ann@example.com"}}},
+		{`"Doe, Ann" <ann@example.com>, bob@example.org`, []Address{{Name: "Doe, Ann", Address: "ann@example.com"}, {Address: "bob@example.org"}}},
+		{`"ann doe"@example.com`, []Address{{Address: "ann doe@example.com"}}},
+		{"Team: ann@example.com, Bob <bob@example.org>;", []Address{{Address: "ann@example.com"}, {Name: "Bob", Address: "bob@example.org"}}},
+		{"Undisclosed recipients:;", []Address{}},
+		{"Jürgen <j@bücher.de>", []Address{{Name: "Jürgen", Address: "j@xn--bcher-kva.de"}}},
+	}
+	for _, tt := range tests {
+		got, err := ParseAddressList(tt.input)
+		if err != nil {
+			t.Errorf("ParseAddressList(%q) error = %v", tt.input, err)
+			continue
+		}
+		if len(got) != len(tt.want) {
+			t.Errorf("ParseAddressList(%q) = %v, want %v", tt.input, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("ParseAddressList(%q)[%d] = %+v, want %+v", tt.input, i, got[i], tt.want[i])
+			}
+		}
+	}
+
+	for _, input := range []string{"", "ann", "ann@", "<ann@example.com", "ann@exa mple.com", "ann@-bad-.com"} {
+		if _, err := ParseAddressList(input); err == nil {
+			t.Errorf("ParseAddressList(%q) expected an error", input)
+		}
+	}
+}
+
+func TestAddressFormat(t *testing.T) {
+	tests := []struct {
+		addr     Address
+		str, dsp string
+	}{
+		{Address{Address: "ann@example.com"}, "ann@example.com", "ann@example.com"},
+		{Address{Name: "Ann Doe", Address: "ann@example.com"}, "Ann Doe <ann@example.com>", "Ann Doe <ann@example.com>"},
+		{Address{Name: "Doe, Ann", Address: "ann@example.com"}, `"Doe, Ann" <ann@example.com>`, `"Doe, Ann" <ann@example.com>`},
+		{Address{Name: "Jürgen", Address: "j@example.com"}, "=?utf-8?q?J=C3=BCrgen?= <j@example.com>", "Jürgen <j@example.com>"},
+		{Address{Address: "ann doe@example.com"}, `"ann doe"@example.com`, `"ann doe"@example.com`},
+	}
+	for _, tt := range tests {
+		if got := tt.addr.String(); got != tt.str {
+			t.Errorf("%+v.String() = %q, want %q", tt.addr, got, tt.str)
+		}
+		if got := tt.addr.Display(); got != tt.dsp {
+			t.Errorf("%+v.Display() = %q, want %q", tt.addr, got, tt.dsp)
+		}
+		if _, err := ParseAddress(tt.addr.String()); err != nil {
+			t.Errorf("ParseAddress(%q) error = %v", tt.addr.String(), err)
+		}
+	}
+}
+
+func TestDedupeAddresses(t *testing.T) {
+	to, _ := ParseAddressLists([]string{"ann@example.com, Bob <bob@example.org>", "ANN@example.com"})
+	cc, _ := ParseAddressLists([]string{"bob@EXAMPLE.org, carol@example.net"})
+	bcc, _ := ParseAddressLists([]string{"Carol <carol@example.net>, dave@example.net"})
+
+	got := DedupeAddresses(to, cc, bcc)
+	want := []string{"ann@example.com, Bob <bob@example.org>", "carol@example.net", "dave@example.net"}
+	for i := range want {
+		if s := strings.Join(FormatAddresses(got[i]), ", "); s != want[i] {
+			t.Errorf("DedupeAddresses()[%d] = %q, want %q", i, s, want[i])
+		}
+	}
+}
+
+func TestSameAddress(t *testing.T) {
+	if !SameAddress("Ann <Ann@Example.com>", "ann@example.com") {
+		t.Error("SameAddress() = false for the same mailbox")
+	}
+	if SameAddress("ann@example.com", "bob@example.com") {
+		t.Error("SameAddress() = true for different mailboxes")
+	}
+}
+
+func TestBuildDedupesRecipients(t *testing.T) {
+	s := NewSender(&config.SMTPConfig{From: "reports@example.com"})
+	msg, err := s.Build([]string{"Ann <ann@example.com>, bob@example.org"}, "Numbers", "Revenue is up.",
+		WithCC([]string{"ANN@example.com", "Team: carol@bücher.de;"}),
+		WithBCC([]string{"bob@example.org", "audit@example.com"}),
+	)
+	if err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+	want := "ann@example.com,bob@example.org,carol@xn--bcher-kva.de,audit@example.com"
+	if got := strings.Join(msg.Recipients, ","); got != want {
+		t.Errorf("Build() recipients = %s, want %s", got, want)
+	}
+
+	if _, err := s.Build([]string{"ann@example.com", "not an address"}, "Numbers", "Revenue is up."); err == nil {
+		t.Error("Build() with a malformed recipient expected an error")
+	}
+}
+
+func TestInternationalAddress(t *testing.T) {
+	addr, err := ParseAddress("José <josé@例え.jp>")
+	if err != nil {
+		t.Fatalf("ParseAddress() error = %v", err)
+	}
+	if addr.Address != "josé@例え.jp" || !addr.International() {
+		t.Errorf("ParseAddress() = %+v, international %v", addr, addr.International())
+	}
+	if got := addr.String(); got != "=?utf-8?q?Jos=C3=A9?= <josé@例え.jp>" {
+		t.Errorf("String() = %q", got)
+	}
+
+	// An ASCII local part keeps the domain in punycode
+	addr, _ = ParseAddress("jose@xn--r8jz45g.jp")
+	if addr.Address != "jose@xn--r8jz45g.jp" || addr.International() {
+		t.Errorf("ParseAddress() = %+v, international %v", addr, addr.International())
+	}
+}
+
+func TestBuildInternational(t *testing.T) {
+	s := NewSender(&config.SMTPConfig{From: "Ann <ann@bücher.de>"})
+	msg, err := s.Build([]string{"José <josé@例え.jp>", "jose@例え.jp"}, "Hola", "Hola, José.")
+	if err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+	if got := strings.Join(msg.Recipients, ","); got != "josé@例え.jp,jose@xn--r8jz45g.jp" {
+		t.Errorf("Build() recipients = %s", got)
+	}
+	header := string(msg.Data[:strings.Index(string(msg.Data), "\r\n\r\n")])
+	for _, want := range []string{"\r\nFrom: Ann <ann@xn--bcher-kva.de>", "To: =?utf-8?q?Jos=C3=A9?= <josé@例え.jp>, jose@xn--r8jz45g.jp\r\n"} {
+		if !strings.Contains("\r\n"+header+"\r\n", want) {
+			t.Errorf("Build() header missing %q:\n%s", want, header)
+		}
+	}
+	if !strings.HasSuffix(msg.MessageID, "@xn--bcher-kva.de>") {
+		t.Errorf("Build() Message-ID = %s", msg.MessageID)
+	}
+
+	if err := requireASCII(msg.From, msg.Recipients, msg.Data); err == nil || !strings.Contains(err.Error(), "josé@例え.jp") {
+		t.Errorf("requireASCII() error = %v", err)
+	}
+	if err := requireASCII(msg.From, msg.Recipients[1:], msg.Data); err == nil || !strings.Contains(err.Error(), "in To") {
+		t.Errorf("requireASCII() with UTF-8 in the header error = %v", err)
+	}
+	if err := requireASCII("ann@xn--bcher-kva.de", []string{"jose@xn--r8jz45g.jp"}, []byte("To: jose@xn--r8jz45g.jp\r\n\r\nHola")); err != nil {
+		t.Errorf("requireASCII() for punycode domains error = %v", err)
+	}
+}
+
+func TestFormatIMAPAddress(t *testing.T) {
+	r := NewReader(&config.IMAPConfig{})
+	tests := []struct {
+		addr *imap.Address
+		want string
+	}{
+		{&imap.Address{PersonalName: "José", MailboxName: "josé", HostName: "例え.jp"}, "José <josé@例え.jp>"},
+		{&imap.Address{MailboxName: "jose", HostName: "xn--r8jz45g.jp"}, "jose@例え.jp"},
+		{&imap.Address{PersonalName: "Doe, Ann", MailboxName: "ann", HostName: "example.com"}, `"Doe, Ann" <ann@example.com>`},
+	}
+	for _, tt := range tests {
+		if got := r.formatAddress(tt.addr); got != tt.want {
+			t.Errorf("formatAddress(%+v) = %q, want %q", tt.addr, got, tt.want)
+		}
+	}
+}
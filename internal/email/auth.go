@@ -0,0 +1,238 @@
+package email
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/tls"
+	"fmt"
+	"net/smtp"
+	"strings"
+
+	"github.com/GodGMN/ghostmail-cli/internal/config"
+	"github.com/GodGMN/ghostmail-cli/internal/oauth"
+	"github.com/emersion/go-sasl"
+)
+
+// SASL mechanism names not defined by go-sasl.
+const (
+	XOAuth2 = "XOAUTH2" // Google's and Microsoft's OAuth mechanism
+	CRAMMD5 = "CRAM-MD5"
+)
+
+// authenticator negotiates the SASL mechanism for a server and creates its
+// client from the credentials configured for a protocol.
+type authenticator struct {
+	protocol  string // "SMTP" or "IMAP", for messages
+	mechanism string // Configured mechanism, one of config.AuthMechanisms
+	authzID   string
+	username  string
+	password  string
+	host      string
+	port      int
+	oauth     *config.OAuthConfig
+}
+
+// choose returns the mechanism to authenticate with among those the server
+// offers. A configured mechanism must be offered. With auto, an OAuth
+// mechanism is chosen if OAuth is configured, PLAIN if an authorization
+// identity is set, and otherwise fallback.
+func (a *authenticator) choose(offered []string, fallback string) (string, error) {
+	configured := a.mechanism
+	if configured == "" || configured == config.AuthAuto {
+		switch {
+		case a.oauth != nil && a.oauth.Enabled():
+			if offers(offered, sasl.OAuthBearer) {
+				return sasl.OAuthBearer, nil
+			}
+			if offers(offered, XOAuth2) {
+				return XOAuth2, nil
+			}
+			return "", fmt.Errorf("%s server does not support OAuth authentication (OAUTHBEARER or XOAUTH2)", a.protocol)
+		case a.authzID != "":
+			configured = config.AuthPlain
+		default:
+			return fallback, nil
+		}
+	}
+
+	mech := strings.ToUpper(configured)
+	if !offers(offered, mech) {
+		list := strings.Join(offered, " ")
+		if list == "" {
+			list = "none"
+		}
+		return "", fmt.Errorf("%s server does not offer %s authentication (offered: %s); set GHOSTMAIL_%s_AUTH", a.protocol, mech, list, a.protocol)
+	}
+	return mech, nil
+}
+
+// cleartext returns an error if a mechanism would send a password or token
+// in clear text over an unencrypted connection to another host, as net/smtp
+// refuses to.
+func (a *authenticator) cleartext(mech string, encrypted bool) error {
+	switch mech {
+	case sasl.Plain, sasl.Login, sasl.OAuthBearer, XOAuth2:
+		if !encrypted && !isLocalhost(a.host) {
+			return fmt.Errorf("refusing to send %s credentials over an unencrypted connection", mech)
+		}
+	}
+	return nil
+}
+
+// client creates the SASL client for a mechanism, refusing those that would
+// send credentials in clear text (see cleartext).
+func (a *authenticator) client(mech string, encrypted bool) (sasl.Client, error) {
+	if err := a.cleartext(mech, encrypted); err != nil {
+		return nil, err
+	}
+
+	switch mech {
+	case sasl.Plain:
+		return sasl.NewPlainClient(a.authzID, a.username, a.password), nil
+	case sasl.Login:
+		return &loginClient{username: a.username, password: a.password}, nil
+	case CRAMMD5:
+		return &cramMD5Client{username: a.username, secret: a.password}, nil
+	case sasl.External:
+		return sasl.NewExternalClient(a.authzID), nil
+	case sasl.OAuthBearer, XOAuth2:
+		token, err := oauth.NewTokenSource(a.oauth).Token(context.Background())
+		if err != nil {
+			return nil, err
+		}
+		if mech == XOAuth2 {
+			return NewXOAuth2Client(a.username, token), nil
+		}
+		return sasl.NewOAuthBearerClient(&sasl.OAuthBearerOptions{Username: a.username, Token: token, Host: a.host, Port: a.port}), nil
+	}
+	return nil, fmt.Errorf("unsupported authentication mechanism %s", mech)
+}
+
+// failed describes a failed authentication, hinting at an expired or
+// under-scoped token for OAuth.
+func (a *authenticator) failed(mech string, err error) error {
+	if mech == sasl.OAuthBearer || mech == XOAuth2 {
+		return fmt.Errorf("OAuth authentication failed (check that the token is valid and has the mail scopes): %w", err)
+	}
+	return fmt.Errorf("%s authentication failed: %w", mech, err)
+}
+
+// offers reports whether a mechanism is in a list, ignoring case.
+func offers(offered []string, mech string) bool {
+	for _, m := range offered {
+		if strings.EqualFold(m, mech) {
+			return true
+		}
+	}
+	return false
+}
+
+// clientTLSConfig returns the TLS configuration for a server, with the
+// client certificate used by EXTERNAL authentication if one is set. The key
+// is read from the certificate file unless keyFile is set.
+func clientTLSConfig(host, certFile, keyFile string) (*tls.Config, error) {
+	tlsConfig := &tls.Config{ServerName: host}
+	if certFile == "" {
+		return tlsConfig, nil
+	}
+	if keyFile == "" {
+		keyFile = certFile
+	}
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load client certificate: %w", err)
+	}
+	tlsConfig.Certificates = []tls.Certificate{cert}
+	return tlsConfig, nil
+}
+
+// xoauth2Client implements the XOAUTH2 SASL mechanism.
+type xoauth2Client struct {
+	username, token string
+}
+
+// NewXOAuth2Client returns a SASL client authenticating with an OAuth 2.0
+// access token using XOAUTH2.
+func NewXOAuth2Client(username, token string) sasl.Client {
+	return &xoauth2Client{username: username, token: token}
+}
+
+func (c *xoauth2Client) Start() (string, []byte, error) {
+	return XOAuth2, []byte("user=" + c.username + "\x01auth=Bearer " + c.token + "\x01\x01"), nil
+}
+
+// Next answers the error challenge the server sends on failure with an
+// empty response, after which the server rejects the authentication.
+func (c *xoauth2Client) Next(challenge []byte) ([]byte, error) {
+	return []byte{}, nil
+}
+
+// loginClient implements the LOGIN mechanism, answering the server's
+// prompts rather than sending the username as an initial response, which
+// some servers reject.
+type loginClient struct {
+	username, password string
+}
+
+func (c *loginClient) Start() (string, []byte, error) {
+	return sasl.Login, nil, nil
+}
+
+func (c *loginClient) Next(challenge []byte) ([]byte, error) {
+	switch strings.ToLower(strings.TrimSpace(string(challenge))) {
+	case "username:":
+		return []byte(c.username), nil
+	case "password:":
+		return []byte(c.password), nil
+	}
+	return nil, fmt.Errorf("unexpected server challenge %q", challenge)
+}
+
+// cramMD5Client implements the CRAM-MD5 mechanism (RFC 2195).
+type cramMD5Client struct {
+	username, secret string
+}
+
+func (c *cramMD5Client) Start() (string, []byte, error) {
+	return CRAMMD5, nil, nil
+}
+
+func (c *cramMD5Client) Next(challenge []byte) ([]byte, error) {
+	d := hmac.New(md5.New, []byte(c.secret))
+	d.Write(challenge)
+	return []byte(fmt.Sprintf("%s %x", c.username, d.Sum(nil))), nil
+}
+
+// saslAuth adapts a SASL client to net/smtp.
+type saslAuth struct {
+	client sasl.Client
+	empty  bool // An empty initial response is pending
+}
+
+// Start begins the exchange. net/smtp cannot send an empty initial
+// response, such as EXTERNAL's without an authorization identity, so it is
+// sent in answer to the server's first challenge instead.
+func (a *saslAuth) Start(server *smtp.ServerInfo) (string, []byte, error) {
+	mech, ir, err := a.client.Start()
+	if ir != nil && len(ir) == 0 {
+		a.empty, ir = true, nil
+	}
+	return mech, ir, err
+}
+
+func (a *saslAuth) Next(fromServer []byte, more bool) ([]byte, error) {
+	if !more {
+		return nil, nil
+	}
+	if a.empty {
+		a.empty = false
+		return []byte{}, nil
+	}
+	return a.client.Next(fromServer)
+}
+
+// isLocalhost reports whether a server name refers to the local machine.
+func isLocalhost(name string) bool {
+	return name == "localhost" || name == "127.0.0.1" || name == "::1"
+}
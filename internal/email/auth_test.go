@@ -0,0 +1,222 @@
+package email
+
+import (
+	"bufio"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/GodGMN/ghostmail-cli/internal/config"
+	"github.com/emersion/go-imap/client"
+)
+
+func TestChooseMechanism(t *testing.T) {
+	oauth := &config.OAuthConfig{Token: "ya29.token"}
+	tests := []struct {
+		name      string
+		mechanism string
+		authzID   string
+		oauth     *config.OAuthConfig
+		offered   []string
+		want      string
+		wantErr   string
+	}{
+		{"auto", config.AuthAuto, "", &config.OAuthConfig{}, []string{"PLAIN", "LOGIN"}, "FALLBACK", ""},
+		{"auto with OAuth", config.AuthAuto, "", oauth, []string{"PLAIN", "XOAUTH2", "OAUTHBEARER"}, "OAUTHBEARER", ""},
+		{"auto with XOAUTH2 only", "", "", oauth, []string{"PLAIN", "XOAUTH2"}, "XOAUTH2", ""},
+		{"auto without OAuth offered", config.AuthAuto, "", oauth, []string{"PLAIN", "LOGIN"}, "", "does not support OAuth"},
+		{"auto with authorization identity", config.AuthAuto, "shared@example.com", nil, []string{"LOGIN", "PLAIN"}, "PLAIN", ""},
+		{"explicit", config.AuthCRAMMD5, "", nil, []string{"PLAIN", "CRAM-MD5"}, "CRAM-MD5", ""},
+		{"explicit case-insensitive", config.AuthExternal, "", nil, []string{"plain", "external"}, "EXTERNAL", ""},
+		{"explicit not offered", config.AuthXOAuth2, "", oauth, []string{"PLAIN", "LOGIN"}, "", "does not offer XOAUTH2 authentication (offered: PLAIN LOGIN)"},
+		{"explicit without AUTH", config.AuthPlain, "", nil, nil, "", "offered: none"},
+	}
+	for _, tt := range tests {
+		a := &authenticator{protocol: "SMTP", mechanism: tt.mechanism, authzID: tt.authzID, oauth: tt.oauth}
+		got, err := a.choose(tt.offered, "FALLBACK")
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("%s: choose() error = %v, want %q", tt.name, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("%s: choose() = %q, %v, want %q", tt.name, got, err, tt.want)
+		}
+	}
+}
+
+func TestAuthenticatorClient(t *testing.T) {
+	a := &authenticator{
+		authzID:  "shared@example.com",
+		username: "ann@example.com",
+		password: "s3cret",
+		host:     "imap.example.com",
+		port:     993,
+		oauth:    &config.OAuthConfig{Token: "ya29.token"},
+	}
+	tests := []struct {
+		mech   string
+		wantIR string
+	}{
+		{"PLAIN", "shared@example.com\x00ann@example.com\x00s3cret"},
+		{"EXTERNAL", "shared@example.com"},
+		{"XOAUTH2", "user=ann@example.com\x01auth=Bearer ya29.token\x01\x01"},
+		{"OAUTHBEARER", "n,a=ann@example.com,\x01host=imap.example.com\x01port=993\x01auth=Bearer ya29.token\x01\x01"},
+	}
+	for _, tt := range tests {
+		sc, err := a.client(tt.mech, true)
+		if err != nil {
+			t.Fatalf("client(%s) error = %v", tt.mech, err)
+		}
+		mech, ir, err := sc.Start()
+		if err != nil || mech != tt.mech || string(ir) != tt.wantIR {
+			t.Errorf("client(%s) start = %s %q, %v, want %q", tt.mech, mech, ir, err, tt.wantIR)
+		}
+	}
+
+	for _, mech := range []string{"PLAIN", "LOGIN", "XOAUTH2"} {
+		if _, err := a.client(mech, false); err == nil || !strings.Contains(err.Error(), "unencrypted") {
+			t.Errorf("client(%s) over an unencrypted connection error = %v", mech, err)
+		}
+	}
+	if _, err := a.client("CRAM-MD5", false); err != nil {
+		t.Errorf("client(CRAM-MD5) over an unencrypted connection error = %v", err)
+	}
+	a.host = "localhost"
+	if _, err := a.client("LOGIN", false); err != nil {
+		t.Errorf("client(LOGIN) over an unencrypted connection to localhost error = %v", err)
+	}
+}
+
+func TestChallengeResponseClients(t *testing.T) {
+	// RFC 2195 example
+	cram := &cramMD5Client{username: "tim", secret: "tanstaaftanstaaf"}
+	resp, err := cram.Next([]byte("<1896.697170952@postoffice.reston.mci.net>"))
+	if err != nil || string(resp) != "tim b913a602c7eda7a495b4e6e7334d3890" {
+		t.Errorf("CRAM-MD5 response = %q, %v", resp, err)
+	}
+
+	login := &loginClient{username: "ann", password: "s3cret"}
+	for challenge, want := range map[string]string{"Username:": "ann", "Password:": "s3cret"} {
+		if resp, err := login.Next([]byte(challenge)); err != nil || string(resp) != want {
+			t.Errorf("LOGIN response to %q = %q, %v", challenge, resp, err)
+		}
+	}
+	if _, err := login.Next([]byte("Token:")); err == nil {
+		t.Error("LOGIN response to an unknown challenge expected an error")
+	}
+}
+
+func TestIMAPLoginDisabled(t *testing.T) {
+	// A server that disables the LOGIN command but offers the SASL mechanism
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		in := bufio.NewReader(conn)
+		fmt.Fprint(conn, "* OK ready\r\n")
+		for {
+			line, err := in.ReadString('\n')
+			if err != nil {
+				return
+			}
+			tag, cmd, _ := strings.Cut(strings.TrimSpace(line), " ")
+			switch strings.ToUpper(cmd) {
+			case "CAPABILITY":
+				fmt.Fprintf(conn, "* CAPABILITY IMAP4rev1 LOGINDISABLED AUTH=LOGIN\r\n%s OK done\r\n", tag)
+			case "AUTHENTICATE LOGIN":
+				var answers []string
+				for _, prompt := range []string{"Username:", "Password:"} {
+					fmt.Fprintf(conn, "+ %s\r\n", base64.StdEncoding.EncodeToString([]byte(prompt)))
+					answer, _ := in.ReadString('\n')
+					decoded, _ := base64.StdEncoding.DecodeString(strings.TrimSpace(answer))
+					answers = append(answers, string(decoded))
+				}
+				if answers[0] == "ann" && answers[1] == "s3cret" {
+					fmt.Fprintf(conn, "%s OK authenticated\r\n", tag)
+				} else {
+					fmt.Fprintf(conn, "%s NO invalid credentials\r\n", tag)
+				}
+			default:
+				fmt.Fprintf(conn, "%s NO LOGIN is disabled\r\n", tag)
+			}
+		}
+	}()
+
+	c, err := client.Dial(l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.ErrorLog = log.New(io.Discard, "", 0)
+	defer c.Terminate()
+	r := NewReader(&config.IMAPConfig{Host: "127.0.0.1", Username: "ann", Password: "s3cret", Auth: config.AuthLogin})
+	if err := r.authenticate(c, false); err != nil {
+		t.Fatalf("authenticate() error = %v", err)
+	}
+	if r.Mechanism() != "LOGIN" {
+		t.Errorf("Mechanism() = %q, want LOGIN", r.Mechanism())
+	}
+}
+
+func TestIMAPLoginUnencrypted(t *testing.T) {
+	for _, capability := range []string{"IMAP4rev1", "IMAP4rev1 LOGINDISABLED AUTH=LOGIN"} {
+		// A server that records the commands it receives
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		commands := make(chan string, 10)
+		go func() {
+			defer close(commands)
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+			in := bufio.NewReader(conn)
+			fmt.Fprint(conn, "* OK ready\r\n")
+			for {
+				line, err := in.ReadString('\n')
+				if err != nil {
+					return
+				}
+				tag, cmd, _ := strings.Cut(strings.TrimSpace(line), " ")
+				commands <- strings.Fields(cmd)[0]
+				if strings.EqualFold(cmd, "CAPABILITY") {
+					fmt.Fprintf(conn, "* CAPABILITY %s\r\n", capability)
+				}
+				fmt.Fprintf(conn, "%s OK done\r\n", tag)
+			}
+		}()
+
+		c, err := client.Dial(l.Addr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		c.ErrorLog = log.New(io.Discard, "", 0)
+		r := NewReader(&config.IMAPConfig{Host: "imap.example.com", Username: "ann", Password: "s3cret"})
+		err = r.authenticate(c, false)
+		if err == nil || !strings.Contains(err.Error(), "unencrypted") {
+			t.Errorf("%s: authenticate() over an unencrypted connection error = %v", capability, err)
+		}
+		c.Terminate()
+		l.Close()
+		for cmd := range commands {
+			if cmd != "CAPABILITY" {
+				t.Errorf("%s: the client sent %s over an unencrypted connection", capability, cmd)
+			}
+		}
+	}
+}
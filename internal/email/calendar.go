@@ -0,0 +1,303 @@
+package email
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	emailtypes "github.com/GodGMN/ghostmail-cli/pkg/email"
+	"github.com/emersion/go-ical"
+	"github.com/emersion/go-message/mail"
+)
+
+// iTIP methods (RFC 5546) used for meeting invitations.
+const (
+	CalendarMethodRequest = "REQUEST"
+	CalendarMethodReply   = "REPLY"
+	CalendarMethodCancel  = "CANCEL"
+)
+
+// Participation statuses an attendee can answer an invitation with.
+const (
+	PartStatAccepted  = "ACCEPTED"
+	PartStatDeclined  = "DECLINED"
+	PartStatTentative = "TENTATIVE"
+)
+
+// calendarProdID identifies ghostmail as the producer of generated calendars.
+const calendarProdID = "-//ghostmail-cli//ghostmail//EN"
+
+// ParseCalendar parses iCalendar data into a list of events.
+func ParseCalendar(data []byte) ([]emailtypes.CalendarEvent, error) {
+	cal, err := ical.NewDecoder(bytes.NewReader(data)).Decode()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse calendar: %w", err)
+	}
+
+	method, _ := cal.Props.Text(ical.PropMethod)
+
+	var events []emailtypes.CalendarEvent
+	for _, event := range cal.Events() {
+		ev := emailtypes.CalendarEvent{
+			Method: strings.ToUpper(method),
+		}
+		ev.UID, _ = event.Props.Text(ical.PropUID)
+		ev.Summary, _ = event.Props.Text(ical.PropSummary)
+		ev.Description, _ = event.Props.Text(ical.PropDescription)
+		ev.Location, _ = event.Props.Text(ical.PropLocation)
+		ev.Status, _ = event.Props.Text(ical.PropStatus)
+
+		if prop := event.Props.Get(ical.PropSequence); prop != nil {
+			ev.Sequence, _ = prop.Int()
+		}
+
+		if prop := event.Props.Get(ical.PropOrganizer); prop != nil {
+			ev.Organizer = formatCalendarAddress(prop)
+		}
+
+		for i := range event.Props.Values(ical.PropAttendee) {
+			prop := &event.Props.Values(ical.PropAttendee)[i]
+			ev.Attendees = append(ev.Attendees, emailtypes.CalendarAttendee{
+				Email:  calendarAddress(prop.Value),
+				Name:   prop.Params.Get(ical.ParamCommonName),
+				Role:   prop.Params.Get(ical.ParamRole),
+				Status: prop.Params.Get(ical.ParamParticipationStatus),
+				RSVP:   strings.EqualFold(prop.Params.Get(ical.ParamRSVP), "TRUE"),
+			})
+		}
+
+		if prop := event.Props.Get(ical.PropDateTimeStart); prop != nil {
+			ev.Start, ev.TimeZone = calendarTime(prop)
+			ev.AllDay = len(prop.Value) == len("20060102")
+		}
+		if prop := event.Props.Get(ical.PropDateTimeEnd); prop != nil {
+			ev.End, _ = calendarTime(prop)
+		} else if prop := event.Props.Get(ical.PropDuration); prop != nil {
+			if dur, err := prop.Duration(); err == nil {
+				ev.End = ev.Start.Add(dur)
+			}
+		} else if ev.AllDay {
+			ev.End = ev.Start.AddDate(0, 0, 1)
+		}
+
+		for _, name := range []string{ical.PropRecurrenceRule, ical.PropRecurrenceDates, ical.PropExceptionDates} {
+			for _, prop := range event.Props.Values(name) {
+				ev.Recurrence = append(ev.Recurrence, name+":"+prop.Value)
+			}
+		}
+
+		events = append(events, ev)
+	}
+
+	return events, nil
+}
+
+// BuildCalendarReply builds an iTIP METHOD:REPLY answering the invitation in
+// data on behalf of attendee with the given participation status.
+func BuildCalendarReply(data []byte, attendee, partStat string) ([]byte, error) {
+	switch partStat {
+	case PartStatAccepted, PartStatDeclined, PartStatTentative:
+	default:
+		return nil, fmt.Errorf("invalid participation status: %s", partStat)
+	}
+
+	cal, err := ical.NewDecoder(bytes.NewReader(data)).Decode()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse calendar: %w", err)
+	}
+
+	if method, _ := cal.Props.Text(ical.PropMethod); method != "" && !strings.EqualFold(method, CalendarMethodRequest) {
+		return nil, fmt.Errorf("cannot reply to a calendar with method %s", method)
+	}
+
+	reply := ical.NewCalendar()
+	reply.Props.SetText(ical.PropProductID, calendarProdID)
+	reply.Props.SetText(ical.PropVersion, "2.0")
+	reply.Props.SetText(ical.PropMethod, CalendarMethodReply)
+
+	// Keep the time zone definitions so TZID references stay resolvable.
+	for _, child := range cal.Children {
+		if child.Name == ical.CompTimezone {
+			reply.Children = append(reply.Children, child)
+		}
+	}
+
+	now := time.Now().UTC()
+	found := false
+	for _, event := range cal.Events() {
+		ev := ical.NewEvent()
+
+		// Properties that identify the event instance being answered.
+		for _, name := range []string{
+			ical.PropUID,
+			ical.PropSequence,
+			ical.PropRecurrenceID,
+			ical.PropDateTimeStart,
+			ical.PropDateTimeEnd,
+			ical.PropDuration,
+			ical.PropSummary,
+			ical.PropOrganizer,
+		} {
+			if prop := event.Props.Get(name); prop != nil {
+				ev.Props.Set(prop)
+			}
+		}
+		ev.Props.SetDateTime(ical.PropDateTimeStamp, now)
+
+		prop := findAttendee(event.Component, attendee)
+		if prop == nil {
+			prop = ical.NewProp(ical.PropAttendee)
+			prop.Value = "mailto:" + attendee
+		} else {
+			found = true
+		}
+		answer := ical.NewProp(ical.PropAttendee)
+		answer.Value = prop.Value
+		if cn := prop.Params.Get(ical.ParamCommonName); cn != "" {
+			answer.Params.Set(ical.ParamCommonName, cn)
+		}
+		answer.Params.Set(ical.ParamParticipationStatus, partStat)
+		ev.Props.Set(answer)
+
+		reply.Children = append(reply.Children, ev.Component)
+	}
+
+	if len(cal.Events()) == 0 {
+		return nil, fmt.Errorf("calendar contains no events")
+	}
+	if !found {
+		return nil, fmt.Errorf("%s is not listed as an attendee of this invitation", attendee)
+	}
+
+	var buf bytes.Buffer
+	if err := ical.NewEncoder(&buf).Encode(reply); err != nil {
+		return nil, fmt.Errorf("failed to encode calendar reply: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// PrepareCalendarRequest validates iCalendar data for sending as an
+// invitation and ensures it carries METHOD:REQUEST.
+func PrepareCalendarRequest(data []byte) ([]byte, error) {
+	cal, err := ical.NewDecoder(bytes.NewReader(data)).Decode()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse calendar: %w", err)
+	}
+
+	events := cal.Events()
+	if len(events) == 0 {
+		return nil, fmt.Errorf("calendar contains no events")
+	}
+	for _, event := range events {
+		if event.Props.Get(ical.PropOrganizer) == nil {
+			return nil, fmt.Errorf("invitation events must have an ORGANIZER")
+		}
+		if event.Props.Get(ical.PropDateTimeStamp) == nil {
+			event.Props.SetDateTime(ical.PropDateTimeStamp, time.Now().UTC())
+		}
+	}
+
+	if method, _ := cal.Props.Text(ical.PropMethod); method != "" && !strings.EqualFold(method, CalendarMethodRequest) {
+		return nil, fmt.Errorf("invitation must use METHOD:%s, got %s", CalendarMethodRequest, method)
+	}
+	cal.Props.SetText(ical.PropMethod, CalendarMethodRequest)
+	if cal.Props.Get(ical.PropProductID) == nil {
+		cal.Props.SetText(ical.PropProductID, calendarProdID)
+	}
+	if cal.Props.Get(ical.PropVersion) == nil {
+		cal.Props.SetText(ical.PropVersion, "2.0")
+	}
+
+	var buf bytes.Buffer
+	if err := ical.NewEncoder(&buf).Encode(cal); err != nil {
+		return nil, fmt.Errorf("failed to encode calendar: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// ExtractCalendar returns the first text/calendar part of a raw message.
+func ExtractCalendar(raw []byte) ([]byte, error) {
+	mr, err := mail.CreateReader(bytes.NewReader(raw))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse message: %w", err)
+	}
+
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			continue
+		}
+
+		if isCalendarPart(part) {
+			return io.ReadAll(part.Body)
+		}
+	}
+
+	return nil, fmt.Errorf("message does not contain a calendar invitation")
+}
+
+// isCalendarPart reports whether a MIME part carries iCalendar data.
+func isCalendarPart(part *mail.Part) bool {
+	var contentType string
+	switch h := part.Header.(type) {
+	case *mail.InlineHeader:
+		contentType, _, _ = h.ContentType()
+	case *mail.AttachmentHeader:
+		contentType, _, _ = h.ContentType()
+	}
+	return contentType == ical.MIMEType || contentType == "application/ics"
+}
+
+// findAttendee returns the ATTENDEE property matching addr, if any.
+func findAttendee(comp *ical.Component, addr string) *ical.Prop {
+	attendees := comp.Props.Values(ical.PropAttendee)
+	for i := range attendees {
+		if strings.EqualFold(calendarAddress(attendees[i].Value), addr) {
+			return &attendees[i]
+		}
+	}
+	return nil
+}
+
+// calendarAddress strips the mailto: scheme from a calendar user address.
+func calendarAddress(value string) string {
+	if len(value) >= 7 && strings.EqualFold(value[:7], "mailto:") {
+		return value[7:]
+	}
+	return value
+}
+
+// formatCalendarAddress formats a calendar user address like an email address.
+func formatCalendarAddress(prop *ical.Prop) string {
+	addr := calendarAddress(prop.Value)
+	if cn := prop.Params.Get(ical.ParamCommonName); cn != "" {
+		return fmt.Sprintf("%s <%s>", cn, addr)
+	}
+	return addr
+}
+
+// calendarTime parses a date-time property and returns it with its TZID.
+// Time zones unknown to the system (e.g. Windows names) are reported but the
+// time is interpreted as UTC.
+func calendarTime(prop *ical.Prop) (time.Time, string) {
+	tzid := prop.Params.Get(ical.PropTimezoneID)
+	t, err := prop.DateTime(time.UTC)
+	if err != nil && tzid != "" {
+		floating := *prop
+		floating.Params = ical.Params{}
+		for k, v := range prop.Params {
+			if k != ical.PropTimezoneID {
+				floating.Params[k] = v
+			}
+		}
+		t, _ = floating.DateTime(time.UTC)
+	}
+	if tzid == "" && strings.HasSuffix(prop.Value, "Z") {
+		tzid = "UTC"
+	}
+	return t, tzid
+}
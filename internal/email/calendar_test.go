@@ -0,0 +1,162 @@
+package email
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+const testInvitation = "BEGIN:VCALENDAR\r\n" +
+	"VERSION:2.0\r\n" +
+	"PRODID:-//Example//Calendar//EN\r\n" +
+	"METHOD:REQUEST\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"UID:meeting-123@example.com\r\n" +
+	"SEQUENCE:2\r\n" +
+	"DTSTAMP:20240520T080000Z\r\n" +
+	"DTSTART;TZID=Europe/Madrid:20240601T090000\r\n" +
+	"DTEND;TZID=Europe/Madrid:20240601T100000\r\n" +
+	"RRULE:FREQ=WEEKLY;COUNT=4\r\n" +
+	"SUMMARY:Sprint planning\r\n" +
+	"LOCATION:Room 4\r\n" +
+	"ORGANIZER;CN=Alice:mailto:alice@example.com\r\n" +
+	"ATTENDEE;CN=Bob;ROLE=REQ-PARTICIPANT;PARTSTAT=NEEDS-ACTION;RSVP=TRUE:mailto:bob@example.com\r\n" +
+	"ATTENDEE;CN=Carol;PARTSTAT=ACCEPTED:mailto:carol@example.com\r\n" +
+	"END:VEVENT\r\n" +
+	"END:VCALENDAR\r\n"
+
+func TestParseCalendar(t *testing.T) {
+	events, err := ParseCalendar([]byte(testInvitation))
+	if err != nil {
+		t.Fatalf("ParseCalendar() error = %v", err)
+	}
+	if len(events) != 1 {
+		t.Fatalf("ParseCalendar() returned %d events, want 1", len(events))
+	}
+
+	ev := events[0]
+	if ev.Method != "REQUEST" {
+		t.Errorf("Method = %v, want %v", ev.Method, "REQUEST")
+	}
+	if ev.UID != "meeting-123@example.com" {
+		t.Errorf("UID = %v, want %v", ev.UID, "meeting-123@example.com")
+	}
+	if ev.Sequence != 2 {
+		t.Errorf("Sequence = %v, want %v", ev.Sequence, 2)
+	}
+	if ev.Summary != "Sprint planning" {
+		t.Errorf("Summary = %v, want %v", ev.Summary, "Sprint planning")
+	}
+	if ev.Location != "Room 4" {
+		t.Errorf("Location = %v, want %v", ev.Location, "Room 4")
+	}
+	if ev.Organizer != "Alice <alice@example.com>" {
+		t.Errorf("Organizer = %v, want %v", ev.Organizer, "Alice <alice@example.com>")
+	}
+	if ev.TimeZone != "Europe/Madrid" {
+		t.Errorf("TimeZone = %v, want %v", ev.TimeZone, "Europe/Madrid")
+	}
+
+	wantStart := time.Date(2024, 6, 1, 7, 0, 0, 0, time.UTC)
+	if !ev.Start.Equal(wantStart) {
+		t.Errorf("Start = %v, want %v", ev.Start.UTC(), wantStart)
+	}
+	if got := ev.End.Sub(ev.Start); got != time.Hour {
+		t.Errorf("End - Start = %v, want %v", got, time.Hour)
+	}
+
+	if len(ev.Recurrence) != 1 || ev.Recurrence[0] != "RRULE:FREQ=WEEKLY;COUNT=4" {
+		t.Errorf("Recurrence = %v, want [RRULE:FREQ=WEEKLY;COUNT=4]", ev.Recurrence)
+	}
+
+	if len(ev.Attendees) != 2 {
+		t.Fatalf("Attendees = %d, want 2", len(ev.Attendees))
+	}
+	bob := ev.Attendees[0]
+	if bob.Email != "bob@example.com" || bob.Name != "Bob" || bob.Status != "NEEDS-ACTION" || !bob.RSVP {
+		t.Errorf("Attendees[0] = %+v", bob)
+	}
+}
+
+func TestParseCalendar_UnknownTimeZone(t *testing.T) {
+	data := strings.Replace(testInvitation, "Europe/Madrid", "W. Europe Standard Time", 2)
+
+	events, err := ParseCalendar([]byte(data))
+	if err != nil {
+		t.Fatalf("ParseCalendar() error = %v", err)
+	}
+	if events[0].TimeZone != "W. Europe Standard Time" {
+		t.Errorf("TimeZone = %v, want %v", events[0].TimeZone, "W. Europe Standard Time")
+	}
+	if events[0].Start.IsZero() {
+		t.Error("Start should be parsed even when the time zone is unknown")
+	}
+}
+
+func TestBuildCalendarReply(t *testing.T) {
+	reply, err := BuildCalendarReply([]byte(testInvitation), "BOB@example.com", PartStatAccepted)
+	if err != nil {
+		t.Fatalf("BuildCalendarReply() error = %v", err)
+	}
+
+	events, err := ParseCalendar(reply)
+	if err != nil {
+		t.Fatalf("reply is not valid iCalendar: %v", err)
+	}
+	if len(events) != 1 {
+		t.Fatalf("reply has %d events, want 1", len(events))
+	}
+
+	ev := events[0]
+	if ev.Method != CalendarMethodReply {
+		t.Errorf("Method = %v, want %v", ev.Method, CalendarMethodReply)
+	}
+	if ev.UID != "meeting-123@example.com" || ev.Sequence != 2 {
+		t.Errorf("reply UID/SEQUENCE = %v/%v, want meeting-123@example.com/2", ev.UID, ev.Sequence)
+	}
+	if len(ev.Attendees) != 1 {
+		t.Fatalf("reply has %d attendees, want 1", len(ev.Attendees))
+	}
+	if ev.Attendees[0].Email != "bob@example.com" || ev.Attendees[0].Status != PartStatAccepted {
+		t.Errorf("reply attendee = %+v", ev.Attendees[0])
+	}
+}
+
+func TestBuildCalendarReply_Errors(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     string
+		attendee string
+		partStat string
+	}{
+		{"not an attendee", testInvitation, "mallory@example.com", PartStatAccepted},
+		{"invalid status", testInvitation, "bob@example.com", "MAYBE"},
+		{"cancelled meeting", strings.Replace(testInvitation, "METHOD:REQUEST", "METHOD:CANCEL", 1), "bob@example.com", PartStatDeclined},
+		{"not a calendar", "hello", "bob@example.com", PartStatAccepted},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := BuildCalendarReply([]byte(tt.data), tt.attendee, tt.partStat); err == nil {
+				t.Error("BuildCalendarReply() expected error, got nil")
+			}
+		})
+	}
+}
+
+func TestPrepareCalendarRequest(t *testing.T) {
+	data := strings.Replace(testInvitation, "METHOD:REQUEST\r\n", "", 1)
+
+	request, err := PrepareCalendarRequest([]byte(data))
+	if err != nil {
+		t.Fatalf("PrepareCalendarRequest() error = %v", err)
+	}
+	if !strings.Contains(string(request), "METHOD:REQUEST") {
+		t.Errorf("PrepareCalendarRequest() did not set METHOD:REQUEST:\n%s", request)
+	}
+
+	noOrganizer := strings.Replace(data, "ORGANIZER;CN=Alice:mailto:alice@example.com\r\n", "", 1)
+	if _, err := PrepareCalendarRequest([]byte(noOrganizer)); err == nil {
+		t.Error("PrepareCalendarRequest() expected error for missing ORGANIZER")
+	}
+}
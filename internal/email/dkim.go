@@ -0,0 +1,225 @@
+package email
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"net/mail"
+	"os"
+	"strings"
+
+	"github.com/emersion/go-msgauth/dkim"
+)
+
+// Supported DKIM key types.
+const (
+	DKIMKeyRSA     = "rsa"
+	DKIMKeyEd25519 = "ed25519"
+)
+
+// DefaultDKIMHeaders are the header fields signed when no list is
+// configured. Fields missing from a message are signed as empty, so they
+// cannot be added in transit.
+var DefaultDKIMHeaders = []string{
+	"From", "To", "Cc", "Subject", "Date", "Message-ID",
+	"Reply-To", "In-Reply-To", "References",
+	"MIME-Version", "Content-Type", "Content-Transfer-Encoding",
+}
+
+// sign adds a DKIM-Signature header to a message if DKIM signing is
+// configured, and returns the message unchanged otherwise. When no signing
+// domain is set, the domain of the From header is used (of Resent-From for
+// redirected messages), so the signature aligns with it for DMARC.
+func (s *Sender) sign(msg []byte) ([]byte, error) {
+	cfg := &s.config.DKIM
+	if !cfg.Enabled() {
+		return msg, nil
+	}
+
+	if s.dkimKey == nil {
+		key, err := loadDKIMKey(cfg.Key, cfg.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+		s.dkimKey = key
+	}
+
+	domain := cfg.Domain
+	if domain == "" {
+		var err error
+		if domain, err = authorDomain(msg); err != nil {
+			return nil, fmt.Errorf("DKIM domain not set and %w", err)
+		}
+	}
+
+	headers := cfg.Headers
+	if len(headers) == 0 {
+		headers = DefaultDKIMHeaders
+	}
+	if !containsFold(headers, "From") {
+		headers = append([]string{"From"}, headers...)
+	}
+
+	var signed bytes.Buffer
+	err := dkim.Sign(&signed, bytes.NewReader(msg), &dkim.SignOptions{
+		Domain:                 domain,
+		Selector:               cfg.Selector,
+		Signer:                 s.dkimKey,
+		Hash:                   crypto.SHA256,
+		HeaderCanonicalization: dkim.CanonicalizationRelaxed,
+		BodyCanonicalization:   dkim.CanonicalizationRelaxed,
+		HeaderKeys:             headers,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign email with DKIM: %w", err)
+	}
+	return signed.Bytes(), nil
+}
+
+// authorDomain returns the domain of the address in the Resent-From header
+// of the latest redirect, or else in the From header.
+func authorDomain(msg []byte) (string, error) {
+	m, err := mail.ReadMessage(bytes.NewReader(msg))
+	if err != nil {
+		return "", fmt.Errorf("the message cannot be parsed: %w", err)
+	}
+	field := "Resent-From"
+	if m.Header.Get(field) == "" {
+		field = "From"
+	}
+	addrs, err := m.Header.AddressList(field)
+	if err != nil || len(addrs) == 0 {
+		return "", fmt.Errorf("the message has no valid %s address", field)
+	}
+	at := strings.LastIndex(addrs[0].Address, "@")
+	if at < 0 {
+		return "", fmt.Errorf("%s address %q has no domain", field, addrs[0].Address)
+	}
+	return addrs[0].Address[at+1:], nil
+}
+
+// loadDKIMKey loads a PEM private key from its contents or, if empty, from
+// a file.
+func loadDKIMKey(key, file string) (crypto.Signer, error) {
+	data := []byte(key)
+	if key == "" {
+		var err error
+		if data, err = os.ReadFile(file); err != nil {
+			return nil, fmt.Errorf("failed to read DKIM key: %w", err)
+		}
+	}
+	signer, err := ParseDKIMKey(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid DKIM key: %w", err)
+	}
+	return signer, nil
+}
+
+// ParseDKIMKey parses a PEM encoded RSA (PKCS #1 or PKCS #8) or Ed25519
+// (PKCS #8) private key.
+func ParseDKIMKey(data []byte) (crypto.Signer, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM data found")
+	}
+
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		return x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		switch key := key.(type) {
+		case *rsa.PrivateKey:
+			return key, nil
+		case ed25519.PrivateKey:
+			return key, nil
+		default:
+			return nil, fmt.Errorf("unsupported key type %T", key)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported PEM block %q", block.Type)
+	}
+}
+
+// GenerateDKIMKey generates a private key of the given type ("rsa" with
+// the given size in bits, or "ed25519") and returns it PEM encoded
+// (PKCS #8) together with the public key record to publish in DNS.
+func GenerateDKIMKey(keyType string, bits int) ([]byte, string, error) {
+	var signer crypto.Signer
+	switch keyType {
+	case DKIMKeyRSA:
+		if bits < 1024 {
+			return nil, "", fmt.Errorf("RSA keys must be at least 1024 bits")
+		}
+		key, err := rsa.GenerateKey(rand.Reader, bits)
+		if err != nil {
+			return nil, "", err
+		}
+		signer = key
+	case DKIMKeyEd25519:
+		_, key, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, "", err
+		}
+		signer = key
+	default:
+		return nil, "", fmt.Errorf("unsupported key type %q (use rsa or ed25519)", keyType)
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(signer)
+	if err != nil {
+		return nil, "", err
+	}
+	record, err := DKIMRecord(signer.Public())
+	if err != nil {
+		return nil, "", err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), record, nil
+}
+
+// DKIMRecord returns the DNS TXT record value publishing a public key.
+func DKIMRecord(pub crypto.PublicKey) (string, error) {
+	switch pub := pub.(type) {
+	case *rsa.PublicKey:
+		der, err := x509.MarshalPKIXPublicKey(pub)
+		if err != nil {
+			return "", err
+		}
+		return "v=DKIM1; k=rsa; p=" + base64.StdEncoding.EncodeToString(der), nil
+	case ed25519.PublicKey:
+		// RFC 8463 publishes the raw key rather than a SubjectPublicKeyInfo
+		return "v=DKIM1; k=ed25519; p=" + base64.StdEncoding.EncodeToString(pub), nil
+	default:
+		return "", fmt.Errorf("unsupported key type %T", pub)
+	}
+}
+
+// SplitTXT splits a TXT record value into the quoted strings of at most
+// 255 characters DNS requires.
+func SplitTXT(value string) []string {
+	var parts []string
+	for len(value) > 255 {
+		parts = append(parts, value[:255])
+		value = value[255:]
+	}
+	return append(parts, value)
+}
+
+// containsFold reports whether list contains s, ignoring case.
+func containsFold(list []string, s string) bool {
+	for _, item := range list {
+		if strings.EqualFold(item, s) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,160 @@
+package email
+
+import (
+	"bytes"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/GodGMN/ghostmail-cli/internal/config"
+	"github.com/emersion/go-msgauth/dkim"
+)
+
+func TestDKIMSignAndVerify(t *testing.T) {
+	for _, keyType := range []string{DKIMKeyRSA, DKIMKeyEd25519} {
+		key, record, err := GenerateDKIMKey(keyType, 1024)
+		if err != nil {
+			t.Fatalf("GenerateDKIMKey(%s) error = %v", keyType, err)
+		}
+		if !strings.HasPrefix(record, "v=DKIM1; k="+keyType+"; p=") {
+			t.Errorf("GenerateDKIMKey(%s) record = %q", keyType, record)
+		}
+
+		s := NewSender(&config.SMTPConfig{
+			From: "Reports <reports@example.com>",
+			DKIM: config.DKIMConfig{Selector: "mail", Key: string(key)},
+		})
+		msg, err := s.Build([]string{"ann@example.org"}, "Weekly report", "Numbers are up.\n")
+		if err != nil {
+			t.Fatalf("Build() error = %v", err)
+		}
+		signed, err := s.sign(msg.Data)
+		if err != nil {
+			t.Fatalf("sign() error = %v", err)
+		}
+		if !bytes.HasPrefix(signed, []byte("DKIM-Signature:")) {
+			t.Fatalf("sign() did not add a DKIM-Signature header:\n%s", signed)
+		}
+
+		verifications, err := dkim.VerifyWithOptions(bytes.NewReader(signed), &dkim.VerifyOptions{
+			LookupTXT: func(domain string) ([]string, error) {
+				if domain != "mail._domainkey.example.com" {
+					return nil, fmt.Errorf("unexpected lookup of %s", domain)
+				}
+				return []string{record}, nil
+			},
+		})
+		if err != nil {
+			t.Fatalf("Verify() error = %v", err)
+		}
+		if len(verifications) != 1 || verifications[0].Err != nil {
+			t.Fatalf("Verify() = %+v", verifications[0])
+		}
+		if verifications[0].Domain != "example.com" {
+			t.Errorf("signing domain = %q, want example.com", verifications[0].Domain)
+		}
+	}
+}
+
+func TestDKIMSignDisabled(t *testing.T) {
+	s := NewSender(&config.SMTPConfig{From: "me@example.com"})
+	msg := []byte("From: me@example.com\r\n\r\nHi\r\n")
+	signed, err := s.sign(msg)
+	if err != nil || !bytes.Equal(signed, msg) {
+		t.Errorf("sign() without DKIM = %q, %v; want message unchanged", signed, err)
+	}
+}
+
+func TestDKIMSignHeaders(t *testing.T) {
+	key, _, err := GenerateDKIMKey(DKIMKeyEd25519, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := NewSender(&config.SMTPConfig{
+		DKIM: config.DKIMConfig{Selector: "ed", Domain: "example.net", Key: string(key), Headers: []string{"Subject"}},
+	})
+	signed, err := s.sign([]byte("From: me@example.com\r\nSubject: Hi\r\n\r\nHi\r\n"))
+	if err != nil {
+		t.Fatalf("sign() error = %v", err)
+	}
+	header := string(signed[:bytes.Index(signed, []byte("\r\nFrom:"))])
+	for _, tag := range []string{"d=example.net", "s=ed", "c=relaxed/relaxed", "a=ed25519-sha256", "h=From:Subject"} {
+		if !strings.Contains(strings.Join(strings.Fields(header), ""), strings.ReplaceAll(tag, " ", "")) {
+			t.Errorf("DKIM-Signature missing %q:\n%s", tag, header)
+		}
+	}
+}
+
+func TestDKIMSignAuthorDomain(t *testing.T) {
+	key, _, err := GenerateDKIMKey(DKIMKeyEd25519, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := NewSender(&config.SMTPConfig{DKIM: config.DKIMConfig{Selector: "ed", Key: string(key)}})
+
+	tests := []struct {
+		msg  string
+		want string
+	}{
+		{"From: Reports <reports@example.com>\r\nSubject: Hi\r\n\r\nHi\r\n", "d=example.com;"},
+		{"Resent-From: relay@example.net\r\nFrom: ann@example.org\r\n\r\nHi\r\n", "d=example.net;"},
+	}
+	for _, tt := range tests {
+		signed, err := s.sign([]byte(tt.msg))
+		if err != nil {
+			t.Fatalf("sign() error = %v", err)
+		}
+		header := strings.Join(strings.Fields(string(signed[:bytes.Index(signed, []byte("\r\n\r\n"))])), "")
+		if !strings.Contains(header, tt.want) {
+			t.Errorf("sign(%q) signature lacks %s:\n%s", tt.msg, tt.want, header)
+		}
+	}
+
+	if _, err := s.sign([]byte("Subject: no author\r\n\r\nHi\r\n")); err == nil {
+		t.Error("sign() without a From header expected an error")
+	}
+}
+
+func TestParseDKIMKey(t *testing.T) {
+	rsaKey, _, err := GenerateDKIMKey(DKIMKeyRSA, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ParseDKIMKey(rsaKey); err != nil {
+		t.Errorf("ParseDKIMKey(PKCS #8 RSA) error = %v", err)
+	}
+
+	// PKCS #1, as written by "openssl genrsa" in older versions
+	signer, _ := ParseDKIMKey(rsaKey)
+	pkcs1 := pem.EncodeToMemory(&pem.Block{
+		Type:  "RSA PRIVATE KEY",
+		Bytes: x509.MarshalPKCS1PrivateKey(signer.(*rsa.PrivateKey)),
+	})
+	if _, err := ParseDKIMKey(pkcs1); err != nil {
+		t.Errorf("ParseDKIMKey(PKCS #1 RSA) error = %v", err)
+	}
+
+	for _, data := range []string{"", "not a key", "-----BEGIN CERTIFICATE-----\nAAAA\n-----END CERTIFICATE-----\n"} {
+		if _, err := ParseDKIMKey([]byte(data)); err == nil {
+			t.Errorf("ParseDKIMKey(%q) expected an error", data)
+		}
+	}
+
+	if _, _, err := GenerateDKIMKey("dsa", 0); err == nil {
+		t.Error("GenerateDKIMKey(dsa) expected an error")
+	}
+}
+
+func TestSplitTXT(t *testing.T) {
+	value := strings.Repeat("a", 600)
+	parts := SplitTXT(value)
+	if len(parts) != 3 || len(parts[0]) != 255 || len(parts[2]) != 90 || strings.Join(parts, "") != value {
+		t.Errorf("SplitTXT() = %d parts", len(parts))
+	}
+	if parts := SplitTXT("v=DKIM1"); len(parts) != 1 {
+		t.Errorf("SplitTXT(short) = %q", parts)
+	}
+}
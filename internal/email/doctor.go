@@ -0,0 +1,542 @@
+package email
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/smtp"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/GodGMN/ghostmail-cli/internal/config"
+	emailtypes "github.com/GodGMN/ghostmail-cli/pkg/email"
+	"github.com/emersion/go-imap"
+	"github.com/emersion/go-imap/client"
+)
+
+// TLS modes reported by Diagnose.
+const (
+	TLSModeImplicit = "implicit"
+	TLSModeSTARTTLS = "starttls"
+)
+
+// certExpiryWarning is how long before its expiry a server certificate is
+// reported.
+const certExpiryWarning = 14 * 24 * time.Hour
+
+// smtpExtensions are the EHLO extensions reported by Diagnose.
+var smtpExtensions = []string{
+	"STARTTLS", "AUTH", "SIZE", "8BITMIME", "SMTPUTF8", "PIPELINING", "CHUNKING",
+	"DSN", "ENHANCEDSTATUSCODES", "REQUIRETLS", "FUTURERELEASE",
+}
+
+// notableIMAPCapabilities are the IMAP capabilities reported as missing
+// when a server lacks them.
+var notableIMAPCapabilities = []string{"IDLE", "MOVE", "UIDPLUS", "CONDSTORE", "SPECIAL-USE", "UTF8=ACCEPT"}
+
+// diagnosis records the checks of a server as they run.
+type diagnosis struct {
+	*emailtypes.ServerDiagnosis
+	timeout time.Duration
+}
+
+func newDiagnosis(protocol, host string, port int, timeout time.Duration) *diagnosis {
+	return &diagnosis{
+		ServerDiagnosis: &emailtypes.ServerDiagnosis{
+			Protocol: protocol,
+			Host:     host,
+			Port:     port,
+			Checks:   []emailtypes.DiagnosticCheck{},
+		},
+		timeout: timeout,
+	}
+}
+
+// check records the result of a step; its duration is measured from start
+// unless start is zero.
+func (d *diagnosis) check(name, status, detail, hint string, start time.Time) {
+	c := emailtypes.DiagnosticCheck{Name: name, Status: status, Detail: detail, Hint: hint}
+	if !start.IsZero() {
+		c.DurationMS = time.Since(start).Milliseconds()
+	}
+	d.Checks = append(d.Checks, c)
+}
+
+// fail records a failed step.
+func (d *diagnosis) fail(name string, err error, hint string, start time.Time) {
+	d.check(name, emailtypes.CheckFailed, err.Error(), hint, start)
+}
+
+// result returns the diagnosis, OK if no check failed.
+func (d *diagnosis) result() *emailtypes.ServerDiagnosis {
+	d.OK = true
+	for _, c := range d.Checks {
+		if c.Status == emailtypes.CheckFailed {
+			d.OK = false
+		}
+	}
+	return d.ServerDiagnosis
+}
+
+// deadline bounds the next step on the connection.
+func (d *diagnosis) deadline(conn net.Conn) {
+	conn.SetDeadline(time.Now().Add(d.timeout))
+}
+
+// dial resolves the host and connects to it, or returns nil.
+func (d *diagnosis) dial() net.Conn {
+	start := time.Now()
+	ctx, cancel := context.WithTimeout(context.Background(), d.timeout)
+	defer cancel()
+	addrs, err := net.DefaultResolver.LookupHost(ctx, d.Host)
+	if err != nil {
+		d.fail("DNS", err, fmt.Sprintf("check GHOSTMAIL_%s_HOST for typos", d.Protocol), start)
+		return nil
+	}
+	d.Addresses = addrs
+	d.check("DNS", emailtypes.CheckOK, "resolved to "+strings.Join(addrs, ", "), "", start)
+
+	start = time.Now()
+	conn, err := net.DialTimeout("tcp", net.JoinHostPort(d.Host, strconv.Itoa(d.Port)), d.timeout)
+	if err != nil {
+		d.fail("TCP", err, dialHint(d.Protocol, d.Port, err), start)
+		return nil
+	}
+	d.check("TCP", emailtypes.CheckOK, "connected to "+conn.RemoteAddr().String(), "", start)
+	return conn
+}
+
+// handshake starts TLS from the beginning of the connection, or returns
+// nil.
+func (d *diagnosis) handshake(conn net.Conn, tlsConfig *tls.Config) *tls.Conn {
+	start := time.Now()
+	tlsConn := tls.Client(conn, insecure(tlsConfig))
+	d.deadline(conn)
+	if err := tlsConn.Handshake(); err != nil {
+		d.fail("TLS", err, modeHint(d.Protocol, d.Port, true, err), start)
+		return nil
+	}
+	if !d.secured("TLS", TLSModeImplicit, tlsConn.ConnectionState(), start) {
+		return nil
+	}
+	return tlsConn
+}
+
+// secured records a TLS connection and checks the server certificate. It
+// reports whether the certificate is trusted.
+func (d *diagnosis) secured(name, mode string, state tls.ConnectionState, start time.Time) bool {
+	info := &emailtypes.TLSInfo{
+		Mode:        mode,
+		Version:     tls.VersionName(state.Version),
+		CipherSuite: tls.CipherSuiteName(state.CipherSuite),
+	}
+	for _, cert := range state.PeerCertificates {
+		info.Chain = append(info.Chain, certificate(cert))
+	}
+	d.TLS = info
+	d.check(name, emailtypes.CheckOK, info.Version+", "+info.CipherSuite, "", start)
+
+	now := time.Now()
+	if err := verifyCertificate(state.PeerCertificates, d.Host, now); err != nil {
+		d.fail("Certificate", err, certHint(err), time.Time{})
+		return false
+	}
+	info.Verified = true
+
+	leaf := state.PeerCertificates[0]
+	left := leaf.NotAfter.Sub(now)
+	detail := fmt.Sprintf("%s, issued by %s, expires %s (%d days)",
+		commonName(leaf.Subject), commonName(leaf.Issuer), leaf.NotAfter.Format("2006-01-02"), int(left.Hours()/24))
+	if left < certExpiryWarning {
+		d.check("Certificate", emailtypes.CheckWarning, detail, "the certificate expires soon; connections will fail unless the provider renews it", time.Time{})
+	} else {
+		d.check("Certificate", emailtypes.CheckOK, detail, "", time.Time{})
+	}
+	return true
+}
+
+// authenticated records a successful login.
+func (d *diagnosis) authenticated(username, mech string, start time.Time) {
+	d.Mechanism = mech
+	detail := "authenticated with " + mech
+	if username != "" {
+		detail = "authenticated as " + username + " with " + mech
+	}
+	d.check("Login", emailtypes.CheckOK, detail, "", start)
+}
+
+// Diagnose connects to the SMTP server step by step, the way sending does,
+// and reports each step: DNS resolution, the TCP connection, TLS or
+// STARTTLS and the server certificate, the EHLO extensions and
+// authentication. No message is sent, and the steps after a failure are
+// not attempted.
+func (s *Sender) Diagnose(timeout time.Duration) *emailtypes.ServerDiagnosis {
+	d := newDiagnosis("SMTP", s.config.Host, s.config.Port, timeout)
+	tlsConfig, err := clientTLSConfig(s.config.Host, s.config.ClientCert, s.config.ClientKey)
+	if err != nil {
+		d.fail("Client certificate", err, "check GHOSTMAIL_SMTP_CLIENT_CERT and GHOSTMAIL_SMTP_CLIENT_KEY", time.Time{})
+		return d.result()
+	}
+
+	conn := d.dial()
+	if conn == nil {
+		return d.result()
+	}
+	defer conn.Close()
+	if s.config.UseTLS {
+		tlsConn := d.handshake(conn, tlsConfig)
+		if tlsConn == nil {
+			return d.result()
+		}
+		conn = tlsConn
+	}
+
+	start := time.Now()
+	d.deadline(conn)
+	c, err := smtp.NewClient(conn, s.config.Host)
+	if err == nil {
+		err = c.Hello("localhost")
+	}
+	if err != nil {
+		d.fail("Greeting", err, modeHint("SMTP", s.config.Port, s.config.UseTLS, err), start)
+		return d.result()
+	}
+	defer c.Close()
+	d.check("Greeting", emailtypes.CheckOK, "EHLO accepted", "", start)
+
+	// Sending uses STARTTLS whenever the server offers it
+	if !s.config.UseTLS {
+		start = time.Now()
+		if ok, _ := c.Extension("STARTTLS"); ok {
+			d.deadline(conn)
+			if err := c.StartTLS(insecure(tlsConfig)); err != nil {
+				d.fail("STARTTLS", err, "", start)
+				return d.result()
+			}
+			state, _ := c.TLSConnectionState()
+			if !d.secured("STARTTLS", TLSModeSTARTTLS, state, start) {
+				return d.result()
+			}
+		} else if isLocalhost(s.config.Host) {
+			d.check("STARTTLS", emailtypes.CheckSkipped, "not offered; the connection to the local server is not encrypted", "", time.Time{})
+		} else {
+			d.check("STARTTLS", emailtypes.CheckWarning, "not offered; the connection is not encrypted",
+				"use port 465 with GHOSTMAIL_SMTP_USE_TLS=true, or the port your provider documents for STARTTLS (usually 587)", time.Time{})
+		}
+	}
+
+	d.deadline(conn)
+	for _, ext := range smtpExtensions {
+		if ok, params := c.Extension(ext); ok {
+			d.Capabilities = append(d.Capabilities, strings.TrimSpace(ext+" "+params))
+		}
+	}
+	if len(d.Capabilities) == 0 {
+		d.check("Capabilities", emailtypes.CheckOK, "none", "", time.Time{})
+	} else {
+		d.check("Capabilities", emailtypes.CheckOK, strings.Join(d.Capabilities, ", "), "", time.Time{})
+	}
+
+	offersAuth, mechs := c.Extension("AUTH")
+	explicit := s.config.Auth != "" && s.config.Auth != config.AuthAuto
+	switch {
+	case s.config.Username == "" && s.config.Auth != config.AuthExternal:
+		d.check("Login", emailtypes.CheckSkipped, "no username configured; messages are sent without authentication", "", time.Time{})
+	case !offersAuth && !explicit:
+		hint := "this port may be for server-to-server mail; submission is usually on port 587 or 465"
+		if _, encrypted := c.TLSConnectionState(); !encrypted {
+			hint = "many servers only offer AUTH over TLS; " + hint
+		}
+		d.check("Login", emailtypes.CheckWarning, "the server does not offer AUTH; messages are sent without authentication", hint, time.Time{})
+	default:
+		start = time.Now()
+		d.deadline(conn)
+		if err := s.authenticate(c, strings.Fields(strings.ToUpper(mechs))); err != nil {
+			d.fail("Login", err, authHint("SMTP", err), start)
+			return d.result()
+		}
+		d.authenticated(s.config.Username, s.mechanism, start)
+	}
+
+	c.Quit()
+	return d.result()
+}
+
+// Diagnose connects to the IMAP server step by step, the way reading does,
+// and reports each step: DNS resolution, the TCP connection, TLS and the
+// server certificate, authentication, the capabilities and selecting the
+// mailbox and the Sent mailbox. Nothing is changed on the server, and the
+// steps after a failure are not attempted.
+func (r *Reader) Diagnose(timeout time.Duration) *emailtypes.ServerDiagnosis {
+	d := newDiagnosis("IMAP", r.config.Host, r.config.Port, timeout)
+	tlsConfig, err := clientTLSConfig(r.config.Host, r.config.ClientCert, r.config.ClientKey)
+	if err != nil {
+		d.fail("Client certificate", err, "check GHOSTMAIL_IMAP_CLIENT_CERT and GHOSTMAIL_IMAP_CLIENT_KEY", time.Time{})
+		return d.result()
+	}
+
+	conn := d.dial()
+	if conn == nil {
+		return d.result()
+	}
+	defer conn.Close()
+	if r.config.UseTLS {
+		tlsConn := d.handshake(conn, tlsConfig)
+		if tlsConn == nil {
+			return d.result()
+		}
+		conn = tlsConn
+	}
+
+	start := time.Now()
+	d.deadline(conn)
+	c, err := client.New(conn)
+	if err != nil {
+		d.fail("Greeting", err, modeHint("IMAP", r.config.Port, r.config.UseTLS, err), start)
+		return d.result()
+	}
+	defer c.Logout()
+	c.Timeout = timeout
+	d.check("Greeting", emailtypes.CheckOK, "server ready", "", start)
+
+	// Reading does not use STARTTLS
+	if !r.config.UseTLS {
+		hint := "use port 993 with GHOSTMAIL_IMAP_USE_TLS=true"
+		switch {
+		case isLocalhost(r.config.Host):
+			d.check("TLS", emailtypes.CheckSkipped, "not used; the connection to the local server is not encrypted", "", time.Time{})
+		case supports(c, "STARTTLS"):
+			d.check("TLS", emailtypes.CheckWarning, "not used; the connection is not encrypted (ghostmail does not use the STARTTLS the server offers)", hint, time.Time{})
+		default:
+			d.check("TLS", emailtypes.CheckWarning, "not used; the connection is not encrypted", hint, time.Time{})
+		}
+	}
+
+	start = time.Now()
+	if err := r.authenticate(c, r.config.UseTLS); err != nil {
+		d.fail("Login", err, authHint("IMAP", err), start)
+		return d.result()
+	}
+	d.authenticated(r.config.Username, r.mechanism, start)
+
+	// Servers often announce more capabilities once logged in
+	caps, err := c.Capability()
+	if err != nil {
+		d.fail("Capabilities", err, "", time.Time{})
+		return d.result()
+	}
+	var detail string
+	d.Capabilities, detail = imapCapabilities(caps)
+	d.check("Capabilities", emailtypes.CheckOK, detail, "", time.Time{})
+
+	start = time.Now()
+	status, err := c.Select(r.config.Mailbox, true)
+	if err != nil {
+		d.fail("Mailbox", err, "check GHOSTMAIL_IMAP_MAILBOX; mailbox names other than INBOX are case-sensitive", start)
+		return d.result()
+	}
+	d.check("Mailbox", emailtypes.CheckOK, fmt.Sprintf("%s selected, %d messages", status.Name, status.Messages), "", start)
+
+	d.sentMailbox(c, r.config.SentMailbox)
+	return d.result()
+}
+
+// sentMailbox checks the mailbox copies of sent messages are saved to.
+// Problems are warnings, since saving copies is optional.
+func (d *diagnosis) sentMailbox(c *client.Client, mailbox string) {
+	if supports(c, "X-GM-EXT-1") {
+		d.check("Sent mailbox", emailtypes.CheckSkipped, "Gmail files sent messages itself", "", time.Time{})
+		return
+	}
+
+	start := time.Now()
+	hint := "set GHOSTMAIL_IMAP_SENT_MAILBOX to the name of the Sent mailbox"
+	if mailbox == "" {
+		var err error
+		if mailbox, err = findSpecialMailbox(c, imap.SentAttr); err != nil {
+			d.check("Sent mailbox", emailtypes.CheckWarning, err.Error(), hint, start)
+			return
+		}
+	}
+	status, err := c.Status(mailbox, []imap.StatusItem{imap.StatusMessages})
+	if err != nil {
+		d.check("Sent mailbox", emailtypes.CheckWarning, fmt.Sprintf("%s: %v", mailbox, err), hint, start)
+		return
+	}
+	d.check("Sent mailbox", emailtypes.CheckOK, fmt.Sprintf("%s, %d messages", status.Name, status.Messages), "", start)
+}
+
+// supports reports whether an IMAP server has a capability, ignoring
+// errors.
+func supports(c *client.Client, capability string) bool {
+	ok, _ := c.Support(capability)
+	return ok
+}
+
+// imapCapabilities returns the capabilities of an IMAP server, sorted, and
+// a description that names the notable ones it lacks.
+func imapCapabilities(caps map[string]bool) ([]string, string) {
+	var names []string
+	has := make(map[string]bool)
+	for name := range caps {
+		names = append(names, name)
+		has[strings.ToUpper(name)] = true
+	}
+	sort.Strings(names)
+
+	detail := strings.Join(names, " ")
+	var missing []string
+	for _, name := range notableIMAPCapabilities {
+		if !has[name] {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		detail += " (missing " + strings.Join(missing, ", ") + ")"
+	}
+	return names, detail
+}
+
+// insecure returns a copy of a TLS configuration that accepts any server
+// certificate, so that an untrusted chain can still be reported. The chain
+// is verified with verifyCertificate before anything else is sent.
+func insecure(tlsConfig *tls.Config) *tls.Config {
+	c := tlsConfig.Clone()
+	c.InsecureSkipVerify = true
+	return c
+}
+
+// verifyCertificate verifies a certificate chain sent by a server the way
+// the TLS client does by default.
+func verifyCertificate(chain []*x509.Certificate, host string, now time.Time) error {
+	if len(chain) == 0 {
+		return errors.New("the server sent no certificate")
+	}
+	opts := x509.VerifyOptions{
+		DNSName:       host,
+		Intermediates: x509.NewCertPool(),
+		CurrentTime:   now,
+	}
+	for _, cert := range chain[1:] {
+		opts.Intermediates.AddCert(cert)
+	}
+	_, err := chain[0].Verify(opts)
+	return err
+}
+
+// certificate describes a server certificate.
+func certificate(cert *x509.Certificate) emailtypes.Certificate {
+	return emailtypes.Certificate{
+		Subject:      cert.Subject.String(),
+		Issuer:       cert.Issuer.String(),
+		SerialNumber: fmt.Sprintf("%X", cert.SerialNumber),
+		NotBefore:    cert.NotBefore,
+		NotAfter:     cert.NotAfter,
+	}
+}
+
+// commonName returns the common name of a certificate subject or issuer,
+// or the whole name if it has none.
+func commonName(name pkix.Name) string {
+	if name.CommonName != "" {
+		return name.CommonName
+	}
+	return name.String()
+}
+
+// usualPorts describes the ports a protocol is usually served on.
+func usualPorts(protocol string) string {
+	if protocol == "SMTP" {
+		return "usually 587 with STARTTLS, or 465 with GHOSTMAIL_SMTP_USE_TLS=true"
+	}
+	return "usually 993 with GHOSTMAIL_IMAP_USE_TLS=true"
+}
+
+// dialHint suggests what to change when connecting to a server fails.
+func dialHint(protocol string, port int, err error) string {
+	var netErr net.Error
+	switch {
+	case errors.As(err, &netErr) && netErr.Timeout():
+		if protocol == "SMTP" && port == 25 {
+			return "ISPs and cloud providers often block outgoing port 25; use " + usualPorts(protocol)
+		}
+		return fmt.Sprintf("no answer on port %d: a firewall may block it, or the port is wrong (%s)", port, usualPorts(protocol))
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return fmt.Sprintf("nothing is listening on port %d; check GHOSTMAIL_%s_PORT (%s)", port, protocol, usualPorts(protocol))
+	}
+	return ""
+}
+
+// modeHint suggests what to change when the TLS handshake or the greeting
+// fails, which usually means the TLS mode does not match the port.
+func modeHint(protocol string, port int, useTLS bool, err error) string {
+	var recordErr tls.RecordHeaderError
+	var netErr net.Error
+	switch {
+	case useTLS && errors.As(err, &recordErr):
+		if protocol == "SMTP" {
+			return fmt.Sprintf("port %d does not start with TLS; set GHOSTMAIL_SMTP_USE_TLS=false to use STARTTLS, or use port 465", port)
+		}
+		return fmt.Sprintf("port %d does not start with TLS; use port 993", port)
+	case !useTLS && (errors.As(err, &netErr) && netErr.Timeout() || errors.Is(err, io.EOF)):
+		return fmt.Sprintf("the server sent no greeting: port %d probably expects TLS from the start; set GHOSTMAIL_%s_USE_TLS=true", port, protocol)
+	}
+	return ""
+}
+
+// certHint explains why a server certificate is not trusted.
+func certHint(err error) string {
+	var hostErr x509.HostnameError
+	var invalidErr x509.CertificateInvalidError
+	var authorityErr x509.UnknownAuthorityError
+	switch {
+	case errors.As(err, &hostErr):
+		return "the certificate is for another name; use the host name your provider documents, not an alias or IP address"
+	case errors.As(err, &invalidErr) && invalidErr.Reason == x509.Expired:
+		return "the certificate has expired or is not valid yet; check the system clock, or contact the provider"
+	case errors.As(err, &authorityErr):
+		return "the certificate is self-signed or from an unknown authority; install the server's CA certificate in the system trust store"
+	}
+	return ""
+}
+
+// authHint suggests what to change when authentication fails, recognizing
+// the replies of common providers.
+func authHint(protocol string, err error) string {
+	msg := strings.ToLower(err.Error())
+	switch {
+	case containsAny(msg, "does not offer", "does not support oauth"):
+		return fmt.Sprintf("set GHOSTMAIL_%s_AUTH to a mechanism the server offers, or to auto", protocol)
+	case strings.Contains(msg, "unencrypted"):
+		return fmt.Sprintf("connect with TLS (%s)", usualPorts(protocol))
+	case containsAny(msg, "application-specific password", "app password", "5.7.9"):
+		return fmt.Sprintf("the provider requires an app password: create one in your account's security settings and set it as GHOSTMAIL_%s_PASSWORD, or use OAuth", protocol)
+	case containsAny(msg, "basic authentication is disabled", "smtpclientauthentication is disabled"):
+		return "Microsoft 365 does not accept passwords for this mailbox; use OAuth (GHOSTMAIL_OAUTH_*), or ask an administrator to enable authenticated SMTP"
+	case containsAny(msg, "web browser", "5.7.14", "webloginrequired"):
+		return "the provider blocked the sign-in; sign in with a web browser to confirm it, or use an app password"
+	case strings.Contains(msg, "oauth"):
+		return "the access token may have expired or lack the mail scope; check the GHOSTMAIL_OAUTH_* settings"
+	case containsAny(msg, "535", "authenticationfailed", "invalid credentials", "authentication failed", "login failed"):
+		return fmt.Sprintf("check GHOSTMAIL_%s_USERNAME and GHOSTMAIL_%s_PASSWORD; with 2-step verification, most providers (Gmail, iCloud, Yahoo, Fastmail) need an app password instead of the account password", protocol, protocol)
+	}
+	return ""
+}
+
+// containsAny reports whether s contains any of the substrings.
+func containsAny(s string, substrs ...string) bool {
+	for _, sub := range substrs {
+		if strings.Contains(s, sub) {
+			return true
+		}
+	}
+	return false
+}
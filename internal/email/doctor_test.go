@@ -0,0 +1,278 @@
+package email
+
+import (
+	"bufio"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/GodGMN/ghostmail-cli/internal/config"
+	emailtypes "github.com/GodGMN/ghostmail-cli/pkg/email"
+	"github.com/emersion/go-imap/backend/memory"
+	"github.com/emersion/go-imap/server"
+)
+
+func TestDialHint(t *testing.T) {
+	timeout := &net.OpError{Op: "dial", Err: timeoutError{}}
+	refused := &net.OpError{Op: "dial", Err: syscall.ECONNREFUSED}
+	tests := []struct {
+		protocol string
+		port     int
+		err      error
+		want     string
+	}{
+		{"SMTP", 25, timeout, "often block outgoing port 25"},
+		{"IMAP", 993, timeout, "no answer on port 993"},
+		{"SMTP", 2525, refused, "nothing is listening on port 2525; check GHOSTMAIL_SMTP_PORT"},
+		{"SMTP", 587, errors.New("no route to host"), ""},
+	}
+	for _, tt := range tests {
+		got := dialHint(tt.protocol, tt.port, tt.err)
+		if tt.want == "" && got != "" || !strings.Contains(got, tt.want) {
+			t.Errorf("dialHint(%s, %d, %v) = %q, want %q", tt.protocol, tt.port, tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestModeHint(t *testing.T) {
+	notTLS := tls.RecordHeaderError{Msg: "first record does not look like a TLS handshake"}
+	tests := []struct {
+		protocol string
+		port     int
+		useTLS   bool
+		err      error
+		want     string
+	}{
+		{"SMTP", 587, true, notTLS, "set GHOSTMAIL_SMTP_USE_TLS=false to use STARTTLS"},
+		{"IMAP", 143, true, notTLS, "use port 993"},
+		{"SMTP", 465, false, &net.OpError{Op: "read", Err: timeoutError{}}, "port 465 probably expects TLS from the start; set GHOSTMAIL_SMTP_USE_TLS=true"},
+		{"IMAP", 993, false, io.EOF, "set GHOSTMAIL_IMAP_USE_TLS=true"},
+		{"SMTP", 587, false, errors.New("554 no service"), ""},
+	}
+	for _, tt := range tests {
+		got := modeHint(tt.protocol, tt.port, tt.useTLS, tt.err)
+		if tt.want == "" && got != "" || !strings.Contains(got, tt.want) {
+			t.Errorf("modeHint(%s, %d, %v, %v) = %q, want %q", tt.protocol, tt.port, tt.useTLS, tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestAuthHint(t *testing.T) {
+	tests := []struct {
+		err  string
+		want string
+	}{
+		{"PLAIN authentication failed: 534 5.7.9 Application-specific password required", "requires an app password"},
+		{"LOGIN authentication failed: 535 5.7.139 Authentication unsuccessful, basic authentication is disabled", "Microsoft 365"},
+		{"PLAIN authentication failed: 534 5.7.14 Please log in with your web browser", "sign in with a web browser"},
+		{"OAuth authentication failed (check that the token is valid and has the mail scopes): 535", "access token"},
+		{"[AUTHENTICATIONFAILED] Invalid credentials (Failure)", "check GHOSTMAIL_IMAP_USERNAME and GHOSTMAIL_IMAP_PASSWORD"},
+		{"IMAP server does not offer XOAUTH2 authentication (offered: PLAIN LOGIN); set GHOSTMAIL_IMAP_AUTH", "to a mechanism the server offers"},
+		{"connection reset by peer", ""},
+	}
+	for _, tt := range tests {
+		got := authHint("IMAP", errors.New(tt.err))
+		if tt.want == "" && got != "" || !strings.Contains(got, tt.want) {
+			t.Errorf("authHint(%q) = %q, want %q", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestVerifyCertificate(t *testing.T) {
+	now := time.Now()
+	cert := selfSignedCertificate(t, "mail.example.com", now.Add(-time.Hour), now.Add(90*24*time.Hour))
+
+	if err := verifyCertificate(nil, "mail.example.com", now); err == nil {
+		t.Error("verifyCertificate() without a chain expected an error")
+	}
+
+	tests := []struct {
+		name string
+		host string
+		now  time.Time
+		want string
+	}{
+		{"unknown authority", "mail.example.com", now, "unknown authority"},
+		{"other name", "imap.example.org", now, "another name"},
+		{"expired", "mail.example.com", now.Add(100 * 24 * time.Hour), "expired"},
+	}
+	for _, tt := range tests {
+		err := verifyCertificate([]*x509.Certificate{cert}, tt.host, tt.now)
+		if err == nil {
+			t.Errorf("%s: verifyCertificate() expected an error", tt.name)
+			continue
+		}
+		if hint := certHint(err); !strings.Contains(hint, tt.want) {
+			t.Errorf("%s: certHint(%v) = %q, want %q", tt.name, err, hint, tt.want)
+		}
+	}
+}
+
+func TestIMAPCapabilities(t *testing.T) {
+	names, detail := imapCapabilities(map[string]bool{"IMAP4rev1": true, "IDLE": true, "move": true, "UIDPLUS": true, "AUTH=PLAIN": true})
+	if strings.Join(names, " ") != "AUTH=PLAIN IDLE IMAP4rev1 UIDPLUS move" {
+		t.Errorf("imapCapabilities() names = %v", names)
+	}
+	if !strings.HasSuffix(detail, "(missing CONDSTORE, SPECIAL-USE, UTF8=ACCEPT)") {
+		t.Errorf("imapCapabilities() detail = %q", detail)
+	}
+}
+
+// timeoutError is a network error that timed out.
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+// selfSignedCertificate returns a self-signed server certificate for a
+// host name.
+func selfSignedCertificate(t *testing.T, host string, notBefore, notAfter time.Time) *x509.Certificate {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: host},
+		DNSNames:     []string{host},
+		NotBefore:    notBefore,
+		NotAfter:     notAfter,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert
+}
+
+func TestReaderDiagnose(t *testing.T) {
+	be := memory.New()
+	user, err := be.Login(nil, "username", "password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := user.CreateMailbox("Sent"); err != nil {
+		t.Fatal(err)
+	}
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := server.New(be)
+	s.AllowInsecureAuth = true
+	go s.Serve(l)
+	defer s.Close()
+	port := l.Addr().(*net.TCPAddr).Port
+
+	cfg := &config.IMAPConfig{Host: "127.0.0.1", Port: port, Username: "username", Password: "password", Mailbox: "INBOX", SentMailbox: "Sent"}
+	d := NewReader(cfg).Diagnose(5 * time.Second)
+	want := map[string]string{
+		"DNS":          emailtypes.CheckOK,
+		"TCP":          emailtypes.CheckOK,
+		"Greeting":     emailtypes.CheckOK,
+		"TLS":          emailtypes.CheckSkipped,
+		"Login":        emailtypes.CheckOK,
+		"Capabilities": emailtypes.CheckOK,
+		"Mailbox":      emailtypes.CheckOK,
+		"Sent mailbox": emailtypes.CheckOK,
+	}
+	assertChecks(t, d, want)
+
+	// The steps after a failed login are not attempted
+	cfg.Password = "wrong"
+	d = NewReader(cfg).Diagnose(5 * time.Second)
+	if last := d.Checks[len(d.Checks)-1]; last.Name != "Login" || last.Status != emailtypes.CheckFailed {
+		t.Errorf("last check with a wrong password = %s %s, want a failed Login", last.Name, last.Status)
+	}
+}
+
+func TestSenderDiagnose(t *testing.T) {
+	// A server that offers AUTH PLAIN and accepts any credentials
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		in := bufio.NewReader(conn)
+		fmt.Fprint(conn, "220 ready\r\n")
+		for {
+			line, err := in.ReadString('\n')
+			if err != nil {
+				return
+			}
+			switch cmd := strings.ToUpper(strings.TrimSpace(line)); {
+			case strings.HasPrefix(cmd, "EHLO"):
+				fmt.Fprint(conn, "250-localhost\r\n250-8BITMIME\r\n250 AUTH PLAIN\r\n")
+			case strings.HasPrefix(cmd, "AUTH PLAIN"):
+				fmt.Fprint(conn, "235 authenticated\r\n")
+			case cmd == "QUIT":
+				fmt.Fprint(conn, "221 bye\r\n")
+				return
+			default:
+				fmt.Fprint(conn, "502 not implemented\r\n")
+			}
+		}
+	}()
+	port := l.Addr().(*net.TCPAddr).Port
+
+	cfg := &config.SMTPConfig{Host: "127.0.0.1", Port: port, Username: "ann", Password: "s3cret"}
+	d := NewSender(cfg).Diagnose(5 * time.Second)
+	want := map[string]string{
+		"DNS":          emailtypes.CheckOK,
+		"TCP":          emailtypes.CheckOK,
+		"Greeting":     emailtypes.CheckOK,
+		"STARTTLS":     emailtypes.CheckSkipped,
+		"Capabilities": emailtypes.CheckOK,
+		"Login":        emailtypes.CheckOK,
+	}
+	assertChecks(t, d, want)
+	if strings.Join(d.Capabilities, ",") != "AUTH PLAIN,8BITMIME" {
+		t.Errorf("Capabilities = %v, want AUTH PLAIN and 8BITMIME", d.Capabilities)
+	}
+}
+
+// assertChecks checks a diagnosis ran exactly the wanted checks with the
+// wanted statuses.
+func assertChecks(t *testing.T, d *emailtypes.ServerDiagnosis, want map[string]string) {
+	t.Helper()
+	for _, c := range d.Checks {
+		status, ok := want[c.Name]
+		if !ok {
+			t.Errorf("unexpected check %s: %s %s", c.Name, c.Status, c.Detail)
+			continue
+		}
+		if c.Status != status {
+			t.Errorf("check %s = %s (%s), want %s", c.Name, c.Status, c.Detail, status)
+		}
+		delete(want, c.Name)
+	}
+	for name := range want {
+		t.Errorf("check %s was not run", name)
+	}
+}
@@ -0,0 +1,149 @@
+package email
+
+import (
+	"bytes"
+	"fmt"
+	"net/mail"
+	"strings"
+	"time"
+
+	"github.com/emersion/go-imap"
+)
+
+// SaveDraft stores a built message in the server's \Drafts mailbox and
+// returns the name of that mailbox.
+func (r *Reader) SaveDraft(msg *OutgoingMessage) (string, error) {
+	c, err := r.Connect()
+	if err != nil {
+		return "", err
+	}
+	defer c.Logout()
+
+	mailbox, err := findSpecialMailbox(c, imap.DraftsAttr)
+	if err != nil {
+		return "", err
+	}
+
+	flags := []string{imap.DraftFlag, imap.SeenFlag}
+	if err := c.Append(mailbox, flags, time.Now(), bytes.NewBuffer(DraftData(msg))); err != nil {
+		return "", fmt.Errorf("failed to save draft to %s: %w", mailbox, err)
+	}
+	return mailbox, nil
+}
+
+// ReplaceDraft stores a new version of a draft in the \Drafts mailbox and
+// then deletes and expunges the old one by UID. The new version is appended
+// first, so the draft is kept if the server rejects it.
+func (r *Reader) ReplaceDraft(uid uint32, raw []byte) (string, error) {
+	c, err := r.Connect()
+	if err != nil {
+		return "", err
+	}
+	defer c.Logout()
+
+	mailbox, err := findSpecialMailbox(c, imap.DraftsAttr)
+	if err != nil {
+		return "", err
+	}
+
+	flags := []string{imap.DraftFlag, imap.SeenFlag}
+	if err := c.Append(mailbox, flags, time.Now(), bytes.NewBuffer(raw)); err != nil {
+		return "", fmt.Errorf("failed to save draft to %s: %w", mailbox, err)
+	}
+
+	if _, err := c.Select(mailbox, false); err != nil {
+		return "", fmt.Errorf("new version saved, but failed to select %s to remove the old one: %w", mailbox, err)
+	}
+	if err := expungeMessage(c, uid); err != nil {
+		return "", fmt.Errorf("new version saved, but the old draft could not be removed: %w", err)
+	}
+	return mailbox, nil
+}
+
+// DraftData returns the message content to store as a draft. Unlike the
+// submitted message it keeps the Bcc header, so the draft can be sent later.
+func DraftData(msg *OutgoingMessage) []byte {
+	return withBccHeader(msg)
+}
+
+// ParseDraft turns a stored draft into a message ready for submission: the
+// envelope is taken from the From, To, Cc and Bcc headers and the Bcc header
+// is removed from the content.
+func ParseDraft(raw []byte) (*OutgoingMessage, error) {
+	m, err := mail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse draft: %w", err)
+	}
+
+	from, err := mail.ParseAddress(m.Header.Get("From"))
+	if err != nil {
+		return nil, fmt.Errorf("draft has no valid From address: %w", err)
+	}
+
+	msg := &OutgoingMessage{From: from.Address}
+	seen := make(map[string]bool)
+	for _, field := range []string{"To", "Cc", "Bcc"} {
+		if m.Header.Get(field) == "" {
+			continue
+		}
+		addrs, err := m.Header.AddressList(field)
+		if err != nil {
+			return nil, fmt.Errorf("draft has an invalid %s header: %w", field, err)
+		}
+		for _, addr := range addrs {
+			if field == "Bcc" {
+				msg.Bcc = append(msg.Bcc, addr.String())
+			}
+			if !seen[strings.ToLower(addr.Address)] {
+				seen[strings.ToLower(addr.Address)] = true
+				msg.Recipients = append(msg.Recipients, addr.Address)
+			}
+		}
+	}
+	if len(msg.Recipients) == 0 {
+		return nil, fmt.Errorf("draft has no recipients")
+	}
+
+	msg.Data = removeHeader(raw, "Bcc")
+	return msg, nil
+}
+
+// removeHeader removes every occurrence of a header field, including folded
+// continuation lines, from a raw message.
+func removeHeader(raw []byte, name string) []byte {
+	var out bytes.Buffer
+	prefix := strings.ToLower(name) + ":"
+	skipping := false
+
+	rest := raw
+	for len(rest) > 0 {
+		end := bytes.IndexByte(rest, '\n')
+		var line []byte
+		if end < 0 {
+			line, rest = rest, nil
+		} else {
+			line, rest = rest[:end+1], rest[end+1:]
+		}
+
+		// End of the header section: copy the body unchanged
+		if len(bytes.TrimRight(line, "\r\n")) == 0 {
+			out.Write(line)
+			out.Write(rest)
+			break
+		}
+
+		if line[0] == ' ' || line[0] == '\t' {
+			if !skipping {
+				out.Write(line)
+			}
+			continue
+		}
+
+		skipping = strings.HasPrefix(strings.ToLower(string(line)), prefix)
+		if !skipping {
+			out.Write(line)
+		}
+	}
+
+	return out.Bytes()
+}
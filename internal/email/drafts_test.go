@@ -0,0 +1,145 @@
+package email
+
+import (
+	"bytes"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/GodGMN/ghostmail-cli/internal/config"
+	"github.com/emersion/go-imap"
+	"github.com/emersion/go-imap/backend/memory"
+	"github.com/emersion/go-imap/server"
+)
+
+func TestDraftData(t *testing.T) {
+	msg := &OutgoingMessage{
+		From:       "bot@example.com",
+		Recipients: []string{"bob@example.com", "audit@example.com"},
+		Bcc:        []string{"audit@example.com"},
+		Data:       []byte("To: bob@example.com\r\nSubject: Hi\r\n\r\nBody\r\n"),
+	}
+
+	got := DraftData(msg)
+	if !bytes.HasPrefix(got, []byte("Bcc: audit@example.com\r\n")) {
+		t.Errorf("DraftData() should start with the Bcc header, got %q", got)
+	}
+	if !bytes.HasSuffix(got, msg.Data) {
+		t.Error("DraftData() must keep the built message unchanged")
+	}
+
+	msg.Bcc = nil
+	if got := DraftData(msg); !bytes.Equal(got, msg.Data) {
+		t.Errorf("DraftData() without Bcc = %q, want message data", got)
+	}
+}
+
+func TestParseDraft(t *testing.T) {
+	raw := []byte("Bcc: Audit <audit@example.com>,\r\n" +
+		" archive@example.com\r\n" +
+		"From: Bot <bot@example.com>\r\n" +
+		"To: Bob <bob@example.com>\r\n" +
+		"Cc: carol@example.com, BOB@example.com\r\n" +
+		"Subject: Proposal\r\n" +
+		"\r\n" +
+		"Bcc: this line is body text\r\n")
+
+	msg, err := ParseDraft(raw)
+	if err != nil {
+		t.Fatalf("ParseDraft() error = %v", err)
+	}
+
+	if msg.From != "bot@example.com" {
+		t.Errorf("From = %q, want bot@example.com", msg.From)
+	}
+	want := []string{"bob@example.com", "carol@example.com", "audit@example.com", "archive@example.com"}
+	if strings.Join(msg.Recipients, ",") != strings.Join(want, ",") {
+		t.Errorf("Recipients = %v, want %v", msg.Recipients, want)
+	}
+	if len(msg.Bcc) != 2 {
+		t.Errorf("Bcc = %v, want 2 addresses", msg.Bcc)
+	}
+	if bytes.Contains(msg.Data, []byte("archive@example.com")) {
+		t.Error("Data should not contain the folded Bcc header")
+	}
+	if !bytes.HasPrefix(msg.Data, []byte("From: Bot <bot@example.com>\r\n")) {
+		t.Errorf("Data should start with the From header, got %q", msg.Data)
+	}
+	if !bytes.HasSuffix(msg.Data, []byte("\r\nBcc: this line is body text\r\n")) {
+		t.Error("Data body must be left unchanged")
+	}
+}
+
+func TestParseDraftErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  string
+	}{
+		{"no from", "To: bob@example.com\r\n\r\nBody"},
+		{"no recipients", "From: bot@example.com\r\n\r\nBody"},
+		{"invalid to", "From: bot@example.com\r\nTo: not an address\r\n\r\nBody"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseDraft([]byte(tt.raw)); err == nil {
+				t.Error("ParseDraft() expected an error")
+			}
+		})
+	}
+}
+
+func TestReplaceDraft(t *testing.T) {
+	be := memory.New()
+	user, err := be.Login(nil, "username", "password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := user.CreateMailbox("Drafts"); err != nil {
+		t.Fatal(err)
+	}
+	drafts, err := user.GetMailbox("Drafts")
+	if err != nil {
+		t.Fatal(err)
+	}
+	old := "From: bot@example.com\r\nTo: bob@example.com\r\nSubject: Old\r\n\r\nOld body\r\n"
+	if err := drafts.CreateMessage([]string{imap.DraftFlag}, time.Now(), strings.NewReader(old)); err != nil {
+		t.Fatal(err)
+	}
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := server.New(be)
+	s.AllowInsecureAuth = true
+	go s.Serve(l)
+	defer s.Close()
+
+	addr := l.Addr().(*net.TCPAddr)
+	r := NewReader(&config.IMAPConfig{Host: "127.0.0.1", Port: addr.Port, Username: "username", Password: "password"})
+
+	edited := "From: bot@example.com\r\nTo: bob@example.com\r\nSubject: New\r\n\r\nNew body\r\n"
+	mailbox, err := r.ReplaceDraft(1, []byte(edited))
+	if err != nil {
+		t.Fatalf("ReplaceDraft() error = %v", err)
+	}
+	if mailbox != "Drafts" {
+		t.Errorf("ReplaceDraft() mailbox = %q, want Drafts", mailbox)
+	}
+
+	msgs := drafts.(*memory.Mailbox).Messages
+	if len(msgs) != 1 {
+		t.Fatalf("Drafts holds %d messages, want only the new version", len(msgs))
+	}
+	if msgs[0].Uid == 1 {
+		t.Error("the old draft was not removed")
+	}
+	if string(msgs[0].Body) != edited {
+		t.Errorf("stored draft = %q, want %q", msgs[0].Body, edited)
+	}
+	if strings.Join(msgs[0].Flags, " ") != imap.DraftFlag+" "+imap.SeenFlag {
+		t.Errorf("stored draft flags = %v, want \\Draft \\Seen", msgs[0].Flags)
+	}
+}
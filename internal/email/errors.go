@@ -0,0 +1,46 @@
+package email
+
+import (
+	"errors"
+	"io"
+	"net"
+	"net/textproto"
+)
+
+// IsRetryable reports whether a failed submission may succeed later:
+// network errors, dropped connections and SMTP 4xx replies are transient,
+// while 5xx replies and invalid messages are permanent.
+func IsRetryable(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	var smtpErr *textproto.Error
+	if errors.As(err, &smtpErr) {
+		return smtpErr.Code >= 400 && smtpErr.Code < 500
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+
+	return errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// ConnectionError is a failure to connect to the SMTP server, secure the
+// connection or log in. It concerns the account or the network rather than
+// a message, so it fails every message sent with the same settings.
+type ConnectionError struct {
+	Err error
+}
+
+func (e *ConnectionError) Error() string { return e.Err.Error() }
+func (e *ConnectionError) Unwrap() error { return e.Err }
+
+// IsConnectionError reports whether a submission failed before any message
+// was offered to the server (see ConnectionError).
+func IsConnectionError(err error) bool {
+	var connErr *ConnectionError
+	return errors.As(err, &connErr)
+}
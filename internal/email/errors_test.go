@@ -0,0 +1,109 @@
+package email
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/textproto"
+	"strings"
+	"testing"
+
+	"github.com/GodGMN/ghostmail-cli/internal/config"
+)
+
+func TestIsRetryable(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"nil", nil, false},
+		{"connection refused", &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}, true},
+		{"dns", fmt.Errorf("failed to send email: %w", &net.DNSError{Err: "no such host", Name: "smtp.example.com"}), true},
+		{"dropped connection", fmt.Errorf("failed to send email: %w", io.EOF), true},
+		{"greylisted", fmt.Errorf("failed to send email: %w", &textproto.Error{Code: 451, Msg: "try again later"}), true},
+		{"unknown user", fmt.Errorf("failed to send email: %w", &textproto.Error{Code: 550, Msg: "no such user"}), false},
+		{"auth failed", &textproto.Error{Code: 535, Msg: "authentication failed"}, false},
+		{"invalid message", errors.New("at least one recipient is required"), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsRetryable(tt.err); got != tt.want {
+				t.Errorf("IsRetryable(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSubmitConnectionError(t *testing.T) {
+	msg := &OutgoingMessage{From: "ann@example.com", Recipients: []string{"bob@example.com"}, Data: []byte("Subject: Hi\r\n\r\nHi\r\n")}
+
+	// A refused login concerns the account, not the message
+	port := scriptedSMTPServer(t, "535 5.7.8 authentication failed", "250 ok")
+	err := NewSender(&config.SMTPConfig{Host: "127.0.0.1", Port: port, Username: "ann", Password: "wrong"}).Submit(msg)
+	if !IsConnectionError(err) || IsRetryable(err) {
+		t.Errorf("Submit() with a refused login error = %v, want a connection error that is not retryable", err)
+	}
+
+	// A rejected recipient concerns the message
+	port = scriptedSMTPServer(t, "235 ok", "550 5.1.1 no such user")
+	err = NewSender(&config.SMTPConfig{Host: "127.0.0.1", Port: port, Username: "ann", Password: "s3cret"}).Submit(msg)
+	if err == nil || IsConnectionError(err) {
+		t.Errorf("Submit() with a rejected recipient error = %v, want a message error", err)
+	}
+
+	// As does a server that cannot be reached
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	port = l.Addr().(*net.TCPAddr).Port
+	l.Close()
+	err = NewSender(&config.SMTPConfig{Host: "127.0.0.1", Port: port}).Submit(msg)
+	if !IsConnectionError(err) {
+		t.Errorf("Submit() to a closed port error = %v, want a connection error", err)
+	}
+}
+
+// scriptedSMTPServer starts an SMTP server offering AUTH PLAIN that answers
+// AUTH and RCPT with the given replies, and returns its port.
+func scriptedSMTPServer(t *testing.T, authReply, rcptReply string) int {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		in := bufio.NewReader(conn)
+		fmt.Fprint(conn, "220 ready\r\n")
+		for {
+			line, err := in.ReadString('\n')
+			if err != nil {
+				return
+			}
+			switch cmd := strings.ToUpper(strings.TrimSpace(line)); {
+			case strings.HasPrefix(cmd, "EHLO"):
+				fmt.Fprint(conn, "250-localhost\r\n250 AUTH PLAIN\r\n")
+			case strings.HasPrefix(cmd, "AUTH"):
+				fmt.Fprintf(conn, "%s\r\n", authReply)
+			case strings.HasPrefix(cmd, "RCPT"):
+				fmt.Fprintf(conn, "%s\r\n", rcptReply)
+			case cmd == "QUIT":
+				fmt.Fprint(conn, "221 bye\r\n")
+				return
+			default:
+				fmt.Fprint(conn, "250 ok\r\n")
+			}
+		}
+	}()
+	return l.Addr().(*net.TCPAddr).Port
+}
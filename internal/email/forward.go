@@ -0,0 +1,151 @@
+package email
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"strings"
+
+	"github.com/GodGMN/ghostmail-cli/internal/mimeutil"
+	emailtypes "github.com/GodGMN/ghostmail-cli/pkg/email"
+	"github.com/emersion/go-message/mail"
+)
+
+// AttachmentData is an attachment held in memory rather than read from disk.
+type AttachmentData struct {
+	Filename    string
+	ContentType string
+	Data        []byte
+}
+
+// ExtractAttachments returns the attachments of a raw message, including
+// inline parts that are not text bodies (e.g. embedded images).
+func ExtractAttachments(raw []byte) ([]AttachmentData, error) {
+	mr, err := mail.CreateReader(bytes.NewReader(raw))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse message: %w", err)
+	}
+
+	var attachments []AttachmentData
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			continue
+		}
+
+		var att AttachmentData
+		switch h := part.Header.(type) {
+		case *mail.AttachmentHeader:
+			att.ContentType, _, _ = h.ContentType()
+			att.Filename, _ = h.Filename()
+		case *mail.InlineHeader:
+			contentType, params, _ := h.ContentType()
+			if strings.HasPrefix(contentType, "text/") || strings.HasPrefix(contentType, "multipart/") {
+				continue
+			}
+			att.ContentType = contentType
+			att.Filename = params["name"]
+		default:
+			continue
+		}
+
+		att.Data, err = io.ReadAll(part.Body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read attachment: %w", err)
+		}
+		if att.Filename == "" {
+			att.Filename = fmt.Sprintf("attachment-%d", len(attachments)+1)
+		}
+		attachments = append(attachments, att)
+	}
+
+	return attachments, nil
+}
+
+// FormatForwardedMessage formats an inline forward: the optional note, a
+// "Forwarded message" header block describing the original and its body.
+func FormatForwardedMessage(note string, original *emailtypes.Message) string {
+	var result strings.Builder
+
+	if note != "" {
+		result.WriteString(note)
+		result.WriteString("\n\n")
+	}
+
+	result.WriteString("---------- Forwarded message ---------\n")
+	result.WriteString("From: " + original.From + "\n")
+	result.WriteString("Date: " + original.Date.Format("Mon, Jan 2, 2006 at 3:04 PM") + "\n")
+	result.WriteString("Subject: " + original.Subject + "\n")
+	if len(original.To) > 0 {
+		result.WriteString("To: " + strings.Join(original.To, ", ") + "\n")
+	}
+	if len(original.CC) > 0 {
+		result.WriteString("Cc: " + strings.Join(original.CC, ", ") + "\n")
+	}
+	result.WriteString("\n")
+	result.WriteString(original.Body)
+
+	return strings.TrimRight(result.String(), "\n")
+}
+
+// ForwardSubject returns subject with a "Fwd:" prefix, unless it already
+// carries a forward prefix.
+func ForwardSubject(subject string) string {
+	lower := strings.ToLower(subject)
+	if strings.HasPrefix(lower, "fwd:") || strings.HasPrefix(lower, "fw:") {
+		return subject
+	}
+	return "Fwd: " + subject
+}
+
+// attachMessages wraps a built message in a multipart/mixed entity with
+// messages attached as message/rfc822 parts in 7bit or 8bit encoding.
+func attachMessages(msg []byte, messages []AttachmentData) ([]byte, error) {
+	outer, inner := mimeutil.SplitEntity(mimeutil.ToCRLF(msg))
+	boundary := mimeutil.NewBoundary()
+
+	var body bytes.Buffer
+	fmt.Fprintf(&body, "Content-Type: multipart/mixed;\r\n boundary=\"%s\"\r\n\r\n", boundary)
+	fmt.Fprintf(&body, "--%s\r\n", boundary)
+	body.Write(inner)
+	for _, att := range messages {
+		data := mimeutil.ToCRLF(att.Data)
+		encoding, err := messageEncoding(data)
+		if err != nil {
+			return nil, fmt.Errorf("cannot attach %s: %w; forward it inline instead", att.Filename, err)
+		}
+		fmt.Fprintf(&body, "\r\n--%s\r\n", boundary)
+		fmt.Fprintf(&body, "Content-Type: %s\r\n", mime.FormatMediaType("message/rfc822", map[string]string{"name": att.Filename}))
+		fmt.Fprintf(&body, "Content-Disposition: %s\r\n", mime.FormatMediaType("attachment", map[string]string{"filename": att.Filename}))
+		fmt.Fprintf(&body, "Content-Transfer-Encoding: %s\r\n\r\n", encoding)
+		body.Write(data)
+	}
+	fmt.Fprintf(&body, "\r\n--%s--\r\n", boundary)
+	return mimeutil.Join(outer, body.Bytes()), nil
+}
+
+// messageEncoding returns the transfer encoding of an attached message.
+// Messages that would need binary encoding (NUL bytes, bare CRs or lines
+// over 998 octets) are refused, since RFC 2046 does not allow base64 for
+// message/rfc822.
+func messageEncoding(data []byte) (string, error) {
+	encoding := "7bit"
+	for _, line := range bytes.Split(data, []byte("\r\n")) {
+		if len(line) > 998 {
+			return "", errors.New("it has lines longer than 998 bytes")
+		}
+		for _, b := range line {
+			switch {
+			case b == 0 || b == '\r':
+				return "", errors.New("it contains binary data")
+			case b >= 0x80:
+				encoding = "8bit"
+			}
+		}
+	}
+	return encoding, nil
+}
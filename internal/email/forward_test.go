@@ -0,0 +1,146 @@
+package email
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	emailtypes "github.com/GodGMN/ghostmail-cli/pkg/email"
+)
+
+const testMultipartMessage = "From: Alice <alice@example.com>\r\n" +
+	"To: bob@example.com\r\n" +
+	"Subject: Report\r\n" +
+	"Message-ID: <report-1@example.com>\r\n" +
+	"MIME-Version: 1.0\r\n" +
+	"Content-Type: multipart/mixed; boundary=\"b1\"\r\n" +
+	"\r\n" +
+	"--b1\r\n" +
+	"Content-Type: text/plain; charset=utf-8\r\n" +
+	"\r\n" +
+	"See attached.\r\n" +
+	"--b1\r\n" +
+	"Content-Type: text/csv; name=\"data.csv\"\r\n" +
+	"Content-Disposition: attachment; filename=\"data.csv\"\r\n" +
+	"Content-Transfer-Encoding: base64\r\n" +
+	"\r\n" +
+	"YSxiCjEsMgo=\r\n" +
+	"--b1\r\n" +
+	"Content-Type: image/png; name=\"logo.png\"\r\n" +
+	"Content-Disposition: inline\r\n" +
+	"Content-Transfer-Encoding: base64\r\n" +
+	"\r\n" +
+	"iVBORw0KGgo=\r\n" +
+	"--b1--\r\n"
+
+func TestExtractAttachments(t *testing.T) {
+	attachments, err := ExtractAttachments([]byte(testMultipartMessage))
+	if err != nil {
+		t.Fatalf("ExtractAttachments() error = %v", err)
+	}
+	if len(attachments) != 2 {
+		t.Fatalf("ExtractAttachments() returned %d attachments, want 2", len(attachments))
+	}
+
+	if attachments[0].Filename != "data.csv" || attachments[0].ContentType != "text/csv" {
+		t.Errorf("attachments[0] = %s (%s), want data.csv (text/csv)", attachments[0].Filename, attachments[0].ContentType)
+	}
+	if string(attachments[0].Data) != "a,b\n1,2\n" {
+		t.Errorf("attachments[0].Data = %q, want %q", attachments[0].Data, "a,b\n1,2\n")
+	}
+	if attachments[1].Filename != "logo.png" || attachments[1].ContentType != "image/png" {
+		t.Errorf("attachments[1] = %s (%s), want logo.png (image/png)", attachments[1].Filename, attachments[1].ContentType)
+	}
+}
+
+func TestFormatForwardedMessage(t *testing.T) {
+	original := &emailtypes.Message{
+		From:    "Alice <alice@example.com>",
+		To:      []string{"bob@example.com"},
+		CC:      []string{"carol@example.com"},
+		Subject: "Disk alert",
+		Date:    time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC),
+		Body:    "Disk usage is at 90%",
+	}
+
+	got := FormatForwardedMessage("FYI", original)
+	want := "FYI\n\n" +
+		"---------- Forwarded message ---------\n" +
+		"From: Alice <alice@example.com>\n" +
+		"Date: Mon, Jan 15, 2024 at 10:30 AM\n" +
+		"Subject: Disk alert\n" +
+		"To: bob@example.com\n" +
+		"Cc: carol@example.com\n" +
+		"\n" +
+		"Disk usage is at 90%"
+	if got != want {
+		t.Errorf("FormatForwardedMessage() =\n%s\nwant\n%s", got, want)
+	}
+
+	if got := FormatForwardedMessage("", original); !strings.HasPrefix(got, "---------- Forwarded message") {
+		t.Errorf("FormatForwardedMessage() without note should start with the header block, got %q", got)
+	}
+}
+
+func TestForwardSubject(t *testing.T) {
+	tests := []struct {
+		subject string
+		want    string
+	}{
+		{"Disk alert", "Fwd: Disk alert"},
+		{"Fwd: Disk alert", "Fwd: Disk alert"},
+		{"FW: Disk alert", "FW: Disk alert"},
+		{"Re: Disk alert", "Fwd: Re: Disk alert"},
+	}
+
+	for _, tt := range tests {
+		if got := ForwardSubject(tt.subject); got != tt.want {
+			t.Errorf("ForwardSubject(%q) = %q, want %q", tt.subject, got, tt.want)
+		}
+	}
+}
+
+func TestAttachMessages(t *testing.T) {
+	msg := []byte("From: bob@example.com\r\nSubject: Fwd: Report\r\nMIME-Version: 1.0\r\n" +
+		"Content-Type: text/plain; charset=UTF-8\r\n\r\nFYI\r\n")
+	got, err := attachMessages(msg, []AttachmentData{{Filename: "Résumé.eml", ContentType: "message/rfc822", Data: []byte(testMultipartMessage)}})
+	if err != nil {
+		t.Fatalf("attachMessages() error = %v", err)
+	}
+	out := string(got)
+	for _, want := range []string{
+		"Subject: Fwd: Report\r\nMIME-Version: 1.0\r\nContent-Type: multipart/mixed;",
+		"Content-Type: text/plain; charset=UTF-8\r\n\r\nFYI\r\n",
+		"Content-Type: message/rfc822; name*=utf-8''R%C3%A9sum%C3%A9.eml\r\n",
+		"Content-Disposition: attachment; filename*=utf-8''R%C3%A9sum%C3%A9.eml\r\n",
+		"Content-Transfer-Encoding: 7bit\r\n\r\n" + testMultipartMessage,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("attachMessages() = %q, want it to contain %q", out, want)
+		}
+	}
+
+	long := []byte("Subject: x\r\n\r\n" + strings.Repeat("a", 1000) + "\r\n")
+	if _, err := attachMessages(msg, []AttachmentData{{Filename: "long.eml", ContentType: "message/rfc822", Data: long}}); err == nil {
+		t.Error("attachMessages() with a 1000-byte line expected an error")
+	}
+}
+
+func TestMessageEncoding(t *testing.T) {
+	tests := []struct {
+		data    string
+		want    string
+		wantErr bool
+	}{
+		{"Subject: hi\r\n\r\nplain\r\n", "7bit", false},
+		{"Subject: hi\r\n\r\ncafé\r\n", "8bit", false},
+		{"Subject: hi\r\n\r\nnul\x00\r\n", "", true},
+		{"Subject: hi\r\n\r\nbare\rcr\r\n", "", true},
+	}
+	for _, tt := range tests {
+		got, err := messageEncoding([]byte(tt.data))
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("messageEncoding(%q) = %q, %v; want %q, error %v", tt.data, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
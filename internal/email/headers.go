@@ -0,0 +1,150 @@
+package email
+
+import (
+	"fmt"
+	"strings"
+)
+
+// reservedHeaders are set from the message fields, generated, or added by
+// servers, and cannot be given as custom headers.
+var reservedHeaders = map[string]bool{
+	"from":           true,
+	"sender":         true,
+	"to":             true,
+	"cc":             true,
+	"bcc":            true,
+	"subject":        true,
+	"date":           true,
+	"message-id":     true,
+	"in-reply-to":    true,
+	"references":     true,
+	"mime-version":   true,
+	"dkim-signature": true,
+	"received":       true,
+	"return-path":    true,
+}
+
+// reservedPrefixes are prefixes of reserved header families: the MIME
+// content headers of the message and the Resent-* headers of redirects.
+var reservedPrefixes = []string{"content-", "resent-"}
+
+// ValidateHeader checks that a custom header has a valid field name, is
+// not one set by ghostmail itself, and has a single-line value.
+func ValidateHeader(name, value string) error {
+	if name == "" {
+		return fmt.Errorf("header name is empty")
+	}
+	for _, c := range name {
+		if c <= ' ' || c > '~' || c == ':' {
+			return fmt.Errorf("invalid header name %q", name)
+		}
+	}
+	if reservedHeader(name) {
+		return fmt.Errorf("header %s cannot be set directly", name)
+	}
+	if strings.ContainsAny(value, "\r\n") {
+		return fmt.Errorf("header %s must not contain line breaks", name)
+	}
+	return nil
+}
+
+// reservedHeader reports whether a header cannot be given as a custom
+// header.
+func reservedHeader(name string) bool {
+	name = strings.ToLower(name)
+	if reservedHeaders[name] {
+		return true
+	}
+	for _, prefix := range reservedPrefixes {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseHeaders parses and validates "Name: value" header arguments. A
+// header may be given only once.
+func ParseHeaders(args []string) (map[string]string, error) {
+	headers := make(map[string]string, len(args))
+	seen := make(map[string]bool, len(args))
+	for _, arg := range args {
+		name, value, ok := strings.Cut(arg, ":")
+		if !ok {
+			return nil, fmt.Errorf("invalid header %q (use \"Name: value\")", arg)
+		}
+		name, value = strings.TrimSpace(name), strings.TrimSpace(value)
+		if err := ValidateHeader(name, value); err != nil {
+			return nil, err
+		}
+		if seen[strings.ToLower(name)] {
+			return nil, fmt.Errorf("header %s given more than once", name)
+		}
+		seen[strings.ToLower(name)] = true
+		headers[name] = value
+	}
+	return headers, nil
+}
+
+// Priority is the importance of a message.
+type Priority string
+
+// Message priorities.
+const (
+	PriorityHigh   Priority = "high"
+	PriorityNormal Priority = "normal"
+	PriorityLow    Priority = "low"
+)
+
+// priorityHeaders are the headers for each priority: X-Priority for most
+// clients, Importance for Outlook and Priority from RFC 2156.
+var priorityHeaders = map[Priority][][2]string{
+	PriorityHigh:   {{"X-Priority", "1 (Highest)"}, {"Importance", "high"}, {"Priority", "urgent"}},
+	PriorityNormal: {{"X-Priority", "3 (Normal)"}, {"Importance", "normal"}, {"Priority", "normal"}},
+	PriorityLow:    {{"X-Priority", "5 (Lowest)"}, {"Importance", "low"}, {"Priority", "non-urgent"}},
+}
+
+// ParsePriority parses "high", "normal" or "low".
+func ParsePriority(s string) (Priority, error) {
+	p := Priority(strings.ToLower(s))
+	if _, ok := priorityHeaders[p]; !ok {
+		return "", fmt.Errorf("invalid priority %q (use high, normal or low)", s)
+	}
+	return p, nil
+}
+
+// WithReplyTo sets the Reply-To addresses.
+func WithReplyTo(addrs []string) SendOption {
+	return func(o *sendOptions) {
+		o.replyTo = addrs
+	}
+}
+
+// WithPriority sets the priority headers.
+func WithPriority(p Priority) SendOption {
+	return func(o *sendOptions) {
+		o.priority = p
+	}
+}
+
+// WithReadReceipt requests a read receipt (Disposition-Notification-To)
+// to the sender.
+func WithReadReceipt() SendOption {
+	return func(o *sendOptions) {
+		o.readReceipt = true
+	}
+}
+
+// overrides reports whether the options set a header themselves, which
+// takes precedence over a custom header of the same name.
+func (o *sendOptions) overrides(name string) bool {
+	switch strings.ToLower(name) {
+	case "reply-to":
+		return len(o.replyTo) > 0
+	case "x-priority", "importance", "priority":
+		return o.priority != ""
+	case "disposition-notification-to":
+		return o.readReceipt
+	}
+	return false
+}
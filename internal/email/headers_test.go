@@ -0,0 +1,91 @@
+package email
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/GodGMN/ghostmail-cli/internal/config"
+)
+
+func TestParseHeaders(t *testing.T) {
+	got, err := ParseHeaders([]string{"X-Ticket-ID: 4711", "Auto-Submitted:auto-generated", "X-Empty:"})
+	if err != nil {
+		t.Fatalf("ParseHeaders() error = %v", err)
+	}
+	want := map[string]string{"X-Ticket-ID": "4711", "Auto-Submitted": "auto-generated", "X-Empty": ""}
+	for name, value := range want {
+		if got[name] != value {
+			t.Errorf("ParseHeaders()[%s] = %q, want %q", name, got[name], value)
+		}
+	}
+
+	tests := [][]string{
+		{"X-Ticket-ID 4711"},
+		{"Bcc: spy@example.com"},
+		{"content-type: text/plain"},
+		{"Content-Disposition: inline"},
+		{"DKIM-Signature: v=1; d=example.com"},
+		{"Sender: boss@example.com"},
+		{"In-Reply-To: <a@example.com>"},
+		{"References: <a@example.com>"},
+		{"Resent-To: spy@example.com"},
+		{"Received: from evil"},
+		{"Return-Path: <spy@example.com>"},
+		{"X Space: 1"},
+		{": value"},
+		{"X-A: 1", "x-a: 2"},
+	}
+	for _, args := range tests {
+		if _, err := ParseHeaders(args); err == nil {
+			t.Errorf("ParseHeaders(%q) expected an error", args)
+		}
+	}
+}
+
+func TestParsePriority(t *testing.T) {
+	for _, s := range []string{"high", "Normal", "LOW"} {
+		if _, err := ParsePriority(s); err != nil {
+			t.Errorf("ParsePriority(%q) error = %v", s, err)
+		}
+	}
+	if _, err := ParsePriority("urgent"); err == nil {
+		t.Error("ParsePriority(urgent) expected an error")
+	}
+}
+
+func TestBuildHeaderOptions(t *testing.T) {
+	sender := NewSender(&config.SMTPConfig{From: "bot@example.com"})
+
+	msg, err := sender.Build([]string{"a@example.com"}, "Outage", "Investigating",
+		WithHeaders(map[string]string{"X-Ticket-ID": "4711", "reply-to": "ignored@example.com", "X-Priority": "2"}),
+		WithReplyTo([]string{"team@example.com"}),
+		WithPriority(PriorityHigh),
+		WithReadReceipt(),
+	)
+	if err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+
+	data := string(msg.Data)
+	for _, want := range []string{
+		"X-Ticket-ID: 4711\r\n",
+		"Reply-To: team@example.com\r\n",
+		"X-Priority: 1 (Highest)\r\n",
+		"Importance: high\r\n",
+		"Priority: urgent\r\n",
+		"Disposition-Notification-To: bot@example.com\r\n",
+	} {
+		if !strings.Contains(data, want) {
+			t.Errorf("Build() data = %q\nwant it to contain %q", data, want)
+		}
+	}
+	for _, unwanted := range []string{"ignored@example.com", "X-Priority: 2"} {
+		if strings.Contains(data, unwanted) {
+			t.Errorf("Build() data should not contain %q, the options take precedence", unwanted)
+		}
+	}
+
+	if _, err := sender.Build([]string{"a@example.com"}, "x", "y", WithReplyTo([]string{"not an address"})); err == nil {
+		t.Error("Build() with an invalid Reply-To expected an error")
+	}
+}
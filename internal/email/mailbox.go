@@ -0,0 +1,126 @@
+package email
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/emersion/go-imap"
+	"github.com/emersion/go-imap/client"
+	"github.com/emersion/go-imap/commands"
+)
+
+// Fallback names for special-use mailboxes on servers without SPECIAL-USE.
+var specialMailboxNames = map[string][]string{
+	imap.DraftsAttr: {"Drafts", "[Gmail]/Drafts", "INBOX.Drafts", "Draft"},
+	imap.SentAttr:   {"Sent", "[Gmail]/Sent Mail", "Sent Items", "Sent Messages", "INBOX.Sent"},
+}
+
+// FindSpecialMailbox returns the name of the mailbox with the given
+// SPECIAL-USE attribute (RFC 6154), e.g. \Drafts or \Sent. Servers without
+// SPECIAL-USE are matched against common mailbox names.
+func (r *Reader) FindSpecialMailbox(attr string) (string, error) {
+	c, err := r.Connect()
+	if err != nil {
+		return "", err
+	}
+	defer c.Logout()
+
+	return findSpecialMailbox(c, attr)
+}
+
+// findSpecialMailbox looks up a special-use mailbox on an open connection.
+func findSpecialMailbox(c *client.Client, attr string) (string, error) {
+	mailboxes := make(chan *imap.MailboxInfo, 10)
+	done := make(chan error, 1)
+	go func() {
+		done <- c.List("", "*", mailboxes)
+	}()
+
+	var names []string
+	found := ""
+	for m := range mailboxes {
+		names = append(names, m.Name)
+		for _, a := range m.Attributes {
+			if strings.EqualFold(a, attr) && found == "" {
+				found = m.Name
+			}
+		}
+	}
+	if err := <-done; err != nil {
+		return "", fmt.Errorf("failed to list mailboxes: %w", err)
+	}
+	if found != "" {
+		return found, nil
+	}
+
+	for _, candidate := range specialMailboxNames[attr] {
+		for _, name := range names {
+			if strings.EqualFold(name, candidate) {
+				return name, nil
+			}
+		}
+	}
+
+	return "", fmt.Errorf("no %s mailbox found on the server", attr)
+}
+
+// AppendMessage stores a raw message in a mailbox with the given flags.
+func (r *Reader) AppendMessage(mailbox string, flags []string, msg []byte) error {
+	c, err := r.Connect()
+	if err != nil {
+		return err
+	}
+	defer c.Logout()
+
+	if err := c.Append(mailbox, flags, time.Now(), bytes.NewBuffer(msg)); err != nil {
+		return fmt.Errorf("failed to append message to %s: %w", mailbox, err)
+	}
+	return nil
+}
+
+// DeleteMessage permanently removes a message by UID from the configured
+// mailbox. Only that message is expunged when the server supports UIDPLUS.
+func (r *Reader) DeleteMessage(uid uint32) error {
+	c, err := r.Connect()
+	if err != nil {
+		return err
+	}
+	defer c.Logout()
+
+	if _, err := c.Select(r.config.Mailbox, false); err != nil {
+		return fmt.Errorf("failed to select mailbox: %w", err)
+	}
+	return expungeMessage(c, uid)
+}
+
+// expungeMessage permanently removes a message by UID from the selected
+// mailbox.
+func expungeMessage(c *client.Client, uid uint32) error {
+	seqSet := new(imap.SeqSet)
+	seqSet.AddNum(uid)
+
+	item := imap.FormatFlagsOp(imap.AddFlags, true)
+	if err := c.UidStore(seqSet, item, []interface{}{imap.DeletedFlag}, nil); err != nil {
+		return fmt.Errorf("failed to mark message as deleted: %w", err)
+	}
+
+	if ok, _ := c.Support("UIDPLUS"); ok {
+		// UID EXPUNGE (RFC 4315) leaves other \Deleted messages alone
+		expunge := &imap.Command{Name: "EXPUNGE", Arguments: []interface{}{seqSet}}
+		status, err := c.Execute(&commands.Uid{Cmd: expunge}, nil)
+		if err == nil {
+			err = status.Err()
+		}
+		if err != nil {
+			return fmt.Errorf("failed to expunge message: %w", err)
+		}
+		return nil
+	}
+
+	if err := c.Expunge(nil); err != nil {
+		return fmt.Errorf("failed to expunge message: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+package email
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+)
+
+// markdownWrapperStyle is applied to the element around the whole body.
+const markdownWrapperStyle = "font-family:-apple-system,'Segoe UI',Helvetica,Arial,sans-serif;font-size:14px;line-height:1.5;color:#24292f"
+
+// markdownStyles are the inline styles for the elements goldmark emits.
+// Many mail clients ignore <style> blocks, so every element carries its
+// own style attribute.
+var markdownStyles = map[string]string{
+	"p":          "margin:0 0 1em 0",
+	"h1":         "margin:1em 0 0.5em 0;font-size:1.6em;font-weight:bold",
+	"h2":         "margin:1em 0 0.5em 0;font-size:1.4em;font-weight:bold",
+	"h3":         "margin:1em 0 0.5em 0;font-size:1.2em;font-weight:bold",
+	"h4":         "margin:1em 0 0.5em 0;font-size:1em;font-weight:bold",
+	"h5":         "margin:1em 0 0.5em 0;font-size:0.9em;font-weight:bold",
+	"h6":         "margin:1em 0 0.5em 0;font-size:0.85em;font-weight:bold;color:#57606a",
+	"a":          "color:#0969da;text-decoration:underline",
+	"blockquote": "margin:0 0 1em 0;padding:0 1em;color:#57606a;border-left:4px solid #d0d7de",
+	"ul":         "margin:0 0 1em 0;padding-left:2em",
+	"ol":         "margin:0 0 1em 0;padding-left:2em",
+	"pre":        "margin:0 0 1em 0;padding:12px;background:#f6f8fa;border-radius:6px;overflow:auto;font-size:13px;line-height:1.45",
+	"code":       "font-family:Menlo,Consolas,'Courier New',monospace;background:#f6f8fa;padding:2px 4px;border-radius:4px;font-size:90%",
+	"table":      "margin:0 0 1em 0;border-collapse:collapse",
+	"th":         "padding:6px 13px;border:1px solid #d0d7de;background:#f6f8fa;font-weight:bold",
+	"td":         "padding:6px 13px;border:1px solid #d0d7de",
+	"hr":         "margin:1.5em 0;border:0;border-top:1px solid #d0d7de",
+	"img":        "max-width:100%",
+}
+
+// codeBlockStyle replaces the inline code style for <code> inside <pre>,
+// which is already styled as a block.
+const codeBlockStyle = "font-family:Menlo,Consolas,'Courier New',monospace"
+
+// markdownTag matches the opening tags of styled elements. Text and code
+// are escaped by goldmark, so only real tags match.
+var markdownTag = regexp.MustCompile(`<(p|h[1-6]|a|blockquote|ul|ol|pre|code|table|th|td|hr|img)([\s/>])`)
+
+// markdown converts CommonMark with the GitHub extensions (tables,
+// strikethrough, autolinks, task lists). Raw HTML in the source is
+// dropped.
+var markdown = goldmark.New(
+	goldmark.WithExtensions(
+		extension.NewTable(extension.WithTableCellAlignMethod(extension.TableCellAlignAttribute)),
+		extension.Strikethrough,
+		extension.Linkify,
+		extension.TaskList,
+	),
+)
+
+// MarkdownToHTML renders Markdown as an HTML body for email, with inline
+// styles on every element.
+func MarkdownToHTML(src string) (string, error) {
+	var buf bytes.Buffer
+	if err := markdown.Convert([]byte(src), &buf); err != nil {
+		return "", fmt.Errorf("failed to render Markdown: %w", err)
+	}
+	html := buf.String()
+
+	var out strings.Builder
+	fmt.Fprintf(&out, "<div style=\"%s\">\n", markdownWrapperStyle)
+
+	last, prevTag, prevEnd := 0, "", -1
+	for _, m := range markdownTag.FindAllStringSubmatchIndex(html, -1) {
+		start, end := m[0], m[1]
+		tag := html[m[2]:m[3]]
+
+		style := markdownStyles[tag]
+		if tag == "code" && prevTag == "pre" && prevEnd == start {
+			style = codeBlockStyle
+		}
+
+		// Insert the style attribute right after the tag name
+		out.WriteString(html[last:m[3]])
+		fmt.Fprintf(&out, " style=\"%s\"", style)
+		out.WriteString(html[m[4]:end])
+
+		last, prevTag, prevEnd = end, tag, end
+	}
+	out.WriteString(html[last:])
+	out.WriteString("</div>\n")
+
+	return out.String(), nil
+}
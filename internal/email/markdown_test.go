@@ -0,0 +1,75 @@
+package email
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMarkdownToHTML(t *testing.T) {
+	tests := []struct {
+		name     string
+		markdown string
+		contains []string
+		excludes []string
+	}{
+		{
+			name:     "paragraph and inline code",
+			markdown: "Run `make`.",
+			contains: []string{
+				`<p style="` + markdownStyles["p"] + `">Run <code style="` + markdownStyles["code"] + `">make</code>.</p>`,
+			},
+		},
+		{
+			name:     "code block",
+			markdown: "```go\nfmt.Println(\"<p>\")\n```",
+			contains: []string{
+				`<pre style="` + markdownStyles["pre"] + `"><code style="` + codeBlockStyle + `" class="language-go">`,
+				`fmt.Println(&quot;&lt;p&gt;&quot;)`,
+			},
+		},
+		{
+			name:     "table with alignment",
+			markdown: "| a | b |\n|:--|--:|\n| 1 | 2 |",
+			contains: []string{
+				`<table style="`,
+				`<th style="` + markdownStyles["th"] + `" align="left">a</th>`,
+				`<td style="` + markdownStyles["td"] + `" align="right">2</td>`,
+			},
+		},
+		{
+			name:     "links",
+			markdown: "[docs](https://example.com) and https://example.org",
+			contains: []string{
+				`<a style="` + markdownStyles["a"] + `" href="https://example.com">docs</a>`,
+				`href="https://example.org"`,
+			},
+		},
+		{
+			name:     "raw HTML is dropped",
+			markdown: "<script>alert(1)</script>\n\nText <b>bold</b>",
+			excludes: []string{"<script>", "<b>"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := MarkdownToHTML(tt.markdown)
+			if err != nil {
+				t.Fatalf("MarkdownToHTML() error = %v", err)
+			}
+			if !strings.HasPrefix(got, `<div style="`+markdownWrapperStyle+`">`) {
+				t.Errorf("MarkdownToHTML() is not wrapped in a styled div: %s", got)
+			}
+			for _, want := range tt.contains {
+				if !strings.Contains(got, want) {
+					t.Errorf("MarkdownToHTML() = %s\nwant it to contain %s", got, want)
+				}
+			}
+			for _, unwanted := range tt.excludes {
+				if strings.Contains(got, unwanted) {
+					t.Errorf("MarkdownToHTML() = %s\nshould not contain %s", got, unwanted)
+				}
+			}
+		})
+	}
+}
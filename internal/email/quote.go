@@ -0,0 +1,220 @@
+package email
+
+import (
+	"regexp"
+	"strings"
+)
+
+// BodyParts holds a message body split into the newly written text, the
+// quoted history and the sender's signature.
+type BodyParts struct {
+	New       string
+	Quoted    string
+	Signature string
+}
+
+var (
+	// attributionRes match reply attribution lines such as
+	// "On Mon, 1 Jan 2024, John <john@example.com> wrote:" in common languages.
+	attributionRes = []*regexp.Regexp{
+		regexp.MustCompile(`(?i)^on\s.+\swrote:$`),                    // English
+		regexp.MustCompile(`(?i)^el\s.+\sescribi[oó]:$`),              // Spanish
+		regexp.MustCompile(`(?i)^le\s.+\sa\s[ée]crit\s?:$`),           // French
+		regexp.MustCompile(`(?i)^am\s.+\sschrieb.*:$`),                // German
+		regexp.MustCompile(`(?i)^il\s.+\sha\sscritto:$`),              // Italian
+		regexp.MustCompile(`(?i)^em\s.+\sescreveu:$`),                 // Portuguese
+		regexp.MustCompile(`(?i)^op\s.+\sschreef.*:$`),                // Dutch
+		regexp.MustCompile(`(?i)^(den|på)\s.+\sskrev.*:$`),            // Swedish, Danish, Norwegian
+		regexp.MustCompile(`(?i)^w\sdniu\s.+\snapisa[łl](\(a\))?:$`),  // Polish
+		regexp.MustCompile(`(?i)^.+\s(пишет|написал\(а\)|написал):$`), // Russian
+		regexp.MustCompile(`^.+(のメッセージ|は書きました)\s?:$`),                 // Japanese
+		regexp.MustCompile(`^.+写道[:：]$`),                              // Chinese
+	}
+
+	// originalMessageRe matches Outlook-style "-----Original Message-----" separators.
+	originalMessageRe = regexp.MustCompile(`(?i)^-{2,}\s*(original message|mensaje original|message d'origine|urspr[üu]ngliche nachricht|messaggio originale|mensagem original|oorspronkelijk bericht)\s*-{2,}$`)
+
+	// outlookSeparatorRe matches the underscore line Outlook puts above the
+	// quoted header block.
+	outlookSeparatorRe = regexp.MustCompile(`^_{20,}$`)
+
+	// headerFromRe and headerDateRe match the header block Outlook inserts
+	// instead of an attribution line.
+	headerFromRe = regexp.MustCompile(`(?i)^\*?(from|de|von|da|van|från|od)\s?:`)
+	headerDateRe = regexp.MustCompile(`(?i)^\*?(sent|date|enviado|fecha|envoyé|gesendet|datum|inviato|data|verzonden|skickat|wysłano)\s?:`)
+
+	// mobileSignatureRe matches the default signature of mobile mail apps.
+	mobileSignatureRe = regexp.MustCompile(`(?i)^(sent from my |get outlook for |enviado desde mi )`)
+
+	// htmlQuoteRe matches the containers mail clients wrap quoted history in.
+	htmlQuoteRe = regexp.MustCompile(`(?i)<(div|blockquote)[^>]*(class="[^"]*(gmail_quote|yahoo_quoted|moz-cite-prefix)[^"]*"|type="cite"|id="(divRplyFwdMsg|appendonsend)")[^>]*>|<hr[^>]*id="stopSpelling"[^>]*>`)
+
+	// htmlSignatureRe matches the containers mail clients wrap signatures in.
+	htmlSignatureRe = regexp.MustCompile(`(?i)<(div|span|pre)[^>]*(class="[^"]*(gmail_signature|moz-signature)[^"]*"|id="Signature")[^>]*>`)
+)
+
+// SplitBody splits a plain-text body into new text, quoted history and
+// signature. Quoted history starts at a reply attribution, an Outlook
+// "Original Message" header or a trailing block of ">" quoted lines. The
+// signature starts at the last "-- " delimiter in the new text.
+func SplitBody(body string) BodyParts {
+	lines := strings.Split(strings.ReplaceAll(body, "\r\n", "\n"), "\n")
+
+	var parts BodyParts
+	newLines := lines
+	if idx := quoteStart(lines); idx >= 0 {
+		newLines = lines[:idx]
+		parts.Quoted = strings.TrimSpace(strings.Join(lines[idx:], "\n"))
+	} else {
+		// Interleaved replies: separate the quoted lines from the answers
+		var kept, quoted []string
+		for _, line := range lines {
+			if isQuotedLine(line) {
+				quoted = append(quoted, line)
+			} else {
+				kept = append(kept, line)
+			}
+		}
+		if len(quoted) > 0 {
+			newLines = kept
+			parts.Quoted = strings.TrimSpace(strings.Join(quoted, "\n"))
+		}
+	}
+
+	if idx := signatureStart(newLines); idx >= 0 {
+		parts.Signature = strings.TrimSpace(strings.Join(newLines[idx+1:], "\n"))
+		if !isSignatureDelimiter(newLines[idx]) {
+			parts.Signature = strings.TrimSpace(strings.Join(newLines[idx:], "\n"))
+		}
+		newLines = newLines[:idx]
+	}
+
+	parts.New = strings.TrimSpace(strings.Join(newLines, "\n"))
+	return parts
+}
+
+// splitHTMLBody splits an HTML body using the quote and signature containers
+// inserted by common mail clients and converts each part to plain text.
+func (r *Reader) splitHTMLBody(html string) BodyParts {
+	var parts BodyParts
+
+	newHTML := html
+	if loc := htmlQuoteRe.FindStringIndex(html); loc != nil {
+		newHTML = html[:loc[0]]
+		parts.Quoted = r.stripHTML(html[loc[0]:])
+	}
+	if loc := htmlSignatureRe.FindStringIndex(newHTML); loc != nil {
+		parts.Signature = r.stripHTML(newHTML[loc[0]:])
+		newHTML = newHTML[:loc[0]]
+	}
+	parts.New = r.stripHTML(newHTML)
+
+	// Fall back to text heuristics for clients without quote containers
+	if parts.Quoted == "" && parts.Signature == "" {
+		return SplitBody(parts.New)
+	}
+	return parts
+}
+
+// quoteStart returns the index of the first line of quoted history, or -1.
+func quoteStart(lines []string) int {
+	for i, line := range lines {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		if isAttribution(line) {
+			return i
+		}
+		// Attributions are often wrapped over two lines
+		if i+1 < len(lines) && isAttribution(line+" "+strings.TrimSpace(lines[i+1])) {
+			return i
+		}
+
+		if originalMessageRe.MatchString(line) {
+			return i
+		}
+
+		if outlookSeparatorRe.MatchString(line) || headerFromRe.MatchString(line) {
+			if isHeaderBlock(lines[i:]) {
+				return i
+			}
+		}
+
+		if isQuotedLine(line) && onlyQuotesRemain(lines[i:]) {
+			return i
+		}
+	}
+	return -1
+}
+
+// isAttribution reports whether line is a reply attribution.
+func isAttribution(line string) bool {
+	for _, re := range attributionRes {
+		if re.MatchString(line) {
+			return true
+		}
+	}
+	return false
+}
+
+// isHeaderBlock reports whether lines start with an Outlook-style quoted
+// header block ("From:" followed closely by "Sent:" or "Date:").
+func isHeaderBlock(lines []string) bool {
+	from := false
+	for i, line := range lines {
+		if i > 5 {
+			break
+		}
+		line = strings.TrimSpace(line)
+		if headerFromRe.MatchString(line) {
+			from = true
+		} else if from && headerDateRe.MatchString(line) {
+			return true
+		}
+	}
+	return false
+}
+
+// isQuotedLine reports whether line is quoted with ">".
+func isQuotedLine(line string) bool {
+	return strings.HasPrefix(strings.TrimSpace(line), ">")
+}
+
+// onlyQuotesRemain reports whether lines contain only quoted or blank lines.
+func onlyQuotesRemain(lines []string) bool {
+	for _, line := range lines {
+		if strings.TrimSpace(line) != "" && !isQuotedLine(line) {
+			return false
+		}
+	}
+	return true
+}
+
+// signatureStart returns the index of the signature delimiter line, or -1.
+func signatureStart(lines []string) int {
+	for i := len(lines) - 1; i >= 0; i-- {
+		if isSignatureDelimiter(lines[i]) {
+			return i
+		}
+	}
+
+	// Mobile clients append a one-line signature without a delimiter
+	for i := len(lines) - 1; i >= 0; i-- {
+		line := strings.TrimSpace(lines[i])
+		if line == "" {
+			continue
+		}
+		if mobileSignatureRe.MatchString(line) {
+			return i
+		}
+		break
+	}
+	return -1
+}
+
+// isSignatureDelimiter reports whether line is the "-- " signature delimiter.
+func isSignatureDelimiter(line string) bool {
+	return strings.TrimRight(line, "\r") == "-- " || strings.TrimSpace(line) == "--"
+}
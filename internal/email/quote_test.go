@@ -0,0 +1,149 @@
+package email
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSplitBody(t *testing.T) {
+	tests := []struct {
+		name          string
+		body          string
+		wantNew       string
+		wantQuoted    string
+		wantSignature string
+	}{
+		{
+			name:    "plain message",
+			body:    "Hello,\n\nJust checking in.",
+			wantNew: "Hello,\n\nJust checking in.",
+		},
+		{
+			name:       "english attribution",
+			body:       "Sounds good.\n\nOn Mon, Jan 15, 2024 at 10:30 AM John <john@example.com> wrote:\n> Can we meet?\n> Thanks",
+			wantNew:    "Sounds good.",
+			wantQuoted: "On Mon, Jan 15, 2024 at 10:30 AM John <john@example.com> wrote:\n> Can we meet?\n> Thanks",
+		},
+		{
+			name:       "wrapped attribution",
+			body:       "Yes.\n\nOn Mon, Jan 15, 2024 at 10:30 AM John Doe <\njohn@example.com> wrote:\n> Can we meet?",
+			wantNew:    "Yes.",
+			wantQuoted: "On Mon, Jan 15, 2024 at 10:30 AM John Doe <\njohn@example.com> wrote:\n> Can we meet?",
+		},
+		{
+			name:       "spanish attribution",
+			body:       "De acuerdo.\n\nEl lun, 15 ene 2024 a las 10:30, Juan (<juan@example.com>) escribió:\n> ¿Nos vemos?",
+			wantNew:    "De acuerdo.",
+			wantQuoted: "El lun, 15 ene 2024 a las 10:30, Juan (<juan@example.com>) escribió:\n> ¿Nos vemos?",
+		},
+		{
+			name:       "german attribution",
+			body:       "Passt.\n\nAm 15.01.2024 um 10:30 schrieb Hans <hans@example.com>:\n> Treffen?",
+			wantNew:    "Passt.",
+			wantQuoted: "Am 15.01.2024 um 10:30 schrieb Hans <hans@example.com>:\n> Treffen?",
+		},
+		{
+			name:       "outlook original message",
+			body:       "Approved.\n\n-----Original Message-----\nFrom: Jane\nSent: Monday\nSubject: Budget\n\nPlease approve.",
+			wantNew:    "Approved.",
+			wantQuoted: "-----Original Message-----\nFrom: Jane\nSent: Monday\nSubject: Budget\n\nPlease approve.",
+		},
+		{
+			name:       "outlook header block",
+			body:       "Approved.\n\n________________________________\nFrom: Jane <jane@example.com>\nSent: Monday, January 15, 2024 10:30 AM\nTo: Bob\nSubject: Budget\n\nPlease approve.",
+			wantNew:    "Approved.",
+			wantQuoted: "________________________________\nFrom: Jane <jane@example.com>\nSent: Monday, January 15, 2024 10:30 AM\nTo: Bob\nSubject: Budget\n\nPlease approve.",
+		},
+		{
+			name:       "trailing quote block",
+			body:       "Agreed.\n\n> The deploy is tomorrow.\n>\n> Bob",
+			wantNew:    "Agreed.",
+			wantQuoted: "> The deploy is tomorrow.\n>\n> Bob",
+		},
+		{
+			name:       "interleaved reply",
+			body:       "> First question?\nFirst answer.\n> Second question?\nSecond answer.",
+			wantNew:    "First answer.\nSecond answer.",
+			wantQuoted: "> First question?\n> Second question?",
+		},
+		{
+			name:          "signature delimiter",
+			body:          "See you there.\n\n-- \nJane Doe\nACME Corp",
+			wantNew:       "See you there.",
+			wantSignature: "Jane Doe\nACME Corp",
+		},
+		{
+			name:          "signature and quote",
+			body:          "Thanks!\n-- \nJane\n\nOn Tue, Bob wrote:\n> Done.",
+			wantNew:       "Thanks!",
+			wantQuoted:    "On Tue, Bob wrote:\n> Done.",
+			wantSignature: "Jane",
+		},
+		{
+			name:          "mobile signature",
+			body:          "On my way.\n\nSent from my iPhone",
+			wantNew:       "On my way.",
+			wantSignature: "Sent from my iPhone",
+		},
+		{
+			name:    "dashes in text are not a signature",
+			body:    "Totals\n---\n42",
+			wantNew: "Totals\n---\n42",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := SplitBody(tt.body)
+			if got.New != tt.wantNew {
+				t.Errorf("New = %q, want %q", got.New, tt.wantNew)
+			}
+			if got.Quoted != tt.wantQuoted {
+				t.Errorf("Quoted = %q, want %q", got.Quoted, tt.wantQuoted)
+			}
+			if got.Signature != tt.wantSignature {
+				t.Errorf("Signature = %q, want %q", got.Signature, tt.wantSignature)
+			}
+		})
+	}
+}
+
+func TestSplitHTMLBody(t *testing.T) {
+	html := `<div dir="ltr">Sounds good<br><div class="gmail_signature">Jane Doe</div></div>` +
+		`<br><div class="gmail_quote"><div class="gmail_attr">On Mon, John wrote:<br></div>` +
+		`<blockquote class="gmail_quote">Can we meet?</blockquote></div>`
+
+	r := &Reader{}
+	got := r.splitHTMLBody(html)
+
+	if got.New != "Sounds good" {
+		t.Errorf("New = %q, want %q", got.New, "Sounds good")
+	}
+	if got.Signature != "Jane Doe" {
+		t.Errorf("Signature = %q, want %q", got.Signature, "Jane Doe")
+	}
+	if !strings.Contains(got.Quoted, "Can we meet?") {
+		t.Errorf("Quoted = %q, want it to contain the quoted text", got.Quoted)
+	}
+}
+
+func TestSplitHTMLBodyWithoutContainers(t *testing.T) {
+	// A client that quotes in plain divs, without gmail_quote or blockquote
+	html := "<html><body>\n<div>Thursday works for me.</div>\n<div>See you then</div>" +
+		"<div>-- <br>Jane Doe<br>Acme Inc.</div><div><br></div>" +
+		"<div>On Mon, 6 Jan 2025 at 10:00, John &lt;john@example.com&gt; wrote:</div>" +
+		"<div>&gt; Can we meet\nthis week?</div><div>&gt; John</div></body></html>"
+
+	r := &Reader{}
+	got := r.splitHTMLBody(html)
+
+	if want := "Thursday works for me.\nSee you then"; got.New != want {
+		t.Errorf("New = %q, want %q", got.New, want)
+	}
+	if want := "Jane Doe\nAcme Inc."; got.Signature != want {
+		t.Errorf("Signature = %q, want %q", got.Signature, want)
+	}
+	if want := "On Mon, 6 Jan 2025 at 10:00, John <john@example.com> wrote:\n> Can we meet this week?\n> John"; got.Quoted != want {
+		t.Errorf("Quoted = %q, want %q", got.Quoted, want)
+	}
+}
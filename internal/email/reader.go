@@ -2,37 +2,94 @@
 package email
 
 import (
+	"bytes"
 	"fmt"
 	"io"
 	"regexp"
+	"sort"
 	"strings"
 
 	"github.com/GodGMN/ghostmail-cli/internal/config"
+	"github.com/GodGMN/ghostmail-cli/internal/pgp"
+	"github.com/GodGMN/ghostmail-cli/internal/smime"
 	emailtypes "github.com/GodGMN/ghostmail-cli/pkg/email"
 	"github.com/emersion/go-imap"
 	"github.com/emersion/go-imap/client"
+	"github.com/emersion/go-imap/commands"
+	"github.com/emersion/go-imap/responses"
 	"github.com/emersion/go-message/mail"
+	"github.com/emersion/go-sasl"
 )
 
 // Reader handles email reading operations via IMAP.
 type Reader struct {
-	config *config.IMAPConfig
+	config      *config.IMAPConfig
+	keyring     *pgp.Keyring
+	loadKeyring func() (*pgp.Keyring, error)
+	smime       *smime.Store
+	loadSMIME   func() (*smime.Store, error)
+	mechanism   string // Authentication mechanism of the last connection
+}
+
+// ReaderOption configures a Reader.
+type ReaderOption func(*Reader)
+
+// WithKeyring makes ReadMessage decrypt and verify OpenPGP messages and
+// collect keys from Autocrypt headers into the keyring for encryption.
+func WithKeyring(keyring *pgp.Keyring) ReaderOption {
+	return func(r *Reader) {
+		r.keyring = keyring
+	}
+}
+
+// WithSMIMEStore makes ReadMessage decrypt and verify S/MIME messages with the
+// certificates in the store.
+func WithSMIMEStore(store *smime.Store) ReaderOption {
+	return func(r *Reader) {
+		r.smime = store
+	}
+}
+
+// WithKeyringLoader is like WithKeyring, but load is only called, once,
+// when a message is OpenPGP signed or encrypted or has an Autocrypt header,
+// so that the passphrase is not needed to read other messages. If load
+// fails, those messages are read as they are.
+func WithKeyringLoader(load func() (*pgp.Keyring, error)) ReaderOption {
+	return func(r *Reader) {
+		r.loadKeyring = load
+	}
+}
+
+// WithSMIMELoader is like WithSMIMEStore, but load is only called, once,
+// when a message is S/MIME signed or encrypted. If load fails, those
+// messages are read as they are.
+func WithSMIMELoader(load func() (*smime.Store, error)) ReaderOption {
+	return func(r *Reader) {
+		r.loadSMIME = load
+	}
 }
 
 // NewReader creates a new email reader.
-func NewReader(cfg *config.IMAPConfig) *Reader {
-	return &Reader{config: cfg}
+func NewReader(cfg *config.IMAPConfig, opts ...ReaderOption) *Reader {
+	r := &Reader{config: cfg}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
 }
 
 // Connect establishes a connection to the IMAP server.
 func (r *Reader) Connect() (*client.Client, error) {
 	addr := fmt.Sprintf("%s:%d", r.config.Host, r.config.Port)
 
-	var c *client.Client
-	var err error
+	tlsConfig, err := clientTLSConfig(r.config.Host, r.config.ClientCert, r.config.ClientKey)
+	if err != nil {
+		return nil, err
+	}
 
+	var c *client.Client
 	if r.config.UseTLS {
-		c, err = client.DialTLS(addr, nil)
+		c, err = client.DialTLS(addr, tlsConfig)
 	} else {
 		c, err = client.Dial(addr)
 	}
@@ -41,7 +98,7 @@ func (r *Reader) Connect() (*client.Client, error) {
 		return nil, fmt.Errorf("failed to connect to IMAP server: %w", err)
 	}
 
-	if err := c.Login(r.config.Username, r.config.Password); err != nil {
+	if err := r.authenticate(c, c.IsTLS()); err != nil {
 		c.Logout()
 		return nil, fmt.Errorf("failed to login: %w", err)
 	}
@@ -49,6 +106,109 @@ func (r *Reader) Connect() (*client.Client, error) {
 	return c, nil
 }
 
+// Mechanism returns the SASL mechanism the last connection authenticated
+// with, or LOGIN for the IMAP LOGIN command.
+func (r *Reader) Mechanism() string {
+	return r.mechanism
+}
+
+// authenticate authenticates with the configured mechanism among those
+// the server offers, or for auto, with the LOGIN command. LOGIN always
+// means the command, which servers accept without advertising AUTH=LOGIN.
+// encrypted tells whether the connection uses TLS.
+func (r *Reader) authenticate(c *client.Client, encrypted bool) error {
+	caps, err := c.Capability()
+	if err != nil {
+		return err
+	}
+	var offered []string
+	for capability := range caps {
+		if strings.HasPrefix(strings.ToUpper(capability), "AUTH=") {
+			offered = append(offered, strings.ToUpper(capability[len("AUTH="):]))
+		}
+	}
+	// LOGIN is either the IMAP command or, when the server disables the
+	// command, the SASL mechanism if it is offered
+	command := !caps["LOGINDISABLED"]
+	if command && !offers(offered, sasl.Login) {
+		offered = append(offered, sasl.Login)
+	}
+	sort.Strings(offered)
+
+	a := &authenticator{
+		protocol:  "IMAP",
+		mechanism: r.config.Auth,
+		authzID:   r.config.AuthzID,
+		username:  r.config.Username,
+		password:  r.config.Password,
+		host:      r.config.Host,
+		port:      r.config.Port,
+		oauth:     &r.config.OAuth,
+	}
+	mech, err := a.choose(offered, sasl.Login)
+	if err != nil {
+		return err
+	}
+
+	if mech == sasl.Login && command {
+		if err := a.cleartext(mech, encrypted); err != nil {
+			return err
+		}
+		err = c.Login(r.config.Username, r.config.Password)
+	} else {
+		var sc sasl.Client
+		if sc, err = a.client(mech, encrypted); err != nil {
+			return err
+		}
+		if err = c.Authenticate(sc); err != nil {
+			err = a.failed(mech, err)
+		}
+	}
+	if err != nil {
+		return err
+	}
+	r.mechanism = mech
+	return nil
+}
+
+// enableUTF8 enables UTF8=ACCEPT (RFC 6855) if the server supports it, so
+// that addresses with UTF-8 local parts are returned as they are instead of
+// being downgraded. The IMAP client sends mailbox names in modified UTF-7,
+// which servers may refuse once UTF-8 is enabled, so it is only enabled for
+// mailboxes with ASCII names. It reports whether UTF-8 was enabled.
+func (r *Reader) enableUTF8(c *client.Client) bool {
+	if !isASCII(r.config.Mailbox) {
+		return false
+	}
+	if ok, _ := c.Support("UTF8=ACCEPT"); !ok {
+		return false
+	}
+	enabled, err := c.Enable([]string{"UTF8=ACCEPT"})
+	if err != nil {
+		return false
+	}
+	for _, capability := range enabled {
+		if strings.EqualFold(capability, "UTF8=ACCEPT") {
+			return true
+		}
+	}
+	return false
+}
+
+// uidSearch searches the selected mailbox. Once UTF-8 is enabled, a search
+// must not name a charset, which the IMAP client always does.
+func uidSearch(c *client.Client, criteria *imap.SearchCriteria, utf8 bool) ([]uint32, error) {
+	if !utf8 {
+		return c.UidSearch(criteria)
+	}
+	res := new(responses.Search)
+	status, err := c.Execute(&commands.Uid{Cmd: &commands.Search{Criteria: criteria}}, res)
+	if err != nil {
+		return nil, err
+	}
+	return res.Ids, status.Err()
+}
+
 // ListMessages retrieves messages from the inbox.
 func (r *Reader) ListMessages(limit int, unreadOnly bool) ([]emailtypes.Message, error) {
 	c, err := r.Connect()
@@ -56,6 +216,7 @@ func (r *Reader) ListMessages(limit int, unreadOnly bool) ([]emailtypes.Message,
 		return nil, err
 	}
 	defer c.Logout()
+	utf8 := r.enableUTF8(c)
 
 	// Select mailbox
 	mbox, err := c.Select(r.config.Mailbox, false)
@@ -75,14 +236,14 @@ func (r *Reader) ListMessages(limit int, unreadOnly bool) ([]emailtypes.Message,
 
 	var uids []uint32
 	if unreadOnly {
-		uids, err = c.UidSearch(&criteria)
+		uids, err = uidSearch(c, &criteria, utf8)
 		if err != nil {
 			return nil, fmt.Errorf("failed to search messages: %w", err)
 		}
 	} else {
 		// Search for all messages (UIDs are not necessarily sequential)
 		allCriteria := &imap.SearchCriteria{}
-		uids, err = c.UidSearch(allCriteria)
+		uids, err = uidSearch(c, allCriteria, utf8)
 		if err != nil {
 			return nil, fmt.Errorf("failed to search messages: %w", err)
 		}
@@ -132,16 +293,24 @@ func (r *Reader) ListMessages(limit int, unreadOnly bool) ([]emailtypes.Message,
 
 // ReadMessage retrieves a specific message by UID.
 func (r *Reader) ReadMessage(uid uint32) (*emailtypes.Message, error) {
+	msg, _, err := r.ReadMessageRaw(uid)
+	return msg, err
+}
+
+// ReadMessageRaw retrieves a specific message by UID along with its raw
+// RFC 822 content.
+func (r *Reader) ReadMessageRaw(uid uint32) (*emailtypes.Message, []byte, error) {
 	c, err := r.Connect()
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	defer c.Logout()
+	r.enableUTF8(c)
 
 	// Select mailbox
 	_, err = c.Select(r.config.Mailbox, false)
 	if err != nil {
-		return nil, fmt.Errorf("failed to select mailbox: %w", err)
+		return nil, nil, fmt.Errorf("failed to select mailbox: %w", err)
 	}
 
 	// Fetch message
@@ -167,17 +336,34 @@ func (r *Reader) ReadMessage(uid uint32) (*emailtypes.Message, error) {
 	}()
 
 	var result *emailtypes.Message
+	var raw []byte
 	for msg := range messages {
 		emsg := r.convertMessage(msg, true)
 
 		// Extract body and Message-ID
 		if sectionData := msg.GetBody(section); sectionData != nil {
-			body, messageID, err := r.extractBody(sectionData)
+			raw, err = io.ReadAll(sectionData)
 			if err == nil {
-				emsg.Body = body
-				emsg.MessageID = messageID
-				// Create preview
-				emsg.BodyPreview = r.createPreview(body, 200)
+				content, security := r.openPGP(raw)
+				if security == nil {
+					content, security = r.openSMIME(content)
+				}
+				if parsed, err := r.extractBody(bytes.NewReader(content)); err == nil {
+					if security == nil {
+						security = r.openInlinePGP(parsed, pgp.Sender(raw))
+					}
+					emsg.Security = security
+					emsg.Body = parsed.body
+					emsg.MessageID = parsed.messageID
+					emsg.Events = parsed.events
+					if parsed.parts.Quoted != "" || parsed.parts.Signature != "" {
+						emsg.BodyNew = parsed.parts.New
+						emsg.BodyQuoted = parsed.parts.Quoted
+						emsg.Signature = parsed.parts.Signature
+					}
+					// Create preview
+					emsg.BodyPreview = r.createPreview(parsed.body, 200)
+				}
 			}
 		}
 
@@ -185,14 +371,70 @@ func (r *Reader) ReadMessage(uid uint32) (*emailtypes.Message, error) {
 	}
 
 	if err := <-done; err != nil {
-		return nil, fmt.Errorf("failed to fetch message: %w", err)
+		return nil, nil, fmt.Errorf("failed to fetch message: %w", err)
 	}
 
 	if result == nil {
-		return nil, fmt.Errorf("message not found")
+		return nil, nil, fmt.Errorf("message not found")
 	}
 
-	return result, nil
+	return result, raw, nil
+}
+
+// openPGP collects the Autocrypt key of a message and decrypts and
+// verifies it if it is PGP/MIME.
+func (r *Reader) openPGP(raw []byte) ([]byte, *emailtypes.Security) {
+	if (!pgp.IsMIME(raw) && !pgp.HasAutocrypt(raw)) || r.pgpKeyring() == nil {
+		return raw, nil
+	}
+	content, security := r.keyring.Open(raw)
+
+	// The key is collected after verifying, so it cannot vouch for the
+	// message that brought it. A malformed Autocrypt header must not
+	// prevent reading the message.
+	_, _ = r.keyring.ImportAutocrypt(raw)
+	return content, security
+}
+
+// openSMIME decrypts and verifies a message if it is S/MIME.
+func (r *Reader) openSMIME(raw []byte) ([]byte, *emailtypes.Security) {
+	if !smime.IsMIME(raw) || r.smimeStore() == nil {
+		return raw, nil
+	}
+	return r.smime.Open(raw)
+}
+
+// openInlinePGP decrypts or verifies inline PGP in a parsed text body of
+// a message from the given address.
+func (r *Reader) openInlinePGP(parsed *parsedBody, from string) *emailtypes.Security {
+	if !pgp.IsInline(parsed.body) || r.pgpKeyring() == nil {
+		return nil
+	}
+	body, security, ok := r.keyring.OpenInline(parsed.body, from)
+	if !ok {
+		return nil
+	}
+	parsed.body = body
+	parsed.parts = SplitBody(body)
+	return security
+}
+
+// pgpKeyring returns the keyring, loading it on first use.
+func (r *Reader) pgpKeyring() *pgp.Keyring {
+	if r.keyring == nil && r.loadKeyring != nil {
+		r.keyring, _ = r.loadKeyring()
+		r.loadKeyring = nil
+	}
+	return r.keyring
+}
+
+// smimeStore returns the S/MIME certificates, loading them on first use.
+func (r *Reader) smimeStore() *smime.Store {
+	if r.smime == nil && r.loadSMIME != nil {
+		r.smime, _ = r.loadSMIME()
+		r.loadSMIME = nil
+	}
+	return r.smime
 }
 
 // convertMessage converts an IMAP message to our Message type.
@@ -227,33 +469,42 @@ func (r *Reader) convertMessage(msg *imap.Message, fullBody bool) emailtypes.Mes
 	return emsg
 }
 
-// formatAddress formats an IMAP address.
+// formatAddress formats an IMAP address. UTF-8 local parts, as sent with
+// UTF8=ACCEPT, are kept, and punycode domains are shown in UTF-8.
 func (r *Reader) formatAddress(addr *imap.Address) string {
 	if addr == nil {
 		return ""
 	}
-	if addr.PersonalName != "" {
-		return fmt.Sprintf("%s <%s@%s>", addr.PersonalName, addr.MailboxName, addr.HostName)
-	}
-	return fmt.Sprintf("%s@%s", addr.MailboxName, addr.HostName)
+	return Address{Name: addr.PersonalName, Address: addr.MailboxName + "@" + displayDomain(addr.HostName)}.Display()
+}
+
+// parsedBody holds the content extracted from a message body.
+type parsedBody struct {
+	body      string
+	messageID string
+	events    []emailtypes.CalendarEvent
+	parts     BodyParts // body split into new text, quotes and signature
 }
 
-// extractBody extracts the text body and Message-ID from an email message.
-func (r *Reader) extractBody(reader io.Reader) (string, string, error) {
+// extractBody extracts the text body, Message-ID and calendar events from an
+// email message.
+func (r *Reader) extractBody(reader io.Reader) (*parsedBody, error) {
 	mr, err := mail.CreateReader(reader)
 	if err != nil {
 		// Fallback: read raw
 		data, err := io.ReadAll(reader)
 		if err != nil {
-			return "", "", err
+			return nil, err
 		}
-		return string(data), "", nil
+		return &parsedBody{body: string(data)}, nil
 	}
 
+	result := &parsedBody{}
+
 	// Extract Message-ID from headers
-	messageID := mr.Header.Get("Message-Id")
-	if messageID == "" {
-		messageID = mr.Header.Get("Message-ID")
+	result.messageID = mr.Header.Get("Message-Id")
+	if result.messageID == "" {
+		result.messageID = mr.Header.Get("Message-ID")
 	}
 
 	var textBody string
@@ -267,6 +518,15 @@ func (r *Reader) extractBody(reader io.Reader) (string, string, error) {
 			continue
 		}
 
+		// Meeting invitations may arrive inline or as .ics attachments
+		if isCalendarPart(part) {
+			data, _ := io.ReadAll(part.Body)
+			if events, err := ParseCalendar(data); err == nil {
+				result.events = append(result.events, events...)
+			}
+			continue
+		}
+
 		switch h := part.Header.(type) {
 		case *mail.InlineHeader:
 			contentType, _, _ := h.ContentType()
@@ -282,26 +542,30 @@ func (r *Reader) extractBody(reader io.Reader) (string, string, error) {
 
 	// Prefer plain text, fallback to HTML
 	if textBody != "" {
-		return textBody, messageID, nil
-	}
-	if htmlBody != "" {
-		return r.stripHTML(htmlBody), messageID, nil
+		result.body = textBody
+		result.parts = SplitBody(textBody)
+	} else if htmlBody != "" {
+		result.body = r.stripHTML(htmlBody)
+		result.parts = r.splitHTMLBody(htmlBody)
 	}
 
-	return "", messageID, nil
+	return result, nil
 }
 
-// stripHTML removes HTML tags and returns plain text.
+// stripHTML removes HTML tags and returns plain text, keeping the line
+// breaks of <br> and the ends of paragraphs, divs and blockquotes.
 func (r *Reader) stripHTML(html string) string {
 	// Remove script and style elements
-	scriptRe := regexp.MustCompile(`(?i)<(script|style)[^>]*>[\s\S]*?</\1>`)
+	scriptRe := regexp.MustCompile(`(?i)<script[^>]*>[\s\S]*?</script>|<style[^>]*>[\s\S]*?</style>`)
 	html = scriptRe.ReplaceAllString(html, "")
 
-	// Replace <br>, <p> with newlines
-	brRe := regexp.MustCompile(`(?i)<br\s*/?>`)
-	html = brRe.ReplaceAllString(html, "\n")
-	pRe := regexp.MustCompile(`(?i)</p>`)
-	html = pRe.ReplaceAllString(html, "\n")
+	// Line breaks in the source are only spaces
+	sourceWsRe := regexp.MustCompile(`\s+`)
+	html = sourceWsRe.ReplaceAllString(html, " ")
+
+	// Replace <br> and the ends of blocks with newlines
+	breakRe := regexp.MustCompile(`(?i)<br\s*/?>|</(p|div|blockquote)\s*>`)
+	html = breakRe.ReplaceAllString(html, "\n")
 
 	// Remove all HTML tags
 	tagRe := regexp.MustCompile(`<[^>]+>`)
@@ -315,9 +579,14 @@ func (r *Reader) stripHTML(html string) string {
 	html = strings.ReplaceAll(html, "&quot;", "\"")
 	html = strings.ReplaceAll(html, "&#39;", "'")
 
-	// Normalize whitespace
-	wsRe := regexp.MustCompile(`\s+`)
-	html = wsRe.ReplaceAllString(html, " ")
+	// Normalize spaces and tabs and drop runs of blank lines
+	wsRe := regexp.MustCompile(`[ \t]+`)
+	lines := strings.Split(html, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimSpace(wsRe.ReplaceAllString(line, " "))
+	}
+	blankRe := regexp.MustCompile(`\n{3,}`)
+	html = blankRe.ReplaceAllString(strings.Join(lines, "\n"), "\n\n")
 
 	return strings.TrimSpace(html)
 }
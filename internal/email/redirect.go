@@ -0,0 +1,71 @@
+package email
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// Redirect re-submits a raw message to new recipients without altering it.
+// RFC 5322 Resent-* headers are prepended; the original From, body and
+// DKIM-signed headers are left intact.
+func (s *Sender) Redirect(raw []byte, to []string) error {
+	addrs, err := ParseAddressLists(to)
+	if err != nil {
+		return err
+	}
+	addrs = DedupeAddresses(addrs)[0]
+	recipients := make([]string, len(addrs))
+	for i, addr := range addrs {
+		recipients[i] = addr.Envelope()
+	}
+
+	from := s.from()
+	msg := AddResentHeaders(raw, from, FormatAddresses(addrs), time.Now())
+	return s.SendRaw(envelopeAddress(from), recipients, msg)
+}
+
+// AddResentHeaders prepends a Resent-From/To/Date/Message-ID block to a raw
+// message, as described in RFC 5322 section 3.6.6.
+func AddResentHeaders(raw []byte, from string, to []string, date time.Time) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("Resent-From: " + from + "\r\n")
+	buf.WriteString("Resent-To: " + strings.Join(to, ", ") + "\r\n")
+	buf.WriteString("Resent-Date: " + date.Format(time.RFC1123Z) + "\r\n")
+	buf.WriteString("Resent-Message-ID: " + GenerateMessageID(from) + "\r\n")
+	buf.Write(raw)
+	return buf.Bytes()
+}
+
+// GenerateMessageID returns a new unique Message-ID using the domain of the
+// sender address.
+func GenerateMessageID(from string) string {
+	domain := ""
+	if addr := envelopeAddress(from); strings.Contains(addr, "@") {
+		domain = asciiDomain(addr[strings.LastIndex(addr, "@")+1:])
+	}
+	if domain == "" {
+		domain, _ = os.Hostname()
+	}
+	if domain == "" {
+		domain = "localhost"
+	}
+
+	random := make([]byte, 12)
+	rand.Read(random)
+
+	return fmt.Sprintf("<%d.%s@%s>", time.Now().UnixNano(), hex.EncodeToString(random), domain)
+}
+
+// envelopeAddress extracts the bare address from a "Name <addr>" string for
+// use in the SMTP envelope.
+func envelopeAddress(addr string) string {
+	if parsed, err := ParseAddress(addr); err == nil {
+		return parsed.Envelope()
+	}
+	return addr
+}
@@ -0,0 +1,57 @@
+package email
+
+import (
+	"bytes"
+	"net/mail"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAddResentHeaders(t *testing.T) {
+	raw := []byte("From: Alice <alice@example.com>\r\n" +
+		"To: bob@example.com\r\n" +
+		"Subject: Ticket\r\n" +
+		"DKIM-Signature: v=1; a=rsa-sha256; d=example.com\r\n" +
+		"\r\n" +
+		"Please help.\r\n")
+	date := time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC)
+
+	got := AddResentHeaders(raw, "Bot <bot@example.org>", []string{"a@example.com", "b@example.com"}, date)
+
+	if !bytes.HasSuffix(got, raw) {
+		t.Error("AddResentHeaders() must leave the original message unchanged")
+	}
+
+	msg, err := mail.ReadMessage(bytes.NewReader(got))
+	if err != nil {
+		t.Fatalf("result is not a valid message: %v", err)
+	}
+	if v := msg.Header.Get("Resent-From"); v != "Bot <bot@example.org>" {
+		t.Errorf("Resent-From = %q", v)
+	}
+	if v := msg.Header.Get("Resent-To"); v != "a@example.com, b@example.com" {
+		t.Errorf("Resent-To = %q", v)
+	}
+	if v := msg.Header.Get("Resent-Date"); v != "Mon, 15 Jan 2024 10:30:00 +0000" {
+		t.Errorf("Resent-Date = %q", v)
+	}
+	if v := msg.Header.Get("Resent-Message-ID"); !strings.HasSuffix(v, "@example.org>") {
+		t.Errorf("Resent-Message-ID = %q, want domain example.org", v)
+	}
+	if v := msg.Header.Get("From"); v != "Alice <alice@example.com>" {
+		t.Errorf("From = %q, want original sender", v)
+	}
+}
+
+func TestGenerateMessageID(t *testing.T) {
+	a := GenerateMessageID("bot@example.com")
+	b := GenerateMessageID("bot@example.com")
+
+	if a == b {
+		t.Error("GenerateMessageID() should return unique IDs")
+	}
+	if !strings.HasPrefix(a, "<") || !strings.HasSuffix(a, "@example.com>") {
+		t.Errorf("GenerateMessageID() = %q, want <...@example.com>", a)
+	}
+}
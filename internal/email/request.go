@@ -0,0 +1,93 @@
+package email
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	emailtypes "github.com/GodGMN/ghostmail-cli/pkg/email"
+)
+
+// ReadSendRequests decodes SendRequest JSON objects from r: a single
+// object, or several separated by newlines (NDJSON). Unknown fields are
+// rejected so typos do not silently drop data.
+func ReadSendRequests(r io.Reader) ([]emailtypes.SendRequest, error) {
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+
+	var requests []emailtypes.SendRequest
+	for {
+		var req emailtypes.SendRequest
+		err := dec.Decode(&req)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid request %d: %w", len(requests)+1, err)
+		}
+		requests = append(requests, req)
+	}
+	if len(requests) == 0 {
+		return nil, fmt.Errorf("no requests in input")
+	}
+	return requests, nil
+}
+
+// ValidateRequest checks that a request has the fields needed to build a
+// message.
+func ValidateRequest(req *emailtypes.SendRequest) error {
+	if len(req.To) == 0 {
+		return fmt.Errorf("at least one recipient (to) is required")
+	}
+	if req.Subject == "" {
+		return fmt.Errorf("subject is required")
+	}
+	if req.Body == "" && req.HTMLBody == "" {
+		return fmt.Errorf("body or html_body is required")
+	}
+	if req.From != "" {
+		if _, err := ParseAddress(req.From); err != nil {
+			return fmt.Errorf("invalid from: %w", err)
+		}
+	}
+	recipients := []struct {
+		field string
+		addrs []string
+	}{{"to", req.To}, {"cc", req.CC}, {"bcc", req.BCC}}
+	for _, r := range recipients {
+		if _, err := ParseAddressLists(r.addrs); err != nil {
+			return fmt.Errorf("invalid %s: %w", r.field, err)
+		}
+	}
+	for name, value := range req.Headers {
+		if err := ValidateHeader(name, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// BuildRequest validates a request and builds its message.
+func (s *Sender) BuildRequest(req *emailtypes.SendRequest) (*OutgoingMessage, error) {
+	if err := ValidateRequest(req); err != nil {
+		return nil, err
+	}
+
+	opts := []SendOption{
+		WithCC(req.CC),
+		WithBCC(req.BCC),
+		WithAttachments(req.Attachments),
+	}
+	if req.From != "" {
+		opts = append(opts, WithFrom(req.From))
+	}
+	if req.HTMLBody != "" {
+		opts = append(opts, WithHTMLBody(req.HTMLBody))
+	}
+	if len(req.Headers) > 0 {
+		opts = append(opts, WithHeaders(req.Headers))
+	}
+
+	return s.Build(req.To, req.Subject, req.Body, opts...)
+}
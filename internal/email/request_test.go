@@ -0,0 +1,97 @@
+package email
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/GodGMN/ghostmail-cli/internal/config"
+	emailtypes "github.com/GodGMN/ghostmail-cli/pkg/email"
+)
+
+func TestReadSendRequests(t *testing.T) {
+	input := `{"to": ["a@example.com"], "subject": "One", "body": "1"}
+{"to": ["b@example.com"], "subject": "Two", "html_body": "<p>2</p>", "headers": {"X-Ticket": "42"}}
+`
+	requests, err := ReadSendRequests(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("ReadSendRequests() error = %v", err)
+	}
+	if len(requests) != 2 {
+		t.Fatalf("ReadSendRequests() returned %d requests, want 2", len(requests))
+	}
+	if requests[1].HTMLBody != "<p>2</p>" || requests[1].Headers["X-Ticket"] != "42" {
+		t.Errorf("requests[1] = %+v", requests[1])
+	}
+
+	for _, bad := range []string{"", `{"to": ["a@example.com"], "subjct": "typo"}`, `{"to": "a@example.com"}`, `{"to": [`} {
+		if _, err := ReadSendRequests(strings.NewReader(bad)); err == nil {
+			t.Errorf("ReadSendRequests(%q) expected an error", bad)
+		}
+	}
+}
+
+func TestValidateRequest(t *testing.T) {
+	valid := func() emailtypes.SendRequest {
+		return emailtypes.SendRequest{To: []string{"a@example.com"}, Subject: "Hi", Body: "Hello"}
+	}
+
+	tests := []struct {
+		name    string
+		modify  func(*emailtypes.SendRequest)
+		wantErr bool
+	}{
+		{"valid", func(r *emailtypes.SendRequest) {}, false},
+		{"html only", func(r *emailtypes.SendRequest) { r.Body, r.HTMLBody = "", "<p>Hi</p>" }, false},
+		{"custom header", func(r *emailtypes.SendRequest) { r.Headers = map[string]string{"X-Ticket": "42"} }, false},
+		{"no recipient", func(r *emailtypes.SendRequest) { r.To = nil }, true},
+		{"no subject", func(r *emailtypes.SendRequest) { r.Subject = "" }, true},
+		{"no body", func(r *emailtypes.SendRequest) { r.Body = "" }, true},
+		{"invalid from", func(r *emailtypes.SendRequest) { r.From = "not an address" }, true},
+		{"reserved header", func(r *emailtypes.SendRequest) { r.Headers = map[string]string{"bcc": "x@example.com"} }, true},
+		{"header injection", func(r *emailtypes.SendRequest) { r.Headers = map[string]string{"X-A": "1\r\nBcc: x@example.com"} }, true},
+		{"invalid header name", func(r *emailtypes.SendRequest) { r.Headers = map[string]string{"X A": "1"} }, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := valid()
+			tt.modify(&req)
+			err := ValidateRequest(&req)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateRequest() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestBuildRequest(t *testing.T) {
+	sender := NewSender(&config.SMTPConfig{From: "bot@example.com"})
+	req := &emailtypes.SendRequest{
+		From:    "Reports <reports@example.com>",
+		To:      []string{"a@example.com"},
+		BCC:     []string{"audit@example.com"},
+		Subject: "Report",
+		Body:    "See below",
+		Headers: map[string]string{"X-Ticket": "42"},
+	}
+
+	msg, err := sender.BuildRequest(req)
+	if err != nil {
+		t.Fatalf("BuildRequest() error = %v", err)
+	}
+	if msg.From != "reports@example.com" {
+		t.Errorf("From = %q, want the request sender", msg.From)
+	}
+	if strings.Join(msg.Recipients, ",") != "a@example.com,audit@example.com" {
+		t.Errorf("Recipients = %v", msg.Recipients)
+	}
+	data := string(msg.Data)
+	for _, want := range []string{"From: Reports <reports@example.com>", "X-Ticket: 42"} {
+		if !strings.Contains(data, want) {
+			t.Errorf("Data = %q\nwant it to contain %q", data, want)
+		}
+	}
+	if strings.Contains(data, "Bcc:") {
+		t.Error("Data must not contain the Bcc header")
+	}
+}
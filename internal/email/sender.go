@@ -2,17 +2,26 @@
 package email
 
 import (
-	"crypto/tls"
+	"bytes"
+	"crypto"
 	"fmt"
+	"io"
+	"mime"
+	"net/smtp"
 	"strings"
+	"time"
 
 	"github.com/GodGMN/ghostmail-cli/internal/config"
+	"github.com/GodGMN/ghostmail-cli/internal/pgp"
+	"github.com/GodGMN/ghostmail-cli/internal/smime"
 	"gopkg.in/gomail.v2"
 )
 
 // Sender handles email sending operations.
 type Sender struct {
-	config *config.SMTPConfig
+	config    *config.SMTPConfig
+	dkimKey   crypto.Signer // Loaded on first use
+	mechanism string        // Authentication mechanism of the last connection
 }
 
 // NewSender creates a new email sender.
@@ -20,37 +29,104 @@ func NewSender(cfg *config.SMTPConfig) *Sender {
 	return &Sender{config: cfg}
 }
 
+// Mechanism returns the SASL mechanism the last connection authenticated
+// with, or "" if it did not authenticate.
+func (s *Sender) Mechanism() string {
+	return s.mechanism
+}
+
+// OutgoingMessage is a fully built message together with the SMTP envelope
+// it is submitted with.
+type OutgoingMessage struct {
+	From       string   `json:"from"`                 // Envelope sender
+	Recipients []string `json:"recipients"`           // Envelope recipients (To, Cc and Bcc)
+	Bcc        []string `json:"bcc,omitempty"`        // Recipients hidden from the headers
+	MessageID  string   `json:"message_id,omitempty"` // Value of the Message-ID header
+	Data       []byte   `json:"-"`                    // RFC 822 content as submitted
+}
+
+// SetDate replaces the Date header, e.g. with the delivery time of a
+// message built earlier. It must be called before DKIM signing.
+func (m *OutgoingMessage) SetDate(t time.Time) {
+	m.Data = append([]byte("Date: "+t.Format(time.RFC1123Z)+"\r\n"), removeHeader(m.Data, "Date")...)
+}
+
 // Send sends an email message.
 func (s *Sender) Send(to []string, subject, body string, opts ...SendOption) error {
-	if len(to) == 0 {
-		return fmt.Errorf("at least one recipient is required")
+	msg, err := s.Build(to, subject, body, opts...)
+	if err != nil {
+		return err
+	}
+	return s.Submit(msg)
+}
+
+// Build builds the complete MIME message Send would submit, without
+// sending it.
+func (s *Sender) Build(to []string, subject, body string, opts ...SendOption) (*OutgoingMessage, error) {
+	// Apply options
+	options := &sendOptions{}
+	for _, opt := range opts {
+		opt(options)
 	}
 
+	// Parse the recipients, expanding lists and groups, and drop repeated
+	// addresses across To, Cc and Bcc
+	toAddrs, err := ParseAddressLists(to)
+	if err != nil {
+		return nil, err
+	}
+	ccAddrs, err := ParseAddressLists(options.cc)
+	if err != nil {
+		return nil, err
+	}
+	bccAddrs, err := ParseAddressLists(options.bcc)
+	if err != nil {
+		return nil, err
+	}
+	if len(toAddrs) == 0 && len(ccAddrs) == 0 && len(bccAddrs) == 0 {
+		return nil, fmt.Errorf("at least one recipient is required")
+	}
+	lists := DedupeAddresses(toAddrs, ccAddrs, bccAddrs)
+	toAddrs, ccAddrs, bccAddrs = lists[0], lists[1], lists[2]
+
 	m := gomail.NewMessage()
 
-	from := s.config.From
-	if from == "" {
-		from = s.config.Username
+	// Address fields holding UTF-8 local parts are written as they are
+	// (RFC 6532), since gomail would encode them as a whole
+	var utf8Fields []string
+	setAddressHeader := func(field string, values ...string) {
+		if isASCII(strings.Join(values, "")) {
+			m.SetHeader(field, values...)
+		} else {
+			utf8Fields = append(utf8Fields, field+": "+strings.Join(values, ", ")+"\r\n")
+		}
 	}
 
-	m.SetHeader("From", from)
-	m.SetHeader("To", to...)
-	m.SetHeader("Subject", subject)
+	from := s.from()
+	if options.from != "" {
+		from = options.from
+	}
+	if addr, err := ParseAddress(from); err == nil {
+		from = addr.String()
+	}
 
-	// Apply options
-	options := &sendOptions{}
-	for _, opt := range opts {
-		opt(options)
+	setAddressHeader("From", from)
+	if len(toAddrs) > 0 {
+		setAddressHeader("To", FormatAddresses(toAddrs)...)
 	}
+	m.SetHeader("Subject", subject)
+	messageID := GenerateMessageID(from)
+	m.SetHeader("Message-ID", messageID)
+	m.SetDateHeader("Date", time.Now())
 
 	// Set CC recipients
-	if len(options.cc) > 0 {
-		m.SetHeader("Cc", options.cc...)
+	if len(ccAddrs) > 0 {
+		setAddressHeader("Cc", FormatAddresses(ccAddrs)...)
 	}
 
 	// Set BCC recipients
-	if len(options.bcc) > 0 {
-		m.SetHeader("Bcc", options.bcc...)
+	if len(bccAddrs) > 0 {
+		m.SetHeader("Bcc", FormatAddresses(bccAddrs)...)
 	}
 
 	// Set In-Reply-To header for threading
@@ -63,9 +139,26 @@ func (s *Sender) Send(to []string, subject, body string, opts ...SendOption) err
 		m.SetHeader("References", options.references...)
 	}
 
-	// Set custom headers
+	// Set custom headers, unless set by an option below
 	for key, value := range options.headers {
-		m.SetHeader(key, value)
+		if !options.overrides(key) {
+			m.SetHeader(key, value)
+		}
+	}
+
+	// Set Reply-To, priority and read receipt
+	if len(options.replyTo) > 0 {
+		replyTo, err := ParseAddressLists(options.replyTo)
+		if err != nil {
+			return nil, fmt.Errorf("invalid Reply-To: %w", err)
+		}
+		setAddressHeader("Reply-To", FormatAddresses(replyTo)...)
+	}
+	for _, h := range priorityHeaders[options.priority] {
+		m.SetHeader(h[0], h[1])
+	}
+	if options.readReceipt {
+		setAddressHeader("Disposition-Notification-To", from)
 	}
 
 	// Set body content
@@ -78,42 +171,251 @@ func (s *Sender) Send(to []string, subject, body string, opts ...SendOption) err
 		m.SetBody("text/plain", body)
 	}
 
+	// Add meeting invitation or reply as an alternative part
+	if options.calendar != "" {
+		m.AddAlternative("text/calendar; method="+options.calendarMethod, options.calendar)
+	}
+
 	// Attach files
 	for _, attachment := range options.attachments {
 		m.Attach(attachment)
 	}
 
-	// Create dialer
-	d := gomail.NewDialer(s.config.Host, s.config.Port, s.config.Username, s.config.Password)
+	// Attach in-memory files. gomail encodes every attachment in base64,
+	// which RFC 2046 forbids for message/rfc822, so attached messages are
+	// added after the message is built.
+	var messages []AttachmentData
+	for _, att := range options.attachmentData {
+		if strings.EqualFold(att.ContentType, "message/rfc822") {
+			messages = append(messages, att)
+			continue
+		}
+		data := att.Data
+		settings := []gomail.FileSetting{
+			gomail.SetCopyFunc(func(w io.Writer) error {
+				_, err := w.Write(data)
+				return err
+			}),
+		}
+		if att.ContentType != "" {
+			settings = append(settings, gomail.SetHeader(map[string][]string{
+				"Content-Type":        {mime.FormatMediaType(att.ContentType, map[string]string{"name": att.Filename})},
+				"Content-Disposition": {mime.FormatMediaType("attachment", map[string]string{"filename": att.Filename})},
+			}))
+		}
+		m.Attach(att.Filename, settings...)
+	}
 
-	if s.config.UseTLS {
-		d.SSL = true
-	} else if s.config.StartTLS {
-		d.TLSConfig = &tls.Config{ServerName: s.config.Host}
+	var recipients, visible []string
+	for i, list := range lists {
+		for _, addr := range list {
+			recipients = append(recipients, addr.Envelope())
+			if i < 2 {
+				visible = append(visible, addr.Envelope())
+			}
+		}
 	}
 
-	// Send the email
-	if err := d.DialAndSend(m); err != nil {
-		return fmt.Errorf("failed to send email: %w", err)
+	var buf bytes.Buffer
+	if _, err := m.WriteTo(&buf); err != nil {
+		return nil, fmt.Errorf("failed to build email: %w", err)
+	}
+	data := buf.Bytes()
+	if len(messages) > 0 {
+		if data, err = attachMessages(data, messages); err != nil {
+			return nil, err
+		}
+	}
+	if len(utf8Fields) > 0 {
+		data = append([]byte(strings.Join(utf8Fields, "")), data...)
 	}
 
+	// Sign and/or encrypt the content as PGP/MIME or S/MIME. Bcc recipients
+	// are left out: their key IDs or certificates would show up in the
+	// encrypted message
+	if options.pgp != nil {
+		data, err = options.pgp.Protect(data, envelopeAddress(from), visible, options.pgpSign, options.pgpEncrypt)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if options.smime != nil {
+		data, err = options.smime.Protect(data, envelopeAddress(from), visible, options.smimeSign, options.smimeEncrypt)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &OutgoingMessage{
+		From:       envelopeAddress(from),
+		Recipients: recipients,
+		Bcc:        FormatAddresses(bccAddrs),
+		MessageID:  messageID,
+		Data:       data,
+	}, nil
+}
+
+// Submit sends a built message over SMTP. If DKIM signing is configured,
+// msg.Data is replaced with the signed message once it has been sent.
+func (s *Sender) Submit(msg *OutgoingMessage) error {
+	if len(msg.Recipients) == 0 {
+		return fmt.Errorf("at least one recipient is required")
+	}
+
+	session, err := s.Open()
+	if err != nil {
+		return err
+	}
+	defer session.Close()
+
+	return session.Submit(msg)
+}
+
+// Render prepares a built message for submission without sending it: if
+// DKIM signing is configured, msg.Data is replaced with the signed message,
+// so it holds exactly what Submit would send.
+func (s *Sender) Render(msg *OutgoingMessage) error {
+	if len(msg.Recipients) == 0 {
+		return fmt.Errorf("at least one recipient is required")
+	}
+	signed, err := s.sign(msg.Data)
+	if err != nil {
+		return err
+	}
+	msg.Data = signed
+	return nil
+}
+
+// SendRaw submits an already built RFC 822 message, using the given
+// envelope sender and recipients. The message is only changed by DKIM
+// signing, if configured.
+func (s *Sender) SendRaw(from string, to []string, msg []byte) error {
+	if len(to) == 0 {
+		return fmt.Errorf("at least one recipient is required")
+	}
+
+	session, err := s.Open()
+	if err != nil {
+		return err
+	}
+	defer session.Close()
+
+	return session.SendRaw(from, to, msg)
+}
+
+// Session is an open SMTP connection for submitting several messages.
+type Session struct {
+	sender *Sender
+	client *smtp.Client
+}
+
+// Open connects to the SMTP server. The session must be closed after use.
+// Failures are returned as a *ConnectionError.
+func (s *Sender) Open() (*Session, error) {
+	c, err := s.dialSMTP()
+	if err != nil {
+		return nil, &ConnectionError{Err: fmt.Errorf("failed to send email: %w", err)}
+	}
+	return &Session{sender: s, client: c}, nil
+}
+
+// Submit sends a built message over the session. If DKIM signing is
+// configured, msg.Data is replaced with the signed message once it has been
+// sent, so saved copies match what was submitted.
+func (ss *Session) Submit(msg *OutgoingMessage) error {
+	signed, err := ss.send(msg.From, msg.Recipients, msg.Data)
+	if err != nil {
+		return err
+	}
+	msg.Data = signed
+	return nil
+}
+
+// SendRaw sends an already built RFC 822 message over the session.
+func (ss *Session) SendRaw(from string, to []string, msg []byte) error {
+	_, err := ss.send(from, to, msg)
+	return err
+}
+
+// send signs and sends a message, returning the submitted content.
+func (ss *Session) send(from string, to []string, msg []byte) ([]byte, error) {
+	if len(to) == 0 {
+		return nil, fmt.Errorf("at least one recipient is required")
+	}
+	if err := checkSMTPUTF8(ss.client, from, to, msg); err != nil {
+		return nil, err
+	}
+	signed, err := ss.sender.sign(msg)
+	if err != nil {
+		return nil, err
+	}
+
+	// Mail declares SMTPUTF8 itself when the server supports it
+	if err := ss.client.Mail(from); err != nil {
+		ss.client.Reset()
+		return nil, fmt.Errorf("failed to send email: %w", err)
+	}
+	if err := sendData(ss.client, to, signed); err != nil {
+		ss.client.Reset()
+		return nil, fmt.Errorf("failed to send email: %w", err)
+	}
+	return signed, nil
+}
+
+// Close ends the session.
+func (ss *Session) Close() error {
+	if err := ss.client.Quit(); err != nil {
+		return ss.client.Close()
+	}
 	return nil
 }
 
+// from returns the configured sender, defaulting to the SMTP username.
+func (s *Sender) from() string {
+	if s.config.From != "" {
+		return s.config.From
+	}
+	return s.config.Username
+}
+
 // sendOptions holds optional parameters for Send.
 type sendOptions struct {
-	cc          []string
-	bcc         []string
-	htmlBody    string
-	attachments []string
-	headers     map[string]string
-	inReplyTo   string   // Message-ID being replied to
-	references  []string // Chain of Message-IDs for threading
+	from           string // Overrides the configured sender
+	cc             []string
+	bcc            []string
+	htmlBody       string
+	attachments    []string
+	attachmentData []AttachmentData
+	headers        map[string]string
+	inReplyTo      string   // Message-ID being replied to
+	references     []string // Chain of Message-IDs for threading
+
+	calendar       string // iCalendar data sent as text/calendar
+	calendarMethod string // iTIP method of the calendar data
+
+	replyTo     []string
+	priority    Priority
+	readReceipt bool // Request a read receipt (Disposition-Notification-To)
+
+	pgp        *pgp.Keyring
+	pgpSign    bool
+	pgpEncrypt bool
+
+	smime        *smime.Store
+	smimeSign    bool
+	smimeEncrypt bool
 }
 
 // SendOption is a function that configures send options.
 type SendOption func(*sendOptions)
 
+// WithFrom sets the sender instead of the configured one.
+func WithFrom(from string) SendOption {
+	return func(o *sendOptions) {
+		o.from = from
+	}
+}
+
 // WithCC adds CC recipients.
 func WithCC(cc []string) SendOption {
 	return func(o *sendOptions) {
@@ -142,6 +444,13 @@ func WithAttachments(files []string) SendOption {
 	}
 }
 
+// WithAttachmentData adds attachments held in memory.
+func WithAttachmentData(attachments []AttachmentData) SendOption {
+	return func(o *sendOptions) {
+		o.attachmentData = attachments
+	}
+}
+
 // WithHeaders adds custom headers.
 func WithHeaders(headers map[string]string) SendOption {
 	return func(o *sendOptions) {
@@ -163,6 +472,35 @@ func WithReferences(refs []string) SendOption {
 	}
 }
 
+// WithCalendar adds an iCalendar part (e.g. a meeting invitation or reply)
+// with the given iTIP method.
+func WithCalendar(method string, data []byte) SendOption {
+	return func(o *sendOptions) {
+		o.calendarMethod = method
+		o.calendar = string(data)
+	}
+}
+
+// WithOpenPGP signs and/or encrypts the message as PGP/MIME with keys from
+// the keyring.
+func WithOpenPGP(keyring *pgp.Keyring, sign, encrypt bool) SendOption {
+	return func(o *sendOptions) {
+		o.pgp = keyring
+		o.pgpSign = sign
+		o.pgpEncrypt = encrypt
+	}
+}
+
+// WithSMIME signs and/or encrypts the message as S/MIME with certificates
+// from the store.
+func WithSMIME(store *smime.Store, sign, encrypt bool) SendOption {
+	return func(o *sendOptions) {
+		o.smime = store
+		o.smimeSign = sign
+		o.smimeEncrypt = encrypt
+	}
+}
+
 // FormatQuotedReply formats a reply body with proper quoting.
 // Returns: replyBody + attribution + quoted original
 func FormatQuotedReply(replyBody, originalBody, from, date string) string {
@@ -0,0 +1,134 @@
+package email
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/GodGMN/ghostmail-cli/internal/config"
+	"github.com/GodGMN/ghostmail-cli/internal/pgp"
+	emailtypes "github.com/GodGMN/ghostmail-cli/pkg/email"
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+)
+
+func TestBuildOpenPGP(t *testing.T) {
+	key, err := openpgp.NewEntity("Reports", "", "reports@example.com", &packet.Config{Algorithm: packet.PubKeyAlgoEdDSA})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := key.SerializePrivateWithoutSigning(&buf, nil); err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "reports.gpg"), buf.Bytes(), 0600); err != nil {
+		t.Fatal(err)
+	}
+	keyring, err := pgp.Load(dir, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := NewSender(&config.SMTPConfig{From: "Reports <reports@example.com>"})
+	msg, err := s.Build([]string{"reports@example.com"}, "Numbers", "Revenue is up.",
+		WithHTMLBody("<p>Revenue is <b>up</b>.</p>"),
+		WithOpenPGP(keyring, true, true))
+	if err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+	if strings.Contains(string(msg.Data), "Revenue") || !strings.Contains(string(msg.Data), "Subject: Numbers") {
+		t.Fatalf("Build() did not encrypt only the content:\n%s", msg.Data)
+	}
+
+	r := NewReader(&config.IMAPConfig{}, WithKeyring(keyring))
+	content, sec := r.openPGP(msg.Data)
+	if sec == nil || !sec.Decrypted || sec.SignatureStatus != emailtypes.SignatureGood {
+		t.Fatalf("openPGP() security = %+v", sec)
+	}
+	parsed, err := r.extractBody(bytes.NewReader(content))
+	if err != nil {
+		t.Fatalf("extractBody() error = %v", err)
+	}
+	if parsed.body != "Revenue is up." || parsed.messageID != msg.MessageID {
+		t.Errorf("extractBody() = %q, %q", parsed.body, parsed.messageID)
+	}
+
+	// Recipients without a key are reported
+	if _, err := s.Build([]string{"ann@example.org"}, "Numbers", "Revenue is up.", WithOpenPGP(keyring, false, true)); err == nil || !strings.Contains(err.Error(), "ann@example.org") {
+		t.Errorf("Build() to a recipient without a key error = %v", err)
+	}
+}
+
+func TestReaderKeyringLoader(t *testing.T) {
+	loads := 0
+	r := NewReader(&config.IMAPConfig{}, WithKeyringLoader(func() (*pgp.Keyring, error) {
+		loads++
+		return nil, errors.New("passphrase command failed")
+	}))
+
+	// Plain messages are read without loading the keyring
+	plain := []byte("From: ann@example.com\r\nSubject: Hi\r\nContent-Type: text/plain\r\n\r\nHello -----BEGIN PGP nothing\r\n")
+	if content, sec := r.openPGP(plain); sec != nil || !bytes.Equal(content, plain) {
+		t.Errorf("openPGP() of a plain message = %q, %+v", content, sec)
+	}
+	if sec := r.openInlinePGP(&parsedBody{body: "Hello"}, "ann@example.com"); sec != nil {
+		t.Errorf("openInlinePGP() of a plain body = %+v", sec)
+	}
+	if loads != 0 {
+		t.Fatalf("the keyring was loaded %d times for a plain message", loads)
+	}
+
+	// Signed messages load it once, and are read as they are if that fails
+	signed := []byte("From: ann@example.com\r\nContent-Type: multipart/signed; protocol=\"application/pgp-signature\"; boundary=b\r\n\r\n--b\r\n\r\nHi\r\n--b--\r\n")
+	for i := 0; i < 2; i++ {
+		if content, sec := r.openPGP(signed); sec != nil || !bytes.Equal(content, signed) {
+			t.Errorf("openPGP() without a keyring = %q, %+v", content, sec)
+		}
+	}
+	if loads != 1 {
+		t.Errorf("the keyring was loaded %d times, want once", loads)
+	}
+}
+
+func TestRender(t *testing.T) {
+	key, _, err := GenerateDKIMKey(DKIMKeyEd25519, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := NewSender(&config.SMTPConfig{
+		From: "reports@example.com",
+		DKIM: config.DKIMConfig{Selector: "mail", Key: string(key)},
+	})
+	msg, err := s.Build([]string{"ann@example.org"}, "Numbers", "Revenue is up.", WithBCC([]string{"audit@example.com"}))
+	if err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+
+	if err := s.Render(msg); err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	if !bytes.HasPrefix(msg.Data, []byte("DKIM-Signature:")) {
+		t.Errorf("Render() did not sign the message:\n%s", msg.Data)
+	}
+	if bytes.Contains(msg.Data, []byte("audit@example.com")) {
+		t.Error("Render() message reveals the Bcc recipient")
+	}
+	if strings.Join(msg.Recipients, ",") != "ann@example.org,audit@example.com" {
+		t.Errorf("Render() recipients = %v", msg.Recipients)
+	}
+}
+
+func TestSetDate(t *testing.T) {
+	msg := &OutgoingMessage{Data: []byte("From: bot@example.com\r\nDate: Mon, 01 Jan 2024 10:00:00 +0000\r\nSubject: Hi\r\n\r\nDate: body text\r\n")}
+	msg.SetDate(time.Date(2024, 6, 1, 9, 0, 0, 0, time.UTC))
+
+	want := "Date: Sat, 01 Jun 2024 09:00:00 +0000\r\nFrom: bot@example.com\r\nSubject: Hi\r\n\r\nDate: body text\r\n"
+	if string(msg.Data) != want {
+		t.Errorf("SetDate() data = %q, want %q", msg.Data, want)
+	}
+}
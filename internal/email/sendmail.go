@@ -0,0 +1,246 @@
+package email
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"net/mail"
+	"strings"
+	"time"
+)
+
+// SendmailOptions holds the sendmail command-line options that ghostmail
+// honours.
+type SendmailOptions struct {
+	ExtractRecipients bool     // -t: read recipients from To, Cc and Bcc
+	From              string   // -f or -r: envelope sender
+	FullName          string   // -F: sender name for a generated From header
+	IgnoreDots        bool     // -i or -oi: a line with a single dot does not end the input
+	Verbose           bool     // -v
+	Recipients        []string // Recipient arguments
+}
+
+// sendmailArgOptions are the sendmail options that take an argument but
+// have no effect here.
+const sendmailArgOptions = "BCNOQRVXhLp"
+
+// sendmailFlagOptions are the sendmail options without an argument that
+// have no effect here.
+const sendmailFlagOptions = "GUmn"
+
+// ParseSendmailArgs parses sendmail command-line arguments. Options can be
+// given separately ("-f addr") or attached ("-faddr"), and options without
+// an argument can be clustered ("-ti"), as with getopt. Queue and daemon
+// modes (-bp, -bd, -q, ...) are not supported.
+func ParseSendmailArgs(args []string) (*SendmailOptions, error) {
+	opts := &SendmailOptions{}
+
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			opts.Recipients = append(opts.Recipients, args[i+1:]...)
+			break
+		}
+		if !strings.HasPrefix(arg, "-") || arg == "-" {
+			opts.Recipients = append(opts.Recipients, arg)
+			continue
+		}
+
+		for j := 1; j < len(arg); j++ {
+			name, value := arg[j], arg[j+1:]
+
+			// Options without an argument may be followed by more options
+			switch {
+			case name == 't':
+				opts.ExtractRecipients = true
+				continue
+			case name == 'i':
+				opts.IgnoreDots = true
+				continue
+			case name == 'v':
+				opts.Verbose = true
+				continue
+			case strings.IndexByte(sendmailFlagOptions, name) >= 0:
+				continue
+			case strings.IndexByte("fFrob"+sendmailArgOptions, name) < 0:
+				return nil, fmt.Errorf("unsupported option -%c in %s", name, arg)
+			}
+
+			// The others take the rest of the argument, or the next one
+			if value == "" {
+				if i+1 >= len(args) {
+					return nil, fmt.Errorf("option -%c requires an argument", name)
+				}
+				i++
+				value = args[i]
+			}
+
+			switch {
+			case name == 'f' || name == 'r':
+				opts.From = value
+			case name == 'F':
+				opts.FullName = value
+			case name == 'o':
+				// -oi is -i; other -o options configure the local MTA
+				if value == "i" {
+					opts.IgnoreDots = true
+				}
+			case name == 'b':
+				if value != "m" {
+					return nil, fmt.Errorf("mode -b%s is not supported (only sending, -bm)", value)
+				}
+			}
+			break
+		}
+	}
+
+	return opts, nil
+}
+
+// ReadSendmailMessage reads a message from sendmail input. Unless
+// ignoreDots is set, a line with a single dot ends the message, like
+// sendmail does without -i.
+func ReadSendmailMessage(r io.Reader, ignoreDots bool) ([]byte, error) {
+	if ignoreDots {
+		return io.ReadAll(r)
+	}
+
+	var buf bytes.Buffer
+	br := bufio.NewReader(r)
+	for {
+		line, err := br.ReadString('\n')
+		if strings.TrimRight(line, "\r\n") == "." {
+			break
+		}
+		buf.WriteString(line)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+// PrepareSendmailMessage turns a message read by sendmail into a message
+// ready for submission. Recipients are the argument addresses plus, with
+// -t, those in the To, Cc and Bcc headers. Bcc headers are removed, and
+// missing From, Date and Message-ID headers are added. The envelope sender
+// is -f, or defaultFrom.
+func PrepareSendmailMessage(raw []byte, opts *SendmailOptions, defaultFrom string, now time.Time) (*OutgoingMessage, error) {
+	raw = normalizeSendmailInput(raw)
+
+	m, err := mail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		return nil, fmt.Errorf("invalid message: %w", err)
+	}
+
+	from := opts.From
+	if from == "" {
+		from = defaultFrom
+	}
+	if from == "" {
+		return nil, fmt.Errorf("no sender address (use -f or set GHOSTMAIL_SMTP_FROM)")
+	}
+
+	msg := &OutgoingMessage{From: envelopeAddress(from)}
+	var args, headers, bcc []Address
+	for _, arg := range opts.Recipients {
+		addrs, err := ParseAddressList(arg)
+		if err != nil {
+			return nil, fmt.Errorf("invalid recipient: %w", err)
+		}
+		args = append(args, addrs...)
+	}
+	if opts.ExtractRecipients {
+		for _, field := range []string{"To", "Cc", "Bcc"} {
+			value := strings.Join(m.Header[field], ", ")
+			if value == "" {
+				continue
+			}
+			addrs, err := ParseAddressList(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s header: %w", field, err)
+			}
+			if field == "Bcc" {
+				bcc = append(bcc, addrs...)
+			} else {
+				headers = append(headers, addrs...)
+			}
+		}
+	}
+	for _, list := range DedupeAddresses(args, headers, bcc) {
+		for _, addr := range list {
+			msg.Recipients = append(msg.Recipients, addr.Envelope())
+		}
+	}
+	msg.Bcc = FormatAddresses(bcc)
+	if len(msg.Recipients) == 0 {
+		return nil, fmt.Errorf("no recipients (give addresses or use -t)")
+	}
+
+	// Headers a local MTA would add
+	var extra bytes.Buffer
+	if m.Header.Get("From") == "" {
+		addr := mail.Address{Name: opts.FullName, Address: msg.From}
+		extra.WriteString("From: " + addr.String() + "\r\n")
+	}
+	if m.Header.Get("Date") == "" {
+		extra.WriteString("Date: " + now.Format(time.RFC1123Z) + "\r\n")
+	}
+	msg.MessageID = m.Header.Get("Message-Id")
+	if msg.MessageID == "" {
+		msg.MessageID = GenerateMessageID(msg.From)
+		extra.WriteString("Message-ID: " + msg.MessageID + "\r\n")
+	}
+
+	msg.Data = append(extra.Bytes(), removeHeader(raw, "Bcc")...)
+	return msg, nil
+}
+
+// normalizeSendmailInput converts line endings to CRLF and makes sure the
+// header section is terminated, so input without headers (e.g. from
+// "echo text | sendmail addr") becomes a message with an empty header.
+func normalizeSendmailInput(raw []byte) []byte {
+	text := strings.ReplaceAll(string(raw), "\r\n", "\n")
+	lines := strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+
+	var out strings.Builder
+	inHeader := true
+	for i, line := range lines {
+		if inHeader {
+			switch {
+			case line == "":
+				inHeader = false
+			case (line[0] == ' ' || line[0] == '\t') && i > 0:
+				// Folded continuation of the previous field
+			case !isHeaderLine(line):
+				// The body starts without a blank line
+				out.WriteString("\r\n")
+				inHeader = false
+			}
+		}
+		out.WriteString(line + "\r\n")
+	}
+	if inHeader {
+		out.WriteString("\r\n")
+	}
+	return []byte(out.String())
+}
+
+// isHeaderLine reports whether a line starts a header field: a name of
+// printable characters other than the colon, followed by a colon.
+func isHeaderLine(line string) bool {
+	colon := strings.IndexByte(line, ':')
+	if colon <= 0 {
+		return false
+	}
+	for _, c := range line[:colon] {
+		if c <= ' ' || c > '~' {
+			return false
+		}
+	}
+	return true
+}
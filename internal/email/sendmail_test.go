@@ -0,0 +1,150 @@
+package email
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseSendmailArgs(t *testing.T) {
+	tests := []struct {
+		args []string
+		want SendmailOptions
+	}{
+		{
+			args: []string{"-t"},
+			want: SendmailOptions{ExtractRecipients: true},
+		},
+		{
+			args: []string{"-oi", "-oem", "-f", "cron@example.com", "ops@example.com"},
+			want: SendmailOptions{IgnoreDots: true, From: "cron@example.com", Recipients: []string{"ops@example.com"}},
+		},
+		{
+			args: []string{"-i", "-rbot@example.com", "-FBackup Bot", "-N", "never", "-bm", "a@example.com", "b@example.com"},
+			want: SendmailOptions{IgnoreDots: true, From: "bot@example.com", FullName: "Backup Bot", Recipients: []string{"a@example.com", "b@example.com"}},
+		},
+		{
+			args: []string{"-v", "--", "-odd@example.com"},
+			want: SendmailOptions{Verbose: true, Recipients: []string{"-odd@example.com"}},
+		},
+		{
+			args: []string{"-ti"},
+			want: SendmailOptions{ExtractRecipients: true, IgnoreDots: true},
+		},
+		{
+			args: []string{"-oi", "-t"},
+			want: SendmailOptions{ExtractRecipients: true, IgnoreDots: true},
+		},
+		{
+			args: []string{"-itf", "bot@example.com", "-vFBot", "a@example.com"},
+			want: SendmailOptions{ExtractRecipients: true, IgnoreDots: true, Verbose: true, From: "bot@example.com", FullName: "Bot", Recipients: []string{"a@example.com"}},
+		},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseSendmailArgs(tt.args)
+		if err != nil {
+			t.Errorf("ParseSendmailArgs(%q) error = %v", tt.args, err)
+			continue
+		}
+		if !reflect.DeepEqual(*got, tt.want) {
+			t.Errorf("ParseSendmailArgs(%q) = %+v, want %+v", tt.args, *got, tt.want)
+		}
+	}
+
+	for _, args := range [][]string{{"-bp"}, {"-q"}, {"-f"}, {"-x"}, {"-tx"}, {"-tibp"}, {"-ti", "-f"}} {
+		if _, err := ParseSendmailArgs(args); err == nil {
+			t.Errorf("ParseSendmailArgs(%q) expected an error", args)
+		}
+	}
+}
+
+func TestReadSendmailMessage(t *testing.T) {
+	input := "Subject: x\n\nline\n.\nafter\n"
+
+	got, err := ReadSendmailMessage(strings.NewReader(input), false)
+	if err != nil {
+		t.Fatalf("ReadSendmailMessage() error = %v", err)
+	}
+	if string(got) != "Subject: x\n\nline\n" {
+		t.Errorf("ReadSendmailMessage() = %q, want input up to the dot", got)
+	}
+
+	got, err = ReadSendmailMessage(strings.NewReader(input), true)
+	if err != nil {
+		t.Fatalf("ReadSendmailMessage() error = %v", err)
+	}
+	if string(got) != input {
+		t.Errorf("ReadSendmailMessage() with -i = %q, want all input", got)
+	}
+}
+
+func TestPrepareSendmailMessage(t *testing.T) {
+	now := time.Date(2024, 6, 1, 9, 0, 0, 0, time.UTC)
+	raw := []byte("To: Ops <ops@example.com>\n" +
+		"Bcc: audit@example.com,\n" +
+		" OPS@example.com\n" +
+		"Subject: Disk full\n" +
+		"\n" +
+		"/var is at 98%\n")
+
+	msg, err := PrepareSendmailMessage(raw, &SendmailOptions{ExtractRecipients: true, Recipients: []string{"extra@example.com"}}, "Host <host@example.com>", now)
+	if err != nil {
+		t.Fatalf("PrepareSendmailMessage() error = %v", err)
+	}
+
+	if msg.From != "host@example.com" {
+		t.Errorf("From = %q, want the bare default sender", msg.From)
+	}
+	wantRcpts := []string{"extra@example.com", "ops@example.com", "audit@example.com"}
+	if !reflect.DeepEqual(msg.Recipients, wantRcpts) {
+		t.Errorf("Recipients = %v, want %v", msg.Recipients, wantRcpts)
+	}
+
+	data := string(msg.Data)
+	if strings.Contains(data, "Bcc:") || strings.Contains(data, "OPS@") {
+		t.Errorf("Data still contains the Bcc header:\n%s", data)
+	}
+	for _, want := range []string{
+		"From: <host@example.com>\r\n",
+		"Date: Sat, 01 Jun 2024 09:00:00 +0000\r\n",
+		"Message-ID: " + msg.MessageID + "\r\n",
+		"Subject: Disk full\r\n\r\n/var is at 98%\r\n",
+	} {
+		if !strings.Contains(data, want) {
+			t.Errorf("Data = %q\nwant it to contain %q", data, want)
+		}
+	}
+}
+
+func TestPrepareSendmailMessageWithoutHeaders(t *testing.T) {
+	raw := []byte("backup done: 3 files\nsecond line\n")
+	opts := &SendmailOptions{From: "cron@example.com", FullName: "Cron Daemon", Recipients: []string{"root@example.com"}}
+
+	msg, err := PrepareSendmailMessage(raw, opts, "", time.Now())
+	if err != nil {
+		t.Fatalf("PrepareSendmailMessage() error = %v", err)
+	}
+	data := string(msg.Data)
+	if !strings.Contains(data, `From: "Cron Daemon" <cron@example.com>`) {
+		t.Errorf("Data = %q, want a From header with the -F name", data)
+	}
+	if !strings.HasSuffix(data, "\r\n\r\nbackup done: 3 files\r\nsecond line\r\n") {
+		t.Errorf("Data = %q, want the input as the body", data)
+	}
+}
+
+func TestPrepareSendmailMessageErrors(t *testing.T) {
+	raw := []byte("Subject: x\n\nbody\n")
+
+	if _, err := PrepareSendmailMessage(raw, &SendmailOptions{}, "host@example.com", time.Now()); err == nil {
+		t.Error("PrepareSendmailMessage() without recipients expected an error")
+	}
+	if _, err := PrepareSendmailMessage(raw, &SendmailOptions{Recipients: []string{"a@example.com"}}, "", time.Now()); err == nil {
+		t.Error("PrepareSendmailMessage() without a sender expected an error")
+	}
+	if _, err := PrepareSendmailMessage(raw, &SendmailOptions{Recipients: []string{"not an address"}}, "host@example.com", time.Now()); err == nil {
+		t.Error("PrepareSendmailMessage() with an invalid recipient expected an error")
+	}
+}
@@ -0,0 +1,89 @@
+package email
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/emersion/go-imap"
+	"github.com/emersion/go-imap/client"
+)
+
+// SaveSent stores a copy of a submitted message, marked \Seen, in the Sent
+// mailbox and returns the name of that mailbox. The mailbox is the
+// configured SentMailbox or the one with the SPECIAL-USE \Sent attribute.
+//
+// Providers that file sent mail themselves (Gmail) are skipped: SaveSent
+// then returns an empty mailbox name and no error.
+func (r *Reader) SaveSent(msg *OutgoingMessage) (string, error) {
+	sent, err := r.OpenSent()
+	if err != nil {
+		return "", err
+	}
+	defer sent.Close()
+
+	return sent.Save(msg)
+}
+
+// SentMailbox is an open IMAP connection for saving copies of several sent
+// messages.
+type SentMailbox struct {
+	client *client.Client
+	name   string // Empty if the provider files sent mail itself
+}
+
+// OpenSent connects to the IMAP server and finds the Sent mailbox, as
+// SaveSent does. The mailbox must be closed after use.
+func (r *Reader) OpenSent() (*SentMailbox, error) {
+	c, err := r.Connect()
+	if err != nil {
+		return nil, err
+	}
+
+	sent := &SentMailbox{client: c}
+	if ok, _ := c.Support("X-GM-EXT-1"); ok {
+		return sent, nil
+	}
+
+	sent.name = r.config.SentMailbox
+	if sent.name == "" {
+		sent.name, err = findSpecialMailbox(c, imap.SentAttr)
+		if err != nil {
+			c.Logout()
+			return nil, err
+		}
+	}
+	return sent, nil
+}
+
+// Save stores a copy of a submitted message, marked \Seen, and returns the
+// name of the mailbox, or "" if the provider files sent mail itself. The
+// copy has the Bcc header the submitted message leaves out, so the sender
+// can still see who was Bcc'd.
+func (s *SentMailbox) Save(msg *OutgoingMessage) (string, error) {
+	if s.name == "" {
+		return "", nil
+	}
+	if err := s.client.Append(s.name, []string{imap.SeenFlag}, time.Now(), bytes.NewBuffer(withBccHeader(msg))); err != nil {
+		return "", fmt.Errorf("failed to save sent message to %s: %w", s.name, err)
+	}
+	return s.name, nil
+}
+
+// Close logs out and closes the connection.
+func (s *SentMailbox) Close() error {
+	return s.client.Logout()
+}
+
+// withBccHeader returns the content of msg with a Bcc header listing its
+// Bcc recipients, for copies only the sender sees.
+func withBccHeader(msg *OutgoingMessage) []byte {
+	if len(msg.Bcc) == 0 {
+		return msg.Data
+	}
+	var buf bytes.Buffer
+	buf.WriteString("Bcc: " + strings.Join(msg.Bcc, ", ") + "\r\n")
+	buf.Write(msg.Data)
+	return buf.Bytes()
+}
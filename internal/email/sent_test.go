@@ -0,0 +1,69 @@
+package email
+
+import (
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/GodGMN/ghostmail-cli/internal/config"
+	"github.com/emersion/go-imap/backend/memory"
+	"github.com/emersion/go-imap/server"
+)
+
+func TestSentMailbox(t *testing.T) {
+	be := memory.New()
+	user, err := be.Login(nil, "username", "password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := user.CreateMailbox("Sent"); err != nil {
+		t.Fatal(err)
+	}
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := server.New(be)
+	s.AllowInsecureAuth = true
+	go s.Serve(l)
+	defer s.Close()
+
+	addr := l.Addr().(*net.TCPAddr)
+	r := NewReader(&config.IMAPConfig{Host: "127.0.0.1", Port: addr.Port, Username: "username", Password: "password", SentMailbox: "Sent"})
+	sent, err := r.OpenSent()
+	if err != nil {
+		t.Fatalf("OpenSent() error = %v", err)
+	}
+	defer sent.Close()
+
+	// Several copies are saved over the same connection
+	data := "From: bot@example.com\r\nSubject: Two\r\n\r\nHi\r\n"
+	msgs := []*OutgoingMessage{
+		{Data: []byte("From: bot@example.com\r\nSubject: One\r\n\r\nHi\r\n")},
+		{Bcc: []string{"boss@example.com"}, Data: []byte(data)},
+	}
+	for _, msg := range msgs {
+		if mailbox, err := sent.Save(msg); err != nil || mailbox != "Sent" {
+			t.Fatalf("Save() = %q, %v, want Sent", mailbox, err)
+		}
+	}
+	if string(msgs[1].Data) != data {
+		t.Errorf("Save() changed the submitted data to %q", msgs[1].Data)
+	}
+
+	mailbox, err := user.GetMailbox("Sent")
+	if err != nil {
+		t.Fatal(err)
+	}
+	saved := mailbox.(*memory.Mailbox).Messages
+	if len(saved) != 2 {
+		t.Fatalf("Sent holds %d messages, want 2", len(saved))
+	}
+	if !strings.HasPrefix(string(saved[1].Body), "Bcc: boss@example.com\r\n") {
+		t.Errorf("saved copy = %q, want the Bcc header", saved[1].Body)
+	}
+	if strings.Contains(string(saved[0].Body), "Bcc:") {
+		t.Errorf("saved copy = %q, want no Bcc header", saved[0].Body)
+	}
+}
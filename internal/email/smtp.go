@@ -0,0 +1,240 @@
+package email
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/GodGMN/ghostmail-cli/internal/config"
+	"github.com/GodGMN/ghostmail-cli/internal/mimeutil"
+	"github.com/emersion/go-sasl"
+)
+
+// ErrFutureReleaseUnsupported is returned by SubmitAt when the server cannot
+// hold the message until the requested time.
+var ErrFutureReleaseUnsupported = errors.New("server does not support FUTURERELEASE for this release time")
+
+// ErrSMTPUTF8Unsupported is returned when a message with UTF-8 addresses is
+// sent to a server that does not support SMTPUTF8.
+var ErrSMTPUTF8Unsupported = errors.New("server does not support internationalized addresses (SMTPUTF8)")
+
+// addressFields are the header fields checked for UTF-8 addresses.
+var addressFields = []string{"From", "Sender", "Reply-To", "To", "Cc", "Resent-From", "Resent-To", "Resent-Cc"}
+
+// SubmitAt submits a built message using the SMTP FUTURERELEASE extension
+// (RFC 4865), so the server holds it and delivers it at the given time.
+// It returns ErrFutureReleaseUnsupported if the server does not advertise
+// the extension or the time is beyond the server's maximum.
+func (s *Sender) SubmitAt(msg *OutgoingMessage, at time.Time) error {
+	if len(msg.Recipients) == 0 {
+		return fmt.Errorf("at least one recipient is required")
+	}
+
+	signed, err := s.sign(msg.Data)
+	if err != nil {
+		return err
+	}
+
+	c, err := s.dialSMTP()
+	if err != nil {
+		return &ConnectionError{Err: fmt.Errorf("failed to send email: %w", err)}
+	}
+	defer c.Close()
+
+	ok, params := c.Extension("FUTURERELEASE")
+	if !ok || !futureReleaseAllowed(params, time.Now(), at) {
+		c.Quit()
+		return ErrFutureReleaseUnsupported
+	}
+	if err := checkSMTPUTF8(c, msg.From, msg.Recipients, signed); err != nil {
+		c.Quit()
+		return err
+	}
+
+	// net/smtp cannot add parameters to MAIL FROM, so it is sent directly
+	mailFrom := fmt.Sprintf("MAIL FROM:<%s> HOLDUNTIL=%s", msg.From, at.UTC().Format(time.RFC3339))
+	if ok, _ := c.Extension("8BITMIME"); ok {
+		mailFrom += " BODY=8BITMIME"
+	}
+	if ok, _ := c.Extension("SMTPUTF8"); ok {
+		mailFrom += " SMTPUTF8"
+	}
+	if err := c.Text.PrintfLine("%s", mailFrom); err != nil {
+		return fmt.Errorf("failed to send email: %w", err)
+	}
+	if _, _, err := c.Text.ReadResponse(250); err != nil {
+		return fmt.Errorf("failed to send email: %w", err)
+	}
+
+	if err := sendData(c, msg.Recipients, signed); err != nil {
+		return fmt.Errorf("failed to send email: %w", err)
+	}
+	msg.Data = signed
+	return c.Quit()
+}
+
+// futureReleaseAllowed reports whether a release time is within the limits
+// advertised with FUTURERELEASE: the maximum interval in seconds and the
+// latest release date-time.
+func futureReleaseAllowed(params string, now, at time.Time) bool {
+	fields := strings.Fields(params)
+	if len(fields) < 2 {
+		return false
+	}
+
+	maxInterval, err := strconv.ParseInt(fields[0], 10, 64)
+	if err != nil {
+		return false
+	}
+	maxDate, err := time.Parse(time.RFC3339, fields[1])
+	if err != nil {
+		return false
+	}
+
+	return at.Sub(now) <= time.Duration(maxInterval)*time.Second && !at.After(maxDate)
+}
+
+// checkSMTPUTF8 fails if the message needs SMTPUTF8 (RFC 6531) and the
+// server does not support it.
+func checkSMTPUTF8(c *smtp.Client, from string, to []string, msg []byte) error {
+	if ok, _ := c.Extension("SMTPUTF8"); ok {
+		return nil
+	}
+	return requireASCII(from, to, msg)
+}
+
+// requireASCII fails with ErrSMTPUTF8Unsupported if the envelope or the
+// address fields of a message hold UTF-8 local parts. Internationalized
+// domains alone are sent as punycode and need no extension.
+func requireASCII(from string, to []string, msg []byte) error {
+	var international []string
+	for _, addr := range append([]string{from}, to...) {
+		if !isASCII(addr) {
+			international = append(international, addr)
+		}
+	}
+	if len(international) > 0 {
+		return fmt.Errorf("cannot send to or from %s: %w", strings.Join(international, ", "), ErrSMTPUTF8Unsupported)
+	}
+
+	fields, _ := mimeutil.SplitHeader(mimeutil.ToCRLF(msg))
+	for _, name := range addressFields {
+		if value := mimeutil.FieldValue(fields, name); !isASCII(value) {
+			return fmt.Errorf("cannot send a message with UTF-8 addresses in %s: %w", name, ErrSMTPUTF8Unsupported)
+		}
+	}
+	return nil
+}
+
+// sendData sends the recipients and content of a message after MAIL FROM.
+func sendData(c *smtp.Client, recipients []string, data []byte) error {
+	for _, rcpt := range recipients {
+		if err := c.Rcpt(rcpt); err != nil {
+			return err
+		}
+	}
+
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(data); err != nil {
+		w.Close()
+		return err
+	}
+	return w.Close()
+}
+
+// dialSMTP connects and authenticates to the configured SMTP server. It
+// uses STARTTLS whenever the server offers it. With OAuth configured, it
+// authenticates with an access token; otherwise it uses CRAM-MD5, LOGIN or
+// PLAIN in that order of preference, LOGIN only if the server does not
+// offer PLAIN.
+func (s *Sender) dialSMTP() (*smtp.Client, error) {
+	addr := net.JoinHostPort(s.config.Host, strconv.Itoa(s.config.Port))
+	tlsConfig, err := clientTLSConfig(s.config.Host, s.config.ClientCert, s.config.ClientKey)
+	if err != nil {
+		return nil, err
+	}
+
+	var conn net.Conn
+	if s.config.UseTLS {
+		conn, err = tls.DialWithDialer(&net.Dialer{Timeout: 10 * time.Second}, "tcp", addr, tlsConfig)
+	} else {
+		conn, err = net.DialTimeout("tcp", addr, 10*time.Second)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	c, err := smtp.NewClient(conn, s.config.Host)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	if !s.config.UseTLS {
+		if ok, _ := c.Extension("STARTTLS"); ok {
+			if err := c.StartTLS(tlsConfig); err != nil {
+				c.Close()
+				return nil, err
+			}
+		}
+	}
+
+	// Without AUTH, sending proceeds unauthenticated unless a mechanism was
+	// configured explicitly
+	if s.config.Username != "" || s.config.Auth == config.AuthExternal {
+		ok, mechs := c.Extension("AUTH")
+		if ok || (s.config.Auth != "" && s.config.Auth != config.AuthAuto) {
+			if err := s.authenticate(c, strings.Fields(strings.ToUpper(mechs))); err != nil {
+				c.Close()
+				return nil, err
+			}
+		}
+	}
+
+	return c, nil
+}
+
+// authenticate authenticates with the configured mechanism, or for auto,
+// with CRAM-MD5 if offered, LOGIN if offered without PLAIN, and otherwise
+// PLAIN.
+func (s *Sender) authenticate(c *smtp.Client, offered []string) error {
+	a := &authenticator{
+		protocol:  "SMTP",
+		mechanism: s.config.Auth,
+		authzID:   s.config.AuthzID,
+		username:  s.config.Username,
+		password:  s.config.Password,
+		host:      s.config.Host,
+		port:      s.config.Port,
+		oauth:     &s.config.OAuth,
+	}
+	fallback := sasl.Plain
+	if offers(offered, CRAMMD5) {
+		fallback = CRAMMD5
+	} else if offers(offered, sasl.Login) && !offers(offered, sasl.Plain) {
+		fallback = sasl.Login
+	}
+	mech, err := a.choose(offered, fallback)
+	if err != nil {
+		return err
+	}
+
+	_, encrypted := c.TLSConnectionState()
+	sc, err := a.client(mech, encrypted)
+	if err != nil {
+		return err
+	}
+	if err := c.Auth(&saslAuth{client: sc}); err != nil {
+		return a.failed(mech, err)
+	}
+	s.mechanism = mech
+	return nil
+}
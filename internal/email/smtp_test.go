@@ -0,0 +1,32 @@
+package email
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFutureReleaseAllowed(t *testing.T) {
+	now := time.Date(2024, 6, 1, 6, 0, 0, 0, time.UTC)
+	params := "86400 2024-06-10T00:00:00Z"
+
+	tests := []struct {
+		name   string
+		params string
+		at     time.Time
+		want   bool
+	}{
+		{"within limits", params, now.Add(3 * time.Hour), true},
+		{"beyond interval", params, now.Add(25 * time.Hour), false},
+		{"beyond date", "2592000 2024-06-01T12:00:00Z", now.Add(7 * time.Hour), false},
+		{"missing params", "", now.Add(time.Hour), false},
+		{"invalid interval", "soon 2024-06-10T00:00:00Z", now.Add(time.Hour), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := futureReleaseAllowed(tt.params, now, tt.at); got != tt.want {
+				t.Errorf("futureReleaseAllowed(%q) = %v, want %v", tt.params, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,253 @@
+package merge
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"text/template"
+	"text/template/parse"
+	"time"
+	"unicode"
+)
+
+// dateLayouts are the layouts accepted for dates given as strings.
+var dateLayouts = []string{
+	time.RFC3339,
+	"2006-01-02 15:04",
+	"2006-01-02",
+}
+
+// Funcs are the helper functions available in templates, in addition to
+// the standard ones such as printf:
+//
+//	now                  current time
+//	date LAYOUT VALUE    format a time or date string, e.g. date "Jan 2" .due
+//	upper, lower, title  change case
+//	trim                 remove leading and trailing white space
+//	default DEF VALUE    DEF if VALUE is empty
+//	join SEP LIST        join a list, e.g. join ", " .names
+//	split SEP STRING     split a string into a list
+var Funcs = template.FuncMap{
+	"now":     time.Now,
+	"date":    formatDate,
+	"upper":   strings.ToUpper,
+	"lower":   strings.ToLower,
+	"title":   title,
+	"trim":    strings.TrimSpace,
+	"default": defaultValue,
+	"join":    join,
+	"split":   split,
+}
+
+// formatDate formats a time.Time, or a string in one of dateLayouts, with
+// a Go time layout.
+func formatDate(layout string, value interface{}) (string, error) {
+	switch v := value.(type) {
+	case time.Time:
+		return v.Format(layout), nil
+	case string:
+		for _, l := range dateLayouts {
+			if t, err := time.ParseInLocation(l, strings.TrimSpace(v), time.Local); err == nil {
+				return t.Format(layout), nil
+			}
+		}
+		return "", fmt.Errorf("date: cannot parse %q (use YYYY-MM-DD or RFC 3339)", v)
+	default:
+		return "", fmt.Errorf("date: unsupported value %v", value)
+	}
+}
+
+// title upper-cases the first letter of every word.
+func title(s string) string {
+	start := true
+	return strings.Map(func(r rune) rune {
+		switch {
+		case unicode.IsSpace(r):
+			start = true
+		case start:
+			start = false
+			return unicode.ToTitle(r)
+		}
+		return r
+	}, s)
+}
+
+// defaultValue returns def if value is empty.
+func defaultValue(def, value interface{}) interface{} {
+	if value == nil {
+		return def
+	}
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.String, reflect.Slice, reflect.Map, reflect.Array:
+		if v.Len() == 0 {
+			return def
+		}
+	}
+	return value
+}
+
+// join joins the elements of a list, which may be a []string or a list
+// decoded from JSON.
+func join(sep string, list interface{}) (string, error) {
+	switch v := list.(type) {
+	case []string:
+		return strings.Join(v, sep), nil
+	case []interface{}:
+		items := make([]string, len(v))
+		for i, item := range v {
+			items[i] = fmt.Sprint(item)
+		}
+		return strings.Join(items, sep), nil
+	case string:
+		return v, nil
+	default:
+		return "", fmt.Errorf("join: unsupported value %v", list)
+	}
+}
+
+// split splits a string at every separator, trimming the items.
+func split(sep, s string) []string {
+	items := strings.Split(s, sep)
+	for i := range items {
+		items[i] = strings.TrimSpace(items[i])
+	}
+	return items
+}
+
+// Variables returns the names of the top-level fields ({{.name}} or
+// {{$.name}}) referenced by all templates of t, in sorted order. Fields
+// inside range and with blocks refer to a different value and are not
+// included.
+func Variables(t *template.Template) []string {
+	return sortedKeys(fields(t))
+}
+
+// OptionalVariables returns the variables of t that are only ever used as
+// the value given to default, as in {{.name | default "none"}}, in sorted
+// order. Rendering does not need them when they are set to "".
+func OptionalVariables(t *template.Template) []string {
+	optional := make(map[string]bool)
+	for name, required := range fields(t) {
+		if !required {
+			optional[name] = true
+		}
+	}
+	return sortedKeys(optional)
+}
+
+// fields returns the top-level fields referenced by all templates of t,
+// mapped to whether any reference is not defaulted.
+func fields(t *template.Template) map[string]bool {
+	seen := make(map[string]bool)
+	for _, tt := range t.Templates() {
+		if tt.Tree != nil {
+			collectFields(tt.Tree.Root, true, seen)
+		}
+	}
+	return seen
+}
+
+// collectFields walks a parse tree and records top-level field names.
+// atRoot reports whether dot is the template's data at this point.
+func collectFields(node parse.Node, atRoot bool, seen map[string]bool) {
+	switch n := node.(type) {
+	case nil:
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, c := range n.Nodes {
+			collectFields(c, atRoot, seen)
+		}
+	case *parse.ActionNode:
+		collectFields(n.Pipe, atRoot, seen)
+	case *parse.PipeNode:
+		if n == nil {
+			return
+		}
+		for i, cmd := range n.Cmds {
+			// {{.name | default "none"}}
+			if i+1 < len(n.Cmds) && isDefault(n.Cmds[i+1]) && len(cmd.Args) == 1 {
+				collectDefaulted(cmd.Args[0], atRoot, seen)
+				continue
+			}
+			collectFields(cmd, atRoot, seen)
+		}
+	case *parse.CommandNode:
+		for i, arg := range n.Args {
+			// {{default "none" .name}}
+			if i > 0 && i == len(n.Args)-1 && isDefault(n) {
+				collectDefaulted(arg, atRoot, seen)
+				continue
+			}
+			collectFields(arg, atRoot, seen)
+		}
+	case *parse.FieldNode:
+		if atRoot {
+			seen[n.Ident[0]] = true
+		}
+	case *parse.ChainNode:
+		collectFields(n.Node, atRoot, seen)
+	case *parse.VariableNode:
+		if n.Ident[0] == "$" && len(n.Ident) > 1 {
+			seen[n.Ident[1]] = true
+		}
+	case *parse.IfNode:
+		collectBranch(&n.BranchNode, atRoot, atRoot, seen)
+	case *parse.RangeNode:
+		collectBranch(&n.BranchNode, false, atRoot, seen)
+	case *parse.WithNode:
+		collectBranch(&n.BranchNode, false, atRoot, seen)
+	case *parse.TemplateNode:
+		collectFields(n.Pipe, atRoot, seen)
+	}
+}
+
+// collectDefaulted records a field given as the value to default without
+// marking it as required. Anything but a plain field is walked as usual.
+func collectDefaulted(node parse.Node, atRoot bool, seen map[string]bool) {
+	var name string
+	switch n := node.(type) {
+	case *parse.FieldNode:
+		if atRoot && len(n.Ident) == 1 {
+			name = n.Ident[0]
+		}
+	case *parse.VariableNode:
+		if n.Ident[0] == "$" && len(n.Ident) == 2 {
+			name = n.Ident[1]
+		}
+	}
+	if name == "" {
+		collectFields(node, atRoot, seen)
+		return
+	}
+	if _, ok := seen[name]; !ok {
+		seen[name] = false
+	}
+}
+
+// isDefault reports whether cmd calls the default function.
+func isDefault(cmd *parse.CommandNode) bool {
+	if len(cmd.Args) == 0 {
+		return false
+	}
+	ident, ok := cmd.Args[0].(*parse.IdentifierNode)
+	return ok && ident.Ident == "default"
+}
+
+func collectBranch(n *parse.BranchNode, listAtRoot, atRoot bool, seen map[string]bool) {
+	collectFields(n.Pipe, atRoot, seen)
+	collectFields(n.List, listAtRoot, seen)
+	collectFields(n.ElseList, atRoot, seen)
+}
+
+func sortedKeys(m map[string]bool) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
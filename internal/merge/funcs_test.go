@@ -0,0 +1,93 @@
+package merge
+
+import (
+	"strings"
+	"testing"
+	"text/template"
+	"time"
+)
+
+func TestFormatDate(t *testing.T) {
+	tests := []struct {
+		value   interface{}
+		want    string
+		wantErr bool
+	}{
+		{"2024-06-01", "Jun 1, 2024", false},
+		{"2024-06-01 14:05", "Jun 1, 2024", false},
+		{"2024-06-01T14:05:00Z", "Jun 1, 2024", false},
+		{time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC), "Jun 1, 2024", false},
+		{"yesterday", "", true},
+		{42, "", true},
+	}
+
+	for _, tt := range tests {
+		got, err := formatDate("Jan 2, 2006", tt.value)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("formatDate(%v) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("formatDate(%v) = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestFuncs(t *testing.T) {
+	tests := []struct {
+		tmpl string
+		want string
+	}{
+		{`{{title "hello big  world"}}`, "Hello Big  World"},
+		{`{{upper .name}}`, "ANN"},
+		{`{{default "n/a" .empty}}`, "n/a"},
+		{`{{default "n/a" .name}}`, "Ann"},
+		{`{{join ", " .list}}`, "a, b"},
+		{`{{range split "," " x, y "}}[{{.}}]{{end}}`, "[x][y]"},
+		{`{{trim "  x  "}}`, "x"},
+	}
+
+	data := map[string]interface{}{
+		"name":  "Ann",
+		"empty": "",
+		"list":  []interface{}{"a", "b"},
+	}
+	for _, tt := range tests {
+		tmpl := template.Must(template.New("").Funcs(Funcs).Parse(tt.tmpl))
+		var buf strings.Builder
+		if err := tmpl.Execute(&buf, data); err != nil {
+			t.Errorf("%s: error = %v", tt.tmpl, err)
+			continue
+		}
+		if buf.String() != tt.want {
+			t.Errorf("%s = %q, want %q", tt.tmpl, buf.String(), tt.want)
+		}
+	}
+}
+
+func TestVariables(t *testing.T) {
+	tmpl := template.Must(template.New("").Funcs(Funcs).Parse(
+		`{{define "subject"}}{{.a}}{{end}}` +
+			`{{if .b}}{{.c}}{{else}}{{.d}}{{end}}` +
+			`{{range .items}}{{.ignored}}{{$.e}}{{end}}` +
+			`{{with .f}}{{.ignored}}{{end}}` +
+			`{{upper .g.sub}}`))
+
+	got := strings.Join(Variables(tmpl), ",")
+	if want := "a,b,c,d,e,f,g,items"; got != want {
+		t.Errorf("Variables() = %s, want %s", got, want)
+	}
+}
+
+func TestOptionalVariables(t *testing.T) {
+	tmpl := template.Must(template.New("").Funcs(Funcs).Parse(
+		`{{.a | default "x"}}{{default "x" .b}}{{default .c "x"}}` +
+			`{{.d | default "x"}}{{.d}}{{.e | upper | default "x"}}`))
+
+	if got, want := strings.Join(OptionalVariables(tmpl), ","), "a,b"; got != want {
+		t.Errorf("OptionalVariables() = %s, want %s", got, want)
+	}
+	if got, want := strings.Join(Variables(tmpl), ","), "a,b,c,d,e"; got != want {
+		t.Errorf("Variables() = %s, want %s", got, want)
+	}
+}
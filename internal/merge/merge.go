@@ -0,0 +1,222 @@
+// Package merge renders personalized messages from a template and rows of
+// recipient data (mail merge).
+package merge
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	htmltemplate "html/template"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"text/template/parse"
+)
+
+// Template names recognized in a merge template file.
+const (
+	SubjectTemplate = "subject"
+	TextTemplate    = "text"
+	HTMLTemplate    = "html"
+)
+
+// Columns with a special meaning in the data.
+const (
+	ColumnEmail       = "email"
+	ColumnTo          = "to"
+	ColumnCC          = "cc"
+	ColumnBCC         = "bcc"
+	ColumnAttachments = "attachments"
+)
+
+// Row is one recipient's data, available to templates as {{.column}}.
+type Row map[string]interface{}
+
+// LoadData reads recipient rows from a CSV file with a header line or a
+// JSON file holding an array of objects, chosen by file extension.
+func LoadData(path string) ([]Row, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open data file: %w", err)
+	}
+	defer f.Close()
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		return readJSON(f)
+	case ".csv":
+		return readCSV(f)
+	default:
+		return nil, fmt.Errorf("unsupported data file %s (use .csv or .json)", path)
+	}
+}
+
+func readCSV(r io.Reader) ([]Row, error) {
+	records, err := csv.NewReader(r).ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("invalid CSV data: %w", err)
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("CSV data has no header line")
+	}
+
+	header := records[0]
+	for i := range header {
+		header[i] = strings.TrimSpace(header[i])
+	}
+
+	rows := make([]Row, 0, len(records)-1)
+	for _, record := range records[1:] {
+		row := make(Row, len(header))
+		for i, name := range header {
+			if i < len(record) {
+				row[name] = record[i]
+			}
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+func readJSON(r io.Reader) ([]Row, error) {
+	var rows []Row
+	if err := json.NewDecoder(r).Decode(&rows); err != nil {
+		return nil, fmt.Errorf("invalid JSON data (expected an array of objects): %w", err)
+	}
+	return rows, nil
+}
+
+// Recipients returns the To, CC and BCC addresses and attachment paths
+// of a row. Lists in a single column are separated by commas or semicolons.
+func (r Row) Recipients() (to, cc, bcc, attachments []string) {
+	to = r.list(ColumnEmail)
+	if len(to) == 0 {
+		to = r.list(ColumnTo)
+	}
+	return to, r.list(ColumnCC), r.list(ColumnBCC), r.list(ColumnAttachments)
+}
+
+// list returns the values of a column that holds a list, either as a
+// separated string or as a JSON array.
+func (r Row) list(column string) []string {
+	var values []string
+	switch v := r[column].(type) {
+	case string:
+		values = strings.FieldsFunc(v, func(c rune) bool { return c == ',' || c == ';' })
+	case []interface{}:
+		for _, item := range v {
+			values = append(values, fmt.Sprint(item))
+		}
+	case nil:
+		return nil
+	default:
+		values = []string{fmt.Sprint(v)}
+	}
+
+	var result []string
+	for _, value := range values {
+		if value = strings.TrimSpace(value); value != "" {
+			result = append(result, value)
+		}
+	}
+	return result
+}
+
+// Template holds the parsed subject, text and HTML templates.
+type Template struct {
+	text *template.Template
+	html *htmltemplate.Template
+}
+
+// Rendered is a message rendered for one row.
+type Rendered struct {
+	Subject string
+	Text    string
+	HTML    string
+}
+
+// ParseTemplate parses a merge template. The file defines the templates
+// "subject", "text" and optionally "html":
+//
+//	{{define "subject"}}Your invoice {{.invoice}}{{end}}
+//	{{define "text"}}Hello {{.name}}, ...{{end}}
+//	{{define "html"}}<p>Hello {{.name}}, ...</p>{{end}}
+//
+// A file without definitions is used as the text body as a whole. The
+// subject can be given separately, and overrides the one in the file.
+// HTML is rendered with html/template, so values are escaped. The helper
+// functions in Funcs are available.
+func ParseTemplate(data, subject string) (*Template, error) {
+	text, err := template.New(TextTemplate).Funcs(Funcs).Option("missingkey=error").Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid template: %w", err)
+	}
+	if subject != "" {
+		if _, err := text.New(SubjectTemplate).Parse(subject); err != nil {
+			return nil, fmt.Errorf("invalid subject template: %w", err)
+		}
+	}
+	if text.Lookup(SubjectTemplate) == nil {
+		return nil, fmt.Errorf("template has no subject (define \"subject\" or use --subject)")
+	}
+
+	t := &Template{text: text}
+
+	if text.Lookup(HTMLTemplate) != nil {
+		html, err := htmltemplate.New("").Funcs(htmltemplate.FuncMap(Funcs)).Option("missingkey=error").Parse(data)
+		if err != nil {
+			return nil, fmt.Errorf("invalid HTML template: %w", err)
+		}
+		t.html = html
+	}
+
+	if parse.IsEmptyTree(text.Lookup(TextTemplate).Root) && t.html == nil {
+		return nil, fmt.Errorf("template has no body (define \"text\" or \"html\")")
+	}
+
+	return t, nil
+}
+
+// Variables returns the names of the columns the template refers to.
+func (t *Template) Variables() []string {
+	return Variables(t.text)
+}
+
+// OptionalVariables returns the columns the template only passes to
+// default.
+func (t *Template) OptionalVariables() []string {
+	return OptionalVariables(t.text)
+}
+
+// Render renders the message for a row.
+func (t *Template) Render(row Row) (*Rendered, error) {
+	var r Rendered
+	var buf bytes.Buffer
+
+	if err := t.text.ExecuteTemplate(&buf, SubjectTemplate, row); err != nil {
+		return nil, fmt.Errorf("failed to render subject: %w", err)
+	}
+	// Subjects are a single line
+	r.Subject = strings.Join(strings.Fields(buf.String()), " ")
+
+	buf.Reset()
+	if err := t.text.ExecuteTemplate(&buf, TextTemplate, row); err != nil {
+		return nil, fmt.Errorf("failed to render text body: %w", err)
+	}
+	if text := strings.TrimSpace(buf.String()); text != "" {
+		r.Text = text + "\n"
+	}
+
+	if t.html != nil {
+		buf.Reset()
+		if err := t.html.ExecuteTemplate(&buf, HTMLTemplate, row); err != nil {
+			return nil, fmt.Errorf("failed to render HTML body: %w", err)
+		}
+		r.HTML = strings.TrimSpace(buf.String())
+	}
+
+	return &r, nil
+}
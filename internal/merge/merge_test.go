@@ -0,0 +1,154 @@
+package merge
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadDataCSV(t *testing.T) {
+	path := writeFile(t, "data.csv", "email, name ,cc\n"+
+		"alice@example.com,Alice,\"boss@example.com; audit@example.com\"\n"+
+		"bob@example.com,Bob,\n")
+
+	rows, err := LoadData(path)
+	if err != nil {
+		t.Fatalf("LoadData() error = %v", err)
+	}
+	if len(rows) != 2 {
+		t.Fatalf("LoadData() returned %d rows, want 2", len(rows))
+	}
+	if rows[0]["name"] != "Alice" {
+		t.Errorf("rows[0][name] = %v, want Alice (header names are trimmed)", rows[0]["name"])
+	}
+
+	to, cc, _, _ := rows[0].Recipients()
+	if len(to) != 1 || to[0] != "alice@example.com" {
+		t.Errorf("Recipients() to = %v", to)
+	}
+	if len(cc) != 2 || cc[1] != "audit@example.com" {
+		t.Errorf("Recipients() cc = %v, want two addresses", cc)
+	}
+	if _, cc, _, _ := rows[1].Recipients(); len(cc) != 0 {
+		t.Errorf("Recipients() cc = %v, want none for an empty column", cc)
+	}
+}
+
+func TestLoadDataJSON(t *testing.T) {
+	path := writeFile(t, "data.json", `[
+		{"to": "alice@example.com", "amount": 42, "attachments": ["a.pdf", "b.pdf"]}
+	]`)
+
+	rows, err := LoadData(path)
+	if err != nil {
+		t.Fatalf("LoadData() error = %v", err)
+	}
+	to, _, _, attachments := rows[0].Recipients()
+	if len(to) != 1 || to[0] != "alice@example.com" {
+		t.Errorf("Recipients() to = %v, want the \"to\" column", to)
+	}
+	if len(attachments) != 2 || attachments[1] != "b.pdf" {
+		t.Errorf("Recipients() attachments = %v", attachments)
+	}
+}
+
+func TestLoadDataErrors(t *testing.T) {
+	if _, err := LoadData(writeFile(t, "data.txt", "email\n")); err == nil {
+		t.Error("LoadData() should reject unknown file types")
+	}
+	if _, err := LoadData(writeFile(t, "data.json", `{"email": "x"}`)); err == nil {
+		t.Error("LoadData() should reject JSON that is not an array")
+	}
+}
+
+const testTemplate = `{{define "subject"}}Invoice {{.invoice}}
+  for {{.name}}{{end}}
+{{define "text"}}
+Hello {{.name}},
+
+invoice {{.invoice}} is due.
+{{end}}
+{{define "html"}}<p>Hello {{.name}}</p>{{end}}`
+
+func TestRender(t *testing.T) {
+	tmpl, err := ParseTemplate(testTemplate, "")
+	if err != nil {
+		t.Fatalf("ParseTemplate() error = %v", err)
+	}
+
+	got, err := tmpl.Render(Row{"name": "Bob <b>", "invoice": "INV-2"})
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	if got.Subject != "Invoice INV-2 for Bob <b>" {
+		t.Errorf("Subject = %q, want a single line", got.Subject)
+	}
+	if got.Text != "Hello Bob <b>,\n\ninvoice INV-2 is due.\n" {
+		t.Errorf("Text = %q", got.Text)
+	}
+	if got.HTML != "<p>Hello Bob &lt;b&gt;</p>" {
+		t.Errorf("HTML = %q, want escaped values", got.HTML)
+	}
+
+	if _, err := tmpl.Render(Row{"name": "Bob"}); err == nil {
+		t.Error("Render() should fail for a missing column")
+	}
+}
+
+func TestParseTemplateWithoutDefinitions(t *testing.T) {
+	tmpl, err := ParseTemplate("Hi {{.name}}", "Hello {{.name}}")
+	if err != nil {
+		t.Fatalf("ParseTemplate() error = %v", err)
+	}
+
+	got, err := tmpl.Render(Row{"name": "Ann"})
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	if got.Subject != "Hello Ann" || got.Text != "Hi Ann\n" || got.HTML != "" {
+		t.Errorf("Render() = %+v", got)
+	}
+}
+
+func TestParseTemplateErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		subject string
+	}{
+		{"no subject", `{{define "text"}}Hi{{end}}`, ""},
+		{"no body", `{{define "subject"}}Hi{{end}}`, ""},
+		{"syntax", `{{define "subject"}}{{.name{{end}}`, ""},
+		{"subject syntax", "Hi", "{{.name"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseTemplate(tt.data, tt.subject); err == nil {
+				t.Errorf("ParseTemplate() expected an error")
+			}
+		})
+	}
+
+	if _, err := ParseTemplate(`{{define "subject"}}A{{end}}{{define "text"}}B{{end}}`, "Override"); err != nil {
+		t.Errorf("ParseTemplate() with subject override error = %v", err)
+	}
+}
+
+func TestRowListTrimsEmpty(t *testing.T) {
+	row := Row{"cc": " a@example.com ,, ;b@example.com "}
+	_, cc, _, _ := row.Recipients()
+	if strings.Join(cc, "|") != "a@example.com|b@example.com" {
+		t.Errorf("Recipients() cc = %v", cc)
+	}
+}
@@ -0,0 +1,143 @@
+// Package mimeutil splits and joins raw MIME entities byte for byte, as
+// needed to sign and verify them (PGP/MIME, S/MIME).
+package mimeutil
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"mime"
+	"strings"
+)
+
+// SplitEntity splits a message into its header fields, except the content
+// fields, and the entity made of the content fields and the body.
+func SplitEntity(msg []byte) ([]string, []byte) {
+	fields, body := SplitHeader(msg)
+
+	var outer []string
+	var inner bytes.Buffer
+	for _, field := range fields {
+		if strings.HasPrefix(strings.ToLower(field), "content-") {
+			inner.WriteString(field)
+		} else {
+			outer = append(outer, field)
+		}
+	}
+	inner.WriteString("\r\n")
+	inner.Write(body)
+	return outer, inner.Bytes()
+}
+
+// SplitHeader splits an entity into its header fields, each including
+// folded lines and the final CRLF, and its body.
+func SplitHeader(entity []byte) ([]string, []byte) {
+	var fields []string
+	rest := entity
+	for len(rest) > 0 {
+		var line string
+		if i := bytes.Index(rest, []byte("\r\n")); i >= 0 {
+			line, rest = string(rest[:i+2]), rest[i+2:]
+		} else {
+			line, rest = string(rest), nil
+		}
+
+		if line == "\r\n" {
+			return fields, rest
+		}
+		if (line[0] == ' ' || line[0] == '\t') && len(fields) > 0 {
+			fields[len(fields)-1] += line
+		} else {
+			fields = append(fields, line)
+		}
+	}
+	return fields, nil
+}
+
+// FieldValue returns the unfolded value of a header field.
+func FieldValue(fields []string, name string) string {
+	for _, field := range fields {
+		key, value, ok := strings.Cut(field, ":")
+		if ok && strings.EqualFold(strings.TrimSpace(key), name) {
+			return strings.TrimSpace(strings.NewReplacer("\r\n", "").Replace(value))
+		}
+	}
+	return ""
+}
+
+// SplitMultipart returns the raw body parts of a multipart body, without
+// the CRLF preceding each delimiter, which belongs to the delimiter.
+func SplitMultipart(body []byte, boundary string) [][]byte {
+	if boundary == "" {
+		return nil
+	}
+	delimiter := []byte("--" + boundary)
+
+	// Skip the preamble
+	var start int
+	if bytes.HasPrefix(body, delimiter) {
+		start = 0
+	} else if i := bytes.Index(body, append([]byte("\r\n"), delimiter...)); i >= 0 {
+		start = i + 2
+	} else {
+		return nil
+	}
+
+	var parts [][]byte
+	rest := body[start:]
+	for {
+		// rest starts with a delimiter line
+		rest = rest[len(delimiter):]
+		if bytes.HasPrefix(rest, []byte("--")) {
+			return parts
+		}
+		eol := bytes.Index(rest, []byte("\r\n"))
+		if eol < 0 {
+			return nil
+		}
+		rest = rest[eol+2:]
+
+		end := bytes.Index(rest, append([]byte("\r\n"), delimiter...))
+		if end < 0 {
+			return nil
+		}
+		parts = append(parts, rest[:end])
+		rest = rest[end+2:]
+	}
+}
+
+// Join builds a message from header fields and an entity.
+func Join(fields []string, entity []byte) []byte {
+	var buf bytes.Buffer
+	for _, field := range fields {
+		buf.WriteString(field)
+	}
+	buf.Write(entity)
+	return buf.Bytes()
+}
+
+// MediaType returns the media type of a message, in lower case, and its
+// parameters, or "" if it has no valid Content-Type.
+func MediaType(msg []byte) (string, map[string]string) {
+	fields, _ := SplitHeader(ToCRLF(msg))
+	mediaType, params, err := mime.ParseMediaType(FieldValue(fields, "Content-Type"))
+	if err != nil {
+		return "", nil
+	}
+	return mediaType, params
+}
+
+// ToCRLF converts line endings to CRLF.
+func ToCRLF(data []byte) []byte {
+	data = bytes.ReplaceAll(data, []byte("\r\n"), []byte("\n"))
+	return bytes.ReplaceAll(data, []byte("\n"), []byte("\r\n"))
+}
+
+// NewBoundary returns a random multipart boundary.
+func NewBoundary() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
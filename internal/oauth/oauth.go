@@ -0,0 +1,239 @@
+// Package oauth obtains OAuth 2.0 access tokens for IMAP and SMTP
+// authentication: given directly, read from a file or a command, or from a
+// token endpoint with a refresh token (RFC 6749 section 6), cached on disk
+// until they expire.
+package oauth
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/GodGMN/ghostmail-cli/internal/config"
+)
+
+// expiryMargin is how long before its expiry a cached token is refreshed,
+// so it does not expire during a session.
+const expiryMargin = time.Minute
+
+// cachedToken is an access token stored in the cache directory.
+type cachedToken struct {
+	AccessToken  string    `json:"access_token"`
+	Expiry       time.Time `json:"expiry"`
+	RefreshToken string    `json:"refresh_token,omitempty"` // Issued in place of the configured one
+}
+
+// tokenResponse is the response of a token endpoint (RFC 6749 sections 5.1
+// and 5.2).
+type tokenResponse struct {
+	AccessToken      string `json:"access_token"`
+	TokenType        string `json:"token_type"`
+	ExpiresIn        int64  `json:"expires_in"`
+	RefreshToken     string `json:"refresh_token"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+// TokenSource returns access tokens for a configuration.
+type TokenSource struct {
+	cfg    *config.OAuthConfig
+	client *http.Client
+	now    func() time.Time
+}
+
+// NewTokenSource creates a token source.
+func NewTokenSource(cfg *config.OAuthConfig) *TokenSource {
+	return &TokenSource{
+		cfg:    cfg,
+		client: &http.Client{Timeout: 30 * time.Second},
+		now:    time.Now,
+	}
+}
+
+// Token returns an access token. A token set directly takes precedence over
+// a token file, then a token command, then the refresh flow.
+func (ts *TokenSource) Token(ctx context.Context) (string, error) {
+	switch {
+	case ts.cfg.Token != "":
+		return ts.cfg.Token, nil
+	case ts.cfg.TokenFile != "":
+		data, err := os.ReadFile(ts.cfg.TokenFile)
+		if err != nil {
+			return "", fmt.Errorf("failed to read OAuth token: %w", err)
+		}
+		return nonEmpty(string(data), "token file "+ts.cfg.TokenFile)
+	case ts.cfg.TokenCmd != "":
+		return ts.runCommand(ctx)
+	case ts.cfg.RefreshToken != "":
+		return ts.refresh(ctx)
+	}
+	return "", fmt.Errorf("no OAuth token configured (set GHOSTMAIL_OAUTH_TOKEN or GHOSTMAIL_OAUTH_REFRESH_TOKEN)")
+}
+
+// runCommand runs the token command with the shell and returns its output.
+func (ts *TokenSource) runCommand(ctx context.Context) (string, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "sh", "-c", ts.cfg.TokenCmd)
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("OAuth token command failed: %w: %s", err, msg)
+		}
+		return "", fmt.Errorf("OAuth token command failed: %w", err)
+	}
+	return nonEmpty(stdout.String(), "token command")
+}
+
+// nonEmpty trims a token read from a source, which must not be empty.
+func nonEmpty(token, source string) (string, error) {
+	token = strings.TrimSpace(token)
+	if token == "" {
+		return "", fmt.Errorf("OAuth %s returned an empty token", source)
+	}
+	return token, nil
+}
+
+// refresh returns the cached access token, or obtains a new one from the
+// token endpoint and caches it.
+func (ts *TokenSource) refresh(ctx context.Context) (string, error) {
+	cached, _ := ts.readCache()
+	if cached != nil && cached.AccessToken != "" && ts.now().Add(expiryMargin).Before(cached.Expiry) {
+		return cached.AccessToken, nil
+	}
+
+	refreshToken := ts.cfg.RefreshToken
+	if cached != nil && cached.RefreshToken != "" {
+		refreshToken = cached.RefreshToken
+	}
+	resp, err := ts.requestToken(ctx, refreshToken)
+	if err != nil {
+		return "", err
+	}
+
+	token := &cachedToken{AccessToken: resp.AccessToken, RefreshToken: resp.RefreshToken}
+	if token.RefreshToken == "" && refreshToken != ts.cfg.RefreshToken {
+		token.RefreshToken = refreshToken
+	}
+	if resp.ExpiresIn > 0 {
+		token.Expiry = ts.now().Add(time.Duration(resp.ExpiresIn) * time.Second)
+	}
+	if err := ts.writeCache(token); err != nil {
+		return "", err
+	}
+	return token.AccessToken, nil
+}
+
+// requestToken exchanges a refresh token at the token endpoint.
+func (ts *TokenSource) requestToken(ctx context.Context, refreshToken string) (*tokenResponse, error) {
+	form := url.Values{
+		"grant_type":    {"refresh_token"},
+		"refresh_token": {refreshToken},
+		"client_id":     {ts.cfg.ClientID},
+	}
+	if ts.cfg.ClientSecret != "" {
+		form.Set("client_secret", ts.cfg.ClientSecret)
+	}
+	if len(ts.cfg.Scopes) > 0 {
+		form.Set("scope", strings.Join(ts.cfg.Scopes, " "))
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, ts.cfg.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("invalid OAuth token endpoint: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	httpResp, err := ts.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to refresh OAuth token: %w", err)
+	}
+	defer httpResp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(httpResp.Body, 1<<20))
+	if err != nil {
+		return nil, fmt.Errorf("failed to refresh OAuth token: %w", err)
+	}
+
+	var resp tokenResponse
+	jsonErr := json.Unmarshal(body, &resp)
+	if httpResp.StatusCode != http.StatusOK || resp.Error != "" {
+		if resp.Error == "" {
+			return nil, fmt.Errorf("failed to refresh OAuth token: token endpoint returned %s", httpResp.Status)
+		}
+		if resp.ErrorDescription != "" {
+			return nil, fmt.Errorf("failed to refresh OAuth token: %s: %s", resp.Error, resp.ErrorDescription)
+		}
+		return nil, fmt.Errorf("failed to refresh OAuth token: %s", resp.Error)
+	}
+	if jsonErr != nil {
+		return nil, fmt.Errorf("failed to refresh OAuth token: invalid response: %w", jsonErr)
+	}
+	if resp.AccessToken == "" {
+		return nil, fmt.Errorf("failed to refresh OAuth token: no access token in response")
+	}
+	if resp.TokenType != "" && !strings.EqualFold(resp.TokenType, "bearer") {
+		return nil, fmt.Errorf("failed to refresh OAuth token: unsupported token type %q", resp.TokenType)
+	}
+	return &resp, nil
+}
+
+// cachePath returns the cache file for the configured token endpoint,
+// client and refresh token, so accounts do not share tokens.
+func (ts *TokenSource) cachePath() string {
+	sum := sha256.Sum256([]byte(ts.cfg.TokenURL + "\n" + ts.cfg.ClientID + "\n" + ts.cfg.RefreshToken))
+	return filepath.Join(ts.cfg.CacheDir, hex.EncodeToString(sum[:8])+".json")
+}
+
+// readCache reads the cached token, if any.
+func (ts *TokenSource) readCache() (*cachedToken, error) {
+	if ts.cfg.CacheDir == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(ts.cachePath())
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var token cachedToken
+	if err := json.Unmarshal(data, &token); err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+// writeCache stores a token in the cache directory, readable by the user
+// only.
+func (ts *TokenSource) writeCache(token *cachedToken) error {
+	if ts.cfg.CacheDir == "" {
+		return nil
+	}
+	if err := os.MkdirAll(ts.cfg.CacheDir, 0700); err != nil {
+		return fmt.Errorf("failed to cache OAuth token: %w", err)
+	}
+	data, err := json.Marshal(token)
+	if err != nil {
+		return fmt.Errorf("failed to cache OAuth token: %w", err)
+	}
+	path := ts.cachePath()
+	if err := os.WriteFile(path+".tmp", data, 0600); err != nil {
+		return fmt.Errorf("failed to cache OAuth token: %w", err)
+	}
+	if err := os.Rename(path+".tmp", path); err != nil {
+		return fmt.Errorf("failed to cache OAuth token: %w", err)
+	}
+	return nil
+}
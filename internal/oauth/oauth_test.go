@@ -0,0 +1,142 @@
+package oauth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/GodGMN/ghostmail-cli/internal/config"
+)
+
+// tokenServer is a mock token endpoint that issues numbered access tokens
+// and rotates the refresh token.
+type tokenServer struct {
+	*httptest.Server
+	requests []map[string]string
+}
+
+func newTokenServer(t *testing.T) *tokenServer {
+	ts := &tokenServer{}
+	ts.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			t.Fatal(err)
+		}
+		req := map[string]string{}
+		for key := range r.PostForm {
+			req[key] = r.PostForm.Get(key)
+		}
+		ts.requests = append(ts.requests, req)
+
+		w.Header().Set("Content-Type", "application/json")
+		if req["refresh_token"] == "revoked" {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant", "error_description": "Token has been revoked."})
+			return
+		}
+		n := len(ts.requests)
+		json.NewEncoder(w).Encode(map[string]any{
+			"access_token":  fmt.Sprintf("access-%d", n),
+			"token_type":    "Bearer",
+			"expires_in":    3600,
+			"refresh_token": fmt.Sprintf("refresh-%d", n),
+		})
+	}))
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+func TestRefreshFlow(t *testing.T) {
+	server := newTokenServer(t)
+	cfg := &config.OAuthConfig{
+		TokenURL:     server.URL,
+		ClientID:     "ghostmail",
+		ClientSecret: "s3cret",
+		RefreshToken: "refresh-0",
+		Scopes:       []string{"https://mail.google.com/"},
+		CacheDir:     t.TempDir(),
+	}
+	now := time.Date(2024, 6, 1, 9, 0, 0, 0, time.UTC)
+	newSource := func() *TokenSource {
+		ts := NewTokenSource(cfg)
+		ts.now = func() time.Time { return now }
+		return ts
+	}
+
+	token, err := newSource().Token(context.Background())
+	if err != nil || token != "access-1" {
+		t.Fatalf("Token() = %q, %v", token, err)
+	}
+	want := map[string]string{"grant_type": "refresh_token", "refresh_token": "refresh-0", "client_id": "ghostmail", "client_secret": "s3cret", "scope": "https://mail.google.com/"}
+	for key, value := range want {
+		if server.requests[0][key] != value {
+			t.Errorf("request %s = %q, want %q", key, server.requests[0][key], value)
+		}
+	}
+
+	// A later run uses the cached token until shortly before it expires
+	now = now.Add(58 * time.Minute)
+	if token, err := newSource().Token(context.Background()); err != nil || token != "access-1" || len(server.requests) != 1 {
+		t.Errorf("Token() from cache = %q, %v after %d requests", token, err, len(server.requests))
+	}
+	info, err := os.Stat(newSource().cachePath())
+	if err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("cache file mode = %v, %v", info, err)
+	}
+
+	// Then it is refreshed with the rotated refresh token
+	now = now.Add(2 * time.Minute)
+	if token, err := newSource().Token(context.Background()); err != nil || token != "access-2" {
+		t.Fatalf("Token() after expiry = %q, %v", token, err)
+	}
+	if got := server.requests[1]["refresh_token"]; got != "refresh-1" {
+		t.Errorf("second request refresh_token = %q, want the rotated refresh-1", got)
+	}
+}
+
+func TestRefreshError(t *testing.T) {
+	server := newTokenServer(t)
+	ts := NewTokenSource(&config.OAuthConfig{TokenURL: server.URL, ClientID: "ghostmail", RefreshToken: "revoked", CacheDir: t.TempDir()})
+	_, err := ts.Token(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "invalid_grant: Token has been revoked.") {
+		t.Errorf("Token() error = %v", err)
+	}
+}
+
+func TestTokenFileAndCommand(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(path, []byte("from-file\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		cfg     config.OAuthConfig
+		want    string
+		wantErr string
+	}{
+		{cfg: config.OAuthConfig{Token: "direct", TokenFile: path}, want: "direct"},
+		{cfg: config.OAuthConfig{TokenFile: path, TokenCmd: "echo from-command"}, want: "from-file"},
+		{cfg: config.OAuthConfig{TokenCmd: "echo from-command"}, want: "from-command"},
+		{cfg: config.OAuthConfig{TokenCmd: "echo 'not logged in' >&2; exit 1"}, wantErr: "not logged in"},
+		{cfg: config.OAuthConfig{TokenCmd: "true"}, wantErr: "empty token"},
+		{cfg: config.OAuthConfig{TokenFile: path + ".missing"}, wantErr: "failed to read OAuth token"},
+	}
+	for _, tt := range tests {
+		got, err := NewTokenSource(&tt.cfg).Token(context.Background())
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Token(%+v) error = %v, want %q", tt.cfg, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("Token(%+v) = %q, %v, want %q", tt.cfg, got, err, tt.want)
+		}
+	}
+}
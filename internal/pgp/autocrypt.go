@@ -0,0 +1,133 @@
+package pgp
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+)
+
+// Autocrypt is a key advertised in an Autocrypt header.
+type Autocrypt struct {
+	Addr          string
+	PreferEncrypt bool
+	Key           *openpgp.Entity
+	Data          []byte // Binary key data
+}
+
+// ParseAutocrypt parses the value of an Autocrypt header (Autocrypt Level 1).
+func ParseAutocrypt(value string) (*Autocrypt, error) {
+	ac := &Autocrypt{}
+	var keydata string
+	for _, attr := range strings.Split(value, ";") {
+		name, val, ok := strings.Cut(attr, "=")
+		if !ok {
+			if strings.TrimSpace(attr) == "" {
+				continue
+			}
+			return nil, fmt.Errorf("invalid attribute %q", strings.TrimSpace(attr))
+		}
+		name = strings.TrimSpace(name)
+		val = strings.TrimSpace(val)
+
+		switch name {
+		case "addr":
+			ac.Addr = strings.ToLower(val)
+		case "prefer-encrypt":
+			ac.PreferEncrypt = val == "mutual"
+		case "keydata":
+			keydata = val
+		default:
+			// Unknown attributes are only allowed if non-critical
+			if !strings.HasPrefix(name, "_") {
+				return nil, fmt.Errorf("unknown critical attribute %q", name)
+			}
+		}
+	}
+	if ac.Addr == "" || keydata == "" {
+		return nil, fmt.Errorf("addr and keydata are required")
+	}
+
+	data, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(keydata), ""))
+	if err != nil {
+		return nil, fmt.Errorf("invalid keydata: %w", err)
+	}
+	entities, err := openpgp.ReadKeyRing(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("invalid keydata: %w", err)
+	}
+	if len(entities) != 1 {
+		return nil, fmt.Errorf("keydata must hold exactly one key")
+	}
+	ac.Key = entities[0]
+	ac.Data = data
+	return ac, nil
+}
+
+// HasAutocrypt reports whether a message has an Autocrypt header.
+func HasAutocrypt(raw []byte) bool {
+	msg, err := mail.ReadMessage(bytes.NewReader(raw))
+	return err == nil && len(msg.Header["Autocrypt"]) > 0
+}
+
+// ImportAutocrypt stores the key advertised in the Autocrypt header of a
+// received message, if its address matches the sender. A stored key is
+// only replaced by one from a newer message. It reports whether a key was
+// stored.
+func (k *Keyring) ImportAutocrypt(raw []byte) (bool, error) {
+	msg, err := mail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		return false, nil
+	}
+
+	// A message with several Autocrypt headers must be ignored
+	values := msg.Header["Autocrypt"]
+	if len(values) != 1 {
+		return false, nil
+	}
+	from, err := mail.ParseAddress(msg.Header.Get("From"))
+	if err != nil {
+		return false, nil
+	}
+
+	ac, err := ParseAutocrypt(values[0])
+	if err != nil {
+		return false, fmt.Errorf("invalid Autocrypt header: %w", err)
+	}
+	if !strings.EqualFold(ac.Addr, from.Address) || strings.ContainsAny(ac.Addr, `/\`) || strings.HasPrefix(ac.Addr, ".") {
+		return false, nil
+	}
+
+	date, err := msg.Header.Date()
+	if err != nil || date.After(time.Now()) {
+		date = time.Now()
+	}
+
+	dir := filepath.Join(k.dir, AutocryptDir)
+	path := filepath.Join(dir, ac.Addr+".pgp")
+	if info, err := os.Stat(path); err == nil && !date.After(info.ModTime()) {
+		return false, nil
+	}
+
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return false, fmt.Errorf("failed to store Autocrypt key: %w", err)
+	}
+	if err := os.WriteFile(path, ac.Data, 0600); err != nil {
+		return false, fmt.Errorf("failed to store Autocrypt key: %w", err)
+	}
+	// The modification time records the date of the message
+	if err := os.Chtimes(path, date, date); err != nil {
+		return false, fmt.Errorf("failed to store Autocrypt key: %w", err)
+	}
+
+	// Prefer the new key over any older one for this address
+	k.autocrypt[ac.Addr] = ac.Key
+	k.entities = append(k.entities, ac.Key)
+	return true, nil
+}
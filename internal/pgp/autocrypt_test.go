@@ -0,0 +1,116 @@
+package pgp
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// autocryptHeader returns an Autocrypt header value for a new key,
+// folded like a real header.
+func autocryptHeader(t *testing.T, addr string) string {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := newKey(t, "", addr).Serialize(&buf); err != nil {
+		t.Fatal(err)
+	}
+	keydata := base64.StdEncoding.EncodeToString(buf.Bytes())
+	var folded []string
+	for len(keydata) > 60 {
+		folded = append(folded, keydata[:60])
+		keydata = keydata[60:]
+	}
+	folded = append(folded, keydata)
+	return "addr=" + addr + "; prefer-encrypt=mutual; keydata=\r\n " + strings.Join(folded, "\r\n ")
+}
+
+func autocryptMessage(from, date, header string) []byte {
+	return []byte(fmt.Sprintf("From: %s\r\nDate: %s\r\nAutocrypt: %s\r\nSubject: Hi\r\n\r\nHello\r\n", from, date, header))
+}
+
+func TestParseAutocrypt(t *testing.T) {
+	header := autocryptHeader(t, "Carol@Example.com")
+	ac, err := ParseAutocrypt(strings.ReplaceAll(header, "\r\n", ""))
+	if err != nil {
+		t.Fatalf("ParseAutocrypt() error = %v", err)
+	}
+	if ac.Addr != "carol@example.com" || !ac.PreferEncrypt || ac.Key == nil {
+		t.Errorf("ParseAutocrypt() = %+v", ac)
+	}
+
+	if _, err := ParseAutocrypt(header + "; _ignored=1"); err != nil {
+		t.Errorf("ParseAutocrypt() with a non-critical attribute error = %v", err)
+	}
+
+	tests := []string{
+		"",
+		"addr=carol@example.com",
+		"keydata=AAAA",
+		"addr=carol@example.com; keydata=!!!",
+		"addr=carol@example.com; keydata=AAAA",
+		header + "; critical=1",
+	}
+	for _, value := range tests {
+		if _, err := ParseAutocrypt(value); err == nil {
+			t.Errorf("ParseAutocrypt(%.40q) expected an error", value)
+		}
+	}
+}
+
+func TestImportAutocrypt(t *testing.T) {
+	dir := t.TempDir()
+	kr, err := Load(dir, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	first := autocryptHeader(t, "carol@example.com")
+	ok, err := kr.ImportAutocrypt(autocryptMessage("Carol <carol@example.com>", "Mon, 03 Jun 2024 10:00:00 +0000", first))
+	if err != nil || !ok {
+		t.Fatalf("ImportAutocrypt() = %v, %v", ok, err)
+	}
+	key := kr.PublicKey("carol@example.com")
+	if key == nil {
+		t.Fatal("PublicKey() = nil after import")
+	}
+
+	// An older message does not replace the key
+	older := autocryptHeader(t, "carol@example.com")
+	if ok, _ := kr.ImportAutocrypt(autocryptMessage("carol@example.com", "Sun, 02 Jun 2024 10:00:00 +0000", older)); ok {
+		t.Error("ImportAutocrypt() replaced the key with an older one")
+	}
+
+	// A newer one does, also after reloading
+	newer := autocryptHeader(t, "carol@example.com")
+	if ok, _ := kr.ImportAutocrypt(autocryptMessage("carol@example.com", "Tue, 04 Jun 2024 10:00:00 +0000", newer)); !ok {
+		t.Error("ImportAutocrypt() did not store a newer key")
+	}
+	if got := kr.PublicKey("carol@example.com"); got == nil || got.PrimaryKey.KeyId == key.PrimaryKey.KeyId {
+		t.Error("PublicKey() did not return the newer Autocrypt key")
+	}
+	reloaded, err := Load(dir, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := reloaded.PublicKey("carol@example.com"); got == nil || got.PrimaryKey.KeyId == key.PrimaryKey.KeyId {
+		t.Error("Load() did not return the newer Autocrypt key")
+	}
+	if _, err := os.Stat(filepath.Join(dir, AutocryptDir, "carol@example.com.pgp")); err != nil {
+		t.Errorf("Autocrypt key not stored: %v", err)
+	}
+
+	// The address must match the sender
+	other := autocryptHeader(t, "mallory@example.com")
+	if ok, _ := kr.ImportAutocrypt(autocryptMessage("carol@example.com", "Wed, 05 Jun 2024 10:00:00 +0000", other)); ok {
+		t.Error("ImportAutocrypt() stored a key for another address")
+	}
+
+	// Messages without the header are ignored
+	if ok, err := kr.ImportAutocrypt([]byte("From: dave@example.com\r\n\r\nHi\r\n")); ok || err != nil {
+		t.Errorf("ImportAutocrypt(no header) = %v, %v", ok, err)
+	}
+}
@@ -0,0 +1,232 @@
+// Package pgp provides OpenPGP keyrings and PGP/MIME (RFC 3156) signing,
+// encryption, decryption and verification.
+package pgp
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+)
+
+// AutocryptDir is the keyring subdirectory holding keys collected from
+// Autocrypt headers.
+const AutocryptDir = "autocrypt"
+
+// keyExts are the file extensions read as keys.
+var keyExts = map[string]bool{".asc": true, ".gpg": true, ".pgp": true, ".key": true}
+
+// Keyring holds the keys in a keyring directory: public keys of
+// correspondents and the user's own secret keys, as armored or binary
+// files, plus keys collected from Autocrypt headers. Collected keys are
+// only used to encrypt; signatures made with them are never good.
+type Keyring struct {
+	dir       string
+	entities  openpgp.EntityList         // All keys
+	user      openpgp.EntityList         // Keys placed by the user
+	autocrypt map[string]*openpgp.Entity // Collected keys by address
+}
+
+// Load reads the keys in dir and its Autocrypt subdirectory, unlocking
+// secret keys with the passphrase. A missing directory is an empty keyring.
+func Load(dir, passphrase string) (*Keyring, error) {
+	k := &Keyring{dir: dir, autocrypt: make(map[string]*openpgp.Entity)}
+
+	files, err := readDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range sortedNames(files) {
+		k.user = append(k.user, files[name]...)
+	}
+	k.entities = append(k.entities, k.user...)
+
+	// Collected keys are stored as <address>.pgp, since Autocrypt does not
+	// require the user ID to match the address
+	collected, err := readDir(filepath.Join(dir, AutocryptDir))
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range sortedNames(collected) {
+		if entities := collected[name]; len(entities) == 1 {
+			addr := strings.ToLower(strings.TrimSuffix(name, filepath.Ext(name)))
+			k.autocrypt[addr] = entities[0]
+			k.entities = append(k.entities, entities[0])
+		}
+	}
+
+	if passphrase != "" {
+		for _, e := range k.entities {
+			if e.PrivateKey != nil && e.PrivateKey.Encrypted {
+				if err := e.DecryptPrivateKeys([]byte(passphrase)); err != nil {
+					return nil, fmt.Errorf("failed to unlock secret key %s: %w", keyID(e.PrimaryKey), err)
+				}
+			}
+		}
+	}
+
+	return k, nil
+}
+
+// readDir reads the key files in a directory, by file name.
+func readDir(dir string) (map[string]openpgp.EntityList, error) {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read keyring: %w", err)
+	}
+
+	files := make(map[string]openpgp.EntityList)
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !keyExts[strings.ToLower(filepath.Ext(name))] {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read key: %w", err)
+		}
+		entities, err := ReadKeys(data)
+		if err != nil {
+			return nil, fmt.Errorf("invalid key file %s: %w", name, err)
+		}
+		files[name] = entities
+	}
+	return files, nil
+}
+
+// sortedNames returns the file names read by readDir in order.
+func sortedNames(files map[string]openpgp.EntityList) []string {
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ReadKeys parses armored or binary OpenPGP keys.
+func ReadKeys(data []byte) (openpgp.EntityList, error) {
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte("-----BEGIN")) {
+		return openpgp.ReadArmoredKeyRing(bytes.NewReader(data))
+	}
+	return openpgp.ReadKeyRing(bytes.NewReader(data))
+}
+
+// Entities returns all keys in the keyring.
+func (k *Keyring) Entities() openpgp.EntityList {
+	return k.entities
+}
+
+// PublicKey returns a usable encryption key for an address, or nil.
+func (k *Keyring) PublicKey(addr string) *openpgp.Entity {
+	now := time.Now()
+	for _, e := range k.user {
+		if hasAddress(e, addr) {
+			if _, ok := e.EncryptionKey(now); ok {
+				return e
+			}
+		}
+	}
+	if e := k.autocrypt[strings.ToLower(addr)]; e != nil {
+		if _, ok := e.EncryptionKey(now); ok {
+			return e
+		}
+	}
+	return nil
+}
+
+// SecretKey returns the unlocked signing key for an address, falling back
+// to the only secret key placed by the user.
+func (k *Keyring) SecretKey(addr string) (*openpgp.Entity, error) {
+	var secret []*openpgp.Entity
+	for _, e := range k.user {
+		if e.PrivateKey != nil {
+			secret = append(secret, e)
+		}
+	}
+
+	var key *openpgp.Entity
+	for _, e := range secret {
+		if hasAddress(e, addr) {
+			key = e
+			break
+		}
+	}
+	if key == nil && len(secret) == 1 {
+		key = secret[0]
+	}
+	if key == nil {
+		return nil, fmt.Errorf("no OpenPGP secret key for %s in %s", addr, k.dir)
+	}
+	if key.PrivateKey.Encrypted {
+		return nil, fmt.Errorf("secret key %s is locked; set GHOSTMAIL_PGP_PASSPHRASE", keyID(key.PrimaryKey))
+	}
+	return key, nil
+}
+
+// Recipients returns the encryption keys for the given addresses. It fails
+// listing every address without a key.
+func (k *Keyring) Recipients(addrs []string) ([]*openpgp.Entity, error) {
+	var keys []*openpgp.Entity
+	var missing []string
+	for _, addr := range addrs {
+		if e := k.PublicKey(addr); e != nil {
+			keys = append(keys, e)
+		} else {
+			missing = append(missing, addr)
+		}
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("no OpenPGP key for %s", strings.Join(missing, ", "))
+	}
+	return keys, nil
+}
+
+// trusted reports whether a key was placed in the keyring by the user, as
+// opposed to collected from an Autocrypt header.
+func (k *Keyring) trusted(e *openpgp.Entity) bool {
+	for _, u := range k.user {
+		if bytes.Equal(u.PrimaryKey.Fingerprint, e.PrimaryKey.Fingerprint) {
+			return true
+		}
+	}
+	return false
+}
+
+// hasAddress reports whether one of the key's user IDs has the address.
+func hasAddress(e *openpgp.Entity, addr string) bool {
+	for _, id := range e.Identities {
+		if id.UserId != nil && strings.EqualFold(id.UserId.Email, addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// keyID formats the 64-bit key ID of a key.
+func keyID(pk *packet.PublicKey) string {
+	return fmt.Sprintf("%016X", pk.KeyId)
+}
+
+// fingerprint formats the fingerprint of a key.
+func fingerprint(pk *packet.PublicKey) string {
+	return fmt.Sprintf("%X", pk.Fingerprint)
+}
+
+// userID returns the primary user ID of a key.
+func userID(e *openpgp.Entity) string {
+	if id := e.PrimaryIdentity(); id != nil {
+		return id.Name
+	}
+	return ""
+}
@@ -0,0 +1,400 @@
+package pgp
+
+import (
+	"bytes"
+	"crypto"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/mail"
+	"strings"
+
+	"github.com/GodGMN/ghostmail-cli/internal/mimeutil"
+	emailtypes "github.com/GodGMN/ghostmail-cli/pkg/email"
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	"github.com/ProtonMail/go-crypto/openpgp/clearsign"
+	pgperrors "github.com/ProtonMail/go-crypto/openpgp/errors"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+)
+
+// Protocol is the protocol name reported in emailtypes.Security.
+const Protocol = "openpgp"
+
+// config signs with SHA-256, announced as micalg=pgp-sha256.
+var config = &packet.Config{DefaultHash: crypto.SHA256}
+
+// Protect signs and/or encrypts a message as PGP/MIME (RFC 3156). The
+// content of the message moves into the signed or encrypted part, while
+// the other header fields stay outside. Encrypted messages are also
+// encrypted to the sender's key, if there is one, and signed inside the
+// encryption when sign is set.
+func (k *Keyring) Protect(msg []byte, from string, recipients []string, sign, encrypt bool) ([]byte, error) {
+	if !sign && !encrypt {
+		return msg, nil
+	}
+
+	var signer *openpgp.Entity
+	if sign {
+		var err error
+		if signer, err = k.SecretKey(from); err != nil {
+			return nil, err
+		}
+	}
+
+	outer, inner := mimeutil.SplitEntity(mimeutil.ToCRLF(msg))
+
+	if !encrypt {
+		body, err := signEntity(inner, signer)
+		if err != nil {
+			return nil, err
+		}
+		return mimeutil.Join(outer, body), nil
+	}
+
+	keys, err := k.Recipients(recipients)
+	if err != nil {
+		return nil, err
+	}
+	if self := k.PublicKey(from); self != nil {
+		keys = append(keys, self)
+	} else if signer != nil {
+		keys = append(keys, signer)
+	}
+	body, err := encryptEntity(inner, dedupe(keys), signer)
+	if err != nil {
+		return nil, err
+	}
+	return mimeutil.Join(outer, body), nil
+}
+
+// signEntity returns the content header and body of a multipart/signed
+// entity for inner.
+func signEntity(inner []byte, signer *openpgp.Entity) ([]byte, error) {
+	var sig bytes.Buffer
+	if err := openpgp.ArmoredDetachSign(&sig, signer, bytes.NewReader(inner), config); err != nil {
+		return nil, fmt.Errorf("failed to sign message: %w", err)
+	}
+
+	boundary := mimeutil.NewBoundary()
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "Content-Type: multipart/signed; micalg=pgp-sha256;\r\n"+
+		" protocol=\"application/pgp-signature\"; boundary=%q\r\n\r\n", boundary)
+	fmt.Fprintf(&buf, "This is an OpenPGP/MIME signed message (RFC 3156).\r\n")
+	fmt.Fprintf(&buf, "--%s\r\n", boundary)
+	buf.Write(inner)
+	fmt.Fprintf(&buf, "\r\n--%s\r\n", boundary)
+	buf.WriteString("Content-Type: application/pgp-signature; name=\"signature.asc\"\r\n" +
+		"Content-Description: OpenPGP digital signature\r\n" +
+		"Content-Disposition: attachment; filename=\"signature.asc\"\r\n\r\n")
+	buf.Write(mimeutil.ToCRLF(sig.Bytes()))
+	fmt.Fprintf(&buf, "\r\n--%s--\r\n", boundary)
+	return buf.Bytes(), nil
+}
+
+// encryptEntity returns the content header and body of a
+// multipart/encrypted entity for inner.
+func encryptEntity(inner []byte, to []*openpgp.Entity, signer *openpgp.Entity) ([]byte, error) {
+	var enc bytes.Buffer
+	w, err := armor.Encode(&enc, "PGP MESSAGE", nil)
+	if err != nil {
+		return nil, err
+	}
+	pt, err := openpgp.Encrypt(w, to, signer, nil, config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt message: %w", err)
+	}
+	if _, err := pt.Write(inner); err != nil {
+		return nil, fmt.Errorf("failed to encrypt message: %w", err)
+	}
+	if err := pt.Close(); err != nil {
+		return nil, fmt.Errorf("failed to encrypt message: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return nil, fmt.Errorf("failed to encrypt message: %w", err)
+	}
+
+	boundary := mimeutil.NewBoundary()
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "Content-Type: multipart/encrypted;\r\n"+
+		" protocol=\"application/pgp-encrypted\"; boundary=%q\r\n\r\n", boundary)
+	fmt.Fprintf(&buf, "This is an OpenPGP/MIME encrypted message (RFC 3156).\r\n")
+	fmt.Fprintf(&buf, "--%s\r\n", boundary)
+	buf.WriteString("Content-Type: application/pgp-encrypted\r\n" +
+		"Content-Description: PGP/MIME version identification\r\n\r\n" +
+		"Version: 1\r\n")
+	fmt.Fprintf(&buf, "\r\n--%s\r\n", boundary)
+	buf.WriteString("Content-Type: application/octet-stream; name=\"encrypted.asc\"\r\n" +
+		"Content-Description: OpenPGP encrypted message\r\n" +
+		"Content-Disposition: inline; filename=\"encrypted.asc\"\r\n\r\n")
+	buf.Write(mimeutil.ToCRLF(enc.Bytes()))
+	fmt.Fprintf(&buf, "\r\n--%s--\r\n", boundary)
+	return buf.Bytes(), nil
+}
+
+// Open decrypts and verifies a PGP/MIME message. It returns the message
+// with the protected content in place of the multipart/signed or
+// multipart/encrypted entity, and the result. A message that is not
+// PGP/MIME is returned unchanged with a nil result. Signatures are only
+// good if made with a key the user placed in the keyring for the address
+// in the From header.
+func (k *Keyring) Open(msg []byte) ([]byte, *emailtypes.Security) {
+	msg = mimeutil.ToCRLF(msg)
+	outer, inner := mimeutil.SplitEntity(msg)
+	fields, body := mimeutil.SplitHeader(inner)
+	from := sender(outer)
+
+	mediaType, params, err := mime.ParseMediaType(mimeutil.FieldValue(fields, "Content-Type"))
+	if err != nil {
+		return msg, nil
+	}
+	protocol := strings.ToLower(params["protocol"])
+
+	switch {
+	case mediaType == "multipart/signed" && protocol == "application/pgp-signature":
+		parts := mimeutil.SplitMultipart(body, params["boundary"])
+		if len(parts) != 2 {
+			return msg, nil
+		}
+		_, sig := mimeutil.SplitHeader(parts[1])
+		sec := k.verifyArmored(parts[0], sig, from)
+		return mimeutil.Join(outer, parts[0]), sec
+
+	case mediaType == "multipart/encrypted" && protocol == "application/pgp-encrypted":
+		sec := &emailtypes.Security{Protocol: Protocol, Encrypted: true}
+		parts := mimeutil.SplitMultipart(body, params["boundary"])
+		if len(parts) != 2 {
+			sec.Error = "malformed PGP/MIME message"
+			return msg, sec
+		}
+		_, data := mimeutil.SplitHeader(parts[1])
+		plaintext, err := k.decrypt(data, sec, from)
+		if err != nil {
+			sec.Error = err.Error()
+			return msg, sec
+		}
+		sec.Decrypted = true
+		opened := mimeutil.Join(outer, mimeutil.ToCRLF(plaintext))
+
+		// Signed, then encrypted (RFC 3156 section 6.1)
+		if signed, inner := k.Open(opened); inner != nil && !inner.Encrypted {
+			opened = signed
+			sec.Signed = inner.Signed
+			sec.SignatureStatus = inner.SignatureStatus
+			sec.SignerKeyID = inner.SignerKeyID
+			sec.SignerFingerprint = inner.SignerFingerprint
+			sec.Signer = inner.Signer
+			if inner.Error != "" {
+				sec.Error = inner.Error
+			}
+		}
+		return opened, sec
+	}
+
+	return msg, nil
+}
+
+// IsMIME reports whether a message is PGP/MIME signed or encrypted.
+func IsMIME(msg []byte) bool {
+	mediaType, params := mimeutil.MediaType(msg)
+	protocol := strings.ToLower(params["protocol"])
+	return (mediaType == "multipart/signed" && protocol == "application/pgp-signature") ||
+		(mediaType == "multipart/encrypted" && protocol == "application/pgp-encrypted")
+}
+
+// IsInline reports whether text holds an inline PGP message or clearsigned
+// text, which OpenInline opens.
+func IsInline(text string) bool {
+	return strings.Contains(text, "-----BEGIN PGP MESSAGE-----") || strings.Contains(text, "-----BEGIN PGP SIGNED MESSAGE-----")
+}
+
+// OpenInline decrypts or verifies an inline PGP message or clearsigned
+// text in the body of a message from the given address. It reports false
+// if the text holds neither.
+func (k *Keyring) OpenInline(text, from string) (string, *emailtypes.Security, bool) {
+	if start := strings.Index(text, "-----BEGIN PGP MESSAGE-----"); start >= 0 {
+		end := strings.Index(text[start:], "-----END PGP MESSAGE-----")
+		if end < 0 {
+			return text, nil, false
+		}
+		end += start + len("-----END PGP MESSAGE-----")
+
+		sec := &emailtypes.Security{Protocol: Protocol, Encrypted: true}
+		plaintext, err := k.decrypt([]byte(text[start:end]), sec, from)
+		if err != nil {
+			sec.Error = err.Error()
+			return text, sec, true
+		}
+		sec.Decrypted = true
+		return text[:start] + string(plaintext) + text[end:], sec, true
+	}
+
+	if strings.Contains(text, "-----BEGIN PGP SIGNED MESSAGE-----") {
+		block, _ := clearsign.Decode([]byte(text))
+		if block == nil {
+			return text, nil, false
+		}
+		sig, err := io.ReadAll(block.ArmoredSignature.Body)
+		if err != nil {
+			return text, nil, false
+		}
+		return string(block.Plaintext), k.verify(block.Bytes, sig, from), true
+	}
+
+	return text, nil, false
+}
+
+// decrypt decrypts an armored message and records any signature in sec.
+func (k *Keyring) decrypt(armored []byte, sec *emailtypes.Security, from string) ([]byte, error) {
+	block, err := armor.Decode(bytes.NewReader(armored))
+	if err != nil {
+		return nil, fmt.Errorf("invalid encrypted data: %w", err)
+	}
+
+	prompt := func([]openpgp.Key, bool) ([]byte, error) {
+		return nil, fmt.Errorf("secret key is locked; set GHOSTMAIL_PGP_PASSPHRASE")
+	}
+	md, err := openpgp.ReadMessage(block.Body, k.entities, prompt, config)
+	if errors.Is(err, pgperrors.ErrKeyIncorrect) {
+		return nil, fmt.Errorf("no secret key to decrypt the message")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt message: %w", err)
+	}
+	plaintext, err := io.ReadAll(md.UnverifiedBody)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt message: %w", err)
+	}
+
+	if md.IsSigned {
+		sec.Signed = true
+		sec.SignerKeyID = fmt.Sprintf("%016X", md.SignedByKeyId)
+		switch {
+		case md.SignedBy == nil:
+			sec.SignatureStatus = emailtypes.SignatureUnknownKey
+		case md.SignatureError != nil:
+			sec.SignatureStatus = emailtypes.SignatureBad
+			sec.Error = md.SignatureError.Error()
+		default:
+			sec.SignatureStatus = emailtypes.SignatureGood
+		}
+		if md.SignedBy != nil {
+			sec.SignerFingerprint = fingerprint(md.SignedBy.PublicKey)
+			sec.Signer = userID(md.SignedBy.Entity)
+			k.judge(sec, md.SignedBy.Entity, from)
+		}
+	}
+	return plaintext, nil
+}
+
+// verifyArmored verifies an armored detached signature.
+func (k *Keyring) verifyArmored(signed, armored []byte, from string) *emailtypes.Security {
+	block, err := armor.Decode(bytes.NewReader(armored))
+	if err != nil {
+		return &emailtypes.Security{
+			Protocol:        Protocol,
+			Signed:          true,
+			SignatureStatus: emailtypes.SignatureBad,
+			Error:           fmt.Sprintf("invalid signature: %v", err),
+		}
+	}
+	sig, err := io.ReadAll(block.Body)
+	if err != nil {
+		return &emailtypes.Security{
+			Protocol:        Protocol,
+			Signed:          true,
+			SignatureStatus: emailtypes.SignatureBad,
+			Error:           fmt.Sprintf("invalid signature: %v", err),
+		}
+	}
+	return k.verify(signed, sig, from)
+}
+
+// verify verifies a binary detached signature.
+func (k *Keyring) verify(signed, sig []byte, from string) *emailtypes.Security {
+	sec := &emailtypes.Security{Protocol: Protocol, Signed: true}
+
+	// The issuer is reported even when its key is unknown
+	if p, err := packet.Read(bytes.NewReader(sig)); err == nil {
+		if s, ok := p.(*packet.Signature); ok {
+			if s.IssuerKeyId != nil {
+				sec.SignerKeyID = fmt.Sprintf("%016X", *s.IssuerKeyId)
+			}
+			if len(s.IssuerFingerprint) > 0 {
+				sec.SignerFingerprint = fmt.Sprintf("%X", s.IssuerFingerprint)
+			}
+		}
+	}
+
+	s, signer, err := openpgp.VerifyDetachedSignature(k.entities, bytes.NewReader(signed), bytes.NewReader(sig), config)
+	switch {
+	case errors.Is(err, pgperrors.ErrUnknownIssuer):
+		sec.SignatureStatus = emailtypes.SignatureUnknownKey
+		return sec
+	case err != nil:
+		sec.SignatureStatus = emailtypes.SignatureBad
+		sec.Error = err.Error()
+	default:
+		sec.SignatureStatus = emailtypes.SignatureGood
+	}
+
+	if signer != nil {
+		sec.Signer = userID(signer)
+		if s != nil && s.IssuerKeyId != nil {
+			for _, key := range k.entities.KeysById(*s.IssuerKeyId) {
+				sec.SignerFingerprint = fingerprint(key.PublicKey)
+			}
+		}
+		k.judge(sec, signer, from)
+	}
+	return sec
+}
+
+// judge downgrades a valid signature made with a key collected from an
+// Autocrypt header, which anyone can send, to untrusted, and one made with
+// a key without the sender's address to a mismatch.
+func (k *Keyring) judge(sec *emailtypes.Security, signer *openpgp.Entity, from string) {
+	if sec.SignatureStatus != emailtypes.SignatureGood {
+		return
+	}
+	switch {
+	case !k.trusted(signer):
+		sec.SignatureStatus = emailtypes.SignatureUntrusted
+		sec.Error = "the signing key was only collected from an Autocrypt header"
+	case from == "":
+		sec.SignatureStatus = emailtypes.SignatureMismatch
+		sec.Error = "the message has no valid From address"
+	case !hasAddress(signer, from):
+		sec.SignatureStatus = emailtypes.SignatureMismatch
+		sec.Error = fmt.Sprintf("the signing key has no user ID for the sender %s", from)
+	}
+}
+
+// sender returns the address in the From header of a message, or "".
+func sender(fields []string) string {
+	addr, err := mail.ParseAddress(mimeutil.FieldValue(fields, "From"))
+	if err != nil {
+		return ""
+	}
+	return addr.Address
+}
+
+// Sender returns the address in the From header of a raw message, or "".
+func Sender(msg []byte) string {
+	fields, _ := mimeutil.SplitHeader(mimeutil.ToCRLF(msg))
+	return sender(fields)
+}
+
+// dedupe removes repeated keys.
+func dedupe(keys []*openpgp.Entity) []*openpgp.Entity {
+	var result []*openpgp.Entity
+	seen := make(map[uint64]bool)
+	for _, key := range keys {
+		if !seen[key.PrimaryKey.KeyId] {
+			seen[key.PrimaryKey.KeyId] = true
+			result = append(result, key)
+		}
+	}
+	return result
+}
@@ -0,0 +1,288 @@
+package pgp
+
+import (
+	"bytes"
+	"encoding/base64"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	emailtypes "github.com/GodGMN/ghostmail-cli/pkg/email"
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	"github.com/ProtonMail/go-crypto/openpgp/clearsign"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+)
+
+const testMessage = "From: Ann <ann@example.com>\r\n" +
+	"To: bob@example.org\r\n" +
+	"Subject: Quarterly numbers\r\n" +
+	"Mime-Version: 1.0\r\n" +
+	"Content-Type: text/plain; charset=UTF-8\r\n" +
+	"Content-Transfer-Encoding: quoted-printable\r\n" +
+	"\r\n" +
+	"Revenue is up 12%.\r\n"
+
+// newKey generates a test key for an address.
+func newKey(t *testing.T, name, addr string) *openpgp.Entity {
+	t.Helper()
+	e, err := openpgp.NewEntity(name, "", addr, &packet.Config{Algorithm: packet.PubKeyAlgoEdDSA})
+	if err != nil {
+		t.Fatalf("NewEntity() error = %v", err)
+	}
+	return e
+}
+
+// writeKey stores a key in a keyring directory, with its secret key if
+// secret is set.
+func writeKey(t *testing.T, dir, name string, e *openpgp.Entity, secret bool) {
+	t.Helper()
+	blockType, serialize := openpgp.PublicKeyType, e.Serialize
+	if secret {
+		blockType = openpgp.PrivateKeyType
+		serialize = func(w io.Writer) error { return e.SerializePrivateWithoutSigning(w, nil) }
+	}
+	var buf bytes.Buffer
+	w, err := armor.Encode(&buf, blockType, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = serialize(w)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Close()
+	if err := os.WriteFile(filepath.Join(dir, name), buf.Bytes(), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// testKeyrings returns the keyrings of the sender Ann and the recipient
+// Bob, each with their own secret key and the other's public key.
+func testKeyrings(t *testing.T) (ann, bob *Keyring) {
+	t.Helper()
+	annKey := newKey(t, "Ann", "ann@example.com")
+	bobKey := newKey(t, "Bob", "bob@example.org")
+
+	annDir, bobDir := t.TempDir(), t.TempDir()
+	writeKey(t, annDir, "ann.asc", annKey, true)
+	writeKey(t, annDir, "bob.asc", bobKey, false)
+	writeKey(t, bobDir, "bob.asc", bobKey, true)
+	writeKey(t, bobDir, "ann.asc", annKey, false)
+
+	var err error
+	if ann, err = Load(annDir, ""); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if bob, err = Load(bobDir, ""); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	return ann, bob
+}
+
+func TestSignAndVerify(t *testing.T) {
+	ann, bob := testKeyrings(t)
+
+	signed, err := ann.Protect([]byte(testMessage), "ann@example.com", []string{"bob@example.org"}, true, false)
+	if err != nil {
+		t.Fatalf("Protect() error = %v", err)
+	}
+	for _, want := range []string{"Subject: Quarterly numbers", "multipart/signed; micalg=pgp-sha256", `protocol="application/pgp-signature"`, "-----BEGIN PGP SIGNATURE-----"} {
+		if !strings.Contains(string(signed), want) {
+			t.Errorf("signed message missing %q:\n%s", want, signed)
+		}
+	}
+
+	opened, sec := bob.Open(signed)
+	if sec == nil || !sec.Signed || sec.Encrypted || sec.SignatureStatus != emailtypes.SignatureGood {
+		t.Fatalf("Open() security = %+v", sec)
+	}
+	if sec.Signer != "Ann <ann@example.com>" || len(sec.SignerKeyID) != 16 || sec.SignerFingerprint == "" {
+		t.Errorf("Open() signer = %q, key ID %q, fingerprint %q", sec.Signer, sec.SignerKeyID, sec.SignerFingerprint)
+	}
+	if string(opened) != testMessage {
+		t.Errorf("Open() message =\n%s\nwant\n%s", opened, testMessage)
+	}
+
+	// Tampering breaks the signature
+	tampered := bytes.Replace(signed, []byte("up 12%"), []byte("up 21%"), 1)
+	if _, sec := bob.Open(tampered); sec == nil || sec.SignatureStatus != emailtypes.SignatureBad {
+		t.Errorf("Open(tampered) security = %+v", sec)
+	}
+
+	// A valid signature of Ann's key on a message from someone else
+	spoofed := bytes.Replace(signed, []byte("From: Ann <ann@example.com>"), []byte("From: Ann <ann@example.net>"), 1)
+	if _, sec := bob.Open(spoofed); sec == nil || sec.SignatureStatus != emailtypes.SignatureMismatch {
+		t.Errorf("Open(other From) security = %+v, want a mismatch", sec)
+	}
+
+	// Without Ann's key the signer is reported by key ID
+	stranger, _ := Load(t.TempDir(), "")
+	if _, sec := stranger.Open(signed); sec == nil || sec.SignatureStatus != emailtypes.SignatureUnknownKey || sec.SignerKeyID == "" {
+		t.Errorf("Open(unknown key) security = %+v", sec)
+	}
+}
+
+func TestEncryptAndDecrypt(t *testing.T) {
+	ann, bob := testKeyrings(t)
+
+	encrypted, err := ann.Protect([]byte(testMessage), "ann@example.com", []string{"bob@example.org"}, true, true)
+	if err != nil {
+		t.Fatalf("Protect() error = %v", err)
+	}
+	if strings.Contains(string(encrypted), "Revenue") {
+		t.Fatal("encrypted message contains the plaintext")
+	}
+	if !strings.Contains(string(encrypted), "multipart/encrypted") || !strings.Contains(string(encrypted), "Version: 1") {
+		t.Errorf("encrypted message is not PGP/MIME:\n%s", encrypted)
+	}
+
+	// Both the recipient and the sender can decrypt it
+	for name, kr := range map[string]*Keyring{"bob": bob, "ann": ann} {
+		opened, sec := kr.Open(encrypted)
+		if sec == nil || !sec.Encrypted || !sec.Decrypted || sec.Error != "" {
+			t.Fatalf("%s: Open() security = %+v", name, sec)
+		}
+		if !sec.Signed || sec.SignatureStatus != emailtypes.SignatureGood {
+			t.Errorf("%s: Open() signature = %+v", name, sec)
+		}
+		if string(opened) != testMessage {
+			t.Errorf("%s: Open() message =\n%s", name, opened)
+		}
+	}
+
+	stranger, _ := Load(t.TempDir(), "")
+	if _, sec := stranger.Open(encrypted); sec == nil || sec.Decrypted || sec.Error == "" {
+		t.Errorf("Open(no key) security = %+v", sec)
+	}
+
+	if _, err := ann.Protect([]byte(testMessage), "ann@example.com", []string{"eve@example.net"}, false, true); err == nil {
+		t.Error("Protect() to a recipient without a key expected an error")
+	}
+}
+
+func TestAutocryptKeyNotTrusted(t *testing.T) {
+	// Mallory signs as Ann and advertises the forged key via Autocrypt
+	forger := newKey(t, "Ann", "ann@example.com")
+	dir := t.TempDir()
+	writeKey(t, dir, "forger.asc", forger, true)
+	mallory, err := Load(dir, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	signed, err := mallory.Protect([]byte(testMessage), "ann@example.com", nil, true, false)
+	if err != nil {
+		t.Fatalf("Protect() error = %v", err)
+	}
+	var key bytes.Buffer
+	if err := forger.Serialize(&key); err != nil {
+		t.Fatal(err)
+	}
+	forged := append([]byte("Autocrypt: addr=ann@example.com; keydata="+base64.StdEncoding.EncodeToString(key.Bytes())+"\r\n"), signed...)
+
+	bob, err := Load(t.TempDir(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, sec := bob.Open(forged); sec == nil || sec.SignatureStatus != emailtypes.SignatureUnknownKey {
+		t.Errorf("Open() security = %+v, want an unknown key", sec)
+	}
+
+	// Once collected, the key encrypts but does not make signatures good
+	if ok, err := bob.ImportAutocrypt(forged); !ok || err != nil {
+		t.Fatalf("ImportAutocrypt() = %v, %v", ok, err)
+	}
+	if bob.PublicKey("ann@example.com") == nil {
+		t.Error("PublicKey() = nil, want the collected key for encryption")
+	}
+	if _, sec := bob.Open(forged); sec == nil || sec.SignatureStatus != emailtypes.SignatureUntrusted {
+		t.Errorf("Open() after import security = %+v, want untrusted", sec)
+	}
+	if _, err := bob.SecretKey("ann@example.com"); err == nil {
+		t.Error("SecretKey() returned a collected key")
+	}
+}
+
+func TestOpenPlainMessage(t *testing.T) {
+	_, bob := testKeyrings(t)
+	opened, sec := bob.Open([]byte(testMessage))
+	if sec != nil || string(opened) != testMessage {
+		t.Errorf("Open(plain) = %+v, message changed: %v", sec, string(opened) != testMessage)
+	}
+}
+
+func TestOpenInline(t *testing.T) {
+	ann, bob := testKeyrings(t)
+	annKey, _ := ann.SecretKey("ann@example.com")
+
+	// Clearsigned text
+	var buf bytes.Buffer
+	w, err := clearsign.Encode(&buf, annKey.PrivateKey, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Write([]byte("Meet at noon.\n"))
+	w.Close()
+
+	text, sec, ok := bob.OpenInline(buf.String(), "ann@example.com")
+	if !ok || sec.SignatureStatus != emailtypes.SignatureGood || text != "Meet at noon.\n" {
+		t.Errorf("OpenInline(clearsigned) = %q, %+v, %v", text, sec, ok)
+	}
+	if _, sec, _ := bob.OpenInline(buf.String(), "mallory@example.com"); sec.SignatureStatus != emailtypes.SignatureMismatch {
+		t.Errorf("OpenInline(clearsigned, other sender) = %+v, want a mismatch", sec)
+	}
+
+	// Encrypted block inside other text
+	buf.Reset()
+	aw, _ := armor.Encode(&buf, "PGP MESSAGE", nil)
+	pt, err := openpgp.Encrypt(aw, []*openpgp.Entity{bob.PublicKey("bob@example.org")}, annKey, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pt.Write([]byte("The code is 1234."))
+	pt.Close()
+	aw.Close()
+
+	text, sec, ok = bob.OpenInline("Hi Bob,\n\n"+buf.String()+"\n", "ann@example.com")
+	if !ok || !sec.Decrypted || sec.SignatureStatus != emailtypes.SignatureGood {
+		t.Fatalf("OpenInline(encrypted) = %+v, %v", sec, ok)
+	}
+	if text != "Hi Bob,\n\nThe code is 1234.\n" {
+		t.Errorf("OpenInline(encrypted) text = %q", text)
+	}
+
+	if _, _, ok := bob.OpenInline("Just text", "ann@example.com"); ok {
+		t.Error("OpenInline(plain) reported PGP content")
+	}
+}
+
+func TestSecretKeyPassphrase(t *testing.T) {
+	key := newKey(t, "Ann", "ann@example.com")
+	if err := key.EncryptPrivateKeys([]byte("s3cret"), nil); err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	writeKey(t, dir, "ann.asc", key, true)
+
+	locked, err := Load(dir, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := locked.SecretKey("ann@example.com"); err == nil || !strings.Contains(err.Error(), "GHOSTMAIL_PGP_PASSPHRASE") {
+		t.Errorf("SecretKey(locked) error = %v", err)
+	}
+
+	if _, err := Load(dir, "wrong"); err == nil {
+		t.Error("Load() with a wrong passphrase expected an error")
+	}
+
+	unlocked, err := Load(dir, "s3cret")
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if _, err := unlocked.SecretKey("ann@example.com"); err != nil {
+		t.Errorf("SecretKey() error = %v", err)
+	}
+}
@@ -0,0 +1,319 @@
+package smime
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"fmt"
+	"mime"
+	"net/mail"
+	"strings"
+	"time"
+
+	"github.com/GodGMN/ghostmail-cli/internal/mimeutil"
+	emailtypes "github.com/GodGMN/ghostmail-cli/pkg/email"
+	"github.com/smallstep/pkcs7"
+)
+
+// Protocol is the protocol name reported in emailtypes.Security.
+const Protocol = "smime"
+
+func init() {
+	// The package default is DES-CBC, which mail clients reject
+	pkcs7.ContentEncryptionAlgorithm = pkcs7.EncryptionAlgorithmAES256CBC
+}
+
+// Protect signs and/or encrypts a message as S/MIME. The content of the
+// message moves into the signed or enveloped part, while the other header
+// fields stay outside. Signed messages use a detached signature
+// (multipart/signed); encrypted ones are signed first when sign is set,
+// and also encrypted to the user's certificate.
+func (s *Store) Protect(msg []byte, from string, recipients []string, sign, encrypt bool) ([]byte, error) {
+	if !sign && !encrypt {
+		return msg, nil
+	}
+	if sign && s.key == nil {
+		return nil, fmt.Errorf("no S/MIME certificate to sign with; set GHOSTMAIL_SMIME_CERT")
+	}
+
+	outer, inner := mimeutil.SplitEntity(mimeutil.ToCRLF(msg))
+
+	if sign {
+		var err error
+		if inner, err = s.signEntity(inner); err != nil {
+			return nil, err
+		}
+	}
+
+	if encrypt {
+		certs, err := s.Recipients(recipients)
+		if err != nil {
+			return nil, err
+		}
+		if self := s.RecipientCertificate(from); self != nil {
+			certs = append(certs, self)
+		} else if s.cert != nil {
+			certs = append(certs, s.cert)
+		}
+		if inner, err = encryptEntity(inner, dedupe(certs)); err != nil {
+			return nil, err
+		}
+	}
+	return mimeutil.Join(outer, inner), nil
+}
+
+// signEntity returns the content header and body of a multipart/signed
+// entity for inner.
+func (s *Store) signEntity(inner []byte) ([]byte, error) {
+	sd, err := pkcs7.NewSignedData(inner)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign message: %w", err)
+	}
+	sd.SetDigestAlgorithm(pkcs7.OIDDigestAlgorithmSHA256)
+	if err := sd.AddSignerChain(s.cert, s.key, s.chain, pkcs7.SignerInfoConfig{}); err != nil {
+		return nil, fmt.Errorf("failed to sign message: %w", err)
+	}
+	sd.Detach()
+	sig, err := sd.Finish()
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign message: %w", err)
+	}
+
+	boundary := mimeutil.NewBoundary()
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "Content-Type: multipart/signed; micalg=sha-256;\r\n"+
+		" protocol=\"application/pkcs7-signature\"; boundary=%q\r\n\r\n", boundary)
+	fmt.Fprintf(&buf, "This is an S/MIME signed message.\r\n")
+	fmt.Fprintf(&buf, "--%s\r\n", boundary)
+	buf.Write(inner)
+	fmt.Fprintf(&buf, "\r\n--%s\r\n", boundary)
+	buf.WriteString("Content-Type: application/pkcs7-signature; name=\"smime.p7s\"\r\n" +
+		"Content-Transfer-Encoding: base64\r\n" +
+		"Content-Description: S/MIME digital signature\r\n" +
+		"Content-Disposition: attachment; filename=\"smime.p7s\"\r\n\r\n")
+	buf.Write(encodeBase64(sig))
+	fmt.Fprintf(&buf, "--%s--\r\n", boundary)
+	return buf.Bytes(), nil
+}
+
+// encryptEntity returns the content header and body of an
+// application/pkcs7-mime entity holding inner as enveloped data.
+func encryptEntity(inner []byte, to []*x509.Certificate) ([]byte, error) {
+	enveloped, err := pkcs7.Encrypt(inner, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt message: %w", err)
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("Content-Type: application/pkcs7-mime; smime-type=enveloped-data;\r\n" +
+		" name=\"smime.p7m\"\r\n" +
+		"Content-Transfer-Encoding: base64\r\n" +
+		"Content-Description: S/MIME encrypted message\r\n" +
+		"Content-Disposition: attachment; filename=\"smime.p7m\"\r\n\r\n")
+	buf.Write(encodeBase64(enveloped))
+	return buf.Bytes(), nil
+}
+
+// IsMIME reports whether a message is S/MIME signed or encrypted.
+func IsMIME(msg []byte) bool {
+	mediaType, params := mimeutil.MediaType(msg)
+	protocol := strings.ToLower(params["protocol"])
+	return (mediaType == "multipart/signed" && (protocol == "application/pkcs7-signature" || protocol == "application/x-pkcs7-signature")) ||
+		mediaType == "application/pkcs7-mime" || mediaType == "application/x-pkcs7-mime"
+}
+
+// Open decrypts and verifies an S/MIME message. It returns the message with
+// the protected content in place of the multipart/signed or
+// application/pkcs7-mime entity, and the result. A message that is not
+// S/MIME is returned unchanged with a nil result.
+func (s *Store) Open(msg []byte) ([]byte, *emailtypes.Security) {
+	msg = mimeutil.ToCRLF(msg)
+	outer, inner := mimeutil.SplitEntity(msg)
+	fields, body := mimeutil.SplitHeader(inner)
+	from := sender(outer)
+
+	mediaType, params, err := mime.ParseMediaType(mimeutil.FieldValue(fields, "Content-Type"))
+	if err != nil {
+		return msg, nil
+	}
+	protocol := strings.ToLower(params["protocol"])
+
+	switch {
+	case mediaType == "multipart/signed" && (protocol == "application/pkcs7-signature" || protocol == "application/x-pkcs7-signature"):
+		parts := mimeutil.SplitMultipart(body, params["boundary"])
+		if len(parts) != 2 {
+			return msg, nil
+		}
+		p7, err := parsePart(mimeutil.SplitHeader(parts[1]))
+		if err != nil {
+			return mimeutil.Join(outer, parts[0]), &emailtypes.Security{
+				Protocol:        Protocol,
+				Signed:          true,
+				SignatureStatus: emailtypes.SignatureBad,
+				Error:           fmt.Sprintf("invalid signature: %v", err),
+			}
+		}
+		p7.Content = parts[0]
+		return mimeutil.Join(outer, parts[0]), s.verify(p7, from)
+
+	case mediaType == "application/pkcs7-mime" || mediaType == "application/x-pkcs7-mime":
+		p7, err := parsePart(fields, body)
+		if err != nil {
+			return msg, &emailtypes.Security{Protocol: Protocol, Error: fmt.Sprintf("invalid S/MIME data: %v", err)}
+		}
+
+		// Opaque signed data carries the content inside the signature
+		if strings.EqualFold(params["smime-type"], "signed-data") || len(p7.Signers) > 0 {
+			return mimeutil.Join(outer, mimeutil.ToCRLF(p7.Content)), s.verify(p7, from)
+		}
+
+		sec := &emailtypes.Security{Protocol: Protocol, Encrypted: true}
+		if s.key == nil {
+			sec.Error = "no S/MIME certificate to decrypt the message; set GHOSTMAIL_SMIME_CERT"
+			return msg, sec
+		}
+		plaintext, err := p7.Decrypt(s.cert, s.key)
+		if err != nil {
+			sec.Error = fmt.Sprintf("failed to decrypt message: %v", err)
+			return msg, sec
+		}
+		sec.Decrypted = true
+		opened := mimeutil.Join(outer, mimeutil.ToCRLF(plaintext))
+
+		// Signed, then encrypted
+		if signed, inner := s.Open(opened); inner != nil && !inner.Encrypted {
+			opened = signed
+			sec.Signed = inner.Signed
+			sec.SignatureStatus = inner.SignatureStatus
+			sec.SignerFingerprint = inner.SignerFingerprint
+			sec.Signer = inner.Signer
+			sec.Certificate = inner.Certificate
+			if inner.Error != "" {
+				sec.Error = inner.Error
+			}
+		}
+		return opened, sec
+	}
+
+	return msg, nil
+}
+
+// parsePart decodes and parses the PKCS #7 structure in a MIME part.
+func parsePart(fields []string, body []byte) (*pkcs7.PKCS7, error) {
+	data := body
+	if strings.EqualFold(mimeutil.FieldValue(fields, "Content-Transfer-Encoding"), "base64") {
+		var err error
+		data, err = base64.StdEncoding.DecodeString(strings.Join(strings.Fields(string(body)), ""))
+		if err != nil {
+			return nil, err
+		}
+	}
+	return pkcs7.Parse(data)
+}
+
+// verify verifies the signature of signed data whose content is set, the
+// certificate of the signer against the trust store, and that the
+// certificate is issued for from, the address in the From header.
+func (s *Store) verify(p7 *pkcs7.PKCS7, from string) *emailtypes.Security {
+	sec := &emailtypes.Security{Protocol: Protocol, Signed: true}
+
+	signer := p7.GetOnlySigner()
+	if signer == nil {
+		sec.SignatureStatus = emailtypes.SignatureUnknownKey
+		sec.Error = "the signer's certificate is not included in the message"
+		return sec
+	}
+	sum := sha256.Sum256(signer.Raw)
+	sec.SignerFingerprint = fmt.Sprintf("%X", sum)
+	sec.Signer = signer.Subject.String()
+	sec.Certificate = certificateInfo(signer)
+
+	if err := p7.Verify(); err != nil {
+		sec.SignatureStatus = emailtypes.SignatureBad
+		sec.Error = err.Error()
+		return sec
+	}
+
+	// The signing time is chosen by the signer, so the certificate must
+	// be valid now
+	intermediates := x509.NewCertPool()
+	for _, cert := range p7.Certificates {
+		intermediates.AddCert(cert)
+	}
+	_, err := signer.Verify(x509.VerifyOptions{
+		Roots:         s.roots,
+		Intermediates: intermediates,
+		CurrentTime:   time.Now(),
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageEmailProtection},
+	})
+	if err != nil {
+		sec.SignatureStatus = emailtypes.SignatureUntrusted
+		sec.Error = fmt.Sprintf("certificate not trusted: %v", err)
+		return sec
+	}
+	switch {
+	case from == "":
+		sec.SignatureStatus = emailtypes.SignatureMismatch
+		sec.Error = "the message has no valid From address"
+		return sec
+	case !hasAddress(signer, from):
+		sec.SignatureStatus = emailtypes.SignatureMismatch
+		sec.Error = fmt.Sprintf("the certificate is not issued for the sender %s", from)
+		return sec
+	}
+	sec.SignatureStatus = emailtypes.SignatureGood
+	return sec
+}
+
+// sender returns the address in the From field of a header, or "".
+func sender(fields []string) string {
+	addr, err := mail.ParseAddress(mimeutil.FieldValue(fields, "From"))
+	if err != nil {
+		return ""
+	}
+	return addr.Address
+}
+
+// certificateInfo describes a certificate.
+func certificateInfo(cert *x509.Certificate) *emailtypes.Certificate {
+	return &emailtypes.Certificate{
+		Subject:      cert.Subject.String(),
+		Issuer:       cert.Issuer.String(),
+		SerialNumber: fmt.Sprintf("%X", cert.SerialNumber),
+		Emails:       Emails(cert),
+		NotBefore:    cert.NotBefore,
+		NotAfter:     cert.NotAfter,
+	}
+}
+
+// encodeBase64 encodes data as base64 in lines of 76 characters, each
+// ending with CRLF.
+func encodeBase64(data []byte) []byte {
+	encoded := base64.StdEncoding.EncodeToString(data)
+	var buf bytes.Buffer
+	for len(encoded) > 76 {
+		buf.WriteString(encoded[:76] + "\r\n")
+		encoded = encoded[76:]
+	}
+	buf.WriteString(encoded + "\r\n")
+	return buf.Bytes()
+}
+
+// dedupe removes repeated certificates.
+func dedupe(certs []*x509.Certificate) []*x509.Certificate {
+	var result []*x509.Certificate
+	for _, cert := range certs {
+		seen := false
+		for _, other := range result {
+			if cert.Equal(other) {
+				seen = true
+				break
+			}
+		}
+		if !seen {
+			result = append(result, cert)
+		}
+	}
+	return result
+}
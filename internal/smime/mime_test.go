@@ -0,0 +1,252 @@
+package smime
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/GodGMN/ghostmail-cli/internal/config"
+	emailtypes "github.com/GodGMN/ghostmail-cli/pkg/email"
+	"software.sslmate.com/src/go-pkcs12"
+)
+
+const testMessage = "From: Ann <ann@example.com>\r\n" +
+	"To: bob@example.org\r\n" +
+	"Subject: Quarterly numbers\r\n" +
+	"Mime-Version: 1.0\r\n" +
+	"Content-Type: text/plain; charset=UTF-8\r\n" +
+	"Content-Transfer-Encoding: quoted-printable\r\n" +
+	"\r\n" +
+	"Revenue is up 12%.\r\n"
+
+// identity is a test certificate and its key.
+type identity struct {
+	cert *x509.Certificate
+	key  *rsa.PrivateKey
+}
+
+// newIdentity issues a certificate for an address, signed by the issuer or
+// self-signed as a CA if issuer is nil.
+func newIdentity(t *testing.T, addr string, issuer *identity) *identity {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	serial, _ := rand.Int(rand.Reader, big.NewInt(1<<62))
+	tmpl := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: addr, Organization: []string{"Example"}},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(24 * time.Hour),
+	}
+	parent, signer := tmpl, key
+	if issuer == nil {
+		tmpl.IsCA = true
+		tmpl.BasicConstraintsValid = true
+		tmpl.KeyUsage = x509.KeyUsageCertSign
+	} else {
+		tmpl.EmailAddresses = []string{addr}
+		tmpl.KeyUsage = x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment
+		tmpl.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageEmailProtection}
+		parent, signer = issuer.cert, issuer.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, parent, &key.PublicKey, signer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &identity{cert: cert, key: key}
+}
+
+// writePEM stores certificates, and a key if set, in a PEM file.
+func writePEM(t *testing.T, path string, key *rsa.PrivateKey, certs ...*x509.Certificate) {
+	t.Helper()
+	var buf bytes.Buffer
+	for _, cert := range certs {
+		pem.Encode(&buf, &pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})
+	}
+	if key != nil {
+		der, err := x509.MarshalPKCS8PrivateKey(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pem.Encode(&buf, &pem.Block{Type: "PRIVATE KEY", Bytes: der})
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// testStores returns the stores of the sender Ann and the recipient Bob,
+// each with their own certificate and key and the other's certificate,
+// trusting the CA that issued both.
+func testStores(t *testing.T) (ann, bob *Store) {
+	t.Helper()
+	ca := newIdentity(t, "ca@example.net", nil)
+	annID := newIdentity(t, "ann@example.com", ca)
+	bobID := newIdentity(t, "bob@example.org", ca)
+
+	dir := t.TempDir()
+	writePEM(t, filepath.Join(dir, "ca.pem"), nil, ca.cert)
+	writePEM(t, filepath.Join(dir, "ann.pem"), annID.key, annID.cert)
+	writePEM(t, filepath.Join(dir, "bob.pem"), bobID.key, bobID.cert)
+	annCerts, bobCerts := t.TempDir(), t.TempDir()
+	writePEM(t, filepath.Join(annCerts, "bob.crt"), nil, bobID.cert)
+	writePEM(t, filepath.Join(bobCerts, "ann.crt"), nil, annID.cert)
+
+	var err error
+	ann, err = Load(&config.SMIMEConfig{Cert: filepath.Join(dir, "ann.pem"), CertsDir: annCerts, TrustStore: filepath.Join(dir, "ca.pem")})
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	bob, err = Load(&config.SMIMEConfig{Cert: filepath.Join(dir, "bob.pem"), CertsDir: bobCerts, TrustStore: filepath.Join(dir, "ca.pem")})
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	return ann, bob
+}
+
+func TestSignAndVerify(t *testing.T) {
+	ann, bob := testStores(t)
+
+	signed, err := ann.Protect([]byte(testMessage), "ann@example.com", []string{"bob@example.org"}, true, false)
+	if err != nil {
+		t.Fatalf("Protect() error = %v", err)
+	}
+	for _, want := range []string{"Subject: Quarterly numbers", "multipart/signed; micalg=sha-256", `protocol="application/pkcs7-signature"`, `filename="smime.p7s"`} {
+		if !strings.Contains(string(signed), want) {
+			t.Errorf("signed message missing %q:\n%s", want, signed)
+		}
+	}
+
+	opened, sec := bob.Open(signed)
+	if sec == nil || !sec.Signed || sec.Encrypted || sec.SignatureStatus != emailtypes.SignatureGood {
+		t.Fatalf("Open() security = %+v", sec)
+	}
+	if sec.Certificate == nil || sec.Certificate.Emails[0] != "ann@example.com" || !strings.Contains(sec.Signer, "CN=ann@example.com") {
+		t.Errorf("Open() signer = %q, certificate %+v", sec.Signer, sec.Certificate)
+	}
+	if string(opened) != testMessage {
+		t.Errorf("Open() message =\n%s\nwant\n%s", opened, testMessage)
+	}
+
+	// Tampering breaks the signature
+	tampered := bytes.Replace(signed, []byte("up 12%"), []byte("up 21%"), 1)
+	if _, sec := bob.Open(tampered); sec == nil || sec.SignatureStatus != emailtypes.SignatureBad {
+		t.Errorf("Open(tampered) security = %+v", sec)
+	}
+
+	// A valid signature whose certificate is not issued for the sender
+	spoofed := bytes.Replace(signed, []byte("From: Ann <ann@example.com>"), []byte("From: Ann <ann@example.net>"), 1)
+	if _, sec := bob.Open(spoofed); sec == nil || sec.SignatureStatus != emailtypes.SignatureMismatch {
+		t.Errorf("Open(other From) security = %+v, want a mismatch", sec)
+	}
+
+	// A valid signature from a CA outside the trust store is untrusted
+	stranger, err := Load(&config.SMIMEConfig{TrustStore: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, sec := stranger.Open(signed); sec == nil || sec.SignatureStatus != emailtypes.SignatureUntrusted || sec.Certificate == nil {
+		t.Errorf("Open(untrusted) security = %+v", sec)
+	}
+}
+
+func TestEncryptAndDecrypt(t *testing.T) {
+	ann, bob := testStores(t)
+
+	encrypted, err := ann.Protect([]byte(testMessage), "ann@example.com", []string{"bob@example.org"}, true, true)
+	if err != nil {
+		t.Fatalf("Protect() error = %v", err)
+	}
+	if strings.Contains(string(encrypted), "Revenue") {
+		t.Fatal("encrypted message contains the plaintext")
+	}
+	if !strings.Contains(string(encrypted), "application/pkcs7-mime; smime-type=enveloped-data") {
+		t.Errorf("encrypted message is not S/MIME:\n%s", encrypted)
+	}
+
+	// Both the recipient and the sender can decrypt it
+	for name, s := range map[string]*Store{"bob": bob, "ann": ann} {
+		opened, sec := s.Open(encrypted)
+		if sec == nil || !sec.Encrypted || !sec.Decrypted || sec.Error != "" {
+			t.Fatalf("%s: Open() security = %+v", name, sec)
+		}
+		if !sec.Signed || sec.SignatureStatus != emailtypes.SignatureGood {
+			t.Errorf("%s: Open() signature = %+v", name, sec)
+		}
+		if string(opened) != testMessage {
+			t.Errorf("%s: Open() message =\n%s", name, opened)
+		}
+	}
+
+	stranger, _ := Load(&config.SMIMEConfig{})
+	if _, sec := stranger.Open(encrypted); sec == nil || sec.Decrypted || sec.Error == "" {
+		t.Errorf("Open(no key) security = %+v", sec)
+	}
+
+	if _, err := ann.Protect([]byte(testMessage), "ann@example.com", []string{"eve@example.net"}, false, true); err == nil || !strings.Contains(err.Error(), "eve@example.net") {
+		t.Errorf("Protect() to a recipient without a certificate error = %v", err)
+	}
+}
+
+func TestOpenPlainMessage(t *testing.T) {
+	_, bob := testStores(t)
+	opened, sec := bob.Open([]byte(testMessage))
+	if sec != nil || string(opened) != testMessage {
+		t.Errorf("Open(plain) = %+v, message changed: %v", sec, string(opened) != testMessage)
+	}
+}
+
+func TestLoadPKCS12(t *testing.T) {
+	ca := newIdentity(t, "ca@example.net", nil)
+	ann := newIdentity(t, "ann@example.com", ca)
+	pfx, err := pkcs12.Modern.Encode(ann.key, ann.cert, []*x509.Certificate{ca.cert}, "s3cret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "ann.p12")
+	if err := os.WriteFile(path, pfx, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := Load(&config.SMIMEConfig{Cert: path, Password: "wrong"}); err == nil {
+		t.Error("Load() with a wrong password expected an error")
+	}
+	s, err := Load(&config.SMIMEConfig{Cert: path, Password: "s3cret"})
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if !s.Certificate().Equal(ann.cert) || len(s.chain) != 1 {
+		t.Errorf("Load() certificate = %v, chain %d", s.Certificate().Subject, len(s.chain))
+	}
+	if _, err := s.Protect([]byte(testMessage), "ann@example.com", nil, true, false); err != nil {
+		t.Errorf("Protect() error = %v", err)
+	}
+}
+
+func TestLoadMismatchedKey(t *testing.T) {
+	ca := newIdentity(t, "ca@example.net", nil)
+	ann := newIdentity(t, "ann@example.com", ca)
+	bob := newIdentity(t, "bob@example.org", ca)
+
+	dir := t.TempDir()
+	writePEM(t, filepath.Join(dir, "ann.crt"), nil, ann.cert)
+	writePEM(t, filepath.Join(dir, "bob.key"), bob.key)
+	if _, err := Load(&config.SMIMEConfig{Cert: filepath.Join(dir, "ann.crt"), Key: filepath.Join(dir, "bob.key")}); err == nil || !strings.Contains(err.Error(), "does not match") {
+		t.Errorf("Load() error = %v", err)
+	}
+}
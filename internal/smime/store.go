@@ -0,0 +1,300 @@
+// Package smime provides S/MIME (RFC 8551) signing, encryption,
+// decryption and verification with X.509 certificates.
+package smime
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/GodGMN/ghostmail-cli/internal/config"
+	"software.sslmate.com/src/go-pkcs12"
+)
+
+// certExts are the file extensions read as certificates.
+var certExts = map[string]bool{".pem": true, ".crt": true, ".cer": true, ".der": true}
+
+// oidEmailAddress is the emailAddress attribute of older certificate
+// subjects.
+var oidEmailAddress = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 1}
+
+// Store holds the user's certificate and private key, the certificates of
+// recipients and the trusted CA certificates.
+type Store struct {
+	cert  *x509.Certificate   // The user's certificate
+	chain []*x509.Certificate // Intermediate CA certificates sent along
+	key   crypto.PrivateKey
+	certs []*x509.Certificate // Certificates of recipients
+	roots *x509.CertPool
+}
+
+// Load reads the certificates and keys named in the configuration. The
+// user's certificate is optional, and a missing certificate directory has
+// no recipients. Without a trust store, the system roots are trusted.
+func Load(cfg *config.SMIMEConfig) (*Store, error) {
+	s := &Store{}
+
+	if cfg.Cert != "" {
+		if err := s.loadIdentity(cfg.Cert, cfg.Key, cfg.Password); err != nil {
+			return nil, err
+		}
+	}
+
+	entries, err := os.ReadDir(cfg.CertsDir)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("failed to read certificates: %w", err)
+	}
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		if !entry.IsDir() && certExts[strings.ToLower(filepath.Ext(entry.Name()))] {
+			names = append(names, entry.Name())
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		certs, err := readCerts(filepath.Join(cfg.CertsDir, name))
+		if err != nil {
+			return nil, err
+		}
+		s.certs = append(s.certs, certs...)
+	}
+
+	if s.roots, err = loadRoots(cfg.TrustStore); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// loadIdentity reads the user's certificate and key from a PKCS#12 file, or
+// from PEM files. The key may be in the certificate file.
+func (s *Store) loadIdentity(certFile, keyFile, password string) error {
+	data, err := os.ReadFile(certFile)
+	if err != nil {
+		return fmt.Errorf("failed to read certificate: %w", err)
+	}
+
+	if !isPEM(data) {
+		key, cert, chain, err := pkcs12.DecodeChain(data, password)
+		if err != nil {
+			return fmt.Errorf("invalid PKCS#12 file %s: %w", certFile, err)
+		}
+		s.cert, s.chain, s.key = cert, chain, key
+		return nil
+	}
+
+	certs, err := ParseCertificates(data)
+	if err != nil {
+		return fmt.Errorf("invalid certificate file %s: %w", certFile, err)
+	}
+	s.cert, s.chain = certs[0], certs[1:]
+
+	keyData := data
+	if keyFile != "" {
+		if keyData, err = os.ReadFile(keyFile); err != nil {
+			return fmt.Errorf("failed to read private key: %w", err)
+		}
+	}
+	if s.key, err = parseKey(keyData); err != nil {
+		return fmt.Errorf("invalid private key: %w", err)
+	}
+
+	pub, ok := s.key.(interface{ Public() crypto.PublicKey })
+	if !ok {
+		return fmt.Errorf("unsupported private key type %T", s.key)
+	}
+	if eq, ok := pub.Public().(interface{ Equal(crypto.PublicKey) bool }); !ok || !eq.Equal(s.cert.PublicKey) {
+		return fmt.Errorf("private key does not match the certificate in %s", certFile)
+	}
+	return nil
+}
+
+// parseKey parses the first PEM private key in data: PKCS#8, PKCS#1 RSA
+// or SEC 1 EC.
+func parseKey(data []byte) (crypto.PrivateKey, error) {
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			return nil, fmt.Errorf("no PEM private key found; set GHOSTMAIL_SMIME_KEY")
+		}
+		if !strings.HasSuffix(block.Type, "PRIVATE KEY") {
+			continue
+		}
+		if block.Type == "ENCRYPTED PRIVATE KEY" || block.Headers["Proc-Type"] != "" {
+			return nil, fmt.Errorf("encrypted PEM keys are not supported; use a PKCS#12 file")
+		}
+
+		if key, err := x509.ParsePKCS8PrivateKey(block.Bytes); err == nil {
+			switch key.(type) {
+			case *rsa.PrivateKey, *ecdsa.PrivateKey, ed25519.PrivateKey:
+				return key, nil
+			}
+			return nil, fmt.Errorf("unsupported private key type %T", key)
+		}
+		if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+			return key, nil
+		}
+		return x509.ParseECPrivateKey(block.Bytes)
+	}
+}
+
+// ParseCertificates parses PEM or DER certificates.
+func ParseCertificates(data []byte) ([]*x509.Certificate, error) {
+	if !isPEM(data) {
+		return x509.ParseCertificates(data)
+	}
+
+	var certs []*x509.Certificate
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		certs = append(certs, cert)
+	}
+	if len(certs) == 0 {
+		return nil, fmt.Errorf("no certificates found")
+	}
+	return certs, nil
+}
+
+// readCerts reads the certificates in a file.
+func readCerts(path string) ([]*x509.Certificate, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read certificate: %w", err)
+	}
+	certs, err := ParseCertificates(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid certificate file %s: %w", filepath.Base(path), err)
+	}
+	return certs, nil
+}
+
+// loadRoots reads the trusted CA certificates from a file or a directory
+// of files, or returns the system roots if path is empty.
+func loadRoots(path string) (*x509.CertPool, error) {
+	if path == "" {
+		roots, err := x509.SystemCertPool()
+		if err != nil {
+			return x509.NewCertPool(), nil
+		}
+		return roots, nil
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read trust store: %w", err)
+	}
+	files := []string{path}
+	if info.IsDir() {
+		entries, err := os.ReadDir(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read trust store: %w", err)
+		}
+		files = nil
+		for _, entry := range entries {
+			if !entry.IsDir() && certExts[strings.ToLower(filepath.Ext(entry.Name()))] {
+				files = append(files, filepath.Join(path, entry.Name()))
+			}
+		}
+	}
+
+	roots := x509.NewCertPool()
+	for _, file := range files {
+		certs, err := readCerts(file)
+		if err != nil {
+			return nil, err
+		}
+		for _, cert := range certs {
+			roots.AddCert(cert)
+		}
+	}
+	return roots, nil
+}
+
+// isPEM reports whether data looks like PEM rather than DER or PKCS#12.
+func isPEM(data []byte) bool {
+	return bytes.Contains(data, []byte("-----BEGIN "))
+}
+
+// Certificate returns the user's certificate, or nil if none is configured.
+func (s *Store) Certificate() *x509.Certificate {
+	return s.cert
+}
+
+// RecipientCertificate returns a currently valid certificate for an
+// address, preferring the one that expires last, or nil.
+func (s *Store) RecipientCertificate(addr string) *x509.Certificate {
+	now := time.Now()
+	var best *x509.Certificate
+	for _, cert := range append([]*x509.Certificate{s.cert}, s.certs...) {
+		if cert == nil || now.Before(cert.NotBefore) || now.After(cert.NotAfter) || !hasAddress(cert, addr) {
+			continue
+		}
+		if best == nil || cert.NotAfter.After(best.NotAfter) {
+			best = cert
+		}
+	}
+	return best
+}
+
+// Recipients returns the certificates for the given addresses. It fails
+// listing every address without a certificate.
+func (s *Store) Recipients(addrs []string) ([]*x509.Certificate, error) {
+	var certs []*x509.Certificate
+	var missing []string
+	for _, addr := range addrs {
+		if cert := s.RecipientCertificate(addr); cert != nil {
+			certs = append(certs, cert)
+		} else {
+			missing = append(missing, addr)
+		}
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("no S/MIME certificate for %s", strings.Join(missing, ", "))
+	}
+	return certs, nil
+}
+
+// Emails returns the addresses of a certificate, from its subject
+// alternative names and its subject.
+func Emails(cert *x509.Certificate) []string {
+	emails := append([]string(nil), cert.EmailAddresses...)
+	for _, name := range cert.Subject.Names {
+		if value, ok := name.Value.(string); ok && name.Type.Equal(oidEmailAddress) {
+			emails = append(emails, value)
+		}
+	}
+	return emails
+}
+
+// hasAddress reports whether a certificate is issued for the address.
+func hasAddress(cert *x509.Certificate, addr string) bool {
+	for _, email := range Emails(cert) {
+		if strings.EqualFold(email, addr) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,78 @@
+package spool
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Layouts accepted for absolute send times, besides RFC 3339. They are
+// interpreted in the local time zone.
+var localLayouts = []string{
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+}
+
+// ParseSendTime returns the send time for either an absolute time (at) or
+// a delay from now (in). Exactly one of them must be set and the result
+// must lie in the future.
+//
+// Absolute times are RFC 3339 (e.g. 2024-06-01T09:00:00+02:00) or local
+// times such as "2024-06-01 09:00". Delays are Go durations (90m, 2h30m)
+// or whole days (1d).
+func ParseSendTime(at, in string, now time.Time) (time.Time, error) {
+	if (at == "") == (in == "") {
+		return time.Time{}, fmt.Errorf("specify exactly one of a send time or a delay")
+	}
+
+	var t time.Time
+	if in != "" {
+		d, err := parseDelay(in)
+		if err != nil {
+			return time.Time{}, err
+		}
+		t = now.Add(d)
+	} else {
+		var err error
+		t, err = parseAbsolute(at)
+		if err != nil {
+			return time.Time{}, err
+		}
+	}
+
+	if !t.After(now) {
+		return time.Time{}, fmt.Errorf("send time %s is not in the future", t.Format(time.RFC3339))
+	}
+	return t, nil
+}
+
+func parseAbsolute(s string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	for _, layout := range localLayouts {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid send time %q (use RFC 3339, e.g. 2024-06-01T09:00:00+02:00)", s)
+}
+
+func parseDelay(s string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n <= 0 {
+			return 0, fmt.Errorf("invalid delay %q", s)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+
+	d, err := time.ParseDuration(s)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid delay %q (use e.g. 90m, 2h or 1d)", s)
+	}
+	return d, nil
+}
@@ -0,0 +1,52 @@
+package spool
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseSendTime(t *testing.T) {
+	now := time.Date(2024, 6, 1, 6, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		at      string
+		in      string
+		want    time.Time
+		wantErr bool
+	}{
+		{"rfc3339", "2024-06-01T09:00:00+02:00", "", time.Date(2024, 6, 1, 7, 0, 0, 0, time.UTC), false},
+		{"duration", "", "2h", now.Add(2 * time.Hour), false},
+		{"days", "", "1d", now.Add(24 * time.Hour), false},
+		{"past", "2024-06-01T07:00:00+02:00", "", time.Time{}, true},
+		{"both", "2024-06-02T09:00:00Z", "2h", time.Time{}, true},
+		{"neither", "", "", time.Time{}, true},
+		{"invalid time", "tomorrow", "", time.Time{}, true},
+		{"invalid delay", "", "soon", time.Time{}, true},
+		{"negative delay", "", "-1h", time.Time{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseSendTime(tt.at, tt.in, now)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseSendTime() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !got.Equal(tt.want) {
+				t.Errorf("ParseSendTime() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseSendTimeLocal(t *testing.T) {
+	now := time.Date(2024, 6, 1, 6, 0, 0, 0, time.Local)
+
+	got, err := ParseSendTime("2024-06-01 09:00", "", now)
+	if err != nil {
+		t.Fatalf("ParseSendTime() error = %v", err)
+	}
+	if want := time.Date(2024, 6, 1, 9, 0, 0, 0, time.Local); !got.Equal(want) {
+		t.Errorf("ParseSendTime() = %v, want %v", got, want)
+	}
+}
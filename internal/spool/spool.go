@@ -0,0 +1,484 @@
+// Package spool stores built messages on disk until they are delivered.
+package spool
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	emailinternal "github.com/GodGMN/ghostmail-cli/internal/email"
+	emailtypes "github.com/GodGMN/ghostmail-cli/pkg/email"
+)
+
+// Entry states.
+const (
+	StatusScheduled = "scheduled"
+	StatusSending   = "sending"
+	StatusFailed    = "failed"
+)
+
+// File name suffixes. An entry's metadata is renamed from .json to
+// .sending while it is being delivered, so concurrent runs never pick up
+// the same message twice, and to .reclaim while Reclaim takes over a stale
+// claim.
+const (
+	messageSuffix = ".eml"
+	entrySuffix   = ".json"
+	sendingSuffix = ".sending"
+	reclaimSuffix = ".reclaim"
+)
+
+// ClaimLease is how long a claim is held. Entries of a run that crashed or
+// was killed while sending stay claimed until Reclaim returns them to the
+// schedule after the lease. It is far longer than a delivery takes, so a
+// live run does not lose its claim.
+const ClaimLease = 30 * time.Minute
+
+// ErrNotFound is returned for unknown entry IDs.
+var ErrNotFound = errors.New("no queued message with this ID")
+
+// ErrClaimed is returned by Claim when another run is delivering the entry.
+var ErrClaimed = errors.New("message is already being sent")
+
+// Spool is a directory of messages waiting to be sent.
+type Spool struct {
+	dir string
+}
+
+// New creates a spool backed by the given directory.
+func New(dir string) *Spool {
+	return &Spool{dir: dir}
+}
+
+// Dir returns the spool directory.
+func (s *Spool) Dir() string {
+	return s.dir
+}
+
+// Add stores a built message to be sent at entry.SendAt. The ID, status,
+// creation time and envelope of the entry are filled in by Add.
+func (s *Spool) Add(msg *emailinternal.OutgoingMessage, entry emailtypes.QueueEntry) (*emailtypes.QueueEntry, error) {
+	if err := os.MkdirAll(s.dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create spool directory: %w", err)
+	}
+
+	// The message is written first, with a new file that reserves the ID:
+	// an entry only exists once its metadata is in place.
+	var id string
+	for attempt := 1; ; attempt++ {
+		var err error
+		if id, err = newID(); err != nil {
+			return nil, err
+		}
+		err = createFile(s.path(id, messageSuffix), msg.Data)
+		if err == nil {
+			break
+		}
+		if !errors.Is(err, os.ErrExist) || attempt == maxIDAttempts {
+			return nil, fmt.Errorf("failed to write queued message: %w", err)
+		}
+	}
+
+	entry.ID = id
+	entry.Status = StatusScheduled
+	entry.CreatedAt = time.Now()
+	entry.From = msg.From
+	entry.To = msg.Recipients
+
+	if err := s.write(&entry, entrySuffix); err != nil {
+		os.Remove(s.path(id, messageSuffix))
+		return nil, err
+	}
+
+	return &entry, nil
+}
+
+// List returns all entries ordered by send time.
+func (s *Spool) List() ([]emailtypes.QueueEntry, error) {
+	files, err := os.ReadDir(s.dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read spool directory: %w", err)
+	}
+
+	var entries []emailtypes.QueueEntry
+	for _, f := range files {
+		name := f.Name()
+		var id string
+		switch {
+		case strings.HasSuffix(name, entrySuffix):
+			id = strings.TrimSuffix(name, entrySuffix)
+		case strings.HasSuffix(name, sendingSuffix):
+			id = strings.TrimSuffix(name, sendingSuffix)
+		default:
+			continue
+		}
+
+		entry, err := s.read(id)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, *entry)
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].SendAt.Before(entries[j].SendAt)
+	})
+	return entries, nil
+}
+
+// Due returns the scheduled entries whose send time has come.
+func (s *Spool) Due(now time.Time) ([]emailtypes.QueueEntry, error) {
+	entries, err := s.List()
+	if err != nil {
+		return nil, err
+	}
+
+	var due []emailtypes.QueueEntry
+	for _, e := range entries {
+		if e.Status == StatusScheduled && !e.SendAt.After(now) {
+			due = append(due, e)
+		}
+	}
+	return due, nil
+}
+
+// Claim marks a scheduled entry as being sent and returns its message.
+// Only one caller can claim an entry; the others get ErrClaimed.
+func (s *Spool) Claim(id string) (*emailinternal.OutgoingMessage, error) {
+	if !validID(id) {
+		return nil, ErrNotFound
+	}
+	if err := os.Rename(s.path(id, entrySuffix), s.path(id, sendingSuffix)); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			if _, serr := os.Stat(s.path(id, sendingSuffix)); serr == nil {
+				return nil, ErrClaimed
+			}
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to claim queued message: %w", err)
+	}
+
+	entry, err := s.read(id)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	entry.ClaimedAt = &now
+	if err := s.write(entry, sendingSuffix); err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(s.path(id, messageSuffix))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read queued message: %w", err)
+	}
+
+	return &emailinternal.OutgoingMessage{
+		From:       entry.From,
+		Recipients: entry.To,
+		Data:       data,
+	}, nil
+}
+
+// Release returns a claimed entry to the schedule after a failed delivery,
+// counting the attempt and recording the error. A non-zero next time
+// reschedules the entry.
+func (s *Spool) Release(id string, sendErr error, next time.Time) error {
+	entry, err := s.read(id)
+	if err != nil {
+		return err
+	}
+
+	entry.Status = StatusScheduled
+	entry.ClaimedAt = nil
+	entry.Attempts++
+	entry.Error = ""
+	if sendErr != nil {
+		entry.Error = sendErr.Error()
+	}
+	if !next.IsZero() {
+		entry.SendAt = next
+	}
+	if err := s.write(entry, entrySuffix); err != nil {
+		return err
+	}
+	return os.Remove(s.path(id, sendingSuffix))
+}
+
+// Unclaim returns a claimed entry to the schedule without counting an
+// attempt, after the server could not hold it with FUTURERELEASE. The entry
+// is marked so that the hand-off is not tried again.
+func (s *Spool) Unclaim(id string) error {
+	return s.unclaim(id, nil, true)
+}
+
+// Requeue returns a claimed entry to the schedule without counting an
+// attempt, recording the error, after a failure that does not concern the
+// message, such as the server refusing the login.
+func (s *Spool) Requeue(id string, sendErr error) error {
+	return s.unclaim(id, sendErr, false)
+}
+
+func (s *Spool) unclaim(id string, sendErr error, noFutureRelease bool) error {
+	entry, err := s.read(id)
+	if err != nil {
+		return err
+	}
+
+	entry.Status = StatusScheduled
+	entry.ClaimedAt = nil
+	if sendErr != nil {
+		entry.Error = sendErr.Error()
+	}
+	if noFutureRelease {
+		entry.NoFutureRelease = true
+	}
+	if err := s.write(entry, entrySuffix); err != nil {
+		return err
+	}
+	return os.Remove(s.path(id, sendingSuffix))
+}
+
+// Reclaim returns entries claimed longer than ClaimLease ago to the
+// schedule and returns their IDs. The interrupted attempt is counted. The
+// message may have been sent before the run stopped, so it can be
+// delivered twice.
+func (s *Spool) Reclaim(now time.Time) ([]string, error) {
+	entries, err := s.List()
+	if err != nil {
+		return nil, err
+	}
+
+	var ids []string
+	for _, e := range entries {
+		if e.Status != StatusSending || !stale(&e, now) {
+			continue
+		}
+
+		// Only one run can move the claim aside. Another run may have
+		// reclaimed and claimed the entry since it was listed, so the
+		// claim is checked again.
+		if err := os.Rename(s.path(e.ID, sendingSuffix), s.path(e.ID, reclaimSuffix)); err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+			return ids, fmt.Errorf("failed to reclaim queued message: %w", err)
+		}
+		entry, err := s.readFile(e.ID, reclaimSuffix)
+		if err != nil {
+			return ids, err
+		}
+		if !stale(entry, now) {
+			if err := os.Rename(s.path(e.ID, reclaimSuffix), s.path(e.ID, sendingSuffix)); err != nil {
+				return ids, fmt.Errorf("failed to restore claim of queued message: %w", err)
+			}
+			continue
+		}
+
+		entry.Status = StatusScheduled
+		entry.ClaimedAt = nil
+		entry.Attempts++
+		entry.Error = "delivery was interrupted"
+		if err := s.write(entry, entrySuffix); err != nil {
+			return ids, err
+		}
+		if err := os.Remove(s.path(e.ID, reclaimSuffix)); err != nil {
+			return ids, fmt.Errorf("failed to remove stale claim: %w", err)
+		}
+		ids = append(ids, e.ID)
+	}
+	return ids, nil
+}
+
+// stale reports whether the claim of an entry has expired. Claims without
+// a time are treated as expired.
+func stale(entry *emailtypes.QueueEntry, now time.Time) bool {
+	return entry.ClaimedAt == nil || now.Sub(*entry.ClaimedAt) >= ClaimLease
+}
+
+// Remove deletes an entry and its message.
+func (s *Spool) Remove(id string) error {
+	if !validID(id) {
+		return ErrNotFound
+	}
+	found := false
+	for _, suffix := range []string{entrySuffix, sendingSuffix, reclaimSuffix, messageSuffix} {
+		err := os.Remove(s.path(id, suffix))
+		if err == nil {
+			found = true
+		} else if !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("failed to remove queued message: %w", err)
+		}
+	}
+	if !found {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// DeadLetterReport describes a message that was given up on.
+type DeadLetterReport struct {
+	emailtypes.QueueEntry
+	FailedAt time.Time `json:"failed_at"`
+}
+
+// DeadLetter moves a claimed entry out of the spool into dir, as the
+// original message (<id>.eml) and a JSON report (<id>.json) with its
+// attempts and final error.
+func (s *Spool) DeadLetter(id string, sendErr error, dir string) (*DeadLetterReport, error) {
+	entry, err := s.read(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create dead-letter directory: %w", err)
+	}
+
+	entry.Status = StatusFailed
+	entry.ClaimedAt = nil
+	entry.Attempts++
+	entry.Error = sendErr.Error()
+	report := &DeadLetterReport{QueueEntry: *entry, FailedAt: time.Now()}
+
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode dead-letter report: %w", err)
+	}
+	if err := os.Rename(s.path(id, messageSuffix), filepath.Join(dir, id+messageSuffix)); err != nil {
+		return nil, fmt.Errorf("failed to move message to dead-letter directory: %w", err)
+	}
+	if err := writeFile(filepath.Join(dir, id+entrySuffix), data); err != nil {
+		return nil, fmt.Errorf("failed to write dead-letter report: %w", err)
+	}
+	if err := os.Remove(s.path(id, sendingSuffix)); err != nil {
+		return nil, fmt.Errorf("failed to remove queue entry: %w", err)
+	}
+
+	return report, nil
+}
+
+// Backoff returns the delay before the next delivery attempt after the
+// given number of failed attempts: one minute, doubling up to an hour.
+func Backoff(attempts int) time.Duration {
+	d := time.Minute
+	for i := 1; i < attempts && d < time.Hour; i++ {
+		d *= 2
+	}
+	if d > time.Hour {
+		d = time.Hour
+	}
+	return d
+}
+
+// read loads the metadata of an entry, scheduled or being sent.
+func (s *Spool) read(id string) (*emailtypes.QueueEntry, error) {
+	if !validID(id) {
+		return nil, ErrNotFound
+	}
+
+	status := StatusScheduled
+	data, err := os.ReadFile(s.path(id, entrySuffix))
+	if errors.Is(err, os.ErrNotExist) {
+		status = StatusSending
+		data, err = os.ReadFile(s.path(id, sendingSuffix))
+	}
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read queue entry %s: %w", id, err)
+	}
+
+	entry, err := decodeEntry(id, data)
+	if err != nil {
+		return nil, err
+	}
+	entry.Status = status
+	return entry, nil
+}
+
+// readFile loads the metadata of an entry from the file with the given
+// suffix.
+func (s *Spool) readFile(id, suffix string) (*emailtypes.QueueEntry, error) {
+	data, err := os.ReadFile(s.path(id, suffix))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read queue entry %s: %w", id, err)
+	}
+	return decodeEntry(id, data)
+}
+
+func decodeEntry(id string, data []byte) (*emailtypes.QueueEntry, error) {
+	var entry emailtypes.QueueEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return nil, fmt.Errorf("invalid queue entry %s: %w", id, err)
+	}
+	return &entry, nil
+}
+
+// write stores the metadata of an entry.
+func (s *Spool) write(entry *emailtypes.QueueEntry, suffix string) error {
+	data, err := json.MarshalIndent(entry, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode queue entry: %w", err)
+	}
+	if err := writeFile(s.path(entry.ID, suffix), data); err != nil {
+		return fmt.Errorf("failed to write queue entry: %w", err)
+	}
+	return nil
+}
+
+func (s *Spool) path(id, suffix string) string {
+	return filepath.Join(s.dir, id+suffix)
+}
+
+// writeFile writes a file atomically by renaming a temporary file.
+func writeFile(path string, data []byte) error {
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// createFile writes a new file, failing with os.ErrExist if it exists.
+func createFile(path string, data []byte) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(path)
+	}
+	return err
+}
+
+// maxIDAttempts is how many IDs Add tries before giving up, should the
+// short random IDs keep colliding with queued messages.
+const maxIDAttempts = 10
+
+// newID returns a short random entry ID. It is a variable for tests.
+var newID = func() (string, error) {
+	b := make([]byte, 4)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate ID: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// validID reports whether id can be used as a file name in the spool.
+func validID(id string) bool {
+	return id != "" && !strings.ContainsAny(id, `/\.`)
+}
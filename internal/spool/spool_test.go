@@ -0,0 +1,285 @@
+package spool
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	emailinternal "github.com/GodGMN/ghostmail-cli/internal/email"
+	emailtypes "github.com/GodGMN/ghostmail-cli/pkg/email"
+)
+
+func testMessage() *emailinternal.OutgoingMessage {
+	return &emailinternal.OutgoingMessage{
+		From:       "bot@example.com",
+		Recipients: []string{"bob@example.com"},
+		Data:       []byte("Subject: Hi\r\n\r\nHello\r\n"),
+	}
+}
+
+func TestSpoolLifecycle(t *testing.T) {
+	s := New(t.TempDir())
+	now := time.Now()
+
+	later, err := s.Add(testMessage(), emailtypes.QueueEntry{Subject: "Later", SendAt: now.Add(time.Hour), SaveSent: true})
+	if err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	due, err := s.Add(testMessage(), emailtypes.QueueEntry{Subject: "Due", SendAt: now.Add(-time.Minute)})
+	if err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+
+	entries, err := s.List()
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(entries) != 2 || entries[0].ID != due.ID || entries[1].ID != later.ID {
+		t.Fatalf("List() = %v, want entries ordered by send time", entries)
+	}
+	if !entries[1].SaveSent || entries[1].Subject != "Later" {
+		t.Errorf("List()[1] = %+v, want the stored metadata", entries[1])
+	}
+
+	dueEntries, err := s.Due(now)
+	if err != nil {
+		t.Fatalf("Due() error = %v", err)
+	}
+	if len(dueEntries) != 1 || dueEntries[0].ID != due.ID {
+		t.Fatalf("Due() = %v, want only %s", dueEntries, due.ID)
+	}
+
+	msg, err := s.Claim(due.ID)
+	if err != nil {
+		t.Fatalf("Claim() error = %v", err)
+	}
+	if string(msg.Data) != string(testMessage().Data) || msg.From != "bot@example.com" {
+		t.Errorf("Claim() = %+v, want the stored message", msg)
+	}
+	if _, err := s.Claim(due.ID); !errors.Is(err, ErrClaimed) {
+		t.Errorf("second Claim() error = %v, want ErrClaimed", err)
+	}
+	if dueEntries, _ := s.Due(now); len(dueEntries) != 0 {
+		t.Errorf("Due() = %v, claimed entries must not be due", dueEntries)
+	}
+
+	if err := s.Release(due.ID, errors.New("connection refused"), time.Time{}); err != nil {
+		t.Fatalf("Release() error = %v", err)
+	}
+	dueEntries, _ = s.Due(now)
+	if len(dueEntries) != 1 || dueEntries[0].Error != "connection refused" || dueEntries[0].Attempts != 1 {
+		t.Errorf("Due() after Release() = %v, want the entry with its error", dueEntries)
+	}
+
+	if err := s.Remove(due.ID); err != nil {
+		t.Fatalf("Remove() error = %v", err)
+	}
+	if err := s.Remove(due.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("second Remove() error = %v, want ErrNotFound", err)
+	}
+	if entries, _ := s.List(); len(entries) != 1 {
+		t.Errorf("List() returned %d entries after Remove(), want 1", len(entries))
+	}
+}
+
+func TestSpoolIDCollision(t *testing.T) {
+	ids := []string{"0000aaaa", "0000aaaa", "0000bbbb"}
+	orig := newID
+	newID = func() (string, error) {
+		id := ids[0]
+		ids = ids[1:]
+		return id, nil
+	}
+	defer func() { newID = orig }()
+
+	s := New(t.TempDir())
+	first, err := s.Add(testMessage(), emailtypes.QueueEntry{Subject: "First"})
+	if err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	second, err := s.Add(testMessage(), emailtypes.QueueEntry{Subject: "Second"})
+	if err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	if first.ID != "0000aaaa" || second.ID != "0000bbbb" {
+		t.Errorf("Add() IDs = %s, %s, want a new ID after the collision", first.ID, second.ID)
+	}
+	if entries, _ := s.List(); len(entries) != 2 {
+		t.Errorf("List() returned %d entries, want both", len(entries))
+	}
+}
+
+func TestSpoolInvalidID(t *testing.T) {
+	s := New(t.TempDir())
+
+	for _, id := range []string{"", "../etc", "a.json"} {
+		if err := s.Remove(id); !errors.Is(err, ErrNotFound) {
+			t.Errorf("Remove(%q) error = %v, want ErrNotFound", id, err)
+		}
+		if _, err := s.Claim(id); !errors.Is(err, ErrNotFound) {
+			t.Errorf("Claim(%q) error = %v, want ErrNotFound", id, err)
+		}
+	}
+}
+
+func TestSpoolListMissingDir(t *testing.T) {
+	entries, err := New(t.TempDir() + "/missing").List()
+	if err != nil || len(entries) != 0 {
+		t.Errorf("List() = %v, %v, want no entries and no error", entries, err)
+	}
+}
+
+func TestSpoolRescheduleAndDeadLetter(t *testing.T) {
+	s := New(t.TempDir())
+	deadDir := t.TempDir()
+	now := time.Now()
+
+	entry, err := s.Add(testMessage(), emailtypes.QueueEntry{Subject: "Alert", SendAt: now})
+	if err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+
+	if _, err := s.Claim(entry.ID); err != nil {
+		t.Fatalf("Claim() error = %v", err)
+	}
+	next := now.Add(Backoff(1))
+	if err := s.Release(entry.ID, errors.New("421 try later"), next); err != nil {
+		t.Fatalf("Release() error = %v", err)
+	}
+	if due, _ := s.Due(now); len(due) != 0 {
+		t.Errorf("Due() = %v, rescheduled entry must not be due yet", due)
+	}
+	if due, _ := s.Due(next); len(due) != 1 {
+		t.Fatalf("Due(next) returned %d entries, want 1", len(due))
+	}
+
+	if _, err := s.Claim(entry.ID); err != nil {
+		t.Fatalf("Claim() error = %v", err)
+	}
+	report, err := s.DeadLetter(entry.ID, errors.New("550 no such user"), deadDir)
+	if err != nil {
+		t.Fatalf("DeadLetter() error = %v", err)
+	}
+	if report.Attempts != 2 || report.Error != "550 no such user" {
+		t.Errorf("DeadLetter() report = %+v, want 2 attempts and the final error", report)
+	}
+	if entries, _ := s.List(); len(entries) != 0 {
+		t.Errorf("List() = %v, dead-lettered entry must leave the spool", entries)
+	}
+
+	data, err := os.ReadFile(filepath.Join(deadDir, entry.ID+".eml"))
+	if err != nil || string(data) != string(testMessage().Data) {
+		t.Errorf("dead-letter message = %q, %v, want the original message", data, err)
+	}
+	if _, err := os.Stat(filepath.Join(deadDir, entry.ID+".json")); err != nil {
+		t.Errorf("dead-letter report missing: %v", err)
+	}
+}
+
+func TestSpoolReclaim(t *testing.T) {
+	s := New(t.TempDir())
+	now := time.Now()
+
+	entry, err := s.Add(testMessage(), emailtypes.QueueEntry{Subject: "Alert", SendAt: now})
+	if err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	if _, err := s.Claim(entry.ID); err != nil {
+		t.Fatalf("Claim() error = %v", err)
+	}
+	entries, _ := s.List()
+	if len(entries) != 1 || entries[0].ClaimedAt == nil {
+		t.Fatalf("List() = %v, want the claim time of the entry", entries)
+	}
+
+	ids, err := s.Reclaim(now.Add(ClaimLease / 2))
+	if err != nil || len(ids) != 0 {
+		t.Errorf("Reclaim() before the lease expired = %v, %v, want nothing reclaimed", ids, err)
+	}
+	if _, err := s.Claim(entry.ID); !errors.Is(err, ErrClaimed) {
+		t.Errorf("Claim() error = %v, want ErrClaimed while the lease holds", err)
+	}
+
+	ids, err = s.Reclaim(now.Add(ClaimLease + time.Minute))
+	if err != nil || len(ids) != 1 || ids[0] != entry.ID {
+		t.Fatalf("Reclaim() after the lease = %v, %v, want %s", ids, err, entry.ID)
+	}
+	due, _ := s.Due(now)
+	if len(due) != 1 || due[0].Attempts != 1 || due[0].ClaimedAt != nil || due[0].Error == "" {
+		t.Fatalf("Due() after Reclaim() = %+v, want the entry with the interrupted attempt", due)
+	}
+	if _, err := s.Claim(entry.ID); err != nil {
+		t.Errorf("Claim() after Reclaim() error = %v", err)
+	}
+}
+
+func TestSpoolUnclaim(t *testing.T) {
+	s := New(t.TempDir())
+	later := time.Now().Add(time.Hour)
+
+	entry, err := s.Add(testMessage(), emailtypes.QueueEntry{Subject: "Later", SendAt: later})
+	if err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	if _, err := s.Claim(entry.ID); err != nil {
+		t.Fatalf("Claim() error = %v", err)
+	}
+	if err := s.Unclaim(entry.ID); err != nil {
+		t.Fatalf("Unclaim() error = %v", err)
+	}
+
+	entries, _ := s.List()
+	if len(entries) != 1 {
+		t.Fatalf("List() returned %d entries, want 1", len(entries))
+	}
+	got := entries[0]
+	if got.Status != StatusScheduled || !got.NoFutureRelease || got.Attempts != 0 || !got.SendAt.Equal(later) {
+		t.Errorf("entry after Unclaim() = %+v, want it scheduled unchanged and marked", got)
+	}
+}
+
+func TestSpoolRequeue(t *testing.T) {
+	s := New(t.TempDir())
+	now := time.Now()
+
+	entry, err := s.Add(testMessage(), emailtypes.QueueEntry{Subject: "Now", SendAt: now, Attempts: 1})
+	if err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	if _, err := s.Claim(entry.ID); err != nil {
+		t.Fatalf("Claim() error = %v", err)
+	}
+	if err := s.Requeue(entry.ID, errors.New("535 authentication failed")); err != nil {
+		t.Fatalf("Requeue() error = %v", err)
+	}
+
+	entries, _ := s.List()
+	if len(entries) != 1 {
+		t.Fatalf("List() returned %d entries, want 1", len(entries))
+	}
+	got := entries[0]
+	if got.Status != StatusScheduled || got.NoFutureRelease || got.Attempts != 1 || !got.SendAt.Equal(now) || got.Error != "535 authentication failed" {
+		t.Errorf("entry after Requeue() = %+v, want it scheduled unchanged with the error", got)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{1, time.Minute},
+		{2, 2 * time.Minute},
+		{4, 8 * time.Minute},
+		{7, time.Hour},
+		{20, time.Hour},
+	}
+
+	for _, tt := range tests {
+		if got := Backoff(tt.attempts); got != tt.want {
+			t.Errorf("Backoff(%d) = %v, want %v", tt.attempts, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,363 @@
+// Package templates loads named message templates from the templates
+// directory.
+//
+// A template file is named <name>.tmpl and starts with optional front
+// matter between "---" lines, followed by the body:
+//
+//	---
+//	description: Incident notification
+//	subject: [{{upper .severity}}] {{.service}} is down
+//	to: oncall@example.com
+//	cc: {{.owner}}
+//	vars: service, owner, severity=sev3
+//	---
+//	{{.service}} has been down since {{date "15:04" .since}}.
+//	{{define "html"}}<p><b>{{.service}}</b> is down.</p>{{end}}
+//
+// The body is a merge template (see the merge package): text outside of
+// definitions is the text body and an optional "html" definition is the
+// HTML body. Every front matter value except description and vars is a
+// template too.
+package templates
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/GodGMN/ghostmail-cli/internal/merge"
+)
+
+// Ext is the file extension of template files.
+const Ext = ".tmpl"
+
+// frontMatterDelim separates the front matter from the body.
+const frontMatterDelim = "---"
+
+// ErrMissingVariables is returned by Render when required variables are
+// not given.
+var ErrMissingVariables = errors.New("missing template variable(s)")
+
+// Template is a named message template.
+type Template struct {
+	Name        string
+	Path        string
+	Description string
+	Subject     string
+	To          string
+	CC          string
+	BCC         string
+	Attachments string
+
+	// Declared lists the variables named in the vars front matter, and
+	// Defaults the values of those declared with name=value.
+	Declared []string
+	Defaults map[string]string
+
+	Body string
+
+	msg     *merge.Template
+	headers *template.Template
+}
+
+// Message is a template rendered with a set of variables.
+type Message struct {
+	Subject     string
+	To          []string
+	CC          []string
+	BCC         []string
+	Attachments []string
+	Text        string
+	HTML        string
+}
+
+// Load reads the template with the given name from dir.
+func Load(dir, name string) (*Template, error) {
+	if name == "" || strings.ContainsAny(name, `/\`) || strings.HasPrefix(name, ".") {
+		return nil, fmt.Errorf("invalid template name %q", name)
+	}
+
+	path := filepath.Join(dir, name+Ext)
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("template %q not found in %s", name, dir)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read template: %w", err)
+	}
+
+	t, err := Parse(name, string(data))
+	if err != nil {
+		return nil, fmt.Errorf("template %s: %w", path, err)
+	}
+	t.Path = path
+	return t, nil
+}
+
+// List loads all templates in dir, sorted by name. A missing directory
+// holds no templates.
+func List(dir string) ([]*Template, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*"+Ext))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+
+	templates := make([]*Template, 0, len(files))
+	for _, file := range files {
+		t, err := Load(dir, strings.TrimSuffix(filepath.Base(file), Ext))
+		if err != nil {
+			return nil, err
+		}
+		templates = append(templates, t)
+	}
+	return templates, nil
+}
+
+// Parse parses a template file.
+func Parse(name, data string) (*Template, error) {
+	t := &Template{Name: name, Defaults: make(map[string]string)}
+
+	body, err := t.parseFrontMatter(data)
+	if err != nil {
+		return nil, err
+	}
+	t.Body = body
+
+	t.msg, err = merge.ParseTemplate(body, t.Subject)
+	if err != nil {
+		return nil, err
+	}
+
+	t.headers = template.New("").Funcs(merge.Funcs).Option("missingkey=error")
+	for field, value := range map[string]string{"to": t.To, "cc": t.CC, "bcc": t.BCC, "attachments": t.Attachments} {
+		if _, err := t.headers.New(field).Parse(value); err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", field, err)
+		}
+	}
+
+	return t, nil
+}
+
+// parseFrontMatter sets the fields of t from the front matter of data and
+// returns the body that follows it.
+func (t *Template) parseFrontMatter(data string) (string, error) {
+	data = strings.ReplaceAll(data, "\r\n", "\n")
+	if !strings.HasPrefix(data, frontMatterDelim+"\n") {
+		return data, nil
+	}
+
+	// Find the closing delimiter line
+	lines := strings.SplitAfter(data[len(frontMatterDelim)+1:], "\n")
+	end := -1
+	for i, line := range lines {
+		if strings.TrimSuffix(line, "\n") == frontMatterDelim {
+			end = i
+			break
+		}
+	}
+	if end < 0 {
+		return "", fmt.Errorf("front matter is not closed with %q", frontMatterDelim)
+	}
+	header := strings.Join(lines[:end], "")
+	body := strings.Join(lines[end+1:], "")
+
+	scanner := bufio.NewScanner(strings.NewReader(header))
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		key, value, ok := strings.Cut(text, ":")
+		if !ok {
+			return "", fmt.Errorf("front matter line %d: expected \"key: value\"", line)
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = unquote(strings.TrimSpace(value))
+
+		switch key {
+		case "description":
+			t.Description = value
+		case "subject":
+			t.Subject = value
+		case "to":
+			t.To = value
+		case "cc":
+			t.CC = value
+		case "bcc":
+			t.BCC = value
+		case "attachments":
+			t.Attachments = value
+		case "vars":
+			for _, v := range splitList(value) {
+				name, def, hasDefault := strings.Cut(v, "=")
+				name = strings.TrimSpace(name)
+				t.Declared = append(t.Declared, name)
+				if hasDefault {
+					t.Defaults[name] = strings.TrimSpace(def)
+				}
+			}
+		default:
+			return "", fmt.Errorf("front matter line %d: unknown key %q", line, key)
+		}
+	}
+
+	return body, nil
+}
+
+// Variables returns the names of all variables the template uses or
+// declares, sorted.
+func (t *Template) Variables() []string {
+	seen := make(map[string]bool)
+	for _, v := range t.msg.Variables() {
+		seen[v] = true
+	}
+	for _, v := range merge.Variables(t.headers) {
+		seen[v] = true
+	}
+	for _, v := range t.Declared {
+		seen[v] = true
+	}
+
+	vars := make([]string, 0, len(seen))
+	for v := range seen {
+		vars = append(vars, v)
+	}
+	sort.Strings(vars)
+	return vars
+}
+
+// Required returns the variables that have no default value, either in
+// the vars front matter or by only being used through the default
+// function.
+func (t *Template) Required() []string {
+	optional := t.optional()
+	var required []string
+	for _, v := range t.Variables() {
+		if _, ok := t.Defaults[v]; !ok && !optional[v] {
+			required = append(required, v)
+		}
+	}
+	return required
+}
+
+// optional returns the variables that the message and the headers only
+// use as the value given to default.
+func (t *Template) optional() map[string]bool {
+	msgVars, msgOptional := t.msg.Variables(), t.msg.OptionalVariables()
+	headerVars, headerOptional := merge.Variables(t.headers), merge.OptionalVariables(t.headers)
+
+	optional := make(map[string]bool)
+	for _, v := range append(msgOptional, headerOptional...) {
+		optional[v] = true
+	}
+	// A variable used without default anywhere is required
+	for _, v := range msgVars {
+		if !slices.Contains(msgOptional, v) {
+			delete(optional, v)
+		}
+	}
+	for _, v := range headerVars {
+		if !slices.Contains(headerOptional, v) {
+			delete(optional, v)
+		}
+	}
+	return optional
+}
+
+// Missing returns the required variables that vars does not provide.
+func (t *Template) Missing(vars map[string]string) []string {
+	var missing []string
+	for _, v := range t.Required() {
+		if _, ok := vars[v]; !ok {
+			missing = append(missing, v)
+		}
+	}
+	return missing
+}
+
+// Render renders the template with the given variables. It fails before
+// rendering anything if a required variable is missing.
+func (t *Template) Render(vars map[string]string) (*Message, error) {
+	if missing := t.Missing(vars); len(missing) > 0 {
+		return nil, fmt.Errorf("%w: %s", ErrMissingVariables, strings.Join(missing, ", "))
+	}
+
+	row := make(merge.Row, len(t.Defaults)+len(vars))
+	for k, v := range t.Defaults {
+		row[k] = v
+	}
+	for k, v := range vars {
+		row[k] = v
+	}
+	// Variables only used through default render as its fallback
+	for k := range t.optional() {
+		if _, ok := row[k]; !ok {
+			row[k] = ""
+		}
+	}
+
+	rendered, err := t.msg.Render(row)
+	if err != nil {
+		return nil, err
+	}
+	msg := &Message{
+		Subject: rendered.Subject,
+		Text:    rendered.Text,
+		HTML:    rendered.HTML,
+	}
+
+	for field, list := range map[string]*[]string{"to": &msg.To, "cc": &msg.CC, "bcc": &msg.BCC, "attachments": &msg.Attachments} {
+		var buf strings.Builder
+		if err := t.headers.ExecuteTemplate(&buf, field, row); err != nil {
+			return nil, fmt.Errorf("failed to render %s: %w", field, err)
+		}
+		*list = splitList(buf.String())
+	}
+
+	return msg, nil
+}
+
+// ParseVars parses name=value pairs as given with --var.
+func ParseVars(pairs []string) (map[string]string, error) {
+	vars := make(map[string]string, len(pairs))
+	for _, pair := range pairs {
+		name, value, ok := strings.Cut(pair, "=")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" {
+			return nil, fmt.Errorf("invalid variable %q (use name=value)", pair)
+		}
+		vars[name] = value
+	}
+	return vars, nil
+}
+
+// splitList splits a list separated by commas or semicolons, dropping
+// empty items.
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.FieldsFunc(s, func(c rune) bool { return c == ',' || c == ';' }) {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// unquote removes double quotes around a front matter value.
+func unquote(s string) string {
+	if len(s) >= 2 && s[0] == '"' && s[len(s)-1] == '"' {
+		if u, err := strconv.Unquote(s); err == nil {
+			return u
+		}
+	}
+	return s
+}
@@ -0,0 +1,177 @@
+package templates
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const incident = `---
+# Sent by the on-call bot
+description: Incident notification
+subject: "[{{upper .severity}}] {{.service}} is down"
+to: oncall@example.com
+cc: {{.owner}}; {{.watchers}}
+vars: service, owner, severity=sev3, watchers=
+---
+{{.service}} is down.
+{{define "html"}}<p>{{.service}} is down.</p>{{end}}
+`
+
+func TestParse(t *testing.T) {
+	tmpl, err := Parse("incident", incident)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	if tmpl.Description != "Incident notification" {
+		t.Errorf("Description = %q", tmpl.Description)
+	}
+	if got := tmpl.Variables(); !reflect.DeepEqual(got, []string{"owner", "service", "severity", "watchers"}) {
+		t.Errorf("Variables() = %v", got)
+	}
+	if got := tmpl.Required(); !reflect.DeepEqual(got, []string{"owner", "service"}) {
+		t.Errorf("Required() = %v", got)
+	}
+	if got := tmpl.Missing(map[string]string{"service": "api"}); !reflect.DeepEqual(got, []string{"owner"}) {
+		t.Errorf("Missing() = %v", got)
+	}
+}
+
+func TestRender(t *testing.T) {
+	tmpl, err := Parse("incident", incident)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	if _, err := tmpl.Render(map[string]string{"service": "api"}); !errors.Is(err, ErrMissingVariables) || !strings.Contains(err.Error(), "owner") {
+		t.Errorf("Render() error = %v, want missing owner", err)
+	}
+
+	msg, err := tmpl.Render(map[string]string{"service": "api", "owner": "ann@example.com"})
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	if msg.Subject != "[SEV3] api is down" {
+		t.Errorf("Subject = %q", msg.Subject)
+	}
+	if !reflect.DeepEqual(msg.To, []string{"oncall@example.com"}) {
+		t.Errorf("To = %v", msg.To)
+	}
+	if !reflect.DeepEqual(msg.CC, []string{"ann@example.com"}) {
+		t.Errorf("CC = %v, want the empty default dropped", msg.CC)
+	}
+	if msg.Text != "api is down.\n" || msg.HTML != "<p>api is down.</p>" {
+		t.Errorf("Text = %q, HTML = %q", msg.Text, msg.HTML)
+	}
+}
+
+func TestRenderDefaultFunc(t *testing.T) {
+	tmpl, err := Parse("note", `---
+subject: {{.topic | default "Update"}}
+cc: {{default "team@example.com" .cc}}
+vars: owner
+---
+Hi {{.owner}}, {{.note | default "nothing new"}}.
+`)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if got := tmpl.Required(); !reflect.DeepEqual(got, []string{"owner"}) {
+		t.Errorf("Required() = %v", got)
+	}
+
+	msg, err := tmpl.Render(map[string]string{"owner": "Ann"})
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	if msg.Subject != "Update" || msg.Text != "Hi Ann, nothing new.\n" {
+		t.Errorf("Subject = %q, Text = %q", msg.Subject, msg.Text)
+	}
+	if !reflect.DeepEqual(msg.CC, []string{"team@example.com"}) {
+		t.Errorf("CC = %v", msg.CC)
+	}
+
+	msg, err = tmpl.Render(map[string]string{"owner": "Ann", "topic": "Release", "note": "we shipped"})
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	if msg.Subject != "Release" || msg.Text != "Hi Ann, we shipped.\n" {
+		t.Errorf("Subject = %q, Text = %q", msg.Subject, msg.Text)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+	}{
+		{"unclosed front matter", "---\nsubject: x\nbody"},
+		{"unknown key", "---\nsubjet: x\n---\nbody"},
+		{"missing colon", "---\nsubject\n---\nbody"},
+		{"no subject", "---\nto: a@example.com\n---\nbody"},
+		{"invalid to", "---\nsubject: x\nto: {{.a\n---\nbody"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Parse("t", tt.data); err == nil {
+				t.Errorf("Parse() expected an error")
+			}
+		})
+	}
+}
+
+func TestLoadAndList(t *testing.T) {
+	dir := t.TempDir()
+	for name, data := range map[string]string{
+		"b.tmpl":     "---\nsubject: B\n---\nb",
+		"a.tmpl":     "---\nsubject: A\n---\na",
+		"notes.txt":  "not a template",
+		".hidden.md": "ignored",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	list, err := List(dir)
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(list) != 2 || list[0].Name != "a" || list[1].Name != "b" {
+		t.Errorf("List() returned %d templates, want a and b", len(list))
+	}
+
+	if _, err := Load(dir, "missing"); err == nil {
+		t.Error("Load() should fail for a missing template")
+	}
+	if _, err := Load(dir, "../a"); err == nil {
+		t.Error("Load() should reject names with path separators")
+	}
+
+	list, err = List(filepath.Join(dir, "none"))
+	if err != nil || len(list) != 0 {
+		t.Errorf("List() of a missing directory = %v, %v", list, err)
+	}
+}
+
+func TestParseVars(t *testing.T) {
+	vars, err := ParseVars([]string{"a=1", "b=x=y", "c="})
+	if err != nil {
+		t.Fatalf("ParseVars() error = %v", err)
+	}
+	want := map[string]string{"a": "1", "b": "x=y", "c": ""}
+	if !reflect.DeepEqual(vars, want) {
+		t.Errorf("ParseVars() = %v, want %v", vars, want)
+	}
+
+	for _, bad := range []string{"novalue", "=x"} {
+		if _, err := ParseVars([]string{bad}); err == nil {
+			t.Errorf("ParseVars(%q) expected an error", bad)
+		}
+	}
+}
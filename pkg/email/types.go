@@ -7,19 +7,24 @@ import (
 
 // Message represents an email message.
 type Message struct {
-	UID         uint32       `json:"uid,omitempty"`
-	SeqNum      uint32       `json:"seq_num,omitempty"`
-	MessageID   string       `json:"message_id,omitempty"`
-	Subject     string       `json:"subject"`
-	From        string       `json:"from"`
-	To          []string     `json:"to"`
-	CC          []string     `json:"cc,omitempty"`
-	BCC         []string     `json:"bcc,omitempty"`
-	Date        time.Time    `json:"date"`
-	Body        string       `json:"body,omitempty"`
-	BodyPreview string       `json:"body_preview,omitempty"`
-	Attachments []Attachment `json:"attachments,omitempty"`
-	Flags       []string     `json:"flags,omitempty"`
+	UID         uint32          `json:"uid,omitempty"`
+	SeqNum      uint32          `json:"seq_num,omitempty"`
+	MessageID   string          `json:"message_id,omitempty"`
+	Subject     string          `json:"subject"`
+	From        string          `json:"from"`
+	To          []string        `json:"to"`
+	CC          []string        `json:"cc,omitempty"`
+	BCC         []string        `json:"bcc,omitempty"`
+	Date        time.Time       `json:"date"`
+	Body        string          `json:"body,omitempty"`
+	BodyPreview string          `json:"body_preview,omitempty"`
+	BodyNew     string          `json:"body_new,omitempty"`
+	BodyQuoted  string          `json:"body_quoted,omitempty"`
+	Signature   string          `json:"signature,omitempty"`
+	Attachments []Attachment    `json:"attachments,omitempty"`
+	Events      []CalendarEvent `json:"events,omitempty"`
+	Security    *Security       `json:"security,omitempty"`
+	Flags       []string        `json:"flags,omitempty"`
 }
 
 // Attachment represents an email attachment.
@@ -29,6 +34,66 @@ type Attachment struct {
 	Size        int    `json:"size"`
 }
 
+// Security describes the encryption and signature of a message.
+type Security struct {
+	Protocol          string       `json:"protocol"` // "openpgp" or "smime"
+	Encrypted         bool         `json:"encrypted"`
+	Decrypted         bool         `json:"decrypted"`
+	Signed            bool         `json:"signed"`
+	SignatureStatus   string       `json:"signature_status,omitempty"` // good, bad, unknown_key, untrusted or mismatch
+	SignerKeyID       string       `json:"signer_key_id,omitempty"`
+	SignerFingerprint string       `json:"signer_fingerprint,omitempty"`
+	Signer            string       `json:"signer,omitempty"`      // User ID or certificate subject of the signer
+	Certificate       *Certificate `json:"certificate,omitempty"` // S/MIME signer certificate
+	Error             string       `json:"error,omitempty"`
+}
+
+// Signature verification results.
+const (
+	SignatureGood       = "good"
+	SignatureBad        = "bad"
+	SignatureUnknownKey = "unknown_key"
+	SignatureUntrusted  = "untrusted" // Valid, but the key or certificate is not trusted
+	SignatureMismatch   = "mismatch"  // Valid, but the signer is not the From address
+)
+
+// Certificate describes an X.509 certificate.
+type Certificate struct {
+	Subject      string    `json:"subject"`
+	Issuer       string    `json:"issuer"`
+	SerialNumber string    `json:"serial_number"`
+	Emails       []string  `json:"emails,omitempty"`
+	NotBefore    time.Time `json:"not_before"`
+	NotAfter     time.Time `json:"not_after"`
+}
+
+// CalendarEvent represents a meeting invitation parsed from a text/calendar part.
+type CalendarEvent struct {
+	Method      string             `json:"method,omitempty"`
+	UID         string             `json:"uid"`
+	Sequence    int                `json:"sequence,omitempty"`
+	Status      string             `json:"status,omitempty"`
+	Summary     string             `json:"summary,omitempty"`
+	Description string             `json:"description,omitempty"`
+	Location    string             `json:"location,omitempty"`
+	Organizer   string             `json:"organizer,omitempty"`
+	Attendees   []CalendarAttendee `json:"attendees,omitempty"`
+	Start       time.Time          `json:"start"`
+	End         time.Time          `json:"end"`
+	TimeZone    string             `json:"time_zone,omitempty"`
+	AllDay      bool               `json:"all_day,omitempty"`
+	Recurrence  []string           `json:"recurrence,omitempty"`
+}
+
+// CalendarAttendee represents an attendee of a calendar event.
+type CalendarAttendee struct {
+	Email  string `json:"email"`
+	Name   string `json:"name,omitempty"`
+	Role   string `json:"role,omitempty"`
+	Status string `json:"status,omitempty"`
+	RSVP   bool   `json:"rsvp,omitempty"`
+}
+
 // SendRequest represents a request to send an email.
 type SendRequest struct {
 	From        string            `json:"from"`
@@ -44,22 +109,226 @@ type SendRequest struct {
 
 // SendResponse represents the response from sending an email.
 type SendResponse struct {
-	Success bool   `json:"success"`
-	Message string `json:"message,omitempty"`
-	Error   string `json:"error,omitempty"`
+	Success       bool   `json:"success"`
+	Message       string `json:"message,omitempty"`
+	Error         string `json:"error,omitempty"`
+	MessageID     string `json:"message_id,omitempty"`
+	SavedTo       string `json:"saved_to,omitempty"`
+	Warning       string `json:"warning,omitempty"`
+	AuthMechanism string `json:"auth_mechanism,omitempty"` // SASL mechanism used with the SMTP server
+}
+
+// DryRunResponse represents a message built by send --dry-run, exactly as
+// it would be submitted.
+type DryRunResponse struct {
+	Success   bool     `json:"success"`
+	MessageID string   `json:"message_id,omitempty"`
+	Envelope  Envelope `json:"envelope"`
+	Size      int      `json:"size"`             // Size of the message in bytes
+	Output    string   `json:"output,omitempty"` // File the message was written to
+	Data      string   `json:"data,omitempty"`   // The message, unless written to a file
+	Error     string   `json:"error,omitempty"`
+}
+
+// Envelope holds the SMTP envelope of a message.
+type Envelope struct {
+	From       string   `json:"from"`
+	Recipients []string `json:"recipients"`
 }
 
 // InboxResponse represents the response for inbox listing.
 type InboxResponse struct {
-	Success  bool      `json:"success"`
-	Messages []Message `json:"messages,omitempty"`
-	Total    int       `json:"total"`
-	Error    string    `json:"error,omitempty"`
+	Success       bool      `json:"success"`
+	Messages      []Message `json:"messages,omitempty"`
+	Total         int       `json:"total"`
+	AuthMechanism string    `json:"auth_mechanism,omitempty"` // SASL mechanism used with the IMAP server
+	Error         string    `json:"error,omitempty"`
 }
 
 // ReadResponse represents the response for reading an email.
 type ReadResponse struct {
-	Success bool    `json:"success"`
-	Message Message `json:"message,omitempty"`
-	Error   string  `json:"error,omitempty"`
+	Success  bool     `json:"success"`
+	Message  Message  `json:"message,omitempty"`
+	Warnings []string `json:"warnings,omitempty"`
+	Error    string   `json:"error,omitempty"`
+}
+
+// QueueEntry represents a message waiting in the local spool.
+type QueueEntry struct {
+	ID        string    `json:"id"`
+	Status    string    `json:"status"`
+	SendAt    time.Time `json:"send_at"`
+	CreatedAt time.Time `json:"created_at"`
+	From      string    `json:"from"`
+	To        []string  `json:"to"`
+	Subject   string    `json:"subject"`
+	SaveSent  bool      `json:"save_sent"`
+	Attempts  int       `json:"attempts,omitempty"`
+	Error     string    `json:"error,omitempty"`
+
+	// ClaimedAt is when a run started sending the message
+	ClaimedAt *time.Time `json:"claimed_at,omitempty"`
+	// NoFutureRelease is set once the server could not hold the message
+	// with SMTP FUTURERELEASE, so it is sent from the spool when due
+	NoFutureRelease bool `json:"no_future_release,omitempty"`
+}
+
+// QueueResponse represents the response for listing queued messages.
+type QueueResponse struct {
+	Success bool         `json:"success"`
+	Entries []QueueEntry `json:"entries,omitempty"`
+	Total   int          `json:"total"`
+	Error   string       `json:"error,omitempty"`
+}
+
+// QueueRunResponse represents the response for delivering queued messages.
+type QueueRunResponse struct {
+	Success  bool         `json:"success"`
+	Sent     []QueueEntry `json:"sent,omitempty"`
+	Held     []QueueEntry `json:"held_by_server,omitempty"`
+	Failed   []QueueEntry `json:"failed,omitempty"`
+	Dead     []QueueEntry `json:"dead_letter,omitempty"`
+	Warnings []string     `json:"warnings,omitempty"`
+	Error    string       `json:"error,omitempty"`
+}
+
+// MergeResult represents the outcome of one recipient in a mail merge.
+type MergeResult struct {
+	Row       int      `json:"row"`
+	To        []string `json:"to"`
+	Subject   string   `json:"subject,omitempty"`
+	Status    string   `json:"status"`
+	MessageID string   `json:"message_id,omitempty"`
+	File      string   `json:"file,omitempty"`
+	Error     string   `json:"error,omitempty"`
+	Warning   string   `json:"warning,omitempty"`
+}
+
+// MergeResponse represents the response for a mail merge.
+type MergeResponse struct {
+	Success  bool   `json:"success"`
+	Total    int    `json:"total"`
+	Sent     int    `json:"sent"`
+	Rendered int    `json:"rendered,omitempty"`
+	Failed   int    `json:"failed"`
+	Log      string `json:"log"`
+	Error    string `json:"error,omitempty"`
+}
+
+// TemplateInfo describes a stored message template.
+type TemplateInfo struct {
+	Name        string            `json:"name"`
+	Path        string            `json:"path"`
+	Description string            `json:"description,omitempty"`
+	Subject     string            `json:"subject"`
+	Variables   []string          `json:"variables"`
+	Required    []string          `json:"required"`
+	Defaults    map[string]string `json:"defaults,omitempty"`
+}
+
+// TemplateListResponse represents the response for listing templates.
+type TemplateListResponse struct {
+	Success   bool           `json:"success"`
+	Templates []TemplateInfo `json:"templates,omitempty"`
+	Total     int            `json:"total"`
+	Error     string         `json:"error,omitempty"`
+}
+
+// TemplateShowResponse represents the response for showing a template.
+type TemplateShowResponse struct {
+	Success  bool         `json:"success"`
+	Template TemplateInfo `json:"template"`
+	Source   string       `json:"source"`
+	Error    string       `json:"error,omitempty"`
+}
+
+// TemplateRenderResponse represents a template rendered with variables.
+type TemplateRenderResponse struct {
+	Success     bool     `json:"success"`
+	Subject     string   `json:"subject"`
+	To          []string `json:"to,omitempty"`
+	CC          []string `json:"cc,omitempty"`
+	BCC         []string `json:"bcc,omitempty"`
+	Attachments []string `json:"attachments,omitempty"`
+	Text        string   `json:"text,omitempty"`
+	HTML        string   `json:"html,omitempty"`
+	Error       string   `json:"error,omitempty"`
+}
+
+// DKIMKeygenResponse represents a generated DKIM key and its DNS record.
+type DKIMKeygenResponse struct {
+	Success bool   `json:"success"`
+	KeyFile string `json:"key_file"`
+	KeyType string `json:"key_type"`
+	Name    string `json:"name"`  // DNS name of the TXT record
+	Value   string `json:"value"` // TXT record value
+	Error   string `json:"error,omitempty"`
+}
+
+// ConfigSetting is an effective configuration setting, named by its
+// environment variable, with secrets masked.
+type ConfigSetting struct {
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	Source string `json:"source"` // environment, config file, default, or the file or command of a secret
+}
+
+// ConfigShowResponse represents the configuration file, its accounts and,
+// with --effective, the merged settings of the selected account.
+type ConfigShowResponse struct {
+	Success  bool            `json:"success"`
+	File     string          `json:"file,omitempty"`
+	Account  string          `json:"account,omitempty"`
+	Accounts []string        `json:"accounts"`
+	Settings []ConfigSetting `json:"settings,omitempty"`
+	Error    string          `json:"error,omitempty"`
+}
+
+// Results of a diagnostic check.
+const (
+	CheckOK      = "ok"
+	CheckWarning = "warning"
+	CheckFailed  = "failed"
+	CheckSkipped = "skipped"
+)
+
+// DiagnosticCheck is the result of one step of connecting to a server.
+type DiagnosticCheck struct {
+	Name       string `json:"name"`
+	Status     string `json:"status"` // ok, warning, failed or skipped
+	Detail     string `json:"detail,omitempty"`
+	Hint       string `json:"hint,omitempty"` // What to change when the check did not pass
+	DurationMS int64  `json:"duration_ms,omitempty"`
+}
+
+// TLSInfo describes the TLS connection to a server.
+type TLSInfo struct {
+	Mode        string        `json:"mode"` // implicit or starttls
+	Version     string        `json:"version"`
+	CipherSuite string        `json:"cipher_suite"`
+	Verified    bool          `json:"verified"`
+	Chain       []Certificate `json:"chain"` // As sent by the server, leaf first
+}
+
+// ServerDiagnosis is the result of diagnosing the connection to the SMTP or
+// IMAP server.
+type ServerDiagnosis struct {
+	Protocol     string            `json:"protocol"`
+	Host         string            `json:"host"`
+	Port         int               `json:"port"`
+	Addresses    []string          `json:"addresses,omitempty"`
+	TLS          *TLSInfo          `json:"tls,omitempty"`
+	Capabilities []string          `json:"capabilities,omitempty"`
+	Mechanism    string            `json:"mechanism,omitempty"`
+	Checks       []DiagnosticCheck `json:"checks"`
+	OK           bool              `json:"ok"` // No check failed
+}
+
+// DoctorResponse represents the diagnosis of the configured servers.
+type DoctorResponse struct {
+	Success bool              `json:"success"` // No check failed on any server
+	Account string            `json:"account,omitempty"`
+	Checks  []DiagnosticCheck `json:"checks,omitempty"` // Checks not tied to a server, such as loading the configuration
+	Servers []ServerDiagnosis `json:"servers"`
+	Error   string            `json:"error,omitempty"`
 }